@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"bookstore-api/internal/config"
+	"bookstore-api/internal/events"
+	"bookstore-api/internal/handlers"
+	"bookstore-api/internal/middleware"
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/notifications"
+	"bookstore-api/internal/openapi"
+	"bookstore-api/internal/pricing"
+	"bookstore-api/internal/repositories"
+	"bookstore-api/internal/services"
+	"bookstore-api/internal/webhooks"
+
+	"gorm.io/gorm"
+)
+
+// app holds the HTTP handler wired over one database, along with the
+// components the gRPC server and background workers share with it.
+type app struct {
+	handler     http.Handler
+	books       services.BookService
+	tenants     services.TenantService
+	idempotency services.IdempotencyService
+	authn       *middleware.Authenticator
+	priceRepo   repositories.PriceRepository
+	webhookRepo repositories.WebhookRepository
+}
+
+// newApp wires the repositories, services and handlers over db and
+// registers every HTTP route.
+func newApp(cfg *config.Config, db *gorm.DB) (*app, error) {
+	// Initialize layers
+	bookRepo := repositories.NewGormBookRepository(db, webhooks.RecordOutbox, pricing.RecordHistory, notifications.RecordPriceDrops)
+	categoryRepo := repositories.NewGormCategoryRepository(db)
+	hub := events.NewHub(cfg.Events.ReplayBuffer)
+	bookService := services.NewBookService(bookRepo, categoryRepo, hub)
+	categoryHandler := handlers.NewCategoryHandler(services.NewCategoryService(categoryRepo))
+	webhookRepo := repositories.NewGormWebhookRepository(db)
+	webhookService := services.NewWebhookService(webhookRepo, cfg.Webhooks.AllowPrivateTargets)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	bookHandler := handlers.NewBookHandler(bookService)
+	eventsHandler := handlers.NewEventsHandler(hub, cfg.Events.HeartbeatInterval)
+	priceRepo := repositories.NewGormPriceRepository(db)
+	pricingHandler := handlers.NewPricingHandler(services.NewPricingService(bookRepo, priceRepo))
+	listHandler := handlers.NewListHandler(services.NewListService(repositories.NewGormListRepository(db), bookRepo))
+	notificationHandler := handlers.NewNotificationHandler(services.NewNotificationService(repositories.NewGormNotificationRepository(db)))
+	authHandler := handlers.NewAuthHandler(cfg.Auth.JWTSecret, cfg.Auth.TokenTTL)
+	tenantRepo := repositories.NewGormTenantRepository(db)
+	tenantService := services.NewTenantService(tenantRepo)
+	tenantHandler := handlers.NewTenantHandler(tenantService)
+	apiKeyService := services.NewAPIKeyService(repositories.NewGormAPIKeyRepository(db), tenantRepo)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	idempotencyService := services.NewIdempotencyService(repositories.NewGormIdempotencyRepository(db), cfg.Idempotency.TTL)
+	authn := middleware.NewAuthenticator(cfg.Auth.JWTSecret, cfg.Auth.AdminSubjects, apiKeyService)
+	graphQLHandler, err := handlers.NewGraphQLHandler(bookService, services.NewReviewService(repositories.NewGormReviewRepository(db), bookRepo))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build GraphQL schema: %w", err)
+	}
+
+	// Setup router
+	mux := http.NewServeMux()
+
+	// Auth middleware: authenticate with an API key, JWT or client
+	// certificate, require the scope the route needs, then replay retried
+	// writes that carry an Idempotency-Key
+	authMiddleware := middleware.Auth(authn)
+	idempotent := middleware.Idempotency(idempotencyService)
+	requireScope := func(scope string, h http.HandlerFunc) http.Handler {
+		return authMiddleware(middleware.RequireScope(scope)(idempotent(h)))
+	}
+
+	// API documentation
+	mux.HandleFunc("GET /openapi.json", openapi.Handler)
+
+	// Auth routes (public - for testing, disable with auth.token_endpoint)
+	if cfg.Auth.TokenEndpoint {
+		mux.HandleFunc("POST /auth/token", authHandler.GenerateToken)
+	}
+
+	// Public routes (no auth required)
+	mux.HandleFunc("GET /books", bookHandler.GetAllBooks)
+	mux.HandleFunc("GET /books/{id}", bookHandler.GetBookByID)
+	mux.HandleFunc("GET /books/{id}/prices", pricingHandler.GetPriceHistory)
+	mux.HandleFunc("GET /categories", categoryHandler.GetCategories)
+	mux.HandleFunc("GET /categories/{id}", categoryHandler.GetCategory)
+	mux.HandleFunc("GET /tags", bookHandler.GetTags)
+	mux.HandleFunc("GET /shared/lists/{token}", listHandler.GetSharedList)
+
+	// Protected routes (auth required)
+	mux.Handle("POST /books", requireScope(models.ScopeBooksWrite, bookHandler.CreateBook))
+	mux.Handle("PUT /books/{id}", requireScope(models.ScopeBooksWrite, bookHandler.UpdateBook))
+	mux.Handle("DELETE /books/{id}", requireScope(models.ScopeBooksWrite, bookHandler.DeleteBook))
+	mux.Handle("GET /books/events", requireScope(models.ScopeBooksRead, eventsHandler.StreamBookEvents))
+	mux.Handle("POST /books/{id}/price-schedules", requireScope(models.ScopeBooksWrite, pricingHandler.CreatePriceSchedule))
+	mux.Handle("GET /books/{id}/price-schedules", requireScope(models.ScopeBooksRead, pricingHandler.GetPriceSchedules))
+	mux.Handle("DELETE /books/{id}/price-schedules/{scheduleId}", requireScope(models.ScopeBooksWrite, pricingHandler.CancelPriceSchedule))
+	mux.Handle("POST /categories", requireScope(models.ScopeBooksWrite, categoryHandler.CreateCategory))
+	mux.Handle("PUT /categories/{id}", requireScope(models.ScopeBooksWrite, categoryHandler.UpdateCategory))
+	mux.Handle("DELETE /categories/{id}", requireScope(models.ScopeBooksWrite, categoryHandler.DeleteCategory))
+
+	// Webhook routes (auth required)
+	mux.Handle("POST /webhooks", requireScope(models.ScopeWebhooks, webhookHandler.CreateSubscription))
+	mux.Handle("GET /webhooks", requireScope(models.ScopeWebhooks, webhookHandler.GetSubscriptions))
+	mux.Handle("DELETE /webhooks/{id}", requireScope(models.ScopeWebhooks, webhookHandler.DeleteSubscription))
+	mux.Handle("GET /webhooks/{id}/deliveries", requireScope(models.ScopeWebhooks, webhookHandler.GetDeliveries))
+	mux.Handle("POST /webhooks/deliveries/{id}/replay", requireScope(models.ScopeWebhooks, webhookHandler.ReplayDelivery))
+
+	// Reading list and notification routes (users only)
+	mux.Handle("POST /lists", requireScope(models.ScopeLists, listHandler.CreateList))
+	mux.Handle("GET /lists", requireScope(models.ScopeLists, listHandler.GetLists))
+	mux.Handle("GET /lists/{id}", requireScope(models.ScopeLists, listHandler.GetList))
+	mux.Handle("PUT /lists/{id}", requireScope(models.ScopeLists, listHandler.UpdateList))
+	mux.Handle("DELETE /lists/{id}", requireScope(models.ScopeLists, listHandler.DeleteList))
+	mux.Handle("POST /lists/{id}/entries", requireScope(models.ScopeLists, listHandler.AddEntry))
+	mux.Handle("PUT /lists/{id}/entries", requireScope(models.ScopeLists, listHandler.ReorderEntries))
+	mux.Handle("DELETE /lists/{id}/entries/{bookId}", requireScope(models.ScopeLists, listHandler.RemoveEntry))
+	mux.Handle("POST /lists/{id}/share", requireScope(models.ScopeLists, listHandler.ShareList))
+	mux.Handle("DELETE /lists/{id}/share", requireScope(models.ScopeLists, listHandler.UnshareList))
+	mux.Handle("GET /notifications", requireScope(models.ScopeLists, notificationHandler.GetNotifications))
+	mux.Handle("POST /notifications/{id}/read", requireScope(models.ScopeLists, notificationHandler.MarkNotificationRead))
+
+	// API key administration (admin scope required)
+	mux.Handle("POST /admin/api-keys", requireScope(models.ScopeAdmin, apiKeyHandler.CreateAPIKey))
+	mux.Handle("GET /admin/api-keys", requireScope(models.ScopeAdmin, apiKeyHandler.GetAPIKeys))
+	mux.Handle("DELETE /admin/api-keys/{id}", requireScope(models.ScopeAdmin, apiKeyHandler.RevokeAPIKey))
+
+	// Tenant administration (admin scope required)
+	mux.Handle("POST /admin/tenants", requireScope(models.ScopeAdmin, tenantHandler.CreateTenant))
+	mux.Handle("GET /admin/tenants", requireScope(models.ScopeAdmin, tenantHandler.GetTenants))
+	mux.Handle("GET /admin/tenants/{id}", requireScope(models.ScopeAdmin, tenantHandler.GetTenant))
+	mux.Handle("PUT /admin/tenants/{id}", requireScope(models.ScopeAdmin, tenantHandler.UpdateTenant))
+	mux.Handle("DELETE /admin/tenants/{id}", requireScope(models.ScopeAdmin, tenantHandler.DeleteTenant))
+
+	// GraphQL (queries are public, book mutations require the books:write
+	// scope and addReview the reviews scope)
+	mux.Handle("POST /graphql", middleware.OptionalAuth(authn)(http.HandlerFunc(graphQLHandler.ServeGraphQL)))
+
+	// Select the tenant from the request host, then log
+	handler := middleware.Logging(middleware.Tenant(tenantService)(mux))
+
+	return &app{
+		handler:     handler,
+		books:       bookService,
+		tenants:     tenantService,
+		idempotency: idempotencyService,
+		authn:       authn,
+		priceRepo:   priceRepo,
+		webhookRepo: webhookRepo,
+	}, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"bookstore-api/internal/models"
+)
+
+func TestTokenEndpointTokensGetNoAdminOrLists(t *testing.T) {
+	ts := newTestServer(t)
+
+	var issued struct {
+		Token string `json:"token"`
+	}
+	ts.mustDo(t, http.MethodPost, "/auth/token", map[string]string{"username": testAdmin}, http.StatusOK, &issued)
+	selfIssued := withAuth("Bearer " + issued.Token)
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/admin/tenants"},
+		{http.MethodPost, "/admin/tenants"},
+		{http.MethodGet, "/admin/api-keys"},
+		{http.MethodPost, "/admin/api-keys"},
+		{http.MethodGet, "/lists"},
+		{http.MethodPost, "/lists"},
+		{http.MethodGet, "/notifications"},
+	} {
+		resp := ts.do(t, route.method, route.path, map[string]string{}, selfIssued)
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s %s with a token from /auth/token: status %d, want 403", route.method, route.path, resp.StatusCode)
+		}
+		assertEnvelope(t, resp, "Insufficient scope")
+	}
+
+	// The token still works for the catalog, and the subject keeps lists
+	// and admin access with a token the endpoint did not issue.
+	book := map[string]interface{}{"title": "Dune", "author": "Frank Herbert", "isbn": "9780441013593", "price": 9.99}
+	ts.mustDo(t, http.MethodPost, "/books", book, http.StatusCreated, nil, selfIssued)
+	ts.mustDo(t, http.MethodGet, "/lists", nil, http.StatusOK, nil, withAuth(userToken(t, testAdmin)))
+	ts.mustDo(t, http.MethodGet, "/admin/tenants", nil, http.StatusOK, nil, withAuth(userToken(t, testAdmin)))
+}
+
+func TestTokenEndpointBindsTokensToTheHostTenant(t *testing.T) {
+	ts := newTestServer(t)
+	admin := withAuth(userToken(t, testAdmin))
+	const domain = "store-b.example.com"
+	ts.mustDo(t, http.MethodPost, "/admin/tenants", map[string]interface{}{"slug": "store-b", "name": "Store B", "domain": domain}, http.StatusCreated, nil, admin)
+	storeB := func(r *http.Request) { r.Host = domain }
+
+	// A tenant_id in the request is ignored; the token is bound to store B.
+	var issued struct {
+		Token string `json:"token"`
+	}
+	ts.mustDo(t, http.MethodPost, "/auth/token", map[string]interface{}{"username": "bob", "tenant_id": models.DefaultTenantID}, http.StatusOK, &issued, storeB)
+	book := map[string]interface{}{"title": "Dune", "author": "Frank Herbert", "isbn": "9780441013593", "price": 9.99}
+	ts.mustDo(t, http.MethodPost, "/books", book, http.StatusCreated, nil, withAuth("Bearer "+issued.Token))
+
+	var books []models.Book
+	ts.mustDo(t, http.MethodGet, "/books", nil, http.StatusOK, &books, storeB)
+	if len(books) != 1 {
+		t.Errorf("store B has %d books, want the one created with its token", len(books))
+	}
+	ts.mustDo(t, http.MethodGet, "/books", nil, http.StatusOK, &books)
+	if len(books) != 0 {
+		t.Errorf("default tenant has %d books, want none", len(books))
+	}
+
+	// Unbound credentials cannot write to another tenant's catalog.
+	resp := ts.do(t, http.MethodPost, "/books", book, withAuth(userToken(t, "mallory")), storeB)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("unbound token on store B: status %d, want 403", resp.StatusCode)
+	}
+	assertEnvelope(t, resp, "Credentials are not valid for this tenant")
+}
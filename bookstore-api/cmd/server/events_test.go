@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"bookstore-api/internal/models"
+)
+
+// sseEvent is one event read from a Server-Sent Events stream.
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// openStream opens GET /books/events with the reader API key, sending
+// lastEventID when it is set. The stream is closed when the test finishes.
+func (ts *testServer) openStream(t *testing.T, f fixtures, lastEventID string) *bufio.Reader {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/books/events", nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("X-API-Key", f.readerKey)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET /books/events: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+// readEvent reads the next event from a stream, skipping comments.
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var event sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && event.event != "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestBookEventsStream(t *testing.T) {
+	ts := newTestServer(t)
+	f := ts.seed(t)
+	user := withAuth(userToken(t, "alice"))
+
+	stream := ts.openStream(t, f, "")
+	ts.mustDo(t, http.MethodPut, fmt.Sprintf("/books/%d", f.book), map[string]interface{}{
+		"title": "Dune", "author": "Frank Herbert", "isbn": "9780441013593", "price": 12.5,
+	}, http.StatusOK, nil, user)
+	ts.mustDo(t, http.MethodDelete, fmt.Sprintf("/books/%d", f.book), nil, http.StatusNoContent, nil, user)
+
+	updated := readEvent(t, stream)
+	if updated.event != models.EventBookUpdated {
+		t.Fatalf("event = %q, want %q", updated.event, models.EventBookUpdated)
+	}
+	var payload struct {
+		ID   uint64      `json:"id"`
+		Type string      `json:"type"`
+		Book models.Book `json:"book"`
+	}
+	if err := json.Unmarshal([]byte(updated.data), &payload); err != nil {
+		t.Fatalf("decode data: %v", err)
+	}
+	if fmt.Sprint(payload.ID) != updated.id || payload.Book.ID != f.book || payload.Book.Price != 12.5 {
+		t.Errorf("data = %s, want book %d at 12.5 with id %s", updated.data, f.book, updated.id)
+	}
+	if deleted := readEvent(t, stream); deleted.event != models.EventBookDeleted {
+		t.Errorf("event = %q, want %q", deleted.event, models.EventBookDeleted)
+	}
+}
+
+func TestBookEventsResume(t *testing.T) {
+	ts := newTestServer(t)
+	f := ts.seed(t)
+
+	// Seeding published the book's creation and price drop as events 1
+	// and 2.
+	ts.mustDo(t, http.MethodDelete, fmt.Sprintf("/books/%d", f.book), nil, http.StatusNoContent, nil, withAuth(userToken(t, "alice")))
+
+	replayed := readEvent(t, ts.openStream(t, f, "2"))
+	if replayed.event != models.EventBookDeleted || replayed.id != "3" {
+		t.Errorf("replayed %s %q, want id 3 %q", replayed.id, replayed.event, models.EventBookDeleted)
+	}
+
+	// IDs beyond the latest, as after a server restart, are reset.
+	if reset := readEvent(t, ts.openStream(t, f, "99")); reset.event != "reset" || reset.id != "3" {
+		t.Errorf("got %s %q, want reset at id 3", reset.id, reset.event)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// graphQLResult is the body of a GraphQL response.
+type graphQLResult struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// errorMessages joins the messages of the result's errors.
+func (r graphQLResult) errorMessages() string {
+	messages := make([]string, len(r.Errors))
+	for i, e := range r.Errors {
+		messages[i] = e.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// graphQL posts a query with its variables and decodes the response.
+func (ts *testServer) graphQL(t *testing.T, query string, variables map[string]interface{}, opts ...requestOption) graphQLResult {
+	t.Helper()
+	var result graphQLResult
+	body := map[string]interface{}{"query": query, "variables": variables}
+	ts.mustDo(t, http.MethodPost, "/graphql", body, http.StatusOK, &result, opts...)
+	return result
+}
+
+// field decodes one field of the result's data, failing the test if the
+// result has errors.
+func (r graphQLResult) field(t *testing.T, name string, out interface{}) {
+	t.Helper()
+	if len(r.Errors) > 0 {
+		t.Fatalf("unexpected errors: %s", r.errorMessages())
+	}
+	if err := json.Unmarshal(r.Data[name], out); err != nil {
+		t.Fatalf("decode %s: %v", name, err)
+	}
+}
+
+const addReviewMutation = `mutation($book: Int!, $rating: Int!, $comment: String) {
+	addReview(bookId: $book, rating: $rating, comment: $comment) { id reviewer rating comment }
+}`
+
+type graphQLReview struct {
+	Reviewer string `json:"reviewer"`
+	Rating   int    `json:"rating"`
+	Comment  string `json:"comment"`
+}
+
+func TestGraphQLBooksWithReviews(t *testing.T) {
+	ts := newTestServer(t)
+	f := ts.seed(t)
+	alice := withAuth(userToken(t, "alice"))
+	bob := withAuth(userToken(t, "bob"))
+
+	var second struct{ ID uint }
+	ts.mustDo(t, http.MethodPost, "/books", map[string]interface{}{"title": "Emma", "author": "Jane Austen", "isbn": "9780141439587", "price": 5}, http.StatusCreated, &second, alice)
+
+	var review graphQLReview
+	ts.graphQL(t, addReviewMutation, map[string]interface{}{"book": f.book, "rating": 5, "comment": " A classic. "}, alice).field(t, "addReview", &review)
+	if review != (graphQLReview{Reviewer: "alice", Rating: 5, Comment: "A classic."}) {
+		t.Errorf("added review = %+v", review)
+	}
+	ts.graphQL(t, addReviewMutation, map[string]interface{}{"book": f.book, "rating": 3}, bob).field(t, "addReview", &review)
+
+	var page struct {
+		Items []struct {
+			ID      uint            `json:"id"`
+			Reviews []graphQLReview `json:"reviews"`
+		} `json:"items"`
+		TotalCount  int  `json:"totalCount"`
+		HasNextPage bool `json:"hasNextPage"`
+	}
+	ts.graphQL(t, `{ books(limit: 10) { items { id reviews { reviewer rating comment } } totalCount hasNextPage } }`, nil).field(t, "books", &page)
+	if page.TotalCount != 2 || page.HasNextPage || len(page.Items) != 2 {
+		t.Fatalf("page = %+v, want both books", page)
+	}
+	reviews := map[uint]string{}
+	for _, item := range page.Items {
+		reviews[item.ID] = fmt.Sprint(item.Reviews)
+	}
+	if got, want := reviews[f.book], "[{alice 5 A classic.} {bob 3 }]"; got != want {
+		t.Errorf("reviews of book %d = %s, want %s", f.book, got, want)
+	}
+	if got := reviews[second.ID]; got != "[]" {
+		t.Errorf("reviews of book %d = %s, want none", second.ID, got)
+	}
+
+	tests := []struct {
+		name      string
+		variables map[string]interface{}
+		opts      []requestOption
+		wantError string
+	}{
+		{"anonymous", map[string]interface{}{"book": f.book, "rating": 4}, nil, "credentials with the reviews scope are required"},
+		{"api key", map[string]interface{}{"book": f.book, "rating": 4}, []requestOption{withAPIKey(f.readerKey)}, "credentials with the reviews scope are required"},
+		{"second review", map[string]interface{}{"book": f.book, "rating": 4}, []requestOption{alice}, "already been reviewed"},
+		{"rating out of range", map[string]interface{}{"book": second.ID, "rating": 6}, []requestOption{alice}, "rating must be between 1 and 5"},
+		{"missing book", map[string]interface{}{"book": 9999, "rating": 4}, []requestOption{alice}, "book 9999 does not exist"},
+		{"negative book ID", map[string]interface{}{"book": -1, "rating": 4}, []requestOption{alice}, "invalid book ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ts.graphQL(t, addReviewMutation, tt.variables, tt.opts...)
+			if got := result.errorMessages(); !strings.Contains(got, tt.wantError) {
+				t.Errorf("errors = %q, want one containing %q", got, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestGraphQLBookLookup(t *testing.T) {
+	ts := newTestServer(t)
+	f := ts.seed(t)
+
+	var book struct {
+		Title string   `json:"title"`
+		Tags  []string `json:"tags"`
+	}
+	ts.graphQL(t, `query($id: Int!) { book(id: $id) { title tags } }`, map[string]interface{}{"id": f.book}).field(t, "book", &book)
+	if book.Title != "Dune" || fmt.Sprint(book.Tags) != "[classic]" {
+		t.Errorf("book = %+v", book)
+	}
+
+	// A missing book is null, not an error.
+	result := ts.graphQL(t, `{ book(id: 9999) { title } }`, nil)
+	if len(result.Errors) > 0 || string(result.Data["book"]) != "null" {
+		t.Errorf("missing book = %s with errors %q, want null", result.Data["book"], result.errorMessages())
+	}
+
+	result = ts.graphQL(t, `{ book(id: -1) { title } }`, nil)
+	if got := result.errorMessages(); got != "invalid book ID" {
+		t.Errorf("errors for a negative ID = %q, want invalid book ID", got)
+	}
+
+	// Storage failures are reported rather than passed off as a missing
+	// book.
+	if err := ts.db.Migrator().DropTable("book_tags"); err != nil {
+		t.Fatalf("drop book_tags: %v", err)
+	}
+	result = ts.graphQL(t, `query($id: Int!) { book(id: $id) { title } }`, map[string]interface{}{"id": f.book})
+	if got := result.errorMessages(); got != "failed to fetch book" {
+		t.Errorf("errors = %q, want failed to fetch book", got)
+	}
+}
+
+func TestGraphQLFilterMatchesWildcardsLiterally(t *testing.T) {
+	ts := newTestServer(t)
+	user := withAuth(userToken(t, "alice"))
+	for i, title := range []string{"100% Pure", "1000 Years", "snake_case", "snakes and ladders"} {
+		book := map[string]interface{}{"title": title, "author": "A. Writer", "isbn": fmt.Sprintf("isbn-%d", i), "price": 5}
+		ts.mustDo(t, http.MethodPost, "/books", book, http.StatusCreated, nil, user)
+	}
+
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"100%", "[100% Pure]"},
+		{"e_c", "[snake_case]"},
+		{"snake", "[snake_case snakes and ladders]"},
+		{`\`, "[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			var page struct {
+				Items []struct {
+					Title string `json:"title"`
+				} `json:"items"`
+			}
+			query := `query($title: String) { books(filter: {title: $title}) { items { title } } }`
+			ts.graphQL(t, query, map[string]interface{}{"title": tt.title}).field(t, "books", &page)
+			titles := make([]string, len(page.Items))
+			for i, item := range page.Items {
+				titles[i] = item.Title
+			}
+			if got := fmt.Sprint(titles); got != tt.want {
+				t.Errorf("titles = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphQLBookMutations(t *testing.T) {
+	ts := newTestServer(t)
+	user := withAuth(userToken(t, "alice"))
+	input := map[string]interface{}{"title": "Emma", "author": "Jane Austen", "isbn": "9780141439587", "price": 5.5, "tags": []string{"Classic"}}
+
+	result := ts.graphQL(t, `mutation($in: BookInput!) { createBook(input: $in) { id } }`, map[string]interface{}{"in": input})
+	if got := result.errorMessages(); !strings.Contains(got, "books:write scope") {
+		t.Fatalf("anonymous createBook errors = %q, want unauthorized", got)
+	}
+
+	var created struct {
+		ID   uint     `json:"id"`
+		Tags []string `json:"tags"`
+	}
+	ts.graphQL(t, `mutation($in: BookInput!) { createBook(input: $in) { id tags } }`, map[string]interface{}{"in": input}, user).field(t, "createBook", &created)
+	if fmt.Sprint(created.Tags) != "[classic]" {
+		t.Errorf("created tags = %v, want [classic]", created.Tags)
+	}
+
+	// Omitting tags on update keeps them.
+	update := map[string]interface{}{"title": "Emma", "author": "Jane Austen", "isbn": "9780141439587", "price": 4.5}
+	var updated struct {
+		Price float64  `json:"price"`
+		Tags  []string `json:"tags"`
+	}
+	ts.graphQL(t, `mutation($id: Int!, $in: BookInput!) { updateBook(id: $id, input: $in) { price tags } }`,
+		map[string]interface{}{"id": created.ID, "in": update}, user).field(t, "updateBook", &updated)
+	if updated.Price != 4.5 || fmt.Sprint(updated.Tags) != "[classic]" {
+		t.Errorf("updated = %+v, want price 4.5 with tags kept", updated)
+	}
+
+	// Service errors keep their meaning rather than all reading as a
+	// missing book.
+	createMutation := `mutation($in: BookInput!) { createBook(input: $in) { id } }`
+	other := map[string]interface{}{"title": "Persuasion", "author": "Jane Austen", "isbn": "9780141439686", "price": 5}
+	var persuasion struct{ ID uint }
+	ts.graphQL(t, createMutation, map[string]interface{}{"in": other}, user).field(t, "createBook", &persuasion)
+	updateMutation := `mutation($id: Int!, $in: BookInput!) { updateBook(id: $id, input: $in) { id } }`
+	withCategory := map[string]interface{}{"title": "Emma", "author": "Jane Austen", "isbn": "9780141439587", "price": 5, "categoryId": 9999}
+	errorTests := []struct {
+		name      string
+		mutation  string
+		variables map[string]interface{}
+		wantError string
+	}{
+		{"create with a taken ISBN", createMutation, map[string]interface{}{"in": other}, "conflict: a book with this ISBN already exists"},
+		{"create with a missing category", createMutation, map[string]interface{}{"in": withCategory}, "invalid input: category 9999 does not exist"},
+		{"update to a taken ISBN", updateMutation, map[string]interface{}{"id": created.ID, "in": other}, "conflict: a book with this ISBN already exists"},
+		{"update with a missing category", updateMutation, map[string]interface{}{"id": created.ID, "in": withCategory}, "invalid input: category 9999 does not exist"},
+		{"update a missing book", updateMutation, map[string]interface{}{"id": 9999, "in": update}, "book not found"},
+		{"update book 0", updateMutation, map[string]interface{}{"id": 0, "in": update}, "invalid book ID"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ts.graphQL(t, tt.mutation, tt.variables, user).errorMessages(); got != tt.wantError {
+				t.Errorf("errors = %q, want %q", got, tt.wantError)
+			}
+		})
+	}
+
+	var deleted bool
+	ts.graphQL(t, `mutation($id: Int!) { deleteBook(id: $id) }`, map[string]interface{}{"id": created.ID}, user).field(t, "deleteBook", &deleted)
+	if !deleted {
+		t.Error("deleteBook returned false")
+	}
+	ts.mustDo(t, http.MethodGet, fmt.Sprintf("/books/%d", created.ID), nil, http.StatusNotFound, nil)
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"bookstore-api/internal/config"
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/testutil"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// testJWTSecret signs the tokens minted by the harness.
+const testJWTSecret = "harness-secret-that-is-at-least-32-chars"
+
+// testAdmin is the subject granted the admin scope.
+const testAdmin = "admin"
+
+func TestMain(m *testing.M) {
+	// The logging middleware would print every request.
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testServer is the full HTTP router from newApp, served over an
+// in-memory database.
+type testServer struct {
+	*httptest.Server
+	app *app
+	cfg *config.Config
+	db  *gorm.DB
+}
+
+// newTestServer boots the router with the default configuration, a known
+// JWT secret, testAdmin as the only admin subject and the token endpoint
+// enabled. Validate refuses that combination, so the tests also show that
+// tokens from the endpoint are not admins. The server is closed when the
+// test finishes.
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.Auth.JWTSecret = testJWTSecret
+	cfg.Auth.AdminSubjects = []string{testAdmin}
+	cfg.Auth.TokenEndpoint = true
+
+	db := testutil.NewDB(t)
+	a, err := newApp(cfg, db)
+	if err != nil {
+		t.Fatalf("newApp: %v", err)
+	}
+	server := httptest.NewServer(a.handler)
+	t.Cleanup(server.Close)
+	return &testServer{Server: server, app: a, cfg: cfg, db: db}
+}
+
+// mintToken signs claims with the harness secret and returns an
+// Authorization header value.
+func mintToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return "Bearer " + token
+}
+
+// userToken returns an Authorization header value for subject, valid for
+// an hour.
+func userToken(t *testing.T, subject string) string {
+	t.Helper()
+	return mintToken(t, jwt.MapClaims{
+		"sub": subject,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+}
+
+// expiredToken returns an Authorization header value whose token expired
+// an hour ago.
+func expiredToken(t *testing.T, subject string) string {
+	t.Helper()
+	return mintToken(t, jwt.MapClaims{
+		"sub": subject,
+		"iat": time.Now().Add(-2 * time.Hour).Unix(),
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	})
+}
+
+// requestOption customizes a request made by testServer.do.
+type requestOption func(*http.Request)
+
+// withAuth sets the Authorization header.
+func withAuth(value string) requestOption {
+	return func(r *http.Request) { r.Header.Set("Authorization", value) }
+}
+
+// withAPIKey sets the API key header.
+func withAPIKey(key string) requestOption {
+	return func(r *http.Request) { r.Header.Set("X-API-Key", key) }
+}
+
+// do sends a request to the server. A string body is sent as is; any other
+// non-nil body is encoded as JSON.
+func (ts *testServer) do(t *testing.T, method, path string, body interface{}, opts ...requestOption) *http.Response {
+	t.Helper()
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("encode body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, ts.URL+path, reader)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// mustDo sends a request like do and fails the test unless the response
+// has the wanted status. The JSON response body is decoded into out when
+// it is non-nil.
+func (ts *testServer) mustDo(t *testing.T, method, path string, body interface{}, wantStatus int, out interface{}, opts ...requestOption) {
+	t.Helper()
+	resp := ts.do(t, method, path, body, opts...)
+	if resp.StatusCode != wantStatus {
+		data, _ := io.ReadAll(resp.Body)
+		t.Fatalf("%s %s: status %d, want %d: %s", method, path, resp.StatusCode, wantStatus, data)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decode response: %v", method, path, err)
+		}
+	}
+}
+
+// createAPIKey creates an API key in the default tenant with the given
+// scopes and returns its ID and raw key.
+func (ts *testServer) createAPIKey(t *testing.T, name string, scopes ...string) (uint, string) {
+	t.Helper()
+	var created struct {
+		ID  uint   `json:"id"`
+		Key string `json:"key"`
+	}
+	body := map[string]interface{}{"name": name, "scopes": scopes}
+	ts.mustDo(t, http.MethodPost, "/admin/api-keys", body, http.StatusCreated, &created, withAuth(userToken(t, testAdmin)))
+	return created.ID, created.Key
+}
+
+// fixtures holds the IDs of the resources seeded by seed.
+type fixtures struct {
+	book         uint
+	category     uint
+	schedule     uint
+	webhook      uint
+	apiKey       uint
+	tenant       uint
+	list         uint
+	notification uint
+	readerKey    string
+	shareToken   string
+}
+
+// seed creates one of each resource through the API: a category holding a
+// book with a pending price schedule, alice's shared wishlist holding the
+// book and her notification of its price drop, a webhook subscription, an
+// API key limited to books:read and a second tenant.
+func (ts *testServer) seed(t *testing.T) fixtures {
+	t.Helper()
+	var f fixtures
+	user := withAuth(userToken(t, "alice"))
+	admin := withAuth(userToken(t, testAdmin))
+
+	var category models.Category
+	ts.mustDo(t, http.MethodPost, "/categories", map[string]interface{}{"name": "Fiction", "slug": "fiction"}, http.StatusCreated, &category, user)
+	f.category = category.ID
+
+	var book models.Book
+	ts.mustDo(t, http.MethodPost, "/books", map[string]interface{}{
+		"title": "Dune", "author": "Frank Herbert", "isbn": "9780441013593", "price": 9.99,
+		"category_id": f.category, "tags": []string{"classic"},
+	}, http.StatusCreated, &book, user)
+	f.book = book.ID
+
+	var list models.ReadingList
+	ts.mustDo(t, http.MethodPost, "/lists", map[string]string{"name": "To buy", "kind": models.ListWishlist}, http.StatusCreated, &list, user)
+	f.list = list.ID
+	ts.mustDo(t, http.MethodPost, fmt.Sprintf("/lists/%d/entries", f.list), map[string]uint{"book_id": f.book}, http.StatusOK, nil, user)
+	ts.mustDo(t, http.MethodPost, fmt.Sprintf("/lists/%d/share", f.list), nil, http.StatusOK, &list, user)
+	f.shareToken = *list.ShareToken
+
+	ts.mustDo(t, http.MethodPut, fmt.Sprintf("/books/%d", f.book), map[string]interface{}{
+		"title": "Dune", "author": "Frank Herbert", "isbn": "9780441013593", "price": 7.99,
+		"category_id": f.category, "tags": []string{"classic"},
+	}, http.StatusOK, nil, user)
+	var notifications []models.Notification
+	ts.mustDo(t, http.MethodGet, "/notifications", nil, http.StatusOK, &notifications, user)
+	if len(notifications) != 1 {
+		t.Fatalf("got %d notifications after the price drop, want 1", len(notifications))
+	}
+	f.notification = notifications[0].ID
+
+	var schedule models.PriceSchedule
+	ts.mustDo(t, http.MethodPost, fmt.Sprintf("/books/%d/price-schedules", f.book), map[string]interface{}{
+		"price": 4.99, "starts_at": time.Now().Add(24 * time.Hour),
+	}, http.StatusCreated, &schedule, user)
+	f.schedule = schedule.ID
+
+	var webhook models.WebhookSubscription
+	ts.mustDo(t, http.MethodPost, "/webhooks", map[string]interface{}{
+		"url": "https://example.com/hooks", "event_types": []string{models.EventBookCreated},
+	}, http.StatusCreated, &webhook, user)
+	f.webhook = webhook.ID
+
+	f.apiKey, f.readerKey = ts.createAPIKey(t, "reader", models.ScopeBooksRead)
+
+	var tenant models.Tenant
+	ts.mustDo(t, http.MethodPost, "/admin/tenants", map[string]interface{}{"slug": "store-b", "name": "Store B"}, http.StatusCreated, &tenant, admin)
+	f.tenant = tenant.ID
+	return f
+}
+
+// path expands the {book}, {category}, {schedule}, {webhook}, {apiKey},
+// {tenant}, {list}, {notification} and {shareToken} placeholders in a path
+// template.
+func (f fixtures) path(template string) string {
+	return strings.NewReplacer(
+		"{book}", fmt.Sprint(f.book),
+		"{category}", fmt.Sprint(f.category),
+		"{schedule}", fmt.Sprint(f.schedule),
+		"{webhook}", fmt.Sprint(f.webhook),
+		"{apiKey}", fmt.Sprint(f.apiKey),
+		"{tenant}", fmt.Sprint(f.tenant),
+		"{list}", fmt.Sprint(f.list),
+		"{notification}", fmt.Sprint(f.notification),
+		"{shareToken}", f.shareToken,
+	).Replace(template)
+}
+
+// assertEnvelope checks the shape of a response body: error responses
+// carry {"error": "<message>"} matching wantError when it is set, 204
+// responses are empty and other successes are JSON. It returns the error
+// message, if any.
+func assertEnvelope(t *testing.T, resp *http.Response, wantError string) string {
+	t.Helper()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNoContent:
+		if len(bytes.TrimSpace(data)) != 0 {
+			t.Errorf("204 response has a body: %s", data)
+		}
+	case resp.StatusCode >= 400:
+		var envelope map[string]interface{}
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			t.Fatalf("error body is not JSON: %s", data)
+		}
+		message, ok := envelope["error"].(string)
+		if !ok || message == "" || len(envelope) != 1 {
+			t.Fatalf(`error body is not {"error": "<message>"}: %s`, data)
+		}
+		if wantError != "" && message != wantError {
+			t.Errorf("error = %q, want %q", message, wantError)
+		}
+		return message
+	default:
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		if !json.Valid(data) {
+			t.Errorf("body is not JSON: %s", data)
+		}
+	}
+	return ""
+}
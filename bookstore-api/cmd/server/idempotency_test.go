@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"bookstore-api/internal/models"
+)
+
+// withIdempotencyKey sets the Idempotency-Key header.
+func withIdempotencyKey(key string) requestOption {
+	return func(r *http.Request) { r.Header.Set("Idempotency-Key", key) }
+}
+
+func TestIdempotentRetries(t *testing.T) {
+	category := map[string]string{"name": "Fiction", "slug": "fiction"}
+
+	// claimed returns the record stored for key.
+	claimed := func(t *testing.T, ts *testServer, key string) models.IdempotencyRecord {
+		t.Helper()
+		var record models.IdempotencyRecord
+		if err := ts.db.Where("idempotency_key = ?", key).First(&record).Error; err != nil {
+			t.Fatalf("load record for %q: %v", key, err)
+		}
+		return record
+	}
+	countCategories := func(t *testing.T, ts *testServer) int {
+		t.Helper()
+		var categories []models.Category
+		ts.mustDo(t, http.MethodGet, "/categories", nil, http.StatusOK, &categories)
+		return len(categories)
+	}
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, ts *testServer, user requestOption)
+	}{
+		{"retry is replayed", func(t *testing.T, ts *testServer, user requestOption) {
+			var first, second models.Category
+			ts.mustDo(t, http.MethodPost, "/categories", category, http.StatusCreated, &first, user, withIdempotencyKey("k1"))
+			resp := ts.do(t, http.MethodPost, "/categories", category, user, withIdempotencyKey("k1"))
+			if resp.StatusCode != http.StatusCreated || resp.Header.Get("Idempotent-Replayed") != "true" {
+				t.Fatalf("retry: status %d, replayed %q; want 201 replayed", resp.StatusCode, resp.Header.Get("Idempotent-Replayed"))
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&second); err != nil {
+				t.Fatalf("decode replay: %v", err)
+			}
+			if second.ID != first.ID {
+				t.Errorf("replayed category %d, want %d", second.ID, first.ID)
+			}
+			if n := countCategories(t, ts); n != 1 {
+				t.Errorf("%d categories after a retry, want 1", n)
+			}
+		}},
+		{"different body with the same key", func(t *testing.T, ts *testServer, user requestOption) {
+			ts.mustDo(t, http.MethodPost, "/categories", category, http.StatusCreated, nil, user, withIdempotencyKey("k1"))
+			other := map[string]string{"name": "Poetry", "slug": "poetry"}
+			resp := ts.do(t, http.MethodPost, "/categories", other, user, withIdempotencyKey("k1"))
+			if resp.StatusCode != http.StatusUnprocessableEntity {
+				t.Errorf("status %d, want 422", resp.StatusCode)
+			}
+			assertEnvelope(t, resp, "Idempotency-Key was already used for a different request")
+		}},
+		{"same key from another caller", func(t *testing.T, ts *testServer, user requestOption) {
+			ts.mustDo(t, http.MethodPost, "/categories", category, http.StatusCreated, nil, user, withIdempotencyKey("k1"))
+			other := map[string]string{"name": "Poetry", "slug": "poetry"}
+			ts.mustDo(t, http.MethodPost, "/categories", other, http.StatusCreated, nil, withAuth(userToken(t, "bob")), withIdempotencyKey("k1"))
+		}},
+		{"in-flight claim", func(t *testing.T, ts *testServer, user requestOption) {
+			ts.mustDo(t, http.MethodPost, "/categories", category, http.StatusCreated, nil, user, withIdempotencyKey("k1"))
+			// Turn the stored response back into a running request.
+			record := claimed(t, ts, "k1")
+			if err := ts.db.Model(&record).Update("status_code", 0).Error; err != nil {
+				t.Fatalf("reset record: %v", err)
+			}
+			resp := ts.do(t, http.MethodPost, "/categories", category, user, withIdempotencyKey("k1"))
+			if resp.StatusCode != http.StatusConflict {
+				t.Errorf("status %d, want 409", resp.StatusCode)
+			}
+			assertEnvelope(t, resp, "A request with this Idempotency-Key is still in progress")
+		}},
+		{"stale claim is taken over", func(t *testing.T, ts *testServer, user requestOption) {
+			ts.mustDo(t, http.MethodPost, "/categories", category, http.StatusCreated, nil, user, withIdempotencyKey("k1"))
+			record := claimed(t, ts, "k1")
+			if err := ts.db.Model(&record).Updates(map[string]interface{}{
+				"status_code": 0, "created_at": time.Now().Add(-2 * time.Minute),
+			}).Error; err != nil {
+				t.Fatalf("age record: %v", err)
+			}
+			// The retry runs again; the category exists, so it conflicts.
+			resp := ts.do(t, http.MethodPost, "/categories", category, user, withIdempotencyKey("k1"))
+			if resp.StatusCode != http.StatusConflict || resp.Header.Get("Idempotent-Replayed") != "" {
+				t.Fatalf("status %d, replayed %q; want a fresh 409", resp.StatusCode, resp.Header.Get("Idempotent-Replayed"))
+			}
+			if got := claimed(t, ts, "k1"); got.ID == record.ID || got.StatusCode != http.StatusConflict {
+				t.Errorf("record %d with status %d, want a new record storing 409", got.ID, got.StatusCode)
+			}
+		}},
+		{"server error releases the key", func(t *testing.T, ts *testServer, user requestOption) {
+			if err := ts.db.Migrator().DropTable(&models.Category{}); err != nil {
+				t.Fatalf("drop categories: %v", err)
+			}
+			resp := ts.do(t, http.MethodPost, "/categories", category, user, withIdempotencyKey("k1"))
+			if resp.StatusCode != http.StatusInternalServerError {
+				data, _ := io.ReadAll(resp.Body)
+				t.Fatalf("status %d, want 500: %s", resp.StatusCode, data)
+			}
+			var records int64
+			ts.db.Model(&models.IdempotencyRecord{}).Count(&records)
+			if records != 0 {
+				t.Errorf("%d records after a server error, want none", records)
+			}
+
+			if err := ts.db.AutoMigrate(&models.Category{}); err != nil {
+				t.Fatalf("recreate categories: %v", err)
+			}
+			resp = ts.do(t, http.MethodPost, "/categories", category, user, withIdempotencyKey("k1"))
+			if resp.StatusCode != http.StatusCreated || resp.Header.Get("Idempotent-Replayed") != "" {
+				t.Errorf("retry: status %d, replayed %q; want a fresh 201", resp.StatusCode, resp.Header.Get("Idempotent-Replayed"))
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t)
+			tt.run(t, ts, withAuth(userToken(t, "alice")))
+		})
+	}
+}
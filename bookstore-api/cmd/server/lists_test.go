@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"bookstore-api/internal/models"
+)
+
+// entryOrder returns the IDs of the books on a list in order, checking
+// that positions are numbered from 1.
+func entryOrder(t *testing.T, list models.ReadingList) []uint {
+	t.Helper()
+	ids := make([]uint, len(list.Entries))
+	for i, entry := range list.Entries {
+		if entry.Position != i+1 {
+			t.Errorf("entry %d has position %d, want %d", i, entry.Position, i+1)
+		}
+		if entry.Book == nil || entry.Book.ID != entry.BookID {
+			t.Errorf("entry %d does not carry book %d", i, entry.BookID)
+		}
+		ids[i] = entry.BookID
+	}
+	return ids
+}
+
+func TestReadingListEntries(t *testing.T) {
+	ts := newTestServer(t)
+	f := ts.seed(t)
+	user := withAuth(userToken(t, "alice"))
+	entries := fmt.Sprintf("/lists/%d/entries", f.list)
+
+	var second, third models.Book
+	ts.mustDo(t, http.MethodPost, "/books", map[string]interface{}{"title": "Emma", "author": "Jane Austen", "isbn": "9780141439587", "price": 5}, http.StatusCreated, &second, user)
+	ts.mustDo(t, http.MethodPost, "/books", map[string]interface{}{"title": "Ulysses", "author": "James Joyce", "isbn": "9780199535675", "price": 8}, http.StatusCreated, &third, user)
+
+	var list models.ReadingList
+	ts.mustDo(t, http.MethodPost, entries, map[string]interface{}{"book_id": second.ID, "position": 1}, http.StatusOK, &list, user)
+	ts.mustDo(t, http.MethodPost, entries, map[string]interface{}{"book_id": third.ID, "position": 99}, http.StatusOK, &list, user)
+	if got, want := fmt.Sprint(entryOrder(t, list)), fmt.Sprint([]uint{second.ID, f.book, third.ID}); got != want {
+		t.Fatalf("order after adding = %s, want %s", got, want)
+	}
+
+	ts.mustDo(t, http.MethodPut, entries, map[string]interface{}{"book_ids": []uint{third.ID, second.ID, f.book}}, http.StatusOK, &list, user)
+	if got, want := fmt.Sprint(entryOrder(t, list)), fmt.Sprint([]uint{third.ID, second.ID, f.book}); got != want {
+		t.Fatalf("order after reordering = %s, want %s", got, want)
+	}
+	ts.mustDo(t, http.MethodPut, entries, map[string]interface{}{"book_ids": []uint{third.ID, third.ID, f.book}}, http.StatusBadRequest, nil, user)
+
+	// Removing an entry or deleting a book closes the gap.
+	ts.mustDo(t, http.MethodDelete, fmt.Sprintf("%s/%d", entries, second.ID), nil, http.StatusNoContent, nil, user)
+	ts.mustDo(t, http.MethodDelete, fmt.Sprintf("/books/%d", third.ID), nil, http.StatusNoContent, nil, user)
+	ts.mustDo(t, http.MethodGet, fmt.Sprintf("/lists/%d", f.list), nil, http.StatusOK, &list, user)
+	if got, want := fmt.Sprint(entryOrder(t, list)), fmt.Sprint([]uint{f.book}); got != want {
+		t.Errorf("order after removals = %s, want %s", got, want)
+	}
+}
+
+func TestReadingListSharing(t *testing.T) {
+	ts := newTestServer(t)
+	f := ts.seed(t)
+	user := withAuth(userToken(t, "alice"))
+	share := fmt.Sprintf("/lists/%d/share", f.list)
+
+	var list models.ReadingList
+	ts.mustDo(t, http.MethodPost, share, nil, http.StatusOK, &list, user)
+	if list.ShareToken == nil || *list.ShareToken != f.shareToken {
+		t.Errorf("sharing again changed the token to %v, want %q", list.ShareToken, f.shareToken)
+	}
+
+	ts.mustDo(t, http.MethodGet, "/shared/lists/"+f.shareToken, nil, http.StatusOK, &list)
+	if list.ID != f.list || len(list.Entries) != 1 {
+		t.Errorf("shared list = %d with %d entries, want %d with 1", list.ID, len(list.Entries), f.list)
+	}
+
+	ts.mustDo(t, http.MethodDelete, share, nil, http.StatusNoContent, nil, user)
+	ts.mustDo(t, http.MethodGet, "/shared/lists/"+f.shareToken, nil, http.StatusNotFound, nil)
+}
+
+func TestPriceDropNotifications(t *testing.T) {
+	ts := newTestServer(t)
+	f := ts.seed(t)
+	alice := withAuth(userToken(t, "alice"))
+	bob := withAuth(userToken(t, "bob"))
+	carol := withAuth(userToken(t, "carol"))
+
+	// Bob also wishes for the book; Carol is only reading it.
+	var list models.ReadingList
+	ts.mustDo(t, http.MethodPost, "/lists", map[string]string{"name": "Gifts", "kind": models.ListWishlist}, http.StatusCreated, &list, bob)
+	ts.mustDo(t, http.MethodPost, fmt.Sprintf("/lists/%d/entries", list.ID), map[string]uint{"book_id": f.book}, http.StatusOK, nil, bob)
+	ts.mustDo(t, http.MethodPost, "/lists", map[string]string{"name": "Now", "kind": models.ListReading}, http.StatusCreated, &list, carol)
+	ts.mustDo(t, http.MethodPost, fmt.Sprintf("/lists/%d/entries", list.ID), map[string]uint{"book_id": f.book}, http.StatusOK, nil, carol)
+
+	setPrice := func(price float64) {
+		ts.mustDo(t, http.MethodPut, fmt.Sprintf("/books/%d", f.book), map[string]interface{}{
+			"title": "Dune", "author": "Frank Herbert", "isbn": "9780441013593", "price": price,
+		}, http.StatusOK, nil, alice)
+	}
+	setPrice(9.5) // a rise notifies nobody
+	setPrice(6.25)
+
+	notifications := func(auth requestOption, query string) []models.Notification {
+		var got []models.Notification
+		ts.mustDo(t, http.MethodGet, "/notifications"+query, nil, http.StatusOK, &got, auth)
+		return got
+	}
+	if got := notifications(carol, ""); len(got) != 0 {
+		t.Errorf("reading list owner got %d notifications, want 0", len(got))
+	}
+	got := notifications(bob, "")
+	if len(got) != 1 || got[0].Type != models.NotificationPriceDrop || got[0].PreviousPrice != 9.5 || got[0].Price != 6.25 || got[0].BookID != f.book {
+		t.Fatalf("bob's notifications = %+v, want one drop from 9.5 to 6.25", got)
+	}
+
+	// Alice was notified while seeding and now.
+	if got := notifications(alice, "?unread=true"); len(got) != 2 {
+		t.Fatalf("alice has %d unread notifications, want 2", len(got))
+	}
+	var read models.Notification
+	ts.mustDo(t, http.MethodPost, fmt.Sprintf("/notifications/%d/read", f.notification), nil, http.StatusOK, &read, alice)
+	if read.ReadAt == nil {
+		t.Error("read_at is not set")
+	}
+	if got := notifications(alice, "?unread=true"); len(got) != 1 || got[0].Price != 6.25 {
+		t.Errorf("alice's unread notifications = %+v, want the drop to 6.25", got)
+	}
+	if got := notifications(alice, ""); len(got) != 2 {
+		t.Errorf("alice has %d notifications, want 2", len(got))
+	}
+}
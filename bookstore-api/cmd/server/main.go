@@ -1,20 +1,55 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"bookstore-api/internal/certs"
 	"bookstore-api/internal/config"
 	"bookstore-api/internal/database"
-	"bookstore-api/internal/handlers"
-	"bookstore-api/internal/middleware"
-	"bookstore-api/internal/repositories"
+	"bookstore-api/internal/pricing"
+	"bookstore-api/internal/rpc"
 	"bookstore-api/internal/services"
+	"bookstore-api/internal/webhooks"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
-	// Load configuration
-	cfg := config.Load()
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	flag.Parse()
+
+	// Load configuration. It is printed before validation so that an
+	// invalid configuration can be inspected.
+	cfg, err := config.Resolve(*configPath)
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	if *printConfig {
+		out, err := cfg.Redacted().YAML()
+		if err != nil {
+			log.Fatalf("Failed to render configuration: %v", err)
+		}
+		fmt.Print(out)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if *printConfig {
+		return
+	}
 
 	// Connect to database
 	db, err := database.Connect(cfg)
@@ -23,37 +58,123 @@ func main() {
 	}
 	log.Println("Database connected successfully")
 
-	// Initialize layers
-	bookRepo := repositories.NewGormBookRepository(db)
-	bookService := services.NewBookService(bookRepo)
-	bookHandler := handlers.NewBookHandler(bookService)
-	authHandler := handlers.NewAuthHandler(cfg.JWTSecret)
+	// Wire the layers and HTTP routes
+	a, err := newApp(cfg, db)
+	if err != nil {
+		log.Fatalf("Failed to initialize: %v", err)
+	}
 
-	// Setup router
-	mux := http.NewServeMux()
+	// Stop both servers on SIGINT/SIGTERM or when either one fails
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Auth middleware
-	authMiddleware := middleware.Auth(cfg.JWTSecret)
+	httpServer := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:      a.handler,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
 
-	// Auth routes (public - for testing)
-	mux.HandleFunc("POST /auth/token", authHandler.GenerateToken)
+	// TLS for both servers, reloaded when the files on disk change
+	var grpcOpts []grpc.ServerOption
+	if cfg.TLS.Enabled {
+		reloader, err := certs.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ClientCAFile, cfg.TLS.RequireClientCert)
+		if err != nil {
+			log.Fatalf("Failed to load TLS certificate: %v", err)
+		}
+		go reloader.Watch(ctx, cfg.TLS.ReloadInterval)
 
-	// Public routes (no auth required)
-	mux.HandleFunc("GET /books", bookHandler.GetAllBooks)
-	mux.HandleFunc("GET /books/{id}", bookHandler.GetBookByID)
+		httpServer.TLSConfig = reloader.TLSConfig()
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(reloader.TLSConfig())))
+	}
+
+	// gRPC server
+	grpcServer := rpc.NewServer(a.books, a.authn, a.tenants, grpcOpts...)
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Server.GRPCPort))
+	if err != nil {
+		log.Fatalf("Failed to listen for gRPC: %v", err)
+	}
 
-	// Protected routes (auth required)
-	mux.Handle("POST /books", authMiddleware(http.HandlerFunc(bookHandler.CreateBook)))
-	mux.Handle("PUT /books/{id}", authMiddleware(http.HandlerFunc(bookHandler.UpdateBook)))
-	mux.Handle("DELETE /books/{id}", authMiddleware(http.HandlerFunc(bookHandler.DeleteBook)))
+	// Webhook dispatcher
+	if cfg.Webhooks.Enabled {
+		go webhooks.NewDispatcher(a.webhookRepo, cfg.Webhooks).Run(ctx)
+	}
+
+	// Scheduled price changes and sales
+	if cfg.Pricing.SchedulerEnabled {
+		go pricing.NewScheduler(a.books, a.priceRepo, cfg.Pricing).Run(ctx)
+	}
+
+	// Expired idempotency keys
+	go purgeIdempotencyKeys(ctx, a.idempotency, cfg.Idempotency.PurgeInterval)
+
+	errCh := make(chan error, 2)
+	go func() {
+		log.Printf("gRPC server starting on %s", grpcListener.Addr())
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			errCh <- fmt.Errorf("gRPC server: %w", err)
+		}
+	}()
+	go func() {
+		var err error
+		if httpServer.TLSConfig != nil {
+			log.Printf("Server starting on %s (HTTPS)", httpServer.Addr)
+			err = httpServer.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("Server starting on %s", httpServer.Addr)
+			err = httpServer.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- fmt.Errorf("HTTP server: %w", err)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		log.Println("Shutting down")
+	case err := <-errCh:
+		log.Printf("Server failed: %v", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP shutdown error: %v", err)
+	}
+	stopGRPC(shutdownCtx, grpcServer)
+}
+
+// purgeIdempotencyKeys deletes expired idempotency records every interval
+// until ctx is cancelled.
+func purgeIdempotencyKeys(ctx context.Context, service services.IdempotencyService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := service.PurgeExpired(); err != nil {
+				log.Printf("Failed to purge idempotency keys: %v", err)
+			}
+		}
+	}
+}
 
-	// Wrap with logging middleware
-	handler := middleware.Logging(mux)
+// stopGRPC gracefully stops the gRPC server, forcing it closed once ctx
+// expires.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
 
-	// Start server
-	addr := ":" + cfg.ServerPort
-	log.Printf("Server starting on %s", addr)
-	if err := http.ListenAndServe(addr, handler); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	select {
+	case <-done:
+	case <-ctx.Done():
+		server.Stop()
 	}
 }
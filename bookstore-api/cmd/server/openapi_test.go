@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"bookstore-api/internal/openapi"
+)
+
+// registeredRoutes collects every "METHOD /path" pattern passed to
+// Handle or HandleFunc in the non-test sources of this package.
+func registeredRoutes(t *testing.T) []string {
+	t.Helper()
+
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatalf("Failed to list sources: %v", err)
+	}
+
+	fset := token.NewFileSet()
+	var routes []string
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", name, err)
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || (sel.Sel.Name != "Handle" && sel.Sel.Name != "HandleFunc") {
+				return true
+			}
+			lit, ok := call.Args[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			pattern, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				t.Fatalf("Failed to unquote %s: %v", lit.Value, err)
+			}
+			routes = append(routes, pattern)
+			return true
+		})
+	}
+
+	if len(routes) == 0 {
+		t.Fatal("Expected to find registered routes")
+	}
+	return routes
+}
+
+// documentedRoutes returns every operation in the OpenAPI document as a
+// "METHOD /path" pattern.
+func documentedRoutes(t *testing.T) []string {
+	t.Helper()
+
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openapi.Spec, &spec); err != nil {
+		t.Fatalf("Failed to decode OpenAPI document: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Fatalf("Expected an OpenAPI 3 document, got version %q", spec.OpenAPI)
+	}
+
+	var routes []string
+	for path, item := range spec.Paths {
+		for method := range item {
+			switch method {
+			case "get", "put", "post", "delete", "patch", "head", "options":
+				routes = append(routes, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	return routes
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	documented := make(map[string]bool)
+	for _, route := range documentedRoutes(t) {
+		documented[route] = true
+	}
+
+	for _, route := range registeredRoutes(t) {
+		if !documented[route] {
+			t.Errorf("Route %q is registered but missing from openapi.json", route)
+		}
+	}
+}
+
+func TestOpenAPIHasNoStaleRoutes(t *testing.T) {
+	registered := make(map[string]bool)
+	for _, route := range registeredRoutes(t) {
+		registered[route] = true
+	}
+
+	var stale []string
+	for _, route := range documentedRoutes(t) {
+		if !registered[route] {
+			stale = append(stale, route)
+		}
+	}
+	sort.Strings(stale)
+	if len(stale) > 0 {
+		t.Errorf("Routes documented in openapi.json but not registered: %v", stale)
+	}
+}
+
+func TestOpenAPIReferencesResolve(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(openapi.Spec, &doc); err != nil {
+		t.Fatalf("Failed to decode OpenAPI document: %v", err)
+	}
+
+	var walk func(node interface{})
+	walk = func(node interface{}) {
+		switch v := node.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				if !resolveRef(doc, ref) {
+					t.Errorf("Unresolved $ref %q", ref)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+}
+
+// resolveRef reports whether a local "#/a/b/c" reference points at a node.
+func resolveRef(doc map[string]interface{}, ref string) bool {
+	if !strings.HasPrefix(ref, "#/") {
+		return false
+	}
+	var node interface{} = doc
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if node, ok = m[part]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// caller selects the credentials a route test sends.
+type caller func(t *testing.T, f fixtures) []requestOption
+
+// Callers used by the route table.
+var (
+	anonymous caller = func(*testing.T, fixtures) []requestOption { return nil }
+	user      caller = func(t *testing.T, _ fixtures) []requestOption {
+		return []requestOption{withAuth(userToken(t, "alice"))}
+	}
+	bob caller = func(t *testing.T, _ fixtures) []requestOption {
+		return []requestOption{withAuth(userToken(t, "bob"))}
+	}
+	admin caller = func(t *testing.T, _ fixtures) []requestOption {
+		return []requestOption{withAuth(userToken(t, testAdmin))}
+	}
+	reader caller = func(_ *testing.T, f fixtures) []requestOption {
+		return []requestOption{withAPIKey(f.readerKey)}
+	}
+	expired caller = func(t *testing.T, _ fixtures) []requestOption {
+		return []requestOption{withAuth(expiredToken(t, "alice"))}
+	}
+	forged caller = func(*testing.T, fixtures) []requestOption {
+		return []requestOption{withAuth("Bearer not.a.token")}
+	}
+	basicAuth caller = func(*testing.T, fixtures) []requestOption {
+		return []requestOption{withAuth("Basic YWxpY2U6c2VjcmV0")}
+	}
+	unknownKey caller = func(*testing.T, fixtures) []requestOption {
+		return []requestOption{withAPIKey("bsk_00000000_" + strings.Repeat("0", 48))}
+	}
+)
+
+// routeCase is one request against a registered route. Paths may use the
+// placeholders expanded by fixtures.path.
+type routeCase struct {
+	route      string
+	name       string
+	as         caller
+	path       string
+	body       interface{}
+	wantStatus int
+	wantError  string
+}
+
+// Error messages shared by many cases.
+const (
+	errMissingAuth  = "Missing authorization header"
+	errInvalidToken = "Invalid or expired token"
+	errScope        = "Insufficient scope"
+	errBody         = "Invalid request body"
+	errNotFound     = "Not found"
+)
+
+// routeCases covers every registered route. TestRouteCasesCoverEveryRoute
+// fails when a route has no case.
+func routeCases() []routeCase {
+	book := map[string]interface{}{"title": "Emma", "author": "Jane Austen", "isbn": "9780141439587", "price": 5}
+	tomorrow := time.Now().Add(24 * time.Hour)
+
+	return []routeCase{
+		// Meta and auth
+		{route: "GET /openapi.json", name: "served", as: anonymous, wantStatus: http.StatusOK},
+		{route: "POST /auth/token", name: "issues token", as: anonymous, body: map[string]string{"username": "alice"}, wantStatus: http.StatusOK},
+		{route: "POST /auth/token", name: "malformed body", as: anonymous, body: "{", wantStatus: http.StatusBadRequest, wantError: errBody},
+
+		// Books
+		{route: "GET /books", name: "public", as: anonymous, wantStatus: http.StatusOK},
+		{route: "GET /books", name: "with facets", as: anonymous, path: "/books?facets=true&category={category}", wantStatus: http.StatusOK},
+		{route: "GET /books", name: "invalid price filter", as: anonymous, path: "/books?min_price=cheap", wantStatus: http.StatusBadRequest, wantError: "Invalid query parameter: min_price"},
+		{route: "GET /books", name: "negative limit", as: anonymous, path: "/books?limit=-1", wantStatus: http.StatusBadRequest, wantError: "Invalid query parameter: limit"},
+		{route: "GET /books/{id}", name: "public", as: anonymous, path: "/books/{book}", wantStatus: http.StatusOK},
+		{route: "GET /books/{id}", name: "unknown", as: anonymous, path: "/books/999", wantStatus: http.StatusNotFound, wantError: "Book not found"},
+		{route: "POST /books", name: "created", as: user, body: book, wantStatus: http.StatusCreated},
+		{route: "POST /books", name: "no credentials", as: anonymous, body: book, wantStatus: http.StatusUnauthorized, wantError: errMissingAuth},
+		{route: "POST /books", name: "expired token", as: expired, body: book, wantStatus: http.StatusUnauthorized, wantError: errInvalidToken},
+		{route: "POST /books", name: "forged token", as: forged, body: book, wantStatus: http.StatusUnauthorized, wantError: errInvalidToken},
+		{route: "POST /books", name: "basic auth", as: basicAuth, body: book, wantStatus: http.StatusUnauthorized, wantError: "Invalid authorization header format"},
+		{route: "POST /books", name: "unknown API key", as: unknownKey, body: book, wantStatus: http.StatusUnauthorized, wantError: "Invalid or revoked API key"},
+		{route: "POST /books", name: "read-only API key", as: reader, body: book, wantStatus: http.StatusForbidden, wantError: errScope},
+		{route: "POST /books", name: "malformed body", as: user, body: "{", wantStatus: http.StatusBadRequest, wantError: errBody},
+		{route: "POST /books", name: "duplicate ISBN", as: user, body: map[string]interface{}{"title": "Dune", "author": "Frank Herbert", "isbn": "9780441013593", "price": 1}, wantStatus: http.StatusConflict, wantError: "conflict: a book with this ISBN already exists"},
+		{route: "POST /books", name: "unknown category", as: user, body: map[string]interface{}{"title": "Emma", "author": "Jane Austen", "isbn": "9780141439587", "price": 5, "category_id": 999}, wantStatus: http.StatusBadRequest, wantError: "invalid input: category 999 does not exist"},
+		{route: "PUT /books/{id}", name: "updated", as: user, path: "/books/{book}", body: book, wantStatus: http.StatusOK},
+		{route: "PUT /books/{id}", name: "no credentials", as: anonymous, path: "/books/{book}", body: book, wantStatus: http.StatusUnauthorized, wantError: errMissingAuth},
+		{route: "PUT /books/{id}", name: "read-only API key", as: reader, path: "/books/{book}", body: book, wantStatus: http.StatusForbidden, wantError: errScope},
+		{route: "PUT /books/{id}", name: "malformed body", as: user, path: "/books/{book}", body: "[]", wantStatus: http.StatusBadRequest, wantError: errBody},
+		{route: "DELETE /books/{id}", name: "deleted", as: user, path: "/books/{book}", wantStatus: http.StatusNoContent},
+		{route: "DELETE /books/{id}", name: "no credentials", as: anonymous, path: "/books/{book}", wantStatus: http.StatusUnauthorized, wantError: errMissingAuth},
+		{route: "DELETE /books/{id}", name: "read-only API key", as: reader, path: "/books/{book}", wantStatus: http.StatusForbidden, wantError: errScope},
+		{route: "GET /books/events", name: "no credentials", as: anonymous, wantStatus: http.StatusUnauthorized, wantError: errMissingAuth},
+		{route: "GET /books/events", name: "invalid last event ID", as: reader, path: "/books/events?last_event_id=abc", wantStatus: http.StatusBadRequest, wantError: "Invalid Last-Event-ID"},
+
+		// Pricing
+		{route: "GET /books/{id}/prices", name: "public", as: anonymous, path: "/books/{book}/prices", wantStatus: http.StatusOK},
+		{route: "GET /books/{id}/prices", name: "unknown book", as: anonymous, path: "/books/999/prices", wantStatus: http.StatusNotFound, wantError: errNotFound},
+		{route: "GET /books/{id}/price-schedules", name: "read-only API key", as: reader, path: "/books/{book}/price-schedules", wantStatus: http.StatusOK},
+		{route: "GET /books/{id}/price-schedules", name: "no credentials", as: anonymous, path: "/books/{book}/price-schedules", wantStatus: http.StatusUnauthorized, wantError: errMissingAuth},
+		{route: "POST /books/{id}/price-schedules", name: "scheduled", as: user, path: "/books/{book}/price-schedules", body: map[string]interface{}{"price": 7.5, "starts_at": tomorrow}, wantStatus: http.StatusCreated},
+		{route: "POST /books/{id}/price-schedules", name: "negative price", as: user, path: "/books/{book}/price-schedules", body: map[string]interface{}{"price": -1, "starts_at": tomorrow}, wantStatus: http.StatusBadRequest},
+		{route: "POST /books/{id}/price-schedules", name: "read-only API key", as: reader, path: "/books/{book}/price-schedules", body: map[string]interface{}{"price": 7.5, "starts_at": tomorrow}, wantStatus: http.StatusForbidden, wantError: errScope},
+		{route: "DELETE /books/{id}/price-schedules/{scheduleId}", name: "cancelled", as: user, path: "/books/{book}/price-schedules/{schedule}", wantStatus: http.StatusOK},
+		{route: "DELETE /books/{id}/price-schedules/{scheduleId}", name: "unknown schedule", as: user, path: "/books/{book}/price-schedules/999", wantStatus: http.StatusNotFound, wantError: errNotFound},
+		{route: "DELETE /books/{id}/price-schedules/{scheduleId}", name: "malformed schedule ID", as: user, path: "/books/{book}/price-schedules/abc", wantStatus: http.StatusBadRequest, wantError: "Invalid schedule ID"},
+
+		// Categories and tags
+		{route: "GET /categories", name: "public", as: anonymous, wantStatus: http.StatusOK},
+		{route: "GET /categories/{id}", name: "public", as: anonymous, path: "/categories/{category}", wantStatus: http.StatusOK},
+		{route: "GET /categories/{id}", name: "unknown", as: anonymous, path: "/categories/999", wantStatus: http.StatusNotFound, wantError: errNotFound},
+		{route: "POST /categories", name: "created", as: user, body: map[string]interface{}{"name": "Science Fiction", "slug": "sci-fi", "parent_id": 1}, wantStatus: http.StatusCreated},
+		{route: "POST /categories", name: "duplicate slug", as: user, body: map[string]string{"name": "Fiction", "slug": "fiction"}, wantStatus: http.StatusConflict, wantError: "conflict: a category with this slug already exists"},
+		{route: "POST /categories", name: "invalid slug", as: user, body: map[string]string{"name": "Fiction", "slug": "Fiction!"}, wantStatus: http.StatusBadRequest},
+		{route: "POST /categories", name: "read-only API key", as: reader, body: map[string]string{"name": "Poetry", "slug": "poetry"}, wantStatus: http.StatusForbidden, wantError: errScope},
+		{route: "PUT /categories/{id}", name: "updated", as: user, path: "/categories/{category}", body: map[string]string{"name": "Novels", "slug": "novels"}, wantStatus: http.StatusOK},
+		{route: "PUT /categories/{id}", name: "own parent", as: user, path: "/categories/{category}", body: map[string]interface{}{"name": "Novels", "slug": "novels", "parent_id": 1}, wantStatus: http.StatusBadRequest, wantError: "invalid input: a category cannot be its own ancestor"},
+		{route: "DELETE /categories/{id}", name: "still has books", as: user, path: "/categories/{category}", wantStatus: http.StatusConflict, wantError: "conflict: category still has 1 books"},
+		{route: "DELETE /categories/{id}", name: "unknown", as: user, path: "/categories/999", wantStatus: http.StatusNotFound, wantError: errNotFound},
+		{route: "GET /tags", name: "public", as: anonymous, wantStatus: http.StatusOK},
+
+		// Reading lists and notifications
+		{route: "POST /lists", name: "created", as: user, body: map[string]string{"name": "Summer", "kind": "reading"}, wantStatus: http.StatusCreated},
+		{route: "POST /lists", name: "invalid kind", as: user, body: map[string]string{"name": "Summer", "kind": "someday"}, wantStatus: http.StatusBadRequest, wantError: "invalid input: kind must be one of wishlist, reading, read"},
+		{route: "POST /lists", name: "API key", as: reader, body: map[string]string{"name": "Summer", "kind": "reading"}, wantStatus: http.StatusForbidden, wantError: errScope},
+		{route: "GET /lists", name: "listed", as: user, wantStatus: http.StatusOK},
+		{route: "GET /lists", name: "no credentials", as: anonymous, wantStatus: http.StatusUnauthorized, wantError: errMissingAuth},
+		{route: "GET /lists/{id}", name: "found", as: user, path: "/lists/{list}", wantStatus: http.StatusOK},
+		{route: "GET /lists/{id}", name: "another user's list", as: bob, path: "/lists/{list}", wantStatus: http.StatusNotFound, wantError: errNotFound},
+		{route: "PUT /lists/{id}", name: "renamed", as: user, path: "/lists/{list}", body: map[string]string{"name": "Gifts", "kind": "wishlist"}, wantStatus: http.StatusOK},
+		{route: "PUT /lists/{id}", name: "blank name", as: user, path: "/lists/{list}", body: map[string]string{"name": " ", "kind": "wishlist"}, wantStatus: http.StatusBadRequest, wantError: "invalid input: name is required"},
+		{route: "DELETE /lists/{id}", name: "deleted", as: user, path: "/lists/{list}", wantStatus: http.StatusNoContent},
+		{route: "DELETE /lists/{id}", name: "another user's list", as: bob, path: "/lists/{list}", wantStatus: http.StatusNotFound, wantError: errNotFound},
+		{route: "POST /lists/{id}/entries", name: "already on list", as: user, path: "/lists/{list}/entries", body: map[string]interface{}{"book_id": 1}, wantStatus: http.StatusConflict, wantError: "conflict: the book is already on this list"},
+		{route: "POST /lists/{id}/entries", name: "unknown book", as: user, path: "/lists/{list}/entries", body: map[string]interface{}{"book_id": 999}, wantStatus: http.StatusBadRequest, wantError: "invalid input: book 999 does not exist"},
+		{route: "PUT /lists/{id}/entries", name: "reordered", as: user, path: "/lists/{list}/entries", body: map[string]interface{}{"book_ids": []uint{1}}, wantStatus: http.StatusOK},
+		{route: "PUT /lists/{id}/entries", name: "missing book", as: user, path: "/lists/{list}/entries", body: map[string]interface{}{"book_ids": []uint{}}, wantStatus: http.StatusBadRequest, wantError: "invalid input: book_ids must list each book on the list exactly once"},
+		{route: "DELETE /lists/{id}/entries/{bookId}", name: "removed", as: user, path: "/lists/{list}/entries/{book}", wantStatus: http.StatusNoContent},
+		{route: "DELETE /lists/{id}/entries/{bookId}", name: "not on list", as: user, path: "/lists/{list}/entries/999", wantStatus: http.StatusNotFound, wantError: errNotFound},
+		{route: "POST /lists/{id}/share", name: "shared", as: user, path: "/lists/{list}/share", wantStatus: http.StatusOK},
+		{route: "DELETE /lists/{id}/share", name: "unshared", as: user, path: "/lists/{list}/share", wantStatus: http.StatusNoContent},
+		{route: "GET /shared/lists/{token}", name: "public", as: anonymous, path: "/shared/lists/{shareToken}", wantStatus: http.StatusOK},
+		{route: "GET /shared/lists/{token}", name: "unknown token", as: anonymous, path: "/shared/lists/0123456789abcdef", wantStatus: http.StatusNotFound, wantError: errNotFound},
+		{route: "GET /notifications", name: "listed", as: user, path: "/notifications?unread=true", wantStatus: http.StatusOK},
+		{route: "GET /notifications", name: "invalid unread filter", as: user, path: "/notifications?unread=maybe", wantStatus: http.StatusBadRequest, wantError: "Invalid query parameter: unread"},
+		{route: "POST /notifications/{id}/read", name: "marked read", as: user, path: "/notifications/{notification}/read", wantStatus: http.StatusOK},
+		{route: "POST /notifications/{id}/read", name: "another user's notification", as: bob, path: "/notifications/{notification}/read", wantStatus: http.StatusNotFound, wantError: errNotFound},
+
+		// Webhooks
+		{route: "POST /webhooks", name: "subscribed", as: user, body: map[string]interface{}{"url": "https://example.com/other", "event_types": []string{"book.deleted"}}, wantStatus: http.StatusCreated},
+		{route: "POST /webhooks", name: "invalid URL", as: user, body: map[string]interface{}{"url": "ftp://example.com", "event_types": []string{"book.deleted"}}, wantStatus: http.StatusBadRequest, wantError: "invalid input: url must be an absolute http or https URL"},
+		{route: "POST /webhooks", name: "read-only API key", as: reader, body: map[string]interface{}{"url": "https://example.com/other", "event_types": []string{"book.deleted"}}, wantStatus: http.StatusForbidden, wantError: errScope},
+		{route: "GET /webhooks", name: "listed", as: user, wantStatus: http.StatusOK},
+		{route: "GET /webhooks", name: "no credentials", as: anonymous, wantStatus: http.StatusUnauthorized, wantError: errMissingAuth},
+		{route: "DELETE /webhooks/{id}", name: "unsubscribed", as: user, path: "/webhooks/{webhook}", wantStatus: http.StatusNoContent},
+		{route: "GET /webhooks/{id}/deliveries", name: "listed", as: user, path: "/webhooks/{webhook}/deliveries", wantStatus: http.StatusOK},
+		{route: "GET /webhooks/{id}/deliveries", name: "invalid status", as: user, path: "/webhooks/{webhook}/deliveries?status=lost", wantStatus: http.StatusBadRequest, wantError: "Invalid delivery status"},
+		{route: "POST /webhooks/deliveries/{id}/replay", name: "unknown delivery", as: user, path: "/webhooks/deliveries/999/replay", wantStatus: http.StatusNotFound, wantError: errNotFound},
+
+		// API key administration
+		{route: "POST /admin/api-keys", name: "created", as: admin, body: map[string]interface{}{"name": "ci", "scopes": []string{"books:write"}}, wantStatus: http.StatusCreated},
+		{route: "POST /admin/api-keys", name: "not an admin", as: user, body: map[string]interface{}{"name": "ci", "scopes": []string{"books:write"}}, wantStatus: http.StatusForbidden, wantError: errScope},
+		{route: "GET /admin/api-keys", name: "listed", as: admin, wantStatus: http.StatusOK},
+		{route: "GET /admin/api-keys", name: "not an admin", as: reader, wantStatus: http.StatusForbidden, wantError: errScope},
+		{route: "DELETE /admin/api-keys/{id}", name: "revoked", as: admin, path: "/admin/api-keys/{apiKey}", wantStatus: http.StatusNoContent},
+		{route: "DELETE /admin/api-keys/{id}", name: "unknown", as: admin, path: "/admin/api-keys/999", wantStatus: http.StatusNotFound, wantError: errNotFound},
+
+		// Tenant administration
+		{route: "POST /admin/tenants", name: "created", as: admin, body: map[string]string{"slug": "store-c", "name": "Store C"}, wantStatus: http.StatusCreated},
+		{route: "POST /admin/tenants", name: "duplicate slug", as: admin, body: map[string]string{"slug": "store-b", "name": "Store B"}, wantStatus: http.StatusConflict},
+		{route: "POST /admin/tenants", name: "not an admin", as: user, body: map[string]string{"slug": "store-c", "name": "Store C"}, wantStatus: http.StatusForbidden, wantError: errScope},
+		{route: "GET /admin/tenants", name: "listed", as: admin, wantStatus: http.StatusOK},
+		{route: "GET /admin/tenants/{id}", name: "found", as: admin, path: "/admin/tenants/{tenant}", wantStatus: http.StatusOK},
+		{route: "GET /admin/tenants/{id}", name: "unknown", as: admin, path: "/admin/tenants/999", wantStatus: http.StatusNotFound, wantError: errNotFound},
+		{route: "PUT /admin/tenants/{id}", name: "updated", as: admin, path: "/admin/tenants/{tenant}", body: map[string]string{"slug": "store-b", "name": "Store B2"}, wantStatus: http.StatusOK},
+		{route: "DELETE /admin/tenants/{id}", name: "deleted", as: admin, path: "/admin/tenants/{tenant}", wantStatus: http.StatusNoContent},
+		{route: "DELETE /admin/tenants/{id}", name: "default tenant", as: admin, path: "/admin/tenants/1", wantStatus: http.StatusConflict, wantError: "conflict: the default tenant cannot be deleted"},
+
+		// GraphQL
+		{route: "POST /graphql", name: "anonymous query", as: anonymous, body: map[string]string{"query": "{ books { totalCount } }"}, wantStatus: http.StatusOK},
+		{route: "POST /graphql", name: "missing query", as: anonymous, body: map[string]string{}, wantStatus: http.StatusBadRequest, wantError: "Missing query"},
+		{route: "POST /graphql", name: "forged token", as: forged, body: map[string]string{"query": "{ books { totalCount } }"}, wantStatus: http.StatusUnauthorized, wantError: errInvalidToken},
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	for _, tc := range routeCases() {
+		t.Run(tc.route+"/"+tc.name, func(t *testing.T) {
+			ts := newTestServer(t)
+			f := ts.seed(t)
+
+			method, pattern, _ := strings.Cut(tc.route, " ")
+			path := tc.path
+			if path == "" {
+				path = pattern
+			}
+			resp := ts.do(t, method, f.path(path), tc.body, tc.as(t, f)...)
+			if resp.StatusCode != tc.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tc.wantStatus)
+			}
+			assertEnvelope(t, resp, tc.wantError)
+		})
+	}
+}
+
+func TestRouteCasesCoverEveryRoute(t *testing.T) {
+	covered := make(map[string]bool)
+	for _, tc := range routeCases() {
+		covered[tc.route] = true
+	}
+
+	for _, route := range registeredRoutes(t) {
+		if !covered[route] {
+			t.Errorf("Route %q has no case in routeCases", route)
+		}
+		delete(covered, route)
+	}
+	for route := range covered {
+		t.Errorf("routeCases covers %q, which is not registered", route)
+	}
+}
+
+// publicRoutes are the routes served without credentials.
+var publicRoutes = map[string]bool{
+	"GET /openapi.json":         true,
+	"POST /auth/token":          true,
+	"GET /books":                true,
+	"GET /books/{id}":           true,
+	"GET /books/{id}/prices":    true,
+	"GET /categories":           true,
+	"GET /categories/{id}":      true,
+	"GET /tags":                 true,
+	"GET /shared/lists/{token}": true,
+	"POST /graphql":             true,
+}
+
+// wildcard matches a path wildcard such as {id}.
+var wildcard = regexp.MustCompile(`\{[^}]+\}`)
+
+// idWildcard matches a path wildcard holding a numeric ID, unlike {token}.
+var idWildcard = regexp.MustCompile(`\{(id|[a-z]+Id)\}`)
+
+// invalidID matches the error for an ID that extractIDFromPath rejects.
+var invalidID = regexp.MustCompile(`^Invalid [a-zA-Z ]+ ID$`)
+
+func TestProtectedRoutesRequireCredentials(t *testing.T) {
+	ts := newTestServer(t)
+
+	for _, route := range registeredRoutes(t) {
+		if publicRoutes[route] {
+			continue
+		}
+		t.Run(route, func(t *testing.T) {
+			method, pattern, _ := strings.Cut(route, " ")
+			resp := ts.do(t, method, wildcard.ReplaceAllString(pattern, "1"), "{}")
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+			}
+			assertEnvelope(t, resp, errMissingAuth)
+		})
+	}
+}
+
+func TestMalformedIDsAreRejected(t *testing.T) {
+	ts := newTestServer(t)
+	auth := withAuth(userToken(t, testAdmin))
+
+	// Values extractIDFromPath rejects: not a number, negative, fractional
+	// and beyond 32 bits.
+	for _, id := range []string{"abc", "-1", "1.5", "4294967296"} {
+		for _, route := range registeredRoutes(t) {
+			method, pattern, _ := strings.Cut(route, " ")
+			if !idWildcard.MatchString(pattern) {
+				continue
+			}
+			t.Run(fmt.Sprintf("%s/%s", route, id), func(t *testing.T) {
+				resp := ts.do(t, method, idWildcard.ReplaceAllString(pattern, id), "{}", auth)
+				if resp.StatusCode != http.StatusBadRequest {
+					t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+				}
+				if message := assertEnvelope(t, resp, ""); !invalidID.MatchString(message) {
+					t.Errorf("error = %q, want \"Invalid <resource> ID\"", message)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"bookstore-api/internal/models"
+)
+
+func TestWebhookSubscriptionsRefusePrivateURLs(t *testing.T) {
+	ts := newTestServer(t)
+	user := withAuth(userToken(t, "alice"))
+
+	for _, url := range []string{
+		"http://localhost:8080/hook",
+		"http://127.0.0.1/hook",
+		"http://[::1]/hook",
+		"https://10.0.0.5/hook",
+		"https://192.168.0.10/hook",
+		"http://169.254.169.254/latest/meta-data",
+	} {
+		body := map[string]interface{}{"url": url, "event_types": []string{models.EventBookCreated}}
+		resp := ts.do(t, http.MethodPost, "/webhooks", body, user)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("subscribing %s: status %d, want 400", url, resp.StatusCode)
+		}
+	}
+
+	// Local development can opt in.
+	ts.cfg.Webhooks.AllowPrivateTargets = true
+	a, err := newApp(ts.cfg, ts.db)
+	if err != nil {
+		t.Fatalf("newApp: %v", err)
+	}
+	ts.Config.Handler = a.handler
+	body := map[string]interface{}{"url": "http://127.0.0.1:9000/hook", "event_types": []string{models.EventBookCreated}}
+	ts.mustDo(t, http.MethodPost, "/webhooks", body, http.StatusCreated, nil, user)
+}
+
+func TestWebhookDeliveryReplay(t *testing.T) {
+	ts := newTestServer(t)
+	f := ts.seed(t)
+	user := withAuth(userToken(t, "alice"))
+
+	delivery := models.WebhookDelivery{
+		SubscriptionID: f.webhook,
+		EventID:        1,
+		EventType:      models.EventBookCreated,
+		Payload:        `{}`,
+		Status:         models.DeliveryDead,
+		Attempts:       8,
+		NextAttemptAt:  time.Now().Add(-time.Hour),
+		LastError:      "unexpected status 502",
+	}
+	if err := ts.db.Create(&delivery).Error; err != nil {
+		t.Fatalf("create delivery: %v", err)
+	}
+	replay := fmt.Sprintf("/webhooks/deliveries/%d/replay", delivery.ID)
+
+	var replayed models.WebhookDelivery
+	ts.mustDo(t, http.MethodPost, replay, nil, http.StatusAccepted, &replayed, user)
+	if replayed.Status != models.DeliveryPending || replayed.Attempts != 0 || replayed.LastError != "" {
+		t.Errorf("replayed delivery = %s, %d attempts, error %q; want pending with a fresh budget", replayed.Status, replayed.Attempts, replayed.LastError)
+	}
+
+	// A pending delivery cannot be replayed again.
+	ts.mustDo(t, http.MethodPost, replay, nil, http.StatusConflict, nil, user)
+
+	// Nor can another tenant's delivery be seen.
+	storeB := withAuth(mintToken(t, map[string]interface{}{"sub": "bob", "tenant_id": f.tenant}))
+	ts.mustDo(t, http.MethodPost, replay, nil, http.StatusNotFound, nil, storeB)
+}
@@ -0,0 +1,157 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader serves a certificate and optional client CA pool loaded from
+// disk, reloading them when the files change.
+type Reloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	requireCert  bool
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes map[string]time.Time
+}
+
+// NewReloader loads the key pair and, when clientCAFile is set, the client
+// CA bundle. requireClientCert rejects clients without a certificate;
+// otherwise a certificate is verified only when one is presented.
+func NewReloader(certFile, keyFile, clientCAFile string, requireClientCert bool) (*Reloader, error) {
+	r := &Reloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		requireCert:  requireClientCert,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig returns a server TLS configuration that always uses the most
+// recently loaded certificate and client CA pool.
+func (r *Reloader) TLSConfig() *tls.Config {
+	base := &tls.Config{MinVersion: tls.VersionTLS12}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+
+		cfg := &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{*r.cert},
+			NextProtos:   []string{"h2", "http/1.1"},
+		}
+		if r.clientCA != nil {
+			cfg.ClientCAs = r.clientCA
+			cfg.ClientAuth = tls.VerifyClientCertIfGiven
+			if r.requireCert {
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+		}
+		return cfg, nil
+	}
+	return base
+}
+
+// Watch checks the files every interval and reloads them after a change
+// until ctx is cancelled. A failed reload keeps the previous certificate.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.reload(); err != nil {
+				log.Printf("TLS reload failed, keeping previous certificate: %v", err)
+				continue
+			}
+			log.Println("TLS certificate reloaded")
+		}
+	}
+}
+
+// reload reads every file and swaps in the new certificate and pool.
+func (r *Reloader) reload() error {
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load key pair: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if r.clientCAFile != "" {
+		pem, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("client CA file contains no PEM certificates")
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.clientCA = pool
+	r.modTimes = modTimes
+	return nil
+}
+
+// changed reports whether any file's modification time differs from the
+// last successful load.
+func (r *Reloader) changed() bool {
+	modTimes, err := r.stat()
+	if err != nil {
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for path, modTime := range modTimes {
+		if !modTime.Equal(r.modTimes[path]) {
+			return true
+		}
+	}
+	return false
+}
+
+// stat returns the modification time of every configured file.
+func (r *Reloader) stat() (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time)
+	for _, path := range []string{r.certFile, r.keyFile, r.clientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[path] = info.ModTime()
+	}
+	return modTimes, nil
+}
@@ -0,0 +1,283 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// Watch logs every reload.
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// authority is a generated CA that issues test certificates.
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newAuthority(t *testing.T) *authority {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &authority{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns PEM certificate and key for a leaf with the given serial
+// number and common name, usable by servers and clients.
+func (a *authority) issue(t *testing.T, serial int64, commonName string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatalf("issue certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// files holds the paths of a reloader's certificate, key and client CA.
+type files struct {
+	cert, key, clientCA string
+}
+
+// write replaces a file's content and moves its modification time
+// forward, so a change is seen even within the file system's timestamp
+// resolution.
+func write(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("chtimes %s: %v", path, err)
+	}
+}
+
+// newFiles writes a server certificate with the given serial and the CA
+// bundle to a temporary directory.
+func newFiles(t *testing.T, ca *authority, serial int64) files {
+	t.Helper()
+	dir := t.TempDir()
+	f := files{cert: filepath.Join(dir, "cert.pem"), key: filepath.Join(dir, "key.pem"), clientCA: filepath.Join(dir, "ca.pem")}
+	certPEM, keyPEM := ca.issue(t, serial, "server")
+	now := time.Now()
+	write(t, f.cert, certPEM, now)
+	write(t, f.key, keyPEM, now)
+	write(t, f.clientCA, ca.pem, now)
+	return f
+}
+
+// servedSerial returns the serial number of the certificate the reloader
+// serves.
+func servedSerial(t *testing.T, r *Reloader) int64 {
+	t.Helper()
+	cfg, err := r.TLSConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("GetConfigForClient: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatalf("parse served certificate: %v", err)
+	}
+	return leaf.SerialNumber.Int64()
+}
+
+// waitForSerial polls until the reloader serves the wanted certificate.
+func waitForSerial(t *testing.T, r *Reloader, want int64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for servedSerial(t, r) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("serving serial %d, want %d", servedSerial(t, r), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReloaderPicksUpChangedFiles(t *testing.T) {
+	ca := newAuthority(t)
+	f := newFiles(t, ca, 100)
+	r, err := NewReloader(f.cert, f.key, "", false)
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+	if got := servedSerial(t, r); got != 100 {
+		t.Fatalf("serving serial %d, want 100", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx, 10*time.Millisecond)
+
+	certPEM, keyPEM := ca.issue(t, 101, "server")
+	later := time.Now().Add(time.Minute)
+	write(t, f.key, keyPEM, later)
+	write(t, f.cert, certPEM, later)
+	waitForSerial(t, r, 101)
+
+	// A broken certificate is not swapped in.
+	write(t, f.cert, []byte("not a certificate"), later.Add(time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if got := servedSerial(t, r); got != 101 {
+		t.Fatalf("serving serial %d after a failed reload, want 101", got)
+	}
+
+	// Fixing it is picked up on a later tick.
+	certPEM, keyPEM = ca.issue(t, 102, "server")
+	write(t, f.key, keyPEM, later.Add(2*time.Minute))
+	write(t, f.cert, certPEM, later.Add(2*time.Minute))
+	waitForSerial(t, r, 102)
+}
+
+func TestReloaderClientAuth(t *testing.T) {
+	ca := newAuthority(t)
+	f := newFiles(t, ca, 100)
+
+	tests := []struct {
+		name     string
+		clientCA string
+		require  bool
+		want     tls.ClientAuthType
+	}{
+		{"no client CA", "", true, tls.NoClientCert},
+		{"optional", f.clientCA, false, tls.VerifyClientCertIfGiven},
+		{"required", f.clientCA, true, tls.RequireAndVerifyClientCert},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReloader(f.cert, f.key, tt.clientCA, tt.require)
+			if err != nil {
+				t.Fatalf("NewReloader: %v", err)
+			}
+			cfg, _ := r.TLSConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+			if cfg.ClientAuth != tt.want {
+				t.Errorf("ClientAuth = %v, want %v", cfg.ClientAuth, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewReloaderErrors(t *testing.T) {
+	ca := newAuthority(t)
+	f := newFiles(t, ca, 100)
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	write(t, empty, []byte("no certificates here"), time.Now())
+
+	tests := []struct {
+		name                string
+		cert, key, clientCA string
+	}{
+		{"missing certificate", filepath.Join(t.TempDir(), "missing.pem"), f.key, ""},
+		{"mismatched key", f.clientCA, f.key, ""},
+		{"client CA without certificates", f.cert, f.key, empty},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewReloader(tt.cert, tt.key, tt.clientCA, false); err == nil {
+				t.Error("NewReloader succeeded")
+			}
+		})
+	}
+}
+
+func TestReloaderVerifiesClientCertificates(t *testing.T) {
+	ca := newAuthority(t)
+	f := newFiles(t, ca, 100)
+	r, err := NewReloader(f.cert, f.key, f.clientCA, true)
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, req.TLS.VerifiedChains[0][0].Subject.CommonName)
+	}))
+	server.TLS = r.TLSConfig()
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			ServerName:   "localhost",
+			Certificates: certs,
+		}}}
+	}
+
+	certPEM, keyPEM := ca.issue(t, 200, "alice")
+	clientCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("client key pair: %v", err)
+	}
+	resp, err := client(clientCert).Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET with a client certificate: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "alice" {
+		t.Errorf("server saw client %q, want alice", body)
+	}
+
+	if _, err := client().Get(server.URL); err == nil {
+		t.Error("GET without a client certificate succeeded")
+	}
+
+	other := newAuthority(t)
+	certPEM, keyPEM = other.issue(t, 300, "mallory")
+	untrusted, _ := tls.X509KeyPair(certPEM, keyPEM)
+	if _, err := client(untrusted).Get(server.URL); err == nil {
+		t.Error("GET with a certificate from another CA succeeded")
+	}
+}
@@ -1,45 +1,430 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
+// redacted replaces secret values in printed configuration.
+const redacted = "[REDACTED]"
+
+// minJWTSecretLength is the shortest JWT secret accepted at startup.
+const minJWTSecretLength = 32
+
 // Config holds all configuration for the application.
+//
+// Every field can be set in a YAML or TOML file and overridden by the
+// environment variable named in its env tag. Fields tagged secret are
+// redacted by Redacted.
 type Config struct {
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	JWTSecret  string
-	ServerPort string
+	Server      ServerConfig      `yaml:"server" toml:"server"`
+	Database    DatabaseConfig    `yaml:"database" toml:"database"`
+	Auth        AuthConfig        `yaml:"auth" toml:"auth"`
+	TLS         TLSConfig         `yaml:"tls" toml:"tls"`
+	Webhooks    WebhookConfig     `yaml:"webhooks" toml:"webhooks"`
+	Idempotency IdempotencyConfig `yaml:"idempotency" toml:"idempotency"`
+	Pricing     PricingConfig     `yaml:"pricing" toml:"pricing"`
+	Events      EventsConfig      `yaml:"events" toml:"events"`
+}
+
+// ServerConfig configures the HTTP and gRPC listeners.
+type ServerConfig struct {
+	Port            int           `yaml:"port" toml:"port" env:"SERVER_PORT"`
+	GRPCPort        int           `yaml:"grpc_port" toml:"grpc_port" env:"GRPC_PORT"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+}
+
+// DatabaseConfig configures the PostgreSQL connection and pool.
+type DatabaseConfig struct {
+	Host            string        `yaml:"host" toml:"host" env:"DB_HOST"`
+	Port            int           `yaml:"port" toml:"port" env:"DB_PORT"`
+	User            string        `yaml:"user" toml:"user" env:"DB_USER"`
+	Password        string        `yaml:"password" toml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name            string        `yaml:"name" toml:"name" env:"DB_NAME"`
+	SSLMode         string        `yaml:"ssl_mode" toml:"ssl_mode" env:"DB_SSL_MODE"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+}
+
+// AuthConfig configures JWT authentication. Token and client certificate
+// subjects listed in AdminSubjects may manage API keys. TokenEndpoint
+// serves POST /auth/token, which issues a token for any username to anyone;
+// it is meant for development and cannot be combined with AdminSubjects.
+// Its tokens do not reach reading lists or notifications.
+type AuthConfig struct {
+	JWTSecret     string        `yaml:"jwt_secret" toml:"jwt_secret" env:"JWT_SECRET" secret:"true"`
+	TokenTTL      time.Duration `yaml:"token_ttl" toml:"token_ttl" env:"JWT_TOKEN_TTL"`
+	TokenEndpoint bool          `yaml:"token_endpoint" toml:"token_endpoint" env:"AUTH_TOKEN_ENDPOINT"`
+	AdminSubjects []string      `yaml:"admin_subjects" toml:"admin_subjects" env:"AUTH_ADMIN_SUBJECTS"`
+}
+
+// TLSConfig configures HTTPS and gRPC TLS serving. Setting ClientCAFile
+// enables mutual TLS: client certificates signed by that CA authenticate
+// callers in place of a JWT.
+type TLSConfig struct {
+	Enabled           bool          `yaml:"enabled" toml:"enabled" env:"TLS_ENABLED"`
+	CertFile          string        `yaml:"cert_file" toml:"cert_file" env:"TLS_CERT_FILE"`
+	KeyFile           string        `yaml:"key_file" toml:"key_file" env:"TLS_KEY_FILE"`
+	ClientCAFile      string        `yaml:"client_ca_file" toml:"client_ca_file" env:"TLS_CLIENT_CA_FILE"`
+	RequireClientCert bool          `yaml:"require_client_cert" toml:"require_client_cert" env:"TLS_REQUIRE_CLIENT_CERT"`
+	ReloadInterval    time.Duration `yaml:"reload_interval" toml:"reload_interval" env:"TLS_RELOAD_INTERVAL"`
+}
+
+// WebhookConfig configures the webhook dispatcher.
+type WebhookConfig struct {
+	Enabled        bool          `yaml:"enabled" toml:"enabled" env:"WEBHOOKS_ENABLED"`
+	PollInterval   time.Duration `yaml:"poll_interval" toml:"poll_interval" env:"WEBHOOKS_POLL_INTERVAL"`
+	RequestTimeout time.Duration `yaml:"request_timeout" toml:"request_timeout" env:"WEBHOOKS_REQUEST_TIMEOUT"`
+	MaxAttempts    int           `yaml:"max_attempts" toml:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS"`
+	BaseBackoff    time.Duration `yaml:"base_backoff" toml:"base_backoff" env:"WEBHOOKS_BASE_BACKOFF"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" toml:"max_backoff" env:"WEBHOOKS_MAX_BACKOFF"`
+	// AllowPrivateTargets permits subscriptions and deliveries to
+	// loopback, private and link-local addresses, for local development.
+	AllowPrivateTargets bool `yaml:"allow_private_targets" toml:"allow_private_targets" env:"WEBHOOKS_ALLOW_PRIVATE_TARGETS"`
+}
+
+// IdempotencyConfig configures replay of write requests that carry an
+// Idempotency-Key header.
+type IdempotencyConfig struct {
+	TTL           time.Duration `yaml:"ttl" toml:"ttl" env:"IDEMPOTENCY_TTL"`
+	PurgeInterval time.Duration `yaml:"purge_interval" toml:"purge_interval" env:"IDEMPOTENCY_PURGE_INTERVAL"`
+}
+
+// PricingConfig configures the scheduler that applies scheduled price
+// changes and sales.
+type PricingConfig struct {
+	SchedulerEnabled bool          `yaml:"scheduler_enabled" toml:"scheduler_enabled" env:"PRICING_SCHEDULER_ENABLED"`
+	PollInterval     time.Duration `yaml:"poll_interval" toml:"poll_interval" env:"PRICING_POLL_INTERVAL"`
+}
+
+// EventsConfig configures the Server-Sent Events stream of book changes.
+// ReplayBuffer is the number of recent events kept for clients resuming
+// with Last-Event-ID.
+type EventsConfig struct {
+	ReplayBuffer      int           `yaml:"replay_buffer" toml:"replay_buffer" env:"EVENTS_REPLAY_BUFFER"`
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval" toml:"heartbeat_interval" env:"EVENTS_HEARTBEAT_INTERVAL"`
 }
 
-// Load reads configuration from .env file and environment variables.
-func Load() *Config {
+// Default returns the configuration used when nothing overrides it. It has
+// no JWT secret, so it does not validate on its own.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            3000,
+			GRPCPort:        9090,
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 10 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            5432,
+			User:            "postgres",
+			Name:            "bookstore",
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Auth: AuthConfig{
+			TokenTTL: 24 * time.Hour,
+		},
+		TLS: TLSConfig{
+			ReloadInterval: 30 * time.Second,
+		},
+		Webhooks: WebhookConfig{
+			Enabled:        true,
+			PollInterval:   2 * time.Second,
+			RequestTimeout: 10 * time.Second,
+			MaxAttempts:    8,
+			BaseBackoff:    30 * time.Second,
+			MaxBackoff:     time.Hour,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:           24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Pricing: PricingConfig{
+			SchedulerEnabled: true,
+			PollInterval:     15 * time.Second,
+		},
+		Events: EventsConfig{
+			ReplayBuffer:      1000,
+			HeartbeatInterval: 15 * time.Second,
+		},
+	}
+}
+
+// Load builds the configuration like Resolve and validates it. Every
+// problem found is reported in the returned error.
+func Load(path string) (*Config, error) {
+	cfg, err := Resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Resolve builds the configuration from defaults, the optional YAML or
+// TOML file at path, a .env file and environment variables, in increasing
+// order of precedence, without validating it. Every environment variable
+// that fails to parse is reported in the returned error.
+func Resolve(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+
 	// Load .env file if it exists (ignore error if not found)
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
 	}
 
-	return &Config{
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "5432"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "postgres"),
-		DBName:     getEnv("DB_NAME", "bookstore"),
-		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key"),
-		ServerPort: getEnv("SERVER_PORT", "3000"),
+	if err := applyEnv(reflect.ValueOf(cfg).Elem()); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile decodes a YAML or TOML file, chosen by extension, into cfg.
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("failed to parse %s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("unsupported config file format %q (use .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+	return nil
+}
+
+// applyEnv overrides fields that have an env tag with the value of that
+// environment variable, collecting every parse failure.
+func applyEnv(v reflect.Value) error {
+	var errs []error
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			if err := applyEnv(value); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		key := field.Tag.Get("env")
+		raw, exists := os.LookupEnv(key)
+		if key == "" || !exists {
+			continue
+		}
+		if err := setField(value, strings.TrimSpace(raw)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setField parses raw into a string, bool, int or duration field, or a
+// comma-separated string slice.
+func setField(value reflect.Value, raw string) error {
+	switch {
+	case value.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		value.SetInt(int64(d))
+	case value.Kind() == reflect.String:
+		value.SetString(raw)
+	case value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		value.SetBool(b)
+	case value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		value.SetInt(int64(n))
+	case value.Type() == reflect.TypeOf([]string(nil)):
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", value.Type())
+	}
+	return nil
+}
+
+// Validate checks every field and returns an error listing all invalid
+// ones, or nil when the configuration is usable.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, field, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	check(validPort(c.Server.Port), "server.port", "must be between 1 and 65535, got %d", c.Server.Port)
+	check(validPort(c.Server.GRPCPort), "server.grpc_port", "must be between 1 and 65535, got %d", c.Server.GRPCPort)
+	check(c.Server.Port != c.Server.GRPCPort, "server.grpc_port", "must differ from server.port")
+	check(c.Server.ReadTimeout > 0, "server.read_timeout", "must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout", "must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout", "must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout", "must be positive")
+
+	check(c.Database.Host != "", "database.host", "is required")
+	check(validPort(c.Database.Port), "database.port", "must be between 1 and 65535, got %d", c.Database.Port)
+	check(c.Database.User != "", "database.user", "is required")
+	check(c.Database.Name != "", "database.name", "is required")
+	check(validSSLMode(c.Database.SSLMode), "database.ssl_mode", "unknown mode %q", c.Database.SSLMode)
+	check(c.Database.MaxOpenConns > 0, "database.max_open_conns", "must be positive")
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns", "must not be negative")
+	check(c.Database.MaxIdleConns <= c.Database.MaxOpenConns, "database.max_idle_conns", "must not exceed database.max_open_conns")
+	check(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime", "must not be negative")
+	check(c.Database.ConnMaxIdleTime >= 0, "database.conn_max_idle_time", "must not be negative")
+
+	check(c.Auth.JWTSecret != "", "auth.jwt_secret", "is required")
+	check(c.Auth.JWTSecret == "" || len(c.Auth.JWTSecret) >= minJWTSecretLength,
+		"auth.jwt_secret", "must be at least %d characters", minJWTSecretLength)
+	check(c.Auth.TokenTTL > 0, "auth.token_ttl", "must be positive")
+	for i, subject := range c.Auth.AdminSubjects {
+		check(strings.TrimSpace(subject) != "", fmt.Sprintf("auth.admin_subjects[%d]", i), "must not be empty")
+	}
+	check(!c.Auth.TokenEndpoint || len(c.Auth.AdminSubjects) == 0,
+		"auth.token_endpoint", "must be disabled when auth.admin_subjects is set, since it issues tokens for any subject")
+
+	if c.TLS.Enabled {
+		check(fileExists(c.TLS.CertFile), "tls.cert_file", "must name a readable file when TLS is enabled")
+		check(fileExists(c.TLS.KeyFile), "tls.key_file", "must name a readable file when TLS is enabled")
+		check(c.TLS.ReloadInterval > 0, "tls.reload_interval", "must be positive")
+	}
+	if c.TLS.ClientCAFile != "" {
+		check(c.TLS.Enabled, "tls.client_ca_file", "requires tls.enabled")
+		check(fileExists(c.TLS.ClientCAFile), "tls.client_ca_file", "must name a readable file")
+	}
+	check(!c.TLS.RequireClientCert || c.TLS.ClientCAFile != "", "tls.require_client_cert", "requires tls.client_ca_file")
+
+	if c.Webhooks.Enabled {
+		check(c.Webhooks.PollInterval > 0, "webhooks.poll_interval", "must be positive")
+		check(c.Webhooks.RequestTimeout > 0, "webhooks.request_timeout", "must be positive")
+		check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts", "must be positive")
+		check(c.Webhooks.BaseBackoff > 0, "webhooks.base_backoff", "must be positive")
+		check(c.Webhooks.MaxBackoff >= c.Webhooks.BaseBackoff, "webhooks.max_backoff", "must not be less than webhooks.base_backoff")
+	}
+
+	check(c.Idempotency.TTL > 0, "idempotency.ttl", "must be positive")
+	check(c.Idempotency.PurgeInterval > 0, "idempotency.purge_interval", "must be positive")
+
+	if c.Pricing.SchedulerEnabled {
+		check(c.Pricing.PollInterval > 0, "pricing.poll_interval", "must be positive")
+	}
+
+	check(c.Events.ReplayBuffer > 0, "events.replay_buffer", "must be positive")
+	check(c.Events.HeartbeatInterval > 0, "events.heartbeat_interval", "must be positive")
+
+	return errors.Join(errs...)
+}
+
+// Redacted returns a copy of the configuration with secrets masked, safe
+// to print or log.
+func (c *Config) Redacted() *Config {
+	copied := *c
+	redact(reflect.ValueOf(&copied).Elem())
+	return &copied
+}
+
+// YAML renders the configuration as YAML.
+func (c *Config) YAML() (string, error) {
+	out, err := yaml.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// redact masks non-empty string fields tagged secret.
+func redact(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			redact(value)
+		case field.Tag.Get("secret") == "true" && value.Kind() == reflect.String && value.String() != "":
+			value.SetString(redacted)
+		}
+	}
+}
+
+// validPort reports whether p is a usable TCP port.
+func validPort(p int) bool {
+	return p > 0 && p <= 65535
+}
+
+// validSSLMode reports whether mode is a libpq sslmode.
+func validSSLMode(mode string) bool {
+	switch mode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+		return true
 	}
+	return false
 }
 
-// getEnv returns the value of an environment variable or a default value.
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// fileExists reports whether path names a readable regular file.
+func fileExists(path string) bool {
+	if path == "" {
+		return false
 	}
-	return defaultValue
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
 }
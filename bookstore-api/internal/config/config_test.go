@@ -0,0 +1,268 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "config-test-secret-of-32-characters"
+
+// isolateEnv unsets every variable Resolve reads for the rest of the test.
+func isolateEnv(t *testing.T) {
+	t.Helper()
+	var walk func(reflect.Type)
+	walk = func(typ reflect.Type) {
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if field.Type.Kind() == reflect.Struct {
+				walk(field.Type)
+				continue
+			}
+			if key := field.Tag.Get("env"); key != "" {
+				t.Setenv(key, "")
+				os.Unsetenv(key)
+			}
+		}
+	}
+	walk(reflect.TypeOf(Config{}))
+}
+
+// writeFile writes a config file named name in a temporary directory.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+// valid returns the default configuration with a JWT secret, which
+// validates.
+func valid() *Config {
+	cfg := Default()
+	cfg.Auth.JWTSecret = testSecret
+	return cfg
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			file: "config.yaml",
+			content: "server:\n  port: 4000\nauth:\n  jwt_secret: " + testSecret +
+				"\n  admin_subjects: [root]\nwebhooks:\n  max_backoff: 2h\n",
+		},
+		{
+			name: "toml",
+			file: "config.toml",
+			content: "[server]\nport = 4000\n[auth]\njwt_secret = \"" + testSecret +
+				"\"\nadmin_subjects = [\"root\"]\n[webhooks]\nmax_backoff = \"2h\"\n",
+		},
+		{name: "empty yaml", file: "config.yml", content: "", wantErr: "auth.jwt_secret: is required"},
+		{name: "unknown yaml key", file: "config.yaml", content: "server:\n  prot: 4000\n", wantErr: "field prot not found"},
+		{name: "unknown toml key", file: "config.toml", content: "[server]\nprot = 4000\n", wantErr: "unknown keys [server.prot]"},
+		{name: "unsupported format", file: "config.json", content: "{}", wantErr: `unsupported config file format ".json"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateEnv(t)
+			cfg, err := Load(writeFile(t, tt.file, tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.Server.Port != 4000 || cfg.Webhooks.MaxBackoff != 2*time.Hour || !reflect.DeepEqual(cfg.Auth.AdminSubjects, []string{"root"}) {
+				t.Errorf("file values not applied: port %d, max_backoff %v, admin_subjects %v",
+					cfg.Server.Port, cfg.Webhooks.MaxBackoff, cfg.Auth.AdminSubjects)
+			}
+			// Fields the file leaves out keep their defaults.
+			if cfg.Server.GRPCPort != Default().Server.GRPCPort {
+				t.Errorf("grpc_port = %d, want the default", cfg.Server.GRPCPort)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		isolateEnv(t)
+		if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil || !strings.Contains(err.Error(), "failed to read config file") {
+			t.Errorf("Load error = %v, want a read failure", err)
+		}
+	})
+}
+
+func TestEnvOverrides(t *testing.T) {
+	tests := []struct {
+		env   string
+		value string
+		got   func(*Config) interface{}
+		want  interface{}
+	}{
+		{"SERVER_PORT", "5000", func(c *Config) interface{} { return c.Server.Port }, 5000},
+		{"DB_HOST", " db.internal ", func(c *Config) interface{} { return c.Database.Host }, "db.internal"},
+		{"JWT_TOKEN_TTL", "90m", func(c *Config) interface{} { return c.Auth.TokenTTL }, 90 * time.Minute},
+		{"WEBHOOKS_ENABLED", "false", func(c *Config) interface{} { return c.Webhooks.Enabled }, false},
+		{"AUTH_ADMIN_SUBJECTS", "alice, bob,", func(c *Config) interface{} { return c.Auth.AdminSubjects }, []string{"alice", "bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			isolateEnv(t)
+			// The environment takes precedence over the file.
+			path := writeFile(t, "config.yaml", "server:\n  port: 4000\ndatabase:\n  host: from-file\n")
+			t.Setenv("JWT_SECRET", testSecret)
+			t.Setenv(tt.env, tt.value)
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if got := tt.got(cfg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s=%q gave %#v, want %#v", tt.env, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvErrorsAreAllReported(t *testing.T) {
+	isolateEnv(t)
+	t.Setenv("SERVER_PORT", "eighty")
+	t.Setenv("JWT_TOKEN_TTL", "soon")
+	t.Setenv("TLS_ENABLED", "maybe")
+
+	_, err := Resolve("")
+	if err == nil {
+		t.Fatal("Resolve accepted unparseable variables")
+	}
+	for _, want := range []string{
+		`SERVER_PORT: invalid integer "eighty"`,
+		`JWT_TOKEN_TTL: invalid duration "soon"`,
+		`TLS_ENABLED: invalid boolean "maybe"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestResolveDoesNotValidate(t *testing.T) {
+	isolateEnv(t)
+	t.Setenv("SERVER_PORT", "0")
+	cfg, err := Resolve("")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if cfg.Server.Port != 0 {
+		t.Errorf("port = %d, want the invalid override kept", cfg.Server.Port)
+	}
+	if _, err := Load(""); err == nil {
+		t.Error("Load accepted an invalid configuration")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("valid configuration rejected: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   string
+	}{
+		{"port", func(c *Config) { c.Server.Port = 70000 }, "server.port: must be between 1 and 65535, got 70000"},
+		{"same ports", func(c *Config) { c.Server.GRPCPort = c.Server.Port }, "server.grpc_port: must differ from server.port"},
+		{"ssl mode", func(c *Config) { c.Database.SSLMode = "sometimes" }, `database.ssl_mode: unknown mode "sometimes"`},
+		{"idle conns", func(c *Config) { c.Database.MaxIdleConns = c.Database.MaxOpenConns + 1 }, "database.max_idle_conns: must not exceed database.max_open_conns"},
+		{"missing secret", func(c *Config) { c.Auth.JWTSecret = "" }, "auth.jwt_secret: is required"},
+		{"short secret", func(c *Config) { c.Auth.JWTSecret = "short" }, "auth.jwt_secret: must be at least 32 characters"},
+		{"blank admin", func(c *Config) { c.Auth.AdminSubjects = []string{"root", " "} }, "auth.admin_subjects[1]: must not be empty"},
+		{"token endpoint with admins", func(c *Config) { c.Auth.TokenEndpoint = true; c.Auth.AdminSubjects = []string{"root"} }, "auth.token_endpoint: must be disabled when auth.admin_subjects is set"},
+		{"token endpoint alone", func(c *Config) { c.Auth.TokenEndpoint = true }, ""},
+		{"tls files", func(c *Config) { c.TLS.Enabled = true; c.TLS.CertFile = "/nonexistent" }, "tls.cert_file: must name a readable file when TLS is enabled"},
+		{"backoff", func(c *Config) { c.Webhooks.MaxBackoff = time.Second }, "webhooks.max_backoff: must not be less than webhooks.base_backoff"},
+		{"disabled webhooks", func(c *Config) { c.Webhooks.Enabled = false; c.Webhooks.MaxAttempts = 0 }, ""},
+		{"replay buffer", func(c *Config) { c.Events.ReplayBuffer = 0 }, "events.replay_buffer: must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.mutate(cfg)
+			err := cfg.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Validate = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("every field reported", func(t *testing.T) {
+		cfg := valid()
+		cfg.Server.Port = 0
+		cfg.Database.Host = ""
+		cfg.Auth.TokenTTL = 0
+		cfg.Idempotency.TTL = -time.Second
+		err := cfg.Validate()
+		if err == nil {
+			t.Fatal("Validate accepted four bad fields")
+		}
+		lines := strings.Split(err.Error(), "\n")
+		want := []string{
+			"server.port: must be between 1 and 65535, got 0",
+			"database.host: is required",
+			"auth.token_ttl: must be positive",
+			"idempotency.ttl: must be positive",
+		}
+		if !reflect.DeepEqual(lines, want) {
+			t.Errorf("Validate reported:\n%s\nwant:\n%s", err, strings.Join(want, "\n"))
+		}
+	})
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := valid()
+	cfg.Database.Password = "hunter2"
+	cfg.Auth.AdminSubjects = []string{"root"}
+
+	redactedCfg := cfg.Redacted()
+	if redactedCfg.Auth.JWTSecret != redacted || redactedCfg.Database.Password != redacted {
+		t.Errorf("secrets not masked: jwt_secret %q, password %q", redactedCfg.Auth.JWTSecret, redactedCfg.Database.Password)
+	}
+	if redactedCfg.Database.User != cfg.Database.User || !reflect.DeepEqual(redactedCfg.Auth.AdminSubjects, cfg.Auth.AdminSubjects) {
+		t.Error("non-secret fields changed")
+	}
+	if cfg.Auth.JWTSecret != testSecret || cfg.Database.Password != "hunter2" {
+		t.Error("Redacted modified the original")
+	}
+
+	out, err := redactedCfg.YAML()
+	if err != nil {
+		t.Fatalf("YAML: %v", err)
+	}
+	if strings.Contains(out, testSecret) || strings.Contains(out, "hunter2") {
+		t.Errorf("rendered configuration leaks a secret:\n%s", out)
+	}
+
+	// Unset secrets stay empty so the output shows they are missing.
+	cfg.Database.Password = ""
+	if got := cfg.Redacted().Database.Password; got != "" {
+		t.Errorf("empty password rendered as %q", got)
+	}
+}
@@ -12,20 +12,80 @@ import (
 
 // Connect establishes a connection to the PostgreSQL database.
 func Connect(cfg *config.Config) (*gorm.DB, error) {
+	dbCfg := cfg.Database
 	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName,
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		dbCfg.Host, dbCfg.Port, dbCfg.User, dbCfg.Password, dbCfg.Name, dbCfg.SSLMode,
 	)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	// Auto-migrate the Book model
-	if err := db.AutoMigrate(&models.Book{}); err != nil {
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	// Configure the connection pool
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure connection pool: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(dbCfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(dbCfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(dbCfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(dbCfg.ConnMaxIdleTime)
+
+	if err := Migrate(db); err != nil {
+		return nil, err
 	}
 
 	return db, nil
 }
+
+// Migrate creates or updates the schema and ensures the default tenant
+// exists. Existing rows are assigned to the default tenant, and the former
+// global ISBN constraint is replaced by one per tenant.
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(
+		&models.Tenant{},
+		&models.Category{},
+		&models.Book{},
+		&models.BookTag{},
+		&models.WebhookSubscription{},
+		&models.OutboxEvent{},
+		&models.WebhookDelivery{},
+		&models.APIKey{},
+		&models.IdempotencyRecord{},
+		&models.PricePoint{},
+		&models.PriceSchedule{},
+		&models.ReadingList{},
+		&models.ListEntry{},
+		&models.Notification{},
+		&models.Review{},
+	); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	// The default tenant is the first row of a new table, so it receives
+	// DefaultTenantID without bypassing the ID sequence.
+	var tenants int64
+	if err := db.Model(&models.Tenant{}).Count(&tenants).Error; err != nil {
+		return fmt.Errorf("failed to check tenants: %w", err)
+	}
+	if tenants == 0 {
+		tenant := models.Tenant{Slug: "default", Name: "Default"}
+		if err := db.Create(&tenant).Error; err != nil {
+			return fmt.Errorf("failed to create default tenant: %w", err)
+		}
+		if tenant.ID != models.DefaultTenantID {
+			return fmt.Errorf("default tenant was created with ID %d, want %d", tenant.ID, models.DefaultTenantID)
+		}
+	}
+
+	// Start the price history of books created before it was recorded.
+	backfill := `INSERT INTO price_points (tenant_id, book_id, price, changed_at)
+		SELECT b.tenant_id, b.id, b.price, b.created_at FROM books b
+		WHERE NOT EXISTS (SELECT 1 FROM price_points p WHERE p.book_id = b.id)`
+	if err := db.Exec(backfill).Error; err != nil {
+		return fmt.Errorf("failed to backfill price history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"bookstore-api/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// postgresDSNEnv names a keyword/value PostgreSQL DSN to also run the
+// migration tests against. Each run works in a schema of its own, which is
+// dropped afterwards.
+const postgresDSNEnv = "BOOKSTORE_TEST_POSTGRES_DSN"
+
+// legacyBook is the books table as it was before tenants, with an ISBN
+// unique across the whole catalog.
+type legacyBook struct {
+	ID        uint    `gorm:"primaryKey"`
+	Title     string  `gorm:"not null"`
+	Author    string  `gorm:"not null"`
+	ISBN      string  `gorm:"unique;not null"`
+	Price     float64 `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (legacyBook) TableName() string { return "books" }
+
+func TestMigrateReplacesGlobalISBNConstraint(t *testing.T) {
+	dialects := map[string]func(t *testing.T) *gorm.DB{"sqlite": openSQLite}
+	if dsn := os.Getenv(postgresDSNEnv); dsn != "" {
+		dialects["postgres"] = func(t *testing.T) *gorm.DB { return openPostgres(t, dsn) }
+	}
+
+	for name, open := range dialects {
+		t.Run(name, func(t *testing.T) {
+			db := open(t)
+			if err := db.AutoMigrate(&legacyBook{}); err != nil {
+				t.Fatalf("create legacy schema: %v", err)
+			}
+			if !db.Migrator().HasConstraint(&legacyBook{}, "uni_books_isbn") {
+				t.Fatal("legacy schema has no uni_books_isbn constraint")
+			}
+			legacy := legacyBook{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441013593", Price: 9.99}
+			if err := db.Create(&legacy).Error; err != nil {
+				t.Fatalf("insert legacy book: %v", err)
+			}
+
+			if err := Migrate(db); err != nil {
+				t.Fatalf("Migrate: %v", err)
+			}
+			// A second run over the migrated schema changes nothing.
+			if err := Migrate(db); err != nil {
+				t.Fatalf("Migrate again: %v", err)
+			}
+
+			if db.Migrator().HasConstraint(&models.Book{}, "uni_books_isbn") {
+				t.Error("uni_books_isbn survived the migration")
+			}
+			var book models.Book
+			if err := db.First(&book, legacy.ID).Error; err != nil {
+				t.Fatalf("load migrated book: %v", err)
+			}
+			if book.TenantID != models.DefaultTenantID {
+				t.Errorf("migrated book tenant = %d, want %d", book.TenantID, models.DefaultTenantID)
+			}
+			var points int64
+			if err := db.Model(&models.PricePoint{}).Where("book_id = ?", legacy.ID).Count(&points).Error; err != nil {
+				t.Fatalf("count price points: %v", err)
+			}
+			if points != 1 {
+				t.Errorf("migrated book has %d price points, want 1", points)
+			}
+
+			storeB := models.Tenant{Slug: "store-b", Name: "Store B"}
+			if err := db.Create(&storeB).Error; err != nil {
+				t.Fatalf("create tenant: %v", err)
+			}
+			copyIn := func(tenantID uint) error {
+				return db.Create(&models.Book{TenantID: tenantID, Title: "Dune", Author: "Frank Herbert", ISBN: legacy.ISBN, Price: 9.99}).Error
+			}
+			if err := copyIn(storeB.ID); err != nil {
+				t.Errorf("same ISBN in another tenant: %v", err)
+			}
+			if err := copyIn(models.DefaultTenantID); !errors.Is(err, gorm.ErrDuplicatedKey) {
+				t.Errorf("same ISBN in the same tenant: got %v, want %v", err, gorm.ErrDuplicatedKey)
+			}
+		})
+	}
+}
+
+// openSQLite returns an empty in-memory SQLite database.
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	// Every connection to :memory: is a separate database.
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// openPostgres returns a connection to dsn using a new, empty schema.
+func openPostgres(t *testing.T, dsn string) *gorm.DB {
+	t.Helper()
+	config := &gorm.Config{TranslateError: true, Logger: logger.Default.LogMode(logger.Silent)}
+	admin, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn+" search_path="+schema), config)
+	if err != nil {
+		t.Fatalf("open schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
@@ -0,0 +1,151 @@
+// Package events fans committed book changes out to in-process
+// subscribers, such as Server-Sent Events streams, and keeps a bounded
+// buffer of recent events so that subscribers can resume after
+// reconnecting.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"bookstore-api/internal/models"
+)
+
+// subscriberQueue is the number of events queued for a subscriber. A
+// subscriber that falls further behind is dropped and must resume from
+// the replay buffer.
+const subscriberQueue = 64
+
+// Event is a committed change to a book. IDs increase by one for every
+// event published, across all tenants.
+type Event struct {
+	ID         uint64      `json:"id"`
+	TenantID   uint        `json:"-"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Book       models.Book `json:"book"`
+}
+
+// Replay is what a new subscriber missed since the event it last saw.
+type Replay struct {
+	// Events are the subscriber's buffered events after the last seen one.
+	Events []Event
+	// Missed is set when events after the last seen one are no longer
+	// buffered, or the ID is unknown, for example after a restart.
+	Missed bool
+	// LatestID is the ID of the newest event published.
+	LatestID uint64
+}
+
+// Hub publishes events to subscribers. It is safe for concurrent use.
+type Hub struct {
+	mu      sync.Mutex
+	lastID  uint64
+	ring    []Event
+	next    int
+	full    bool
+	streams map[*Subscription]bool
+}
+
+// NewHub creates a Hub that keeps the last bufferSize events for replay.
+func NewHub(bufferSize int) *Hub {
+	return &Hub{
+		ring:    make([]Event, bufferSize),
+		streams: make(map[*Subscription]bool),
+	}
+}
+
+// Publish records a change to book and delivers it to the subscribers of
+// the book's tenant. Subscribers whose queue is full are dropped.
+func (h *Hub) Publish(eventType string, book models.Book) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	event := Event{
+		ID:         h.lastID,
+		TenantID:   book.TenantID,
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Book:       book,
+	}
+	h.ring[h.next] = event
+	h.next = (h.next + 1) % len(h.ring)
+	h.full = h.full || h.next == 0
+
+	for sub := range h.streams {
+		if sub.tenantID != event.TenantID {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			h.drop(sub)
+		}
+	}
+}
+
+// Subscribe starts delivering the tenant's events. When lastID is not zero
+// the events published after it are returned for replay; no event is
+// both replayed and delivered.
+func (h *Hub) Subscribe(tenantID uint, lastID uint64) (*Subscription, Replay) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &Subscription{hub: h, tenantID: tenantID, events: make(chan Event, subscriberQueue)}
+	h.streams[sub] = true
+
+	replay := Replay{LatestID: h.lastID}
+	if lastID == 0 {
+		return sub, replay
+	}
+	buffered := h.buffered()
+	oldest := h.lastID + 1
+	if len(buffered) > 0 {
+		oldest = buffered[0].ID
+	}
+	replay.Missed = lastID > h.lastID || lastID+1 < oldest
+	for _, event := range buffered {
+		if event.ID > lastID && event.TenantID == tenantID {
+			replay.Events = append(replay.Events, event)
+		}
+	}
+	return sub, replay
+}
+
+// buffered returns the buffered events, oldest first. h.mu must be held.
+func (h *Hub) buffered() []Event {
+	if !h.full {
+		return h.ring[:h.next]
+	}
+	return append(append([]Event(nil), h.ring[h.next:]...), h.ring[:h.next]...)
+}
+
+// drop unregisters a subscription and closes its channel. h.mu must be
+// held.
+func (h *Hub) drop(sub *Subscription) {
+	if h.streams[sub] {
+		delete(h.streams, sub)
+		close(sub.events)
+	}
+}
+
+// Subscription is a subscriber's stream of one tenant's events.
+type Subscription struct {
+	hub      *Hub
+	tenantID uint
+	events   chan Event
+}
+
+// Events returns the channel of new events. It is closed when the
+// subscription is closed or dropped for falling behind.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close stops delivery to the subscription.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.drop(s)
+}
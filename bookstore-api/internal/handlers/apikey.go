@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"bookstore-api/internal/middleware"
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/services"
+)
+
+// APIKeyHandler handles HTTP requests for API key administration.
+type APIKeyHandler struct {
+	service services.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler with the given service.
+func NewAPIKeyHandler(service services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+// CreateAPIKeyRequest represents the request body for creating an API key.
+// The key is bound to TenantID, or to the request's tenant when omitted.
+type CreateAPIKeyRequest struct {
+	Name     string   `json:"name"`
+	Scopes   []string `json:"scopes"`
+	TenantID uint     `json:"tenant_id,omitempty"`
+}
+
+// CreateAPIKeyResponse is returned once on creation and is the only
+// response that includes the raw key.
+type CreateAPIKeyResponse struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKey handles POST /admin/api-keys
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	key := models.APIKey{Name: req.Name, Scopes: req.Scopes, TenantID: req.TenantID}
+	if key.TenantID == 0 {
+		key.TenantID = middleware.TenantFromContext(r.Context())
+	}
+	if identity, ok := middleware.IdentityFromContext(r.Context()); ok {
+		key.CreatedBy = identity.Subject
+	}
+	raw, err := h.service.CreateAPIKey(&key)
+	if err != nil {
+		respondServiceError(w, err, "Failed to create API key")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, CreateAPIKeyResponse{APIKey: key, Key: raw})
+}
+
+// GetAPIKeys handles GET /admin/api-keys
+func (h *APIKeyHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.ListAPIKeys()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch API keys")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, keys)
+}
+
+// RevokeAPIKey handles DELETE /admin/api-keys/{id}
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(id); err != nil {
+		respondServiceError(w, err, "Failed to revoke API key")
+		return
+	}
+
+	respondJSON(w, http.StatusNoContent, nil)
+}
@@ -5,17 +5,21 @@ import (
 	"net/http"
 	"time"
 
+	"bookstore-api/internal/middleware"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
 // AuthHandler handles authentication-related requests.
 type AuthHandler struct {
 	jwtSecret string
+	tokenTTL  time.Duration
 }
 
-// NewAuthHandler creates a new AuthHandler with the given JWT secret.
-func NewAuthHandler(jwtSecret string) *AuthHandler {
-	return &AuthHandler{jwtSecret: jwtSecret}
+// NewAuthHandler creates a new AuthHandler that signs tokens with the given
+// JWT secret, valid for tokenTTL.
+func NewAuthHandler(jwtSecret string, tokenTTL time.Duration) *AuthHandler {
+	return &AuthHandler{jwtSecret: jwtSecret, tokenTTL: tokenTTL}
 }
 
 // TokenRequest represents the request body for generating a token.
@@ -30,6 +34,9 @@ type TokenResponse struct {
 }
 
 // GenerateToken handles POST /auth/token - generates a test JWT token.
+// Tokens carry middleware.TokenEndpointIssuer, so they never grant admin
+// access whatever the username, and are bound to the tenant serving the
+// request host.
 func (h *AuthHandler) GenerateToken(w http.ResponseWriter, r *http.Request) {
 	var req TokenRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -42,12 +49,14 @@ func (h *AuthHandler) GenerateToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create token with claims
-	expiresAt := time.Now().Add(24 * time.Hour)
+	expiresAt := time.Now().Add(h.tokenTTL)
 	claims := jwt.MapClaims{
-		"sub":  req.Username,
-		"name": req.Username,
-		"iat":  time.Now().Unix(),
-		"exp":  expiresAt.Unix(),
+		"iss":       middleware.TokenEndpointIssuer,
+		"sub":       req.Username,
+		"name":      req.Username,
+		"iat":       time.Now().Unix(),
+		"exp":       expiresAt.Unix(),
+		"tenant_id": middleware.TenantFromContext(r.Context()),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"bookstore-api/internal/middleware"
 	"bookstore-api/internal/models"
 	"bookstore-api/internal/services"
 )
@@ -27,23 +29,57 @@ func (h *BookHandler) CreateBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.CreateBook(&book); err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to create book")
+	if err := h.service.CreateBook(middleware.TenantFromContext(r.Context()), &book); err != nil {
+		respondServiceError(w, err, "Failed to create book")
 		return
 	}
 
 	respondJSON(w, http.StatusCreated, book)
 }
 
+// BookListResponse is the body of GET /books when facets are requested.
+type BookListResponse struct {
+	Items  []models.Book      `json:"items"`
+	Total  int64              `json:"total"`
+	Facets *models.BookFacets `json:"facets"`
+}
+
 // GetAllBooks handles GET /books
 func (h *BookHandler) GetAllBooks(w http.ResponseWriter, r *http.Request) {
-	books, err := h.service.GetAllBooks()
+	filter, withFacets, param := parseBookQuery(r)
+	if param != "" {
+		respondError(w, http.StatusBadRequest, "Invalid query parameter: "+param)
+		return
+	}
+
+	tenantID := middleware.TenantFromContext(r.Context())
+	books, total, err := h.service.SearchBooks(tenantID, filter)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch books")
+		return
+	}
+	if !withFacets {
+		respondJSON(w, http.StatusOK, books)
+		return
+	}
+
+	facets, err := h.service.GetFacets(tenantID, filter)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to fetch books")
 		return
 	}
+	respondJSON(w, http.StatusOK, BookListResponse{Items: books, Total: total, Facets: facets})
+}
+
+// GetTags handles GET /tags
+func (h *BookHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.service.ListTags(middleware.TenantFromContext(r.Context()))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch tags")
+		return
+	}
 
-	respondJSON(w, http.StatusOK, books)
+	respondJSON(w, http.StatusOK, tags)
 }
 
 // GetBookByID handles GET /books/{id}
@@ -54,7 +90,7 @@ func (h *BookHandler) GetBookByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	book, err := h.service.GetBookByID(id)
+	book, err := h.service.GetBookByID(middleware.TenantFromContext(r.Context()), id)
 	if err != nil {
 		respondError(w, http.StatusNotFound, "Book not found")
 		return
@@ -78,8 +114,8 @@ func (h *BookHandler) UpdateBook(w http.ResponseWriter, r *http.Request) {
 	}
 
 	book.ID = id
-	if err := h.service.UpdateBook(&book); err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to update book")
+	if err := h.service.UpdateBook(middleware.TenantFromContext(r.Context()), &book); err != nil {
+		respondServiceError(w, err, "Failed to update book")
 		return
 	}
 
@@ -94,7 +130,7 @@ func (h *BookHandler) DeleteBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.DeleteBook(id); err != nil {
+	if err := h.service.DeleteBook(middleware.TenantFromContext(r.Context()), id); err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to delete book")
 		return
 	}
@@ -102,9 +138,69 @@ func (h *BookHandler) DeleteBook(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusNoContent, nil)
 }
 
+// parseBookQuery reads the GET /books filter, paging and facets query
+// parameters. It returns the name of the first invalid parameter, if any.
+func parseBookQuery(r *http.Request) (models.BookFilter, bool, string) {
+	query := r.URL.Query()
+	filter := models.BookFilter{
+		Title:  query.Get("title"),
+		Author: query.Get("author"),
+		ISBN:   query.Get("isbn"),
+		Tag:    strings.ToLower(strings.TrimSpace(query.Get("tag"))),
+	}
+
+	for _, p := range []struct {
+		name string
+		dst  **float64
+	}{{"min_price", &filter.MinPrice}, {"max_price", &filter.MaxPrice}} {
+		if v := query.Get(p.name); v != "" {
+			price, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return filter, false, p.name
+			}
+			*p.dst = &price
+		}
+	}
+	if v := query.Get("category"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return filter, false, "category"
+		}
+		categoryID := uint(id)
+		filter.CategoryID = &categoryID
+	}
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"limit", &filter.Limit}, {"offset", &filter.Offset}} {
+		if v := query.Get(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return filter, false, p.name
+			}
+			*p.dst = n
+		}
+	}
+
+	withFacets := false
+	if v := query.Get("facets"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, false, "facets"
+		}
+		withFacets = b
+	}
+	return filter, withFacets, ""
+}
+
 // extractIDFromPath extracts the book ID from the request path parameter.
 func extractIDFromPath(r *http.Request) (uint, error) {
-	idStr := r.PathValue("id")
+	return extractPathID(r, "id")
+}
+
+// extractPathID extracts a numeric ID from the named path parameter.
+func extractPathID(r *http.Request, name string) (uint, error) {
+	idStr := r.PathValue(name)
 	if idStr == "" {
 		return 0, http.ErrNotSupported
 	}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"bookstore-api/internal/middleware"
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/services"
+)
+
+// CategoryHandler handles HTTP requests for book categories.
+type CategoryHandler struct {
+	service services.CategoryService
+}
+
+// NewCategoryHandler creates a new CategoryHandler with the given service.
+func NewCategoryHandler(service services.CategoryService) *CategoryHandler {
+	return &CategoryHandler{service: service}
+}
+
+// CategoryRequest represents the request body for creating or updating a
+// category. A null parent_id makes it a top-level category.
+type CategoryRequest struct {
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// CreateCategory handles POST /categories
+func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var req CategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	category := models.Category{Name: req.Name, Slug: req.Slug, ParentID: req.ParentID}
+	if err := h.service.CreateCategory(middleware.TenantFromContext(r.Context()), &category); err != nil {
+		respondServiceError(w, err, "Failed to create category")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, category)
+}
+
+// GetCategories handles GET /categories
+func (h *CategoryHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.service.ListCategories(middleware.TenantFromContext(r.Context()))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch categories")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, categories)
+}
+
+// GetCategory handles GET /categories/{id}
+func (h *CategoryHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+
+	category, err := h.service.GetCategory(middleware.TenantFromContext(r.Context()), id)
+	if err != nil {
+		respondServiceError(w, err, "Failed to fetch category")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, category)
+}
+
+// UpdateCategory handles PUT /categories/{id}
+func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+
+	var req CategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	category := models.Category{ID: id, Name: req.Name, Slug: req.Slug, ParentID: req.ParentID}
+	if err := h.service.UpdateCategory(middleware.TenantFromContext(r.Context()), &category); err != nil {
+		respondServiceError(w, err, "Failed to update category")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, category)
+}
+
+// DeleteCategory handles DELETE /categories/{id}
+func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+
+	if err := h.service.DeleteCategory(middleware.TenantFromContext(r.Context()), id); err != nil {
+		respondServiceError(w, err, "Failed to delete category")
+		return
+	}
+
+	respondJSON(w, http.StatusNoContent, nil)
+}
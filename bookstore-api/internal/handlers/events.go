@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bookstore-api/internal/events"
+	"bookstore-api/internal/middleware"
+)
+
+// EventResetType is the SSE event sent when a resuming client missed
+// events that are no longer buffered. The client should refetch the
+// catalog; its id is the latest event ID.
+const EventResetType = "reset"
+
+// EventsHandler streams book changes as Server-Sent Events.
+type EventsHandler struct {
+	hub       *events.Hub
+	heartbeat time.Duration
+}
+
+// NewEventsHandler creates a new EventsHandler that sends a heartbeat
+// comment on streams idle for the given interval.
+func NewEventsHandler(hub *events.Hub, heartbeat time.Duration) *EventsHandler {
+	return &EventsHandler{hub: hub, heartbeat: heartbeat}
+}
+
+// StreamBookEvents handles GET /books/events
+func (h *EventsHandler) StreamBookEvents(w http.ResponseWriter, r *http.Request) {
+	// EventSource sends Last-Event-ID when reconnecting; the query
+	// parameter lets a new connection resume too.
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	var lastID uint64
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid Last-Event-ID")
+			return
+		}
+		lastID = id
+	}
+
+	// The stream outlives the server's write timeout.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		respondError(w, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	sub, replay := h.hub.Subscribe(middleware.TenantFromContext(r.Context()), lastID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if replay.Missed {
+		fmt.Fprintf(w, "id: %d\nevent: %s\ndata: {}\n\n", replay.LatestID, EventResetType)
+	}
+	for _, event := range replay.Events {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				// Dropped for falling behind; the client reconnects
+				// and resumes from its last event.
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+			heartbeat.Reset(h.heartbeat)
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes one event in SSE framing.
+func writeEvent(w io.Writer, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
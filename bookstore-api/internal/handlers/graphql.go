@@ -0,0 +1,494 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+
+	"bookstore-api/internal/middleware"
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/services"
+
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var (
+	errUnauthorized       = errors.New("unauthorized: credentials with the books:write scope are required")
+	errReviewUnauthorized = errors.New("unauthorized: credentials with the reviews scope are required")
+)
+
+// GraphQLHandler serves the GraphQL API over the book and review services.
+type GraphQLHandler struct {
+	service services.BookService
+	reviews services.ReviewService
+	schema  graphql.Schema
+}
+
+// graphQLRequest represents the body of a GraphQL HTTP request.
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// NewGraphQLHandler creates a new GraphQLHandler with the given services.
+func NewGraphQLHandler(service services.BookService, reviews services.ReviewService) (*GraphQLHandler, error) {
+	h := &GraphQLHandler{service: service, reviews: reviews}
+	schema, err := h.buildSchema()
+	if err != nil {
+		return nil, err
+	}
+	h.schema = schema
+	return h, nil
+}
+
+// ServeGraphQL handles POST /graphql
+func (h *GraphQLHandler) ServeGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphQLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Query == "" {
+		respondError(w, http.StatusBadRequest, "Missing query")
+		return
+	}
+
+	loader := &reviewLoader{service: h.reviews, tenantID: middleware.TenantFromContext(r.Context())}
+	result := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        context.WithValue(r.Context(), reviewLoaderKey{}, loader),
+	})
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+// buildSchema defines the GraphQL types, queries and mutations.
+func (h *GraphQLHandler) buildSchema() (graphql.Schema, error) {
+	reviewType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Review",
+		Fields: graphql.Fields{
+			"id":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"reviewer": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"rating":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"comment":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"createdAt": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.DateTime),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(models.Review).CreatedAt, nil
+				},
+			},
+		},
+	})
+
+	bookType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Book",
+		Fields: graphql.Fields{
+			"id":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"title":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"author": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"isbn":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"price":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"categoryId": &graphql.Field{
+				Type: graphql.Int,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if id := p.Source.(models.Book).CategoryID; id != nil {
+						return int(*id), nil
+					}
+					return nil, nil
+				},
+			},
+			"tags": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(models.Book).Tags, nil
+				},
+			},
+			"createdAt": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.DateTime),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(models.Book).CreatedAt, nil
+				},
+			},
+			"updatedAt": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.DateTime),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(models.Book).UpdatedAt, nil
+				},
+			},
+			"reviews": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(reviewType))),
+				Resolve: resolveBookReviews,
+			},
+		},
+	})
+
+	bookPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BookPage",
+		Fields: graphql.Fields{
+			"items":       &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bookType)))},
+			"totalCount":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		},
+	})
+
+	bookFilterType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "BookFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"author":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"isbn":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"minPrice":   &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"maxPrice":   &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"categoryId": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"tag":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	bookInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "BookInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"author": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"isbn":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"price":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+			// Omitting categoryId or tags in updateBook keeps the current
+			// value.
+			"categoryId": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"tags":       &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"book": &graphql.Field{
+				Type: bookType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: h.resolveBook,
+			},
+			"books": &graphql.Field{
+				Type: graphql.NewNonNull(bookPageType),
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: bookFilterType},
+					"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize},
+					"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: h.resolveBooks,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createBook": &graphql.Field{
+				Type: graphql.NewNonNull(bookType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(bookInputType)},
+				},
+				Resolve: h.resolveCreateBook,
+			},
+			"updateBook": &graphql.Field{
+				Type: graphql.NewNonNull(bookType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(bookInputType)},
+				},
+				Resolve: h.resolveUpdateBook,
+			},
+			"deleteBook": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: h.resolveDeleteBook,
+			},
+			"addReview": &graphql.Field{
+				Type: graphql.NewNonNull(reviewType),
+				Args: graphql.FieldConfigArgument{
+					"bookId":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"rating":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"comment": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: h.resolveAddReview,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+}
+
+// resolveBook resolves Query.book
+func (h *GraphQLHandler) resolveBook(p graphql.ResolveParams) (interface{}, error) {
+	id, err := bookIDArg(p, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := h.service.GetBookByID(middleware.TenantFromContext(p.Context), id)
+	if errors.Is(err, services.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to fetch book")
+	}
+	return *book, nil
+}
+
+// resolveBooks resolves Query.books
+func (h *GraphQLHandler) resolveBooks(p graphql.ResolveParams) (interface{}, error) {
+	limit, _ := p.Args["limit"].(int)
+	offset, _ := p.Args["offset"].(int)
+	if limit <= 0 || limit > maxPageSize {
+		return nil, errors.New("limit must be between 1 and 100")
+	}
+	if offset < 0 {
+		return nil, errors.New("offset must not be negative")
+	}
+
+	filter := models.BookFilter{Limit: limit, Offset: offset}
+	if args, ok := p.Args["filter"].(map[string]interface{}); ok {
+		filter.Title, _ = args["title"].(string)
+		filter.Author, _ = args["author"].(string)
+		filter.ISBN, _ = args["isbn"].(string)
+		if v, ok := args["minPrice"].(float64); ok {
+			filter.MinPrice = &v
+		}
+		if v, ok := args["maxPrice"].(float64); ok {
+			filter.MaxPrice = &v
+		}
+		if v, ok := args["categoryId"].(int); ok && v >= 0 {
+			id := uint(v)
+			filter.CategoryID = &id
+		}
+		if v, ok := args["tag"].(string); ok {
+			filter.Tag = strings.ToLower(strings.TrimSpace(v))
+		}
+	}
+
+	books, total, err := h.service.SearchBooks(middleware.TenantFromContext(p.Context), filter)
+	if err != nil {
+		return nil, errors.New("failed to fetch books")
+	}
+	if loader, ok := p.Context.Value(reviewLoaderKey{}).(*reviewLoader); ok {
+		loader.prime(books)
+	}
+
+	return map[string]interface{}{
+		"items":       books,
+		"totalCount":  total,
+		"hasNextPage": int64(offset+len(books)) < total,
+	}, nil
+}
+
+// resolveCreateBook resolves Mutation.createBook
+func (h *GraphQLHandler) resolveCreateBook(p graphql.ResolveParams) (interface{}, error) {
+	if !hasScope(p, models.ScopeBooksWrite) {
+		return nil, errUnauthorized
+	}
+
+	book := bookFromInput(p.Args["input"])
+	err := h.service.CreateBook(middleware.TenantFromContext(p.Context), &book)
+	switch {
+	case errors.Is(err, services.ErrInvalidInput), errors.Is(err, services.ErrConflict):
+		return nil, err
+	case err != nil:
+		return nil, errors.New("failed to create book")
+	}
+	return book, nil
+}
+
+// resolveUpdateBook resolves Mutation.updateBook
+func (h *GraphQLHandler) resolveUpdateBook(p graphql.ResolveParams) (interface{}, error) {
+	if !hasScope(p, models.ScopeBooksWrite) {
+		return nil, errUnauthorized
+	}
+
+	id, err := bookIDArg(p, "id")
+	if err != nil {
+		return nil, err
+	}
+	existing, err := h.service.GetBookByID(middleware.TenantFromContext(p.Context), id)
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		return nil, errors.New("book not found")
+	case err != nil:
+		return nil, errors.New("failed to fetch book")
+	}
+
+	book := bookFromInput(p.Args["input"])
+	book.ID = id
+	book.CreatedAt = existing.CreatedAt
+	input, _ := p.Args["input"].(map[string]interface{})
+	if _, ok := input["categoryId"]; !ok {
+		book.CategoryID = existing.CategoryID
+	}
+	if _, ok := input["tags"]; !ok {
+		book.Tags = existing.Tags
+	}
+	err = h.service.UpdateBook(middleware.TenantFromContext(p.Context), &book)
+	switch {
+	case errors.Is(err, services.ErrInvalidInput), errors.Is(err, services.ErrConflict):
+		return nil, err
+	case err != nil:
+		return nil, errors.New("failed to update book")
+	}
+	return book, nil
+}
+
+// resolveDeleteBook resolves Mutation.deleteBook
+func (h *GraphQLHandler) resolveDeleteBook(p graphql.ResolveParams) (interface{}, error) {
+	if !hasScope(p, models.ScopeBooksWrite) {
+		return nil, errUnauthorized
+	}
+
+	id, err := bookIDArg(p, "id")
+	if err != nil {
+		return nil, err
+	}
+	if err := h.service.DeleteBook(middleware.TenantFromContext(p.Context), id); err != nil {
+		return nil, errors.New("failed to delete book")
+	}
+	return true, nil
+}
+
+// resolveAddReview resolves Mutation.addReview
+func (h *GraphQLHandler) resolveAddReview(p graphql.ResolveParams) (interface{}, error) {
+	identity, ok := middleware.IdentityFromContext(p.Context)
+	if !ok || !identity.HasScope(models.ScopeReviews) {
+		return nil, errReviewUnauthorized
+	}
+
+	bookID, err := bookIDArg(p, "bookId")
+	if err != nil {
+		return nil, err
+	}
+	review := models.Review{BookID: bookID}
+	review.Rating, _ = p.Args["rating"].(int)
+	review.Comment, _ = p.Args["comment"].(string)
+	err = h.reviews.AddReview(middleware.TenantFromContext(p.Context), identity.Subject, &review)
+	switch {
+	case errors.Is(err, services.ErrInvalidInput), errors.Is(err, services.ErrConflict):
+		return nil, err
+	case err != nil:
+		return nil, errors.New("failed to add review")
+	}
+	return review, nil
+}
+
+// resolveBookReviews resolves Book.reviews
+func resolveBookReviews(p graphql.ResolveParams) (interface{}, error) {
+	loader, _ := p.Context.Value(reviewLoaderKey{}).(*reviewLoader)
+	if loader == nil {
+		return []models.Review{}, nil
+	}
+	return loader.load(p.Source.(models.Book).ID)
+}
+
+// reviewLoaderKey is the context key of the request's reviewLoader.
+type reviewLoaderKey struct{}
+
+// reviewLoader fetches reviews for Book.reviews. Books primed by a listing
+// are fetched together on the first lookup, so a page of books costs one
+// review query rather than one per book.
+type reviewLoader struct {
+	service  services.ReviewService
+	tenantID uint
+
+	mu      sync.Mutex
+	pending []uint
+	loaded  map[uint][]models.Review
+}
+
+// prime queues books to be fetched with the next lookup.
+func (l *reviewLoader) prime(books []models.Book) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, book := range books {
+		if _, ok := l.loaded[book.ID]; !ok {
+			l.pending = append(l.pending, book.ID)
+		}
+	}
+}
+
+// load returns a book's reviews, fetching them and any primed books'
+// reviews if they have not been fetched yet.
+func (l *reviewLoader) load(bookID uint) ([]models.Review, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if reviews, ok := l.loaded[bookID]; ok {
+		return reviews, nil
+	}
+
+	ids := append(l.pending, bookID)
+	found, err := l.service.ReviewsForBooks(l.tenantID, ids)
+	if err != nil {
+		return nil, errors.New("failed to fetch reviews")
+	}
+	if l.loaded == nil {
+		l.loaded = make(map[uint][]models.Review, len(ids))
+	}
+	for _, id := range ids {
+		l.loaded[id] = found[id]
+		if l.loaded[id] == nil {
+			l.loaded[id] = []models.Review{}
+		}
+	}
+	l.pending = nil
+	return l.loaded[bookID], nil
+}
+
+// hasScope reports whether the caller was granted scope.
+func hasScope(p graphql.ResolveParams, scope string) bool {
+	identity, ok := middleware.IdentityFromContext(p.Context)
+	return ok && identity.HasScope(scope)
+}
+
+// bookIDArg extracts and validates the named book ID argument.
+func bookIDArg(p graphql.ResolveParams, name string) (uint, error) {
+	arg, ok := p.Args[name].(int)
+	if !ok || arg <= 0 || arg > math.MaxUint32 {
+		return 0, errors.New("invalid book ID")
+	}
+	return uint(arg), nil
+}
+
+// bookFromInput converts a BookInput argument into a book model.
+func bookFromInput(arg interface{}) models.Book {
+	input, _ := arg.(map[string]interface{})
+	book := models.Book{}
+	book.Title, _ = input["title"].(string)
+	book.Author, _ = input["author"].(string)
+	book.ISBN, _ = input["isbn"].(string)
+	book.Price, _ = input["price"].(float64)
+	if v, ok := input["categoryId"].(int); ok && v >= 0 {
+		id := uint(v)
+		book.CategoryID = &id
+	}
+	if tags, ok := input["tags"].([]interface{}); ok {
+		for _, tag := range tags {
+			if s, ok := tag.(string); ok {
+				book.Tags = append(book.Tags, s)
+			}
+		}
+	}
+	return book
+}
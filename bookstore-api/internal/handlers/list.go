@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"bookstore-api/internal/middleware"
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/services"
+)
+
+// ListHandler handles HTTP requests for users' reading lists.
+type ListHandler struct {
+	service services.ListService
+}
+
+// NewListHandler creates a new ListHandler with the given service.
+func NewListHandler(service services.ListService) *ListHandler {
+	return &ListHandler{service: service}
+}
+
+// ListRequest represents the request body for creating or updating a
+// reading list.
+type ListRequest struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// ListEntryRequest represents the request body for adding a book to a
+// reading list. A zero or omitted position appends the book.
+type ListEntryRequest struct {
+	BookID   uint `json:"book_id"`
+	Position int  `json:"position"`
+}
+
+// ListOrderRequest represents the request body for reordering a reading
+// list.
+type ListOrderRequest struct {
+	BookIDs []uint `json:"book_ids"`
+}
+
+// CreateList handles POST /lists
+func (h *ListHandler) CreateList(w http.ResponseWriter, r *http.Request) {
+	var req ListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	list := models.ReadingList{Name: req.Name, Kind: req.Kind}
+	if err := h.service.CreateList(middleware.TenantFromContext(r.Context()), requestSubject(r), &list); err != nil {
+		respondServiceError(w, err, "Failed to create list")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, list)
+}
+
+// GetLists handles GET /lists
+func (h *ListHandler) GetLists(w http.ResponseWriter, r *http.Request) {
+	lists, err := h.service.ListLists(middleware.TenantFromContext(r.Context()), requestSubject(r))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch lists")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, lists)
+}
+
+// GetList handles GET /lists/{id}
+func (h *ListHandler) GetList(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid list ID")
+		return
+	}
+
+	list, err := h.service.GetList(middleware.TenantFromContext(r.Context()), requestSubject(r), id)
+	if err != nil {
+		respondServiceError(w, err, "Failed to fetch list")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, list)
+}
+
+// UpdateList handles PUT /lists/{id}
+func (h *ListHandler) UpdateList(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid list ID")
+		return
+	}
+
+	var req ListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	list := models.ReadingList{ID: id, Name: req.Name, Kind: req.Kind}
+	if err := h.service.UpdateList(middleware.TenantFromContext(r.Context()), requestSubject(r), &list); err != nil {
+		respondServiceError(w, err, "Failed to update list")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, list)
+}
+
+// DeleteList handles DELETE /lists/{id}
+func (h *ListHandler) DeleteList(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid list ID")
+		return
+	}
+
+	if err := h.service.DeleteList(middleware.TenantFromContext(r.Context()), requestSubject(r), id); err != nil {
+		respondServiceError(w, err, "Failed to delete list")
+		return
+	}
+
+	respondJSON(w, http.StatusNoContent, nil)
+}
+
+// AddEntry handles POST /lists/{id}/entries
+func (h *ListHandler) AddEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid list ID")
+		return
+	}
+
+	var req ListEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	list, err := h.service.AddEntry(middleware.TenantFromContext(r.Context()), requestSubject(r), id, req.BookID, req.Position)
+	if err != nil {
+		respondServiceError(w, err, "Failed to add book to list")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, list)
+}
+
+// ReorderEntries handles PUT /lists/{id}/entries
+func (h *ListHandler) ReorderEntries(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid list ID")
+		return
+	}
+
+	var req ListOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	list, err := h.service.ReorderEntries(middleware.TenantFromContext(r.Context()), requestSubject(r), id, req.BookIDs)
+	if err != nil {
+		respondServiceError(w, err, "Failed to reorder list")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, list)
+}
+
+// RemoveEntry handles DELETE /lists/{id}/entries/{bookId}
+func (h *ListHandler) RemoveEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid list ID")
+		return
+	}
+	bookID, err := extractPathID(r, "bookId")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+
+	if err := h.service.RemoveEntry(middleware.TenantFromContext(r.Context()), requestSubject(r), id, bookID); err != nil {
+		respondServiceError(w, err, "Failed to remove book from list")
+		return
+	}
+
+	respondJSON(w, http.StatusNoContent, nil)
+}
+
+// ShareList handles POST /lists/{id}/share
+func (h *ListHandler) ShareList(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid list ID")
+		return
+	}
+
+	list, err := h.service.ShareList(middleware.TenantFromContext(r.Context()), requestSubject(r), id)
+	if err != nil {
+		respondServiceError(w, err, "Failed to share list")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, list)
+}
+
+// UnshareList handles DELETE /lists/{id}/share
+func (h *ListHandler) UnshareList(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid list ID")
+		return
+	}
+
+	if err := h.service.UnshareList(middleware.TenantFromContext(r.Context()), requestSubject(r), id); err != nil {
+		respondServiceError(w, err, "Failed to unshare list")
+		return
+	}
+
+	respondJSON(w, http.StatusNoContent, nil)
+}
+
+// GetSharedList handles GET /shared/lists/{token}
+func (h *ListHandler) GetSharedList(w http.ResponseWriter, r *http.Request) {
+	list, err := h.service.GetSharedList(middleware.TenantFromContext(r.Context()), r.PathValue("token"))
+	if err != nil {
+		respondServiceError(w, err, "Failed to fetch list")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, list)
+}
+
+// requestSubject returns the subject of the request's credentials.
+func requestSubject(r *http.Request) string {
+	identity, _ := middleware.IdentityFromContext(r.Context())
+	return identity.Subject
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"bookstore-api/internal/middleware"
+	"bookstore-api/internal/services"
+)
+
+// NotificationHandler handles HTTP requests for users' in-app
+// notifications.
+type NotificationHandler struct {
+	service services.NotificationService
+}
+
+// NewNotificationHandler creates a new NotificationHandler with the given
+// service.
+func NewNotificationHandler(service services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{service: service}
+}
+
+// GetNotifications handles GET /notifications
+func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	var unreadOnly bool
+	if value := r.URL.Query().Get("unread"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid query parameter: unread")
+			return
+		}
+		unreadOnly = parsed
+	}
+
+	notifications, err := h.service.ListNotifications(middleware.TenantFromContext(r.Context()), requestSubject(r), unreadOnly)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch notifications")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, notifications)
+}
+
+// MarkNotificationRead handles POST /notifications/{id}/read
+func (h *NotificationHandler) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	notification, err := h.service.MarkRead(middleware.TenantFromContext(r.Context()), requestSubject(r), id)
+	if err != nil {
+		respondServiceError(w, err, "Failed to mark notification read")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, notification)
+}
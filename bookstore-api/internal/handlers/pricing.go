@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"bookstore-api/internal/middleware"
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/services"
+)
+
+// PricingHandler handles HTTP requests for book price history and
+// scheduled price changes.
+type PricingHandler struct {
+	service services.PricingService
+}
+
+// NewPricingHandler creates a new PricingHandler with the given service.
+func NewPricingHandler(service services.PricingService) *PricingHandler {
+	return &PricingHandler{service: service}
+}
+
+// PriceScheduleRequest represents the request body for scheduling a price
+// change. Setting EndsAt makes it a sale.
+type PriceScheduleRequest struct {
+	Price    float64    `json:"price"`
+	StartsAt time.Time  `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
+
+// GetPriceHistory handles GET /books/{id}/prices
+func (h *PricingHandler) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+
+	points, err := h.service.GetPriceHistory(middleware.TenantFromContext(r.Context()), id)
+	if err != nil {
+		respondServiceError(w, err, "Failed to fetch price history")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, points)
+}
+
+// CreatePriceSchedule handles POST /books/{id}/price-schedules
+func (h *PricingHandler) CreatePriceSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+
+	var req PriceScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	schedule := models.PriceSchedule{
+		BookID:   id,
+		Price:    req.Price,
+		StartsAt: req.StartsAt,
+		EndsAt:   req.EndsAt,
+	}
+	if identity, ok := middleware.IdentityFromContext(r.Context()); ok {
+		schedule.CreatedBy = identity.Subject
+	}
+	if err := h.service.SchedulePrice(middleware.TenantFromContext(r.Context()), &schedule); err != nil {
+		respondServiceError(w, err, "Failed to schedule price change")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, schedule)
+}
+
+// GetPriceSchedules handles GET /books/{id}/price-schedules
+func (h *PricingHandler) GetPriceSchedules(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+
+	schedules, err := h.service.ListSchedules(middleware.TenantFromContext(r.Context()), id)
+	if err != nil {
+		respondServiceError(w, err, "Failed to fetch price schedules")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, schedules)
+}
+
+// CancelPriceSchedule handles DELETE /books/{id}/price-schedules/{scheduleId}
+func (h *PricingHandler) CancelPriceSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+	scheduleID, err := extractPathID(r, "scheduleId")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid schedule ID")
+		return
+	}
+
+	schedule, err := h.service.CancelSchedule(middleware.TenantFromContext(r.Context()), id, scheduleID)
+	if err != nil {
+		respondServiceError(w, err, "Failed to cancel price schedule")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, schedule)
+}
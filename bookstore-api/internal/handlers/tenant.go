@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/services"
+)
+
+// TenantHandler handles HTTP requests for tenant administration.
+type TenantHandler struct {
+	service services.TenantService
+}
+
+// NewTenantHandler creates a new TenantHandler with the given service.
+func NewTenantHandler(service services.TenantService) *TenantHandler {
+	return &TenantHandler{service: service}
+}
+
+// TenantRequest represents the request body for creating or updating a
+// tenant. An empty domain leaves the tenant reachable only through
+// credentials bound to it.
+type TenantRequest struct {
+	Slug   string  `json:"slug"`
+	Name   string  `json:"name"`
+	Domain *string `json:"domain"`
+}
+
+// CreateTenant handles POST /admin/tenants
+func (h *TenantHandler) CreateTenant(w http.ResponseWriter, r *http.Request) {
+	var req TenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	tenant := models.Tenant{Slug: req.Slug, Name: req.Name, Domain: req.Domain}
+	if err := h.service.CreateTenant(&tenant); err != nil {
+		respondServiceError(w, err, "Failed to create tenant")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, tenant)
+}
+
+// GetTenants handles GET /admin/tenants
+func (h *TenantHandler) GetTenants(w http.ResponseWriter, r *http.Request) {
+	tenants, err := h.service.ListTenants()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch tenants")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, tenants)
+}
+
+// GetTenant handles GET /admin/tenants/{id}
+func (h *TenantHandler) GetTenant(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid tenant ID")
+		return
+	}
+
+	tenant, err := h.service.GetTenant(id)
+	if err != nil {
+		respondServiceError(w, err, "Failed to fetch tenant")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, tenant)
+}
+
+// UpdateTenant handles PUT /admin/tenants/{id}
+func (h *TenantHandler) UpdateTenant(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid tenant ID")
+		return
+	}
+
+	var req TenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	tenant := models.Tenant{ID: id, Slug: req.Slug, Name: req.Name, Domain: req.Domain}
+	if err := h.service.UpdateTenant(&tenant); err != nil {
+		respondServiceError(w, err, "Failed to update tenant")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, tenant)
+}
+
+// DeleteTenant handles DELETE /admin/tenants/{id}
+func (h *TenantHandler) DeleteTenant(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid tenant ID")
+		return
+	}
+
+	if err := h.service.DeleteTenant(id); err != nil {
+		respondServiceError(w, err, "Failed to delete tenant")
+		return
+	}
+
+	respondJSON(w, http.StatusNoContent, nil)
+}
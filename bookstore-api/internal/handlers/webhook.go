@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"bookstore-api/internal/middleware"
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/services"
+)
+
+// WebhookHandler handles HTTP requests for webhook subscriptions.
+type WebhookHandler struct {
+	service services.WebhookService
+}
+
+// NewWebhookHandler creates a new WebhookHandler with the given service.
+func NewWebhookHandler(service services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// SubscriptionRequest represents the request body for creating a webhook.
+type SubscriptionRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types"`
+}
+
+// SubscriptionResponse is returned once on creation and is the only
+// response that includes the signing secret.
+type SubscriptionResponse struct {
+	models.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// CreateSubscription handles POST /webhooks
+func (h *WebhookHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var req SubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	sub := models.WebhookSubscription{
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+	}
+	if err := h.service.Subscribe(middleware.TenantFromContext(r.Context()), &sub); err != nil {
+		respondServiceError(w, err, "Failed to create webhook")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, SubscriptionResponse{WebhookSubscription: sub, Secret: sub.Secret})
+}
+
+// GetSubscriptions handles GET /webhooks
+func (h *WebhookHandler) GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.service.ListSubscriptions(middleware.TenantFromContext(r.Context()))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch webhooks")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, subs)
+}
+
+// DeleteSubscription handles DELETE /webhooks/{id}
+func (h *WebhookHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	if err := h.service.Unsubscribe(middleware.TenantFromContext(r.Context()), id); err != nil {
+		respondServiceError(w, err, "Failed to delete webhook")
+		return
+	}
+
+	respondJSON(w, http.StatusNoContent, nil)
+}
+
+// GetDeliveries handles GET /webhooks/{id}/deliveries
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+	default:
+		respondError(w, http.StatusBadRequest, "Invalid delivery status")
+		return
+	}
+
+	deliveries, err := h.service.ListDeliveries(middleware.TenantFromContext(r.Context()), id, status)
+	if err != nil {
+		respondServiceError(w, err, "Failed to fetch deliveries")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, deliveries)
+}
+
+// ReplayDelivery handles POST /webhooks/deliveries/{id}/replay
+func (h *WebhookHandler) ReplayDelivery(w http.ResponseWriter, r *http.Request) {
+	id, err := extractIDFromPath(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid delivery ID")
+		return
+	}
+
+	delivery, err := h.service.ReplayDelivery(middleware.TenantFromContext(r.Context()), id)
+	if err != nil {
+		respondServiceError(w, err, "Failed to replay delivery")
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, delivery)
+}
+
+// respondServiceError maps service errors to HTTP error responses, using
+// message for unexpected failures.
+func respondServiceError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		respondError(w, http.StatusNotFound, "Not found")
+	case errors.Is(err, services.ErrInvalidInput):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrConflict):
+		respondError(w, http.StatusConflict, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, message)
+	}
+}
@@ -1,47 +1,286 @@
 package middleware
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"net/http"
 	"strings"
 
+	"bookstore-api/internal/models"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// Auth returns a middleware that validates JWT tokens.
-func Auth(jwtSecret string) func(http.Handler) http.Handler {
+// contextKey is the type for request context keys set by this package.
+type contextKey string
+
+const identityKey contextKey = "identity"
+
+// APIKeyHeader is the request header carrying an API key.
+const APIKeyHeader = "X-API-Key"
+
+// TokenEndpointIssuer is the "iss" claim of tokens issued by POST
+// /auth/token. Anyone can obtain one for any subject, so they are never
+// granted the admin or lists scopes.
+const TokenEndpointIssuer = "bookstore-api/auth/token"
+
+// Authentication failures reported by Authenticator.
+var (
+	ErrMissingAuthHeader = errors.New("missing authorization header")
+	ErrInvalidAuthHeader = errors.New("invalid authorization header format")
+	ErrInvalidToken      = errors.New("invalid or expired token")
+	ErrInvalidAPIKey     = errors.New("invalid or revoked API key")
+	ErrWrongTenant       = errors.New("credentials are not valid for this tenant")
+)
+
+// authErrorMessages maps authentication failures to HTTP error messages.
+var authErrorMessages = map[error]string{
+	ErrMissingAuthHeader: "Missing authorization header",
+	ErrInvalidAuthHeader: "Invalid authorization header format",
+	ErrInvalidToken:      "Invalid or expired token",
+	ErrInvalidAPIKey:     "Invalid or revoked API key",
+}
+
+// Authentication methods recorded on an Identity.
+const (
+	MethodJWT    = "jwt"
+	MethodMTLS   = "mtls"
+	MethodAPIKey = "api_key"
+)
+
+// Identity describes the authenticated caller of a request. TenantID is
+// set when the credentials are bound to one tenant: a JWT with a
+// "tenant_id" claim or an API key. Unbound identities, such as client
+// certificates, may only act on the default tenant.
+type Identity struct {
+	Subject  string
+	Method   string
+	Scopes   []string
+	TenantID uint
+}
+
+// HasScope reports whether the identity was granted scope.
+func (i Identity) HasScope(scope string) bool {
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IdentityFromContext returns the identity stored by the auth middleware.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey).(Identity)
+	return identity, ok
+}
+
+// WithIdentity returns a copy of ctx carrying the given identity.
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey, identity)
+}
+
+// APIKeyVerifier looks up the stored API key matching a raw key value.
+type APIKeyVerifier interface {
+	VerifyAPIKey(key string) (*models.APIKey, error)
+}
+
+// Authenticator validates request credentials: API keys, bearer JWTs and
+// verified client certificates.
+type Authenticator struct {
+	jwtSecret     string
+	adminSubjects map[string]bool
+	apiKeys       APIKeyVerifier
+}
+
+// NewAuthenticator creates an Authenticator. Client certificate and JWT
+// identities whose subject is listed in adminSubjects are granted the
+// admin scope, except for tokens issued by the token endpoint. apiKeys may
+// be nil to disable API key authentication.
+func NewAuthenticator(jwtSecret string, adminSubjects []string, apiKeys APIKeyVerifier) *Authenticator {
+	admins := make(map[string]bool, len(adminSubjects))
+	for _, subject := range adminSubjects {
+		admins[subject] = true
+	}
+	return &Authenticator{jwtSecret: jwtSecret, adminSubjects: admins, apiKeys: apiKeys}
+}
+
+// HasCredentials reports whether any credential was supplied.
+func HasCredentials(authHeader, apiKey string, state *tls.ConnectionState) bool {
+	_, hasCert := IdentityFromTLS(state)
+	return authHeader != "" || apiKey != "" || hasCert
+}
+
+// Authenticate resolves the caller's identity. An API key takes precedence
+// over an Authorization header, which takes precedence over a verified
+// client certificate.
+func (a *Authenticator) Authenticate(authHeader, apiKey string, state *tls.ConnectionState) (Identity, error) {
+	switch {
+	case apiKey != "":
+		return a.authenticateAPIKey(apiKey)
+	case authHeader != "":
+		identity, issuer, err := a.authenticateJWT(authHeader)
+		if err != nil {
+			return Identity{}, err
+		}
+		return a.withUserScopes(identity, issuer != TokenEndpointIssuer), nil
+	}
+
+	if identity, ok := IdentityFromTLS(state); ok {
+		return a.withUserScopes(identity, true), nil
+	}
+	return Identity{}, ErrMissingAuthHeader
+}
+
+// authenticateAPIKey verifies an API key and grants its scopes.
+func (a *Authenticator) authenticateAPIKey(apiKey string) (Identity, error) {
+	if a.apiKeys == nil {
+		return Identity{}, ErrInvalidAPIKey
+	}
+	key, err := a.apiKeys.VerifyAPIKey(apiKey)
+	if err != nil {
+		return Identity{}, ErrInvalidAPIKey
+	}
+	return Identity{
+		Subject:  "api-key:" + key.Prefix,
+		Method:   MethodAPIKey,
+		Scopes:   key.Scopes,
+		TenantID: key.TenantID,
+	}, nil
+}
+
+// authenticateJWT validates a "Bearer <token>" authorization value and
+// returns the identity and the token's issuer.
+func (a *Authenticator) authenticateJWT(authHeader string) (Identity, string, error) {
+	// Check for Bearer prefix
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return Identity{}, "", ErrInvalidAuthHeader
+	}
+
+	tokenString := parts[1]
+
+	// Parse and validate the token
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// Validate signing method
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(a.jwtSecret), nil
+	})
+
+	if err != nil || !token.Valid {
+		return Identity{}, "", ErrInvalidToken
+	}
+
+	subject, _ := token.Claims.GetSubject()
+	issuer, _ := token.Claims.GetIssuer()
+	identity := Identity{Subject: subject, Method: MethodJWT}
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && claims["tenant_id"] != nil {
+		tenantID, ok := claims["tenant_id"].(float64)
+		if !ok || tenantID < 1 || tenantID != float64(uint32(tenantID)) {
+			return Identity{}, "", ErrInvalidToken
+		}
+		identity.TenantID = uint(tenantID)
+	}
+	return identity, issuer, nil
+}
+
+// withUserScopes grants user identities (JWT and client certificate) every
+// book, webhook and review scope. Identities whose subject is verified also
+// get the lists scope, since reading lists and notifications are keyed on
+// the subject, and configured subjects get admin.
+func (a *Authenticator) withUserScopes(identity Identity, verified bool) Identity {
+	identity.Scopes = []string{models.ScopeBooksRead, models.ScopeBooksWrite, models.ScopeWebhooks, models.ScopeReviews}
+	if !verified {
+		return identity
+	}
+	identity.Scopes = append(identity.Scopes, models.ScopeLists)
+	if a.adminSubjects[identity.Subject] {
+		identity.Scopes = append(identity.Scopes, models.ScopeAdmin)
+	}
+	return identity
+}
+
+// TenantForIdentity returns the tenant an identity operates on when the
+// request host resolved to hostTenant. Bound credentials select their own
+// tenant; unbound ones are refused with ErrWrongTenant outside the default
+// tenant, so they cannot write to another store's catalog.
+func TenantForIdentity(identity Identity, hostTenant uint) (uint, error) {
+	if identity.TenantID != 0 {
+		return identity.TenantID, nil
+	}
+	if hostTenant != models.DefaultTenantID {
+		return 0, ErrWrongTenant
+	}
+	return hostTenant, nil
+}
+
+// IdentityFromTLS maps a verified client certificate to an identity whose
+// subject is the certificate's common name.
+func IdentityFromTLS(state *tls.ConnectionState) (Identity, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return Identity{}, false
+	}
+	cert := state.VerifiedChains[0][0]
+	subject := cert.Subject.CommonName
+	if subject == "" {
+		subject = cert.Subject.String()
+	}
+	return Identity{Subject: subject, Method: MethodMTLS}, true
+}
+
+// Auth returns a middleware that requires a valid API key, JWT or client
+// certificate. Credentials bound to a tenant select that tenant for the
+// request; unbound ones are refused outside the default tenant.
+func Auth(authn *Authenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Extract token from Authorization header
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				http.Error(w, `{"error":"Missing authorization header"}`, http.StatusUnauthorized)
+			identity, err := authn.Authenticate(r.Header.Get("Authorization"), r.Header.Get(APIKeyHeader), r.TLS)
+			if err != nil {
+				http.Error(w, `{"error":"`+authErrorMessages[err]+`"}`, http.StatusUnauthorized)
 				return
 			}
 
-			// Check for Bearer prefix
-			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || parts[0] != "Bearer" {
-				http.Error(w, `{"error":"Invalid authorization header format"}`, http.StatusUnauthorized)
+			tenantID, err := TenantForIdentity(identity, TenantFromContext(r.Context()))
+			if err != nil {
+				http.Error(w, `{"error":"Credentials are not valid for this tenant"}`, http.StatusForbidden)
 				return
 			}
 
-			tokenString := parts[1]
-
-			// Parse and validate the token
-			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-				// Validate signing method
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, jwt.ErrSignatureInvalid
-				}
-				return []byte(jwtSecret), nil
-			})
+			// Credentials are valid, proceed to next handler
+			ctx := WithTenant(WithIdentity(r.Context(), identity), tenantID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
 
-			if err != nil || !token.Valid {
-				http.Error(w, `{"error":"Invalid or expired token"}`, http.StatusUnauthorized)
+// OptionalAuth returns a middleware that authenticates requests like Auth
+// when they carry credentials. Requests without any pass through
+// anonymously.
+func OptionalAuth(authn *Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasCredentials(r.Header.Get("Authorization"), r.Header.Get(APIKeyHeader), r.TLS) {
+				next.ServeHTTP(w, r)
 				return
 			}
+			Auth(authn)(next).ServeHTTP(w, r)
+		})
+	}
+}
 
-			// Token is valid, proceed to next handler
+// RequireScope returns a middleware that rejects identities without the
+// given scope. It must run after Auth.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, ok := IdentityFromContext(r.Context())
+			if !ok || !identity.HasScope(scope) {
+				http.Error(w, `{"error":"Insufficient scope"}`, http.StatusForbidden)
+				return
+			}
 			next.ServeHTTP(w, r)
 		})
 	}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+)
+
+func TestIdentityFromTLS(t *testing.T) {
+	leaf := func(name pkix.Name) *x509.Certificate { return &x509.Certificate{Subject: name} }
+	verified := func(cert *x509.Certificate) *tls.ConnectionState {
+		return &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+			VerifiedChains:   [][]*x509.Certificate{{cert, leaf(pkix.Name{CommonName: "Test CA"})}},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		state       *tls.ConnectionState
+		wantOK      bool
+		wantSubject string
+	}{
+		{"no TLS", nil, false, ""},
+		{"no client certificate", &tls.ConnectionState{}, false, ""},
+		{
+			// A presented certificate that was not verified, as with
+			// VerifyClientCertIfGiven and no client CA, is ignored.
+			"unverified certificate",
+			&tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf(pkix.Name{CommonName: "alice"})}},
+			false, "",
+		},
+		{"empty chain", &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{}}}, false, ""},
+		{"common name", verified(leaf(pkix.Name{CommonName: "alice", Organization: []string{"Books"}})), true, "alice"},
+		{"no common name", verified(leaf(pkix.Name{Organization: []string{"Books"}, Country: []string{"NZ"}})), true, "O=Books,C=NZ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, ok := IdentityFromTLS(tt.state)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if identity.Subject != tt.wantSubject || identity.Method != MethodMTLS {
+				t.Errorf("identity = %+v, want subject %q via mTLS", identity, tt.wantSubject)
+			}
+		})
+	}
+}
+
+func TestClientCertificatesGetUserScopes(t *testing.T) {
+	authn := NewAuthenticator(testSecret, []string{"root"}, nil)
+	state := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "root"}}}}}
+
+	identity, err := authn.Authenticate("", "", state)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if identity.Subject != "root" || identity.TenantID != 0 {
+		t.Errorf("identity = %+v, want the unbound root subject", identity)
+	}
+	for _, scope := range []string{"books:write", "lists", "admin"} {
+		if !identity.HasScope(scope) {
+			t.Errorf("client certificate identity lacks %s", scope)
+		}
+	}
+
+	// An Authorization header takes precedence over the certificate.
+	if _, err := authn.Authenticate("Bearer nope", "", state); err != ErrInvalidToken {
+		t.Errorf("Authenticate with a bad token = %v, want ErrInvalidToken", err)
+	}
+}
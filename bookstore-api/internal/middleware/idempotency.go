@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"bookstore-api/internal/models"
+)
+
+// Idempotency headers.
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+)
+
+// Limits applied to idempotent requests.
+const (
+	maxIdempotencyKeyLength = 255
+	maxIdempotentBodyBytes  = 1 << 20
+)
+
+// IdempotencyStore claims idempotency keys and stores the responses to the
+// requests that claimed them.
+type IdempotencyStore interface {
+	Begin(scope, key, requestHash string) (*models.IdempotencyRecord, bool, error)
+	Complete(record *models.IdempotencyRecord) error
+	Release(record *models.IdempotencyRecord) error
+}
+
+// captureWriter passes a response through while keeping a copy of it.
+type captureWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+// WriteHeader captures the status code.
+func (cw *captureWriter) WriteHeader(code int) {
+	if cw.statusCode == 0 {
+		cw.statusCode = code
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+// Write captures the body.
+func (cw *captureWriter) Write(b []byte) (int, error) {
+	if cw.statusCode == 0 {
+		cw.statusCode = http.StatusOK
+	}
+	cw.body.Write(b)
+	return cw.ResponseWriter.Write(b)
+}
+
+// Idempotency returns a middleware that makes write requests carrying an
+// Idempotency-Key header safe to retry. The first response for a key is
+// stored per tenant and caller and replayed for retries with the same
+// method, path and body; reusing the key for a different request is
+// rejected with 422. Server errors are not stored, so the request can be
+// retried. It must run after Auth.
+func Idempotency(store IdempotencyStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			identity, authenticated := IdentityFromContext(r.Context())
+			if key == "" || !authenticated || isSafeMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				http.Error(w, `{"error":"Idempotency-Key must be at most 255 characters"}`, http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					http.Error(w, `{"error":"Request body too large"}`, http.StatusRequestEntityTooLarge)
+					return
+				}
+				http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			scope := fmt.Sprintf("%d:%s", TenantFromContext(r.Context()), identity.Subject)
+			hash := requestHash(r, body)
+			record, claimed, err := store.Begin(scope, key, hash)
+			if err != nil {
+				log.Printf("Idempotency lookup failed: %v", err)
+				http.Error(w, `{"error":"Failed to process Idempotency-Key"}`, http.StatusInternalServerError)
+				return
+			}
+
+			if !claimed {
+				switch {
+				case record.RequestHash != hash:
+					http.Error(w, `{"error":"Idempotency-Key was already used for a different request"}`, http.StatusUnprocessableEntity)
+				case record.StatusCode == 0:
+					http.Error(w, `{"error":"A request with this Idempotency-Key is still in progress"}`, http.StatusConflict)
+				default:
+					replay(w, record)
+				}
+				return
+			}
+
+			capture := &captureWriter{ResponseWriter: w}
+			next.ServeHTTP(capture, r)
+			if capture.statusCode == 0 {
+				capture.statusCode = http.StatusOK
+			}
+
+			if capture.statusCode >= http.StatusInternalServerError {
+				if err := store.Release(record); err != nil {
+					log.Printf("Failed to release Idempotency-Key: %v", err)
+				}
+				return
+			}
+			record.StatusCode = capture.statusCode
+			record.ContentType = capture.Header().Get("Content-Type")
+			record.Body = capture.body.Bytes()
+			if err := store.Complete(record); err != nil {
+				log.Printf("Failed to store idempotent response: %v", err)
+			}
+		})
+	}
+}
+
+// replay writes a stored response.
+func replay(w http.ResponseWriter, record *models.IdempotencyRecord) {
+	if record.ContentType != "" {
+		w.Header().Set("Content-Type", record.ContentType)
+	}
+	w.Header().Set(IdempotencyReplayedHeader, "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write(record.Body)
+}
+
+// requestHash fingerprints a request's method, target and body.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.RequestURI())
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// isSafeMethod reports whether method has no side effects.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
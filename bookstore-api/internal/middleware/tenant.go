@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"bookstore-api/internal/models"
+)
+
+const tenantKey contextKey = "tenant"
+
+// TenantResolver maps a request host name to the tenant serving it.
+type TenantResolver interface {
+	ResolveHost(host string) (uint, bool)
+}
+
+// TenantFromContext returns the tenant a request operates on, or the
+// default tenant when none was resolved.
+func TenantFromContext(ctx context.Context) uint {
+	if tenantID, ok := ctx.Value(tenantKey).(uint); ok {
+		return tenantID
+	}
+	return models.DefaultTenantID
+}
+
+// WithTenant returns a copy of ctx operating on the given tenant.
+func WithTenant(ctx context.Context, tenantID uint) context.Context {
+	return context.WithValue(ctx, tenantKey, tenantID)
+}
+
+// TenantForHost resolves the tenant for a Host header value, falling back
+// to the default tenant for unknown hosts.
+func TenantForHost(resolver TenantResolver, host string) uint {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if tenantID, ok := resolver.ResolveHost(host); ok {
+		return tenantID
+	}
+	return models.DefaultTenantID
+}
+
+// Tenant returns a middleware that selects the tenant from the request
+// host. Auth replaces it with the tenant bound to the caller's
+// credentials, if any.
+func Tenant(resolver TenantResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := WithTenant(r.Context(), TenantForHost(resolver, r.Host))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
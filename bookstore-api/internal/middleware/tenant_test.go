@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bookstore-api/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret-that-is-at-least-32-characters"
+
+// hostResolver resolves hosts from a fixed map.
+type hostResolver map[string]uint
+
+func (r hostResolver) ResolveHost(host string) (uint, bool) {
+	tenantID, ok := r[host]
+	return tenantID, ok
+}
+
+// keyVerifier accepts one API key bound to a tenant.
+type keyVerifier struct {
+	key      string
+	tenantID uint
+}
+
+func (v keyVerifier) VerifyAPIKey(key string) (*models.APIKey, error) {
+	if key != v.key {
+		return nil, ErrInvalidAPIKey
+	}
+	return &models.APIKey{Prefix: "test", TenantID: v.tenantID, Scopes: []string{models.ScopeBooksWrite}}, nil
+}
+
+func TestTenantResolution(t *testing.T) {
+	resolver := hostResolver{"a.example.com": 2, "b.example.com": 3}
+	authn := NewAuthenticator(testSecret, nil, keyVerifier{key: "bsk_key", tenantID: 3})
+
+	tests := []struct {
+		name       string
+		host       string
+		headers    map[string]string
+		auth       bool
+		want       uint
+		wantStatus int
+	}{
+		{name: "known host", host: "a.example.com", want: 2},
+		{name: "host with port", host: "A.example.com:8443", want: 2},
+		{name: "unknown host", host: "other.example.com", want: models.DefaultTenantID},
+		{
+			name:    "token claim overrides host",
+			host:    "a.example.com",
+			headers: map[string]string{"Authorization": "Bearer " + signToken(t, jwt.MapClaims{"sub": "u", "tenant_id": 3})},
+			auth:    true,
+			want:    3,
+		},
+		{
+			name:    "unbound token on default tenant",
+			host:    "other.example.com",
+			headers: map[string]string{"Authorization": "Bearer " + signToken(t, jwt.MapClaims{"sub": "u"})},
+			auth:    true,
+			want:    models.DefaultTenantID,
+		},
+		{
+			name:       "unbound token refused on another tenant",
+			host:       "b.example.com",
+			headers:    map[string]string{"Authorization": "Bearer " + signToken(t, jwt.MapClaims{"sub": "u"})},
+			auth:       true,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:    "API key overrides host",
+			host:    "a.example.com",
+			headers: map[string]string{APIKeyHeader: "bsk_key"},
+			auth:    true,
+			want:    3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got uint
+			var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = TenantFromContext(r.Context())
+			})
+			if tt.auth {
+				handler = Auth(authn)(handler)
+			}
+			handler = Tenant(resolver)(handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/books", nil)
+			req.Host = tt.host
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			wantStatus := tt.wantStatus
+			if wantStatus == 0 {
+				wantStatus = http.StatusOK
+			}
+			if rec.Code != wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, wantStatus, rec.Body)
+			}
+			if got != tt.want {
+				t.Errorf("tenant = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidTenantClaimRejected(t *testing.T) {
+	authn := NewAuthenticator(testSecret, nil, nil)
+	token := signToken(t, jwt.MapClaims{"sub": "u", "tenant_id": "store-a"})
+
+	_, err := authn.Authenticate("Bearer "+token, "", nil)
+	if err != ErrInvalidToken {
+		t.Errorf("Authenticate: got %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+// signToken signs claims with the test secret, adding an expiry.
+func signToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return token
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// Permission scopes carried by authenticated identities.
+const (
+	ScopeBooksRead  = "books:read"
+	ScopeBooksWrite = "books:write"
+	ScopeWebhooks   = "webhooks"
+	ScopeLists      = "lists"
+	ScopeReviews    = "reviews"
+	ScopeAdmin      = "admin"
+)
+
+// APIKeyScopes lists the scopes that may be granted to an API key. Reading
+// lists and reviews belong to users, and admin access is reserved for configured
+// users.
+var APIKeyScopes = []string{ScopeBooksRead, ScopeBooksWrite, ScopeWebhooks}
+
+// APIKey is a long-lived credential for service-to-service access to one
+// tenant. Only a SHA-256 hash of the key is stored; the prefix identifies
+// it in listings.
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	TenantID   uint       `json:"tenant_id" gorm:"not null;default:1;index"`
+	Name       string     `json:"name" gorm:"not null"`
+	Prefix     string     `json:"prefix" gorm:"not null;uniqueIndex"`
+	Hash       string     `json:"-" gorm:"not null;uniqueIndex"`
+	Scopes     []string   `json:"scopes" gorm:"serializer:json;not null"`
+	CreatedBy  string     `json:"created_by"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
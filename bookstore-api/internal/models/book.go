@@ -4,13 +4,31 @@ import (
 	"time"
 )
 
-// Book represents a book entity in the bookstore.
+// Book represents a book entity in a tenant's catalog. ISBNs are unique
+// within a tenant. Tags are stored as BookTag rows by the repository.
 type Book struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Title     string    `json:"title" gorm:"not null"`
-	Author    string    `json:"author" gorm:"not null"`
-	ISBN      string    `json:"isbn" gorm:"unique;not null"`
-	Price     float64   `json:"price" gorm:"not null"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	TenantID   uint      `json:"tenant_id" gorm:"not null;default:1;uniqueIndex:idx_books_tenant_isbn,priority:1"`
+	Title      string    `json:"title" gorm:"not null"`
+	Author     string    `json:"author" gorm:"not null"`
+	ISBN       string    `json:"isbn" gorm:"not null;uniqueIndex:idx_books_tenant_isbn,priority:2"`
+	Price      float64   `json:"price" gorm:"not null"`
+	CategoryID *uint     `json:"category_id" gorm:"index"`
+	Tags       []string  `json:"tags" gorm:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// BookFilter narrows and pages a book listing. Zero values are ignored.
+// CategoryID matches books in the category or any of its subcategories.
+type BookFilter struct {
+	Title      string
+	Author     string
+	ISBN       string
+	MinPrice   *float64
+	MaxPrice   *float64
+	CategoryID *uint
+	Tag        string
+	Limit      int
+	Offset     int
 }
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+)
+
+// Category is a node in a tenant's category tree. Top-level categories
+// have no parent. Slugs are unique within a tenant.
+type Category struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TenantID  uint      `json:"-" gorm:"not null;uniqueIndex:idx_categories_tenant_slug,priority:1"`
+	ParentID  *uint     `json:"parent_id" gorm:"index"`
+	Name      string    `json:"name" gorm:"not null"`
+	Slug      string    `json:"slug" gorm:"not null;uniqueIndex:idx_categories_tenant_slug,priority:2"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BookTag attaches a free-form tag to a book.
+type BookTag struct {
+	TenantID uint   `gorm:"not null;index:idx_book_tags_tenant_tag,priority:1"`
+	BookID   uint   `gorm:"primaryKey"`
+	Tag      string `gorm:"primaryKey;index:idx_book_tags_tenant_tag,priority:2"`
+}
+
+// TagCount is a tag and the number of books carrying it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// PriceBucketBounds are the upper bounds of the price ranges counted by
+// the price facet. The last range is open-ended.
+var PriceBucketBounds = []float64{10, 25, 50, 100}
+
+// BookFacets counts the books matching a filter by category, author and
+// price range.
+type BookFacets struct {
+	Categories  []CategoryFacet `json:"categories"`
+	Authors     []FacetCount    `json:"authors"`
+	PriceRanges []PriceRange    `json:"price_ranges"`
+}
+
+// CategoryFacet counts the matching books in a category and its
+// subcategories.
+type CategoryFacet struct {
+	ID       uint   `json:"id"`
+	ParentID *uint  `json:"parent_id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	Count    int64  `json:"count"`
+}
+
+// FacetCount counts the matching books with one value of a field.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// PriceRange counts the matching books priced from Min up to, but not
+// including, Max. Max is nil for the last range.
+type PriceRange struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max"`
+	Count int64    `json:"count"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyRecord stores the response to the first write request made
+// with an Idempotency-Key, so retries can be answered without repeating
+// the write. StatusCode is 0 while that request is still running.
+type IdempotencyRecord struct {
+	ID          uint   `gorm:"primaryKey"`
+	Scope       string `gorm:"not null;uniqueIndex:idx_idempotency_scope_key,priority:1"`
+	Key         string `gorm:"column:idempotency_key;not null;uniqueIndex:idx_idempotency_scope_key,priority:2"`
+	RequestHash string `gorm:"not null"`
+	StatusCode  int    `gorm:"not null;default:0"`
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time `gorm:"not null;index"`
+}
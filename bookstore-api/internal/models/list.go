@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+)
+
+// Reading list kinds.
+const (
+	ListWishlist = "wishlist"
+	ListReading  = "reading"
+	ListRead     = "read"
+)
+
+// ListKinds lists every valid reading list kind.
+var ListKinds = []string{ListWishlist, ListReading, ListRead}
+
+// ReadingList is a user's named, ordered list of books in one tenant's
+// catalog. A list with a ShareToken can be read by anyone holding it.
+type ReadingList struct {
+	ID         uint        `json:"id" gorm:"primaryKey"`
+	TenantID   uint        `json:"-" gorm:"not null;index:idx_reading_lists_owner,priority:1"`
+	Owner      string      `json:"-" gorm:"not null;index:idx_reading_lists_owner,priority:2"`
+	Name       string      `json:"name" gorm:"not null"`
+	Kind       string      `json:"kind" gorm:"not null"`
+	ShareToken *string     `json:"share_token,omitempty" gorm:"uniqueIndex"`
+	Entries    []ListEntry `json:"entries" gorm:"-"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// ListEntry is a book on a reading list. Entries are ordered by Position,
+// starting at 1.
+type ListEntry struct {
+	ListID   uint      `json:"-" gorm:"primaryKey"`
+	BookID   uint      `json:"book_id" gorm:"primaryKey;index"`
+	Position int       `json:"position" gorm:"not null"`
+	AddedAt  time.Time `json:"added_at" gorm:"not null"`
+	Book     *Book     `json:"book,omitempty" gorm:"-"`
+}
+
+// Notification types.
+const (
+	NotificationPriceDrop = "price_drop"
+)
+
+// Notification is an in-app message for one user. Price drop
+// notifications are written when the price of a book on one of the
+// user's wishlists goes down.
+type Notification struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	TenantID      uint       `json:"-" gorm:"not null;index:idx_notifications_owner,priority:1"`
+	Owner         string     `json:"-" gorm:"not null;index:idx_notifications_owner,priority:2"`
+	Type          string     `json:"type" gorm:"not null"`
+	BookID        uint       `json:"book_id" gorm:"not null"`
+	Message       string     `json:"message" gorm:"not null"`
+	PreviousPrice float64    `json:"previous_price"`
+	Price         float64    `json:"price"`
+	ReadAt        *time.Time `json:"read_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// Price schedule states.
+const (
+	ScheduleScheduled = "scheduled"
+	ScheduleActive    = "active"
+	ScheduleCompleted = "completed"
+	ScheduleCancelled = "cancelled"
+)
+
+// PricePoint records a price a book had from ChangedAt until the next
+// point. A point is written when a book is created and whenever its price
+// changes.
+type PricePoint struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	TenantID      uint      `json:"-" gorm:"not null;index:idx_price_points_book,priority:1"`
+	BookID        uint      `json:"book_id" gorm:"not null;index:idx_price_points_book,priority:2"`
+	Price         float64   `json:"price" gorm:"not null"`
+	PreviousPrice *float64  `json:"previous_price"`
+	ChangedAt     time.Time `json:"changed_at" gorm:"not null"`
+}
+
+// PriceSchedule is a future price change. Without EndsAt the new price is
+// permanent; with EndsAt it is a sale, after which the price from before
+// the sale is restored unless the price was changed in the meantime.
+type PriceSchedule struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	TenantID    uint       `json:"-" gorm:"not null;index"`
+	BookID      uint       `json:"book_id" gorm:"not null;index"`
+	Price       float64    `json:"price" gorm:"not null"`
+	StartsAt    time.Time  `json:"starts_at" gorm:"not null;index"`
+	EndsAt      *time.Time `json:"ends_at"`
+	Status      string     `json:"status" gorm:"not null;index"`
+	RevertPrice *float64   `json:"revert_price,omitempty"`
+	AppliedAt   *time.Time `json:"applied_at"`
+	EndedAt     *time.Time `json:"ended_at"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
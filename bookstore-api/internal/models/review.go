@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// Review is a user's rating of a book, from 1 to 5, with an optional
+// comment. Each reviewer reviews a book at most once.
+type Review struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TenantID  uint      `json:"-" gorm:"not null;index"`
+	BookID    uint      `json:"book_id" gorm:"not null;uniqueIndex:idx_reviews_book_reviewer,priority:1"`
+	Reviewer  string    `json:"reviewer" gorm:"not null;uniqueIndex:idx_reviews_book_reviewer,priority:2"`
+	Rating    int       `json:"rating" gorm:"not null"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// DefaultTenantID is the tenant that owns data created before multi-tenancy
+// and serves requests that match no other tenant.
+const DefaultTenantID uint = 1
+
+// Tenant is a storefront with its own catalog. Requests are routed to a
+// tenant by the tenant bound to their credentials or by Domain matching
+// the request host.
+type Tenant struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Slug      string    `json:"slug" gorm:"not null;uniqueIndex"`
+	Name      string    `json:"name" gorm:"not null"`
+	Domain    *string   `json:"domain" gorm:"uniqueIndex"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
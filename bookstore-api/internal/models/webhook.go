@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+)
+
+// Book event types published to webhook subscribers.
+const (
+	EventBookCreated  = "book.created"
+	EventBookUpdated  = "book.updated"
+	EventBookRepriced = "book.repriced"
+	EventBookDeleted  = "book.deleted"
+)
+
+// EventTypes lists every event type a webhook may subscribe to.
+var EventTypes = []string{EventBookCreated, EventBookUpdated, EventBookRepriced, EventBookDeleted}
+
+// Webhook delivery states.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// WebhookSubscription is a partner endpoint that receives one tenant's
+// catalog events.
+type WebhookSubscription struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	TenantID   uint      `json:"tenant_id" gorm:"not null;default:1;index"`
+	URL        string    `json:"url" gorm:"not null"`
+	Secret     string    `json:"-" gorm:"not null"`
+	EventTypes []string  `json:"event_types" gorm:"serializer:json;not null"`
+	Active     bool      `json:"active" gorm:"not null;default:true"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// OutboxEvent is a catalog event recorded in the same transaction as the
+// book mutation that caused it.
+type OutboxEvent struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	TenantID    uint       `json:"tenant_id" gorm:"not null;default:1"`
+	EventType   string     `json:"event_type" gorm:"not null;index"`
+	Payload     string     `json:"payload" gorm:"type:text;not null"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ProcessedAt *time.Time `json:"processed_at" gorm:"index"`
+}
+
+// WebhookDelivery tracks sending one outbox event to one subscription.
+type WebhookDelivery struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint       `json:"subscription_id" gorm:"not null;index"`
+	EventID        uint       `json:"event_id" gorm:"not null;index"`
+	EventType      string     `json:"event_type" gorm:"not null"`
+	Payload        string     `json:"-" gorm:"type:text;not null"`
+	Status         string     `json:"status" gorm:"not null;index"`
+	Attempts       int        `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"index"`
+	LastStatusCode int        `json:"last_status_code"`
+	LastError      string     `json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	Subscription WebhookSubscription `json:"-"`
+}
@@ -0,0 +1,49 @@
+// Package notifications writes in-app notifications about books that
+// users follow. Like reading lists, notifications belong to the subject of
+// the caller's credentials.
+package notifications
+
+import (
+	"fmt"
+
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// RecordPriceDrops is a repositories.ChangeHook that notifies the owners
+// of wishlists holding a book when its price goes down, inside the
+// mutation's transaction.
+func RecordPriceDrops(tx *gorm.DB, change repositories.BookChange) error {
+	if change.Type != repositories.ChangeUpdated || change.After.Price >= change.Before.Price {
+		return nil
+	}
+	book := change.After
+
+	var owners []string
+	err := tx.Model(&models.ReadingList{}).
+		Joins("JOIN list_entries ON list_entries.list_id = reading_lists.id").
+		Where("reading_lists.tenant_id = ? AND reading_lists.kind = ? AND list_entries.book_id = ?", book.TenantID, models.ListWishlist, book.ID).
+		Distinct("reading_lists.owner").
+		Order("reading_lists.owner").
+		Pluck("reading_lists.owner", &owners).Error
+	if err != nil || len(owners) == 0 {
+		return err
+	}
+
+	message := fmt.Sprintf("%s by %s dropped from %.2f to %.2f", book.Title, book.Author, change.Before.Price, book.Price)
+	notifications := make([]models.Notification, len(owners))
+	for i, owner := range owners {
+		notifications[i] = models.Notification{
+			TenantID:      book.TenantID,
+			Owner:         owner,
+			Type:          models.NotificationPriceDrop,
+			BookID:        book.ID,
+			Message:       message,
+			PreviousPrice: change.Before.Price,
+			Price:         book.Price,
+		}
+	}
+	return tx.Create(&notifications).Error
+}
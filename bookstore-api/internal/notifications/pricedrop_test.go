@@ -0,0 +1,72 @@
+package notifications
+
+import (
+	"testing"
+
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+	"bookstore-api/internal/services"
+	"bookstore-api/internal/testutil"
+)
+
+func TestRecordPriceDrops(t *testing.T) {
+	db := testutil.NewDB(t)
+	books := repositories.NewGormBookRepository(db, RecordPriceDrops)
+	lists := services.NewListService(repositories.NewGormListRepository(db), books)
+
+	book := models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441013593", Price: 10}
+	if err := books.Create(models.DefaultTenantID, &book); err != nil {
+		t.Fatalf("create book: %v", err)
+	}
+	// Only alice follows the book on a wishlist; bob is reading it.
+	follow := func(owner, kind string) {
+		t.Helper()
+		list := models.ReadingList{Name: "Mine", Kind: kind}
+		if err := lists.CreateList(models.DefaultTenantID, owner, &list); err != nil {
+			t.Fatalf("create list: %v", err)
+		}
+		if _, err := lists.AddEntry(models.DefaultTenantID, owner, list.ID, book.ID, 0); err != nil {
+			t.Fatalf("add entry: %v", err)
+		}
+	}
+	follow("alice", models.ListWishlist)
+	follow("bob", models.ListReading)
+
+	tests := []struct {
+		name  string
+		price float64
+		want  []string
+	}{
+		{name: "increase", price: 12},
+		{name: "unchanged", price: 12},
+		{name: "drop", price: 8, want: []string{"alice"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := db.Where("1 = 1").Delete(&models.Notification{}).Error; err != nil {
+				t.Fatalf("clear notifications: %v", err)
+			}
+			previous := book.Price
+			book.Price = tt.price
+			if err := books.Update(models.DefaultTenantID, &book); err != nil {
+				t.Fatalf("update book: %v", err)
+			}
+
+			var notifications []models.Notification
+			if err := db.Order("owner").Find(&notifications).Error; err != nil {
+				t.Fatalf("load notifications: %v", err)
+			}
+			if len(notifications) != len(tt.want) {
+				t.Fatalf("got %d notifications, want %d", len(notifications), len(tt.want))
+			}
+			for i, n := range notifications {
+				if n.Owner != tt.want[i] || n.Type != models.NotificationPriceDrop || n.BookID != book.ID {
+					t.Errorf("notification %d = %+v, want a price drop of book %d for %s", i, n, book.ID, tt.want[i])
+				}
+				if n.PreviousPrice != previous || n.Price != tt.price {
+					t.Errorf("notification %d prices %v -> %v, want %v -> %v", i, n.PreviousPrice, n.Price, previous, tt.price)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// Spec is the OpenAPI 3 document describing the bookstore API.
+//
+//go:embed openapi.json
+var Spec []byte
+
+// Handler handles GET /openapi.json
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(Spec)
+}
@@ -0,0 +1,31 @@
+package pricing
+
+import (
+	"time"
+
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// RecordHistory is a repositories.ChangeHook that writes a price point
+// when a book is created or its price changes, inside the mutation's
+// transaction.
+func RecordHistory(tx *gorm.DB, change repositories.BookChange) error {
+	point := models.PricePoint{ChangedAt: time.Now().UTC()}
+	switch {
+	case change.Type == repositories.ChangeCreated:
+		point.Price = change.After.Price
+	case change.Type == repositories.ChangeUpdated && change.Before.Price != change.After.Price:
+		previous := change.Before.Price
+		point.Price = change.After.Price
+		point.PreviousPrice = &previous
+	default:
+		return nil
+	}
+
+	point.TenantID = change.After.TenantID
+	point.BookID = change.After.ID
+	return tx.Create(&point).Error
+}
@@ -0,0 +1,161 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"bookstore-api/internal/config"
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+	"bookstore-api/internal/services"
+
+	"gorm.io/gorm"
+)
+
+// batchSize bounds the schedules applied per poll.
+const batchSize = 50
+
+// Scheduler applies due price schedules: it starts permanent price changes
+// and sales and ends sales. Only one scheduler should run per database.
+type Scheduler struct {
+	books        services.BookService
+	prices       repositories.PriceRepository
+	pollInterval time.Duration
+}
+
+// NewScheduler creates a new Scheduler with the given polling settings.
+// Prices are changed through the book service, so price history, webhook
+// events and change notifications are recorded as for any other update.
+func NewScheduler(books services.BookService, prices repositories.PriceRepository, cfg config.PricingConfig) *Scheduler {
+	return &Scheduler{books: books, prices: prices, pollInterval: cfg.PollInterval}
+}
+
+// Run polls for due schedules until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick applies every schedule due by now once.
+func (s *Scheduler) tick(ctx context.Context, now time.Time) {
+	schedules, err := s.prices.FindDueSchedules(now, batchSize)
+	if err != nil {
+		log.Printf("pricing: loading schedules failed: %v", err)
+		return
+	}
+
+	for i := range schedules {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.apply(&schedules[i], now); err != nil {
+			log.Printf("pricing: applying schedule %d failed: %v", schedules[i].ID, err)
+		}
+	}
+}
+
+// apply moves a schedule to its next state, changing the book's price as
+// needed. A schedule whose book was deleted is cancelled.
+func (s *Scheduler) apply(schedule *models.PriceSchedule, now time.Time) error {
+	book, err := s.books.GetBookByID(schedule.TenantID, schedule.BookID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		schedule.Status = models.ScheduleCancelled
+		return s.prices.UpdateSchedule(schedule)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case schedule.Status == models.ScheduleActive:
+		err = s.endSale(schedule, book, now)
+	case schedule.EndsAt == nil:
+		err = s.changePrice(schedule, book, now)
+	case !now.Before(*schedule.EndsAt):
+		// The whole sale was missed, e.g. while the server was down.
+		schedule.Status = models.ScheduleCompleted
+		schedule.EndedAt = &now
+	default:
+		err = s.startSale(schedule, book, now)
+	}
+	if err != nil {
+		return err
+	}
+	return s.prices.UpdateSchedule(schedule)
+}
+
+// changePrice applies a permanent price change. During a sale the new
+// price becomes the one restored when the sale ends.
+func (s *Scheduler) changePrice(schedule *models.PriceSchedule, book *models.Book, now time.Time) error {
+	sale, err := s.prices.FindActiveSale(schedule.TenantID, schedule.BookID)
+	if err != nil {
+		return err
+	}
+	if sale != nil {
+		price := schedule.Price
+		sale.RevertPrice = &price
+		if err := s.prices.UpdateSchedule(sale); err != nil {
+			return err
+		}
+	} else if book.Price != schedule.Price {
+		book.Price = schedule.Price
+		if err := s.books.UpdateBook(book.TenantID, book); err != nil {
+			return err
+		}
+	}
+
+	schedule.Status = models.ScheduleCompleted
+	schedule.AppliedAt = &now
+	return nil
+}
+
+// startSale remembers the current price and applies the sale price. The
+// price to restore is saved first so a retry after a failure does not
+// mistake the sale price for it.
+func (s *Scheduler) startSale(schedule *models.PriceSchedule, book *models.Book, now time.Time) error {
+	if schedule.RevertPrice == nil {
+		price := book.Price
+		schedule.RevertPrice = &price
+		if err := s.prices.UpdateSchedule(schedule); err != nil {
+			return err
+		}
+	}
+
+	if book.Price != schedule.Price {
+		book.Price = schedule.Price
+		if err := s.books.UpdateBook(book.TenantID, book); err != nil {
+			return err
+		}
+	}
+
+	schedule.Status = models.ScheduleActive
+	schedule.AppliedAt = &now
+	return nil
+}
+
+// endSale restores the price from before the sale, unless the price was
+// changed by other means while the sale ran.
+func (s *Scheduler) endSale(schedule *models.PriceSchedule, book *models.Book, now time.Time) error {
+	if schedule.RevertPrice != nil && book.Price == schedule.Price && book.Price != *schedule.RevertPrice {
+		book.Price = *schedule.RevertPrice
+		if err := s.books.UpdateBook(book.TenantID, book); err != nil {
+			return err
+		}
+	}
+
+	schedule.Status = models.ScheduleCompleted
+	schedule.EndedAt = &now
+	return nil
+}
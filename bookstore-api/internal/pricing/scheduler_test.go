@@ -0,0 +1,230 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"bookstore-api/internal/config"
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+	"bookstore-api/internal/services"
+	"bookstore-api/internal/testutil"
+)
+
+func TestMain(m *testing.M) {
+	// Failed schedules are logged.
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// flakyPrices fails the next save of a sale that is starting, after the
+// book's price was already changed.
+type flakyPrices struct {
+	repositories.PriceRepository
+	failStart bool
+}
+
+func (p *flakyPrices) UpdateSchedule(schedule *models.PriceSchedule) error {
+	if p.failStart && schedule.Status == models.ScheduleActive && schedule.EndedAt == nil {
+		p.failStart = false
+		return errors.New("connection reset")
+	}
+	return p.PriceRepository.UpdateSchedule(schedule)
+}
+
+// schedulerFixture is a scheduler over an in-memory database holding one
+// book priced at 10.
+type schedulerFixture struct {
+	scheduler *Scheduler
+	books     services.BookService
+	pricing   services.PricingService
+	prices    *flakyPrices
+	book      models.Book
+}
+
+func newSchedulerFixture(t *testing.T) *schedulerFixture {
+	t.Helper()
+	db := testutil.NewDB(t)
+	bookRepo := repositories.NewGormBookRepository(db, RecordHistory)
+	books := services.NewBookService(bookRepo, repositories.NewGormCategoryRepository(db), nil)
+	prices := &flakyPrices{PriceRepository: repositories.NewGormPriceRepository(db)}
+
+	f := &schedulerFixture{
+		scheduler: NewScheduler(books, prices, config.PricingConfig{PollInterval: time.Minute}),
+		books:     books,
+		pricing:   services.NewPricingService(bookRepo, prices),
+		prices:    prices,
+		book:      models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441013593", Price: 10},
+	}
+	if err := books.CreateBook(models.DefaultTenantID, &f.book); err != nil {
+		t.Fatalf("create book: %v", err)
+	}
+	return f
+}
+
+// price returns the book's current price, or 0 once it was deleted.
+func (f *schedulerFixture) price(t *testing.T) float64 {
+	t.Helper()
+	book, err := f.books.GetBookByID(models.DefaultTenantID, f.book.ID)
+	if errors.Is(err, services.ErrNotFound) {
+		return 0
+	}
+	if err != nil {
+		t.Fatalf("load book: %v", err)
+	}
+	return book.Price
+}
+
+func TestSchedulerTick(t *testing.T) {
+	// Schedule times are relative to base.
+	base := time.Now().UTC().Truncate(time.Second)
+	at := func(d time.Duration) *time.Time {
+		t := base.Add(d)
+		return &t
+	}
+
+	type step struct {
+		at        time.Duration
+		before    func(t *testing.T, f *schedulerFixture)
+		wantPrice float64
+	}
+	tests := []struct {
+		name        string
+		schedules   []models.PriceSchedule
+		steps       []step
+		wantStatus  []string
+		wantHistory int
+	}{
+		{
+			name:        "sale",
+			schedules:   []models.PriceSchedule{{Price: 8, StartsAt: base, EndsAt: at(2 * time.Hour)}},
+			steps:       []step{{at: -time.Minute, wantPrice: 10}, {at: 0, wantPrice: 8}, {at: time.Hour, wantPrice: 8}, {at: 2 * time.Hour, wantPrice: 10}},
+			wantStatus:  []string{models.ScheduleCompleted},
+			wantHistory: 3,
+		},
+		{
+			name:        "missed sale",
+			schedules:   []models.PriceSchedule{{Price: 8, StartsAt: base.Add(-3 * time.Hour), EndsAt: at(-time.Hour)}},
+			steps:       []step{{at: 0, wantPrice: 10}},
+			wantStatus:  []string{models.ScheduleCompleted},
+			wantHistory: 1,
+		},
+		{
+			name: "permanent change during a sale",
+			schedules: []models.PriceSchedule{
+				{Price: 8, StartsAt: base, EndsAt: at(2 * time.Hour)},
+				{Price: 12, StartsAt: base.Add(time.Hour)},
+			},
+			steps:       []step{{at: 0, wantPrice: 8}, {at: time.Hour, wantPrice: 8}, {at: 2 * time.Hour, wantPrice: 12}},
+			wantStatus:  []string{models.ScheduleCompleted, models.ScheduleCompleted},
+			wantHistory: 3,
+		},
+		{
+			name:      "manual change during a sale is kept",
+			schedules: []models.PriceSchedule{{Price: 8, StartsAt: base, EndsAt: at(2 * time.Hour)}},
+			steps: []step{
+				{at: 0, wantPrice: 8},
+				{at: 2 * time.Hour, wantPrice: 9, before: func(t *testing.T, f *schedulerFixture) {
+					book, _ := f.books.GetBookByID(models.DefaultTenantID, f.book.ID)
+					book.Price = 9
+					if err := f.books.UpdateBook(models.DefaultTenantID, book); err != nil {
+						t.Fatalf("update book: %v", err)
+					}
+				}},
+			},
+			wantStatus:  []string{models.ScheduleCompleted},
+			wantHistory: 3,
+		},
+		{
+			name:      "cancelling a running sale",
+			schedules: []models.PriceSchedule{{Price: 8, StartsAt: base, EndsAt: at(24 * time.Hour)}},
+			steps: []step{
+				{at: 0, wantPrice: 8},
+				{at: time.Minute, wantPrice: 10, before: func(t *testing.T, f *schedulerFixture) {
+					schedules, _ := f.pricing.ListSchedules(models.DefaultTenantID, f.book.ID)
+					if _, err := f.pricing.CancelSchedule(models.DefaultTenantID, f.book.ID, schedules[0].ID); err != nil {
+						t.Fatalf("cancel: %v", err)
+					}
+				}},
+			},
+			wantStatus:  []string{models.ScheduleCompleted},
+			wantHistory: 3,
+		},
+		{
+			name:      "retry after a partial failure",
+			schedules: []models.PriceSchedule{{Price: 8, StartsAt: base, EndsAt: at(2 * time.Hour)}},
+			steps: []step{
+				// The price changes but the sale is not marked running.
+				{at: 0, wantPrice: 8, before: func(t *testing.T, f *schedulerFixture) { f.prices.failStart = true }},
+				{at: time.Minute, wantPrice: 8},
+				// The price from before the sale is still restored.
+				{at: 2 * time.Hour, wantPrice: 10},
+			},
+			wantStatus:  []string{models.ScheduleCompleted},
+			wantHistory: 3,
+		},
+		{
+			name:      "deleted book",
+			schedules: []models.PriceSchedule{{Price: 8, StartsAt: base, EndsAt: at(2 * time.Hour)}, {Price: 12, StartsAt: base}},
+			steps: []step{
+				{at: 0, wantPrice: 0, before: func(t *testing.T, f *schedulerFixture) {
+					if err := f.books.DeleteBook(models.DefaultTenantID, f.book.ID); err != nil {
+						t.Fatalf("delete book: %v", err)
+					}
+				}},
+			},
+			wantStatus: []string{models.ScheduleCancelled, models.ScheduleCancelled},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSchedulerFixture(t)
+			for _, schedule := range tt.schedules {
+				schedule.TenantID = models.DefaultTenantID
+				schedule.BookID = f.book.ID
+				schedule.Status = models.ScheduleScheduled
+				if err := f.prices.CreateSchedule(&schedule); err != nil {
+					t.Fatalf("create schedule: %v", err)
+				}
+			}
+
+			for i, step := range tt.steps {
+				if step.before != nil {
+					step.before(t, f)
+				}
+				f.scheduler.tick(context.Background(), base.Add(step.at))
+				if got := f.price(t); got != step.wantPrice {
+					t.Fatalf("step %d: price = %v, want %v", i, got, step.wantPrice)
+				}
+			}
+
+			schedules, err := f.prices.FindSchedules(models.DefaultTenantID, f.book.ID)
+			if err != nil {
+				t.Fatalf("load schedules: %v", err)
+			}
+			for i, schedule := range schedules {
+				if i < len(tt.wantStatus) && schedule.Status != tt.wantStatus[i] {
+					t.Errorf("schedule %d status = %s, want %s", i, schedule.Status, tt.wantStatus[i])
+				}
+			}
+			if len(schedules) != len(tt.wantStatus) {
+				t.Errorf("got %d schedules, want %d", len(schedules), len(tt.wantStatus))
+			}
+			if tt.wantHistory > 0 {
+				history, err := f.pricing.GetPriceHistory(models.DefaultTenantID, f.book.ID)
+				if err != nil {
+					t.Fatalf("load history: %v", err)
+				}
+				if len(history) != tt.wantHistory {
+					t.Errorf("got %d price points, want %d", len(history), tt.wantHistory)
+				}
+			}
+		})
+	}
+}
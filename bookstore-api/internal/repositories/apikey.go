@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"time"
+
+	"bookstore-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyRepository defines the interface for API key data access.
+type APIKeyRepository interface {
+	Create(key *models.APIKey) error
+	FindAll() ([]models.APIKey, error)
+	FindByID(id uint) (*models.APIKey, error)
+	FindByHash(hash string) (*models.APIKey, error)
+	Revoke(id uint, at time.Time) error
+	TouchLastUsed(id uint, at time.Time) error
+}
+
+// gormAPIKeyRepository implements APIKeyRepository using GORM.
+type gormAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewGormAPIKeyRepository creates a new APIKeyRepository using GORM.
+func NewGormAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &gormAPIKeyRepository{db: db}
+}
+
+// Create inserts a new API key.
+func (r *gormAPIKeyRepository) Create(key *models.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+// FindAll retrieves all API keys, including revoked ones.
+func (r *gormAPIKeyRepository) FindAll() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.Order("id").Find(&keys).Error
+	return keys, err
+}
+
+// FindByID retrieves an API key by its ID.
+func (r *gormAPIKeyRepository) FindByID(id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.First(&key, id).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// FindByHash retrieves an API key by the hash of its value.
+func (r *gormAPIKeyRepository) FindByHash(hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.Where("hash = ?", hash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Revoke marks an API key as revoked.
+func (r *gormAPIKeyRepository) Revoke(id uint, at time.Time) error {
+	return r.db.Model(&models.APIKey{}).Where("id = ?", id).Update("revoked_at", at).Error
+}
+
+// TouchLastUsed records when an API key was last used.
+func (r *gormAPIKeyRepository) TouchLastUsed(id uint, at time.Time) error {
+	return r.db.Model(&models.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", at).Error
+}
@@ -1,58 +1,386 @@
 package repositories
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"bookstore-api/internal/models"
 
 	"gorm.io/gorm"
 )
 
-// BookRepository defines the interface for book data access.
+// ChangeType identifies the kind of mutation applied to a book.
+type ChangeType string
+
+// Book change types passed to change hooks.
+const (
+	ChangeCreated ChangeType = "created"
+	ChangeUpdated ChangeType = "updated"
+	ChangeDeleted ChangeType = "deleted"
+)
+
+// BookChange describes a book mutation. Before is nil for creations and
+// After is nil for deletions.
+type BookChange struct {
+	Type   ChangeType
+	Before *models.Book
+	After  *models.Book
+}
+
+// authorFacetLimit bounds the authors counted by FindFacets.
+const authorFacetLimit = 25
+
+// categoryTreeSQL selects a category's ID and the IDs of all its
+// subcategories.
+const categoryTreeSQL = `WITH RECURSIVE tree(id) AS (
+	SELECT id FROM categories WHERE id = ?
+	UNION
+	SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id
+) SELECT id FROM tree`
+
+// ChangeHook is called inside the transaction of every book mutation.
+// Returning an error rolls the mutation back.
+type ChangeHook func(tx *gorm.DB, change BookChange) error
+
+// BookRepository defines the interface for book data access. Every method
+// is scoped to one tenant's catalog: books of other tenants are never
+// returned, modified or deleted.
 type BookRepository interface {
-	Create(book *models.Book) error
-	FindAll() ([]models.Book, error)
-	FindByID(id uint) (*models.Book, error)
-	Update(book *models.Book) error
-	Delete(id uint) error
+	Create(tenantID uint, book *models.Book) error
+	FindAll(tenantID uint) ([]models.Book, error)
+	FindPage(tenantID uint, filter models.BookFilter) ([]models.Book, int64, error)
+	FindByID(tenantID, id uint) (*models.Book, error)
+	Update(tenantID uint, book *models.Book) error
+	Delete(tenantID, id uint) error
+	// FindFacets counts the books matching the filter by author, price
+	// range and category. Category counts only include books directly in
+	// each category.
+	FindFacets(tenantID uint, filter models.BookFilter) (*models.BookFacets, error)
+	// FindTags returns every tag in the tenant's catalog with its number
+	// of books.
+	FindTags(tenantID uint) ([]models.TagCount, error)
 }
 
 // gormBookRepository implements BookRepository using GORM.
 type gormBookRepository struct {
-	db *gorm.DB
+	db    *gorm.DB
+	hooks []ChangeHook
+}
+
+// NewGormBookRepository creates a new BookRepository using GORM. The hooks
+// run in the same transaction as each create, update and delete.
+func NewGormBookRepository(db *gorm.DB, hooks ...ChangeHook) BookRepository {
+	return &gormBookRepository{db: db, hooks: hooks}
 }
 
-// NewGormBookRepository creates a new BookRepository using GORM.
-func NewGormBookRepository(db *gorm.DB) BookRepository {
-	return &gormBookRepository{db: db}
+// Create inserts a new book and its tags into the tenant's catalog.
+func (r *gormBookRepository) Create(tenantID uint, book *models.Book) error {
+	book.TenantID = tenantID
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(book).Error; err != nil {
+			return err
+		}
+		if err := saveTags(tx, book); err != nil {
+			return err
+		}
+		return r.runHooks(tx, BookChange{Type: ChangeCreated, After: book})
+	})
 }
 
-// Create inserts a new book into the database.
-func (r *gormBookRepository) Create(book *models.Book) error {
-	return r.db.Create(book).Error
+// FindAll retrieves all books in the tenant's catalog.
+func (r *gormBookRepository) FindAll(tenantID uint) ([]models.Book, error) {
+	var books []models.Book
+	if err := r.db.Scopes(forTenant(tenantID)).Find(&books).Error; err != nil {
+		return nil, err
+	}
+	return books, loadTags(r.db, books)
 }
 
-// FindAll retrieves all books from the database.
-func (r *gormBookRepository) FindAll() ([]models.Book, error) {
+// FindPage retrieves one page of the tenant's books matching the filter,
+// along with the total number of matches.
+func (r *gormBookRepository) FindPage(tenantID uint, filter models.BookFilter) ([]models.Book, int64, error) {
+	var total int64
+	if err := applyBookFilter(r.db.Model(&models.Book{}).Scopes(forTenant(tenantID)), filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
 	var books []models.Book
-	err := r.db.Find(&books).Error
-	return books, err
+	query := applyBookFilter(r.db.Scopes(forTenant(tenantID)), filter).Order("id")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+	if err := query.Find(&books).Error; err != nil {
+		return nil, 0, err
+	}
+	return books, total, loadTags(r.db, books)
+}
+
+// FindByID retrieves a book in the tenant's catalog by its ID.
+func (r *gormBookRepository) FindByID(tenantID, id uint) (*models.Book, error) {
+	book, err := findBook(r.db, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if book == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return book, nil
 }
 
-// FindByID retrieves a book by its ID.
-func (r *gormBookRepository) FindByID(id uint) (*models.Book, error) {
+// Update modifies an existing book in the tenant's catalog, replacing its
+// tags. Saving a book whose ID does not exist yet inserts it and is
+// reported to hooks as a creation; an ID taken by another tenant's book is
+// not found.
+func (r *gormBookRepository) Update(tenantID uint, book *models.Book) error {
+	book.TenantID = tenantID
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		before, err := findBook(tx, tenantID, book.ID)
+		if err != nil {
+			return err
+		}
+
+		if before == nil {
+			var taken int64
+			if err := tx.Model(&models.Book{}).Where("id = ?", book.ID).Count(&taken).Error; err != nil {
+				return err
+			}
+			if taken > 0 {
+				return gorm.ErrRecordNotFound
+			}
+			if err := tx.Create(book).Error; err != nil {
+				return err
+			}
+			if err := saveTags(tx, book); err != nil {
+				return err
+			}
+			return r.runHooks(tx, BookChange{Type: ChangeCreated, After: book})
+		}
+
+		if err := tx.Save(book).Error; err != nil {
+			return err
+		}
+		if err := saveTags(tx, book); err != nil {
+			return err
+		}
+		return r.runHooks(tx, BookChange{Type: ChangeUpdated, Before: before, After: book})
+	})
+}
+
+// Delete removes a book from the tenant's catalog by its ID.
+func (r *gormBookRepository) Delete(tenantID, id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		before, err := findBook(tx, tenantID, id)
+		if err != nil || before == nil {
+			return err
+		}
+		if err := tx.Scopes(forTenant(tenantID)).Delete(&models.Book{}, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("book_id = ?", id).Delete(&models.BookTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("book_id = ?", id).Delete(&models.Review{}).Error; err != nil {
+			return err
+		}
+		if err := removeFromLists(tx, id); err != nil {
+			return err
+		}
+		return r.runHooks(tx, BookChange{Type: ChangeDeleted, Before: before})
+	})
+}
+
+// FindFacets counts the tenant's books matching the filter by author,
+// price range and category.
+func (r *gormBookRepository) FindFacets(tenantID uint, filter models.BookFilter) (*models.BookFacets, error) {
+	filter.Limit, filter.Offset = 0, 0
+	matching := func() *gorm.DB {
+		return applyBookFilter(r.db.Model(&models.Book{}).Scopes(forTenant(tenantID)), filter)
+	}
+
+	facets := &models.BookFacets{}
+	err := matching().Select("category_id AS id, COUNT(*) AS count").
+		Where("category_id IS NOT NULL").Group("category_id").Order("category_id").
+		Scan(&facets.Categories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = matching().Select("author AS value, COUNT(*) AS count").
+		Group("author").Order("count DESC, author").Limit(authorFacetLimit).
+		Scan(&facets.Authors).Error
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, args := priceBucketExpr()
+	var buckets []struct {
+		Bucket int
+		Count  int64
+	}
+	err = matching().Select(bucket+" AS bucket, COUNT(*) AS count", args...).
+		Group("bucket").Scan(&buckets).Error
+	if err != nil {
+		return nil, err
+	}
+	facets.PriceRanges = make([]models.PriceRange, len(models.PriceBucketBounds)+1)
+	for i := range facets.PriceRanges {
+		if i > 0 {
+			facets.PriceRanges[i].Min = models.PriceBucketBounds[i-1]
+		}
+		if i < len(models.PriceBucketBounds) {
+			facets.PriceRanges[i].Max = &models.PriceBucketBounds[i]
+		}
+	}
+	for _, b := range buckets {
+		facets.PriceRanges[b.Bucket].Count = b.Count
+	}
+
+	if facets.Categories == nil {
+		facets.Categories = []models.CategoryFacet{}
+	}
+	if facets.Authors == nil {
+		facets.Authors = []models.FacetCount{}
+	}
+	return facets, nil
+}
+
+// FindTags returns the tenant's tags in alphabetical order with their
+// number of books.
+func (r *gormBookRepository) FindTags(tenantID uint) ([]models.TagCount, error) {
+	tags := []models.TagCount{}
+	err := r.db.Model(&models.BookTag{}).Scopes(forTenant(tenantID)).
+		Select("tag, COUNT(*) AS count").Group("tag").Order("tag").
+		Scan(&tags).Error
+	return tags, err
+}
+
+// runHooks calls every change hook, stopping at the first error.
+func (r *gormBookRepository) runHooks(tx *gorm.DB, change BookChange) error {
+	for _, hook := range r.hooks {
+		if err := hook(tx, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forTenant restricts a query to one tenant's rows.
+func forTenant(tenantID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("tenant_id = ?", tenantID)
+	}
+}
+
+// findBook loads a tenant's book and its tags by ID, returning nil when it
+// does not exist.
+func findBook(db *gorm.DB, tenantID, id uint) (*models.Book, error) {
 	var book models.Book
-	err := r.db.First(&book, id).Error
+	err := db.Scopes(forTenant(tenantID)).First(&book, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &book, nil
+	books := []models.Book{book}
+	if err := loadTags(db, books); err != nil {
+		return nil, err
+	}
+	return &books[0], nil
 }
 
-// Update modifies an existing book in the database.
-func (r *gormBookRepository) Update(book *models.Book) error {
-	return r.db.Save(book).Error
+// loadTags fills in the tags of each book, in alphabetical order.
+func loadTags(db *gorm.DB, books []models.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(books))
+	byID := make(map[uint]*models.Book, len(books))
+	for i := range books {
+		ids[i] = books[i].ID
+		byID[books[i].ID] = &books[i]
+		books[i].Tags = []string{}
+	}
+
+	var tags []models.BookTag
+	if err := db.Where("book_id IN ?", ids).Order("tag").Find(&tags).Error; err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if book := byID[tag.BookID]; book != nil {
+			book.Tags = append(book.Tags, tag.Tag)
+		}
+	}
+	return nil
+}
+
+// saveTags replaces the stored tags of a book with book.Tags.
+func saveTags(tx *gorm.DB, book *models.Book) error {
+	if err := tx.Where("book_id = ?", book.ID).Delete(&models.BookTag{}).Error; err != nil {
+		return err
+	}
+	if book.Tags == nil {
+		book.Tags = []string{}
+	}
+	if len(book.Tags) == 0 {
+		return nil
+	}
+	rows := make([]models.BookTag, len(book.Tags))
+	for i, tag := range book.Tags {
+		rows[i] = models.BookTag{TenantID: book.TenantID, BookID: book.ID, Tag: tag}
+	}
+	return tx.Create(&rows).Error
+}
+
+// priceBucketExpr returns a SQL expression numbering the price range of
+// models.PriceBucketBounds that each book falls in, and its arguments.
+func priceBucketExpr() (string, []interface{}) {
+	var expr strings.Builder
+	args := make([]interface{}, 0, len(models.PriceBucketBounds))
+	expr.WriteString("CASE")
+	for i, bound := range models.PriceBucketBounds {
+		fmt.Fprintf(&expr, " WHEN price < ? THEN %d", i)
+		args = append(args, bound)
+	}
+	fmt.Fprintf(&expr, " ELSE %d END", len(models.PriceBucketBounds))
+	return expr.String(), args
 }
 
-// Delete removes a book from the database by its ID.
-func (r *gormBookRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Book{}, id).Error
+// likeEscaper escapes the LIKE wildcards and the escape character itself.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// containsPattern returns a LIKE pattern, used with ESCAPE '\', matching
+// lowercased values that contain s literally.
+func containsPattern(s string) string {
+	return "%" + likeEscaper.Replace(strings.ToLower(s)) + "%"
+}
+
+// applyBookFilter adds the filter's WHERE clauses to the query.
+func applyBookFilter(db *gorm.DB, filter models.BookFilter) *gorm.DB {
+	if filter.Title != "" {
+		db = db.Where(`LOWER(title) LIKE ? ESCAPE '\'`, containsPattern(filter.Title))
+	}
+	if filter.Author != "" {
+		db = db.Where(`LOWER(author) LIKE ? ESCAPE '\'`, containsPattern(filter.Author))
+	}
+	if filter.ISBN != "" {
+		db = db.Where("isbn = ?", filter.ISBN)
+	}
+	if filter.MinPrice != nil {
+		db = db.Where("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		db = db.Where("price <= ?", *filter.MaxPrice)
+	}
+	if filter.CategoryID != nil {
+		db = db.Where("category_id IN ("+categoryTreeSQL+")", *filter.CategoryID)
+	}
+	if filter.Tag != "" {
+		db = db.Where("id IN (SELECT book_id FROM book_tags WHERE tag = ?)", filter.Tag)
+	}
+	return db
 }
@@ -0,0 +1,219 @@
+package repositories
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// newTestDB returns a migrated in-memory database with two tenants besides
+// the default one.
+func newTestDB(t *testing.T) (*gorm.DB, uint, uint) {
+	t.Helper()
+
+	db := testutil.NewDB(t)
+	tenants := NewGormTenantRepository(db)
+	a := models.Tenant{Slug: "store-a", Name: "Store A"}
+	b := models.Tenant{Slug: "store-b", Name: "Store B"}
+	for _, tenant := range []*models.Tenant{&a, &b} {
+		if err := tenants.Create(tenant); err != nil {
+			t.Fatalf("create tenant: %v", err)
+		}
+	}
+	return db, a.ID, b.ID
+}
+
+func TestBookRepositoryIsolatesTenants(t *testing.T) {
+	db, tenantA, tenantB := newTestDB(t)
+	repo := NewGormBookRepository(db)
+
+	bookA := models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441013593", Price: 9.99}
+	if err := repo.Create(tenantA, &bookA); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	t.Run("FindAll", func(t *testing.T) {
+		books, err := repo.FindAll(tenantB)
+		if err != nil {
+			t.Fatalf("FindAll: %v", err)
+		}
+		if len(books) != 0 {
+			t.Errorf("tenant B sees %d books of tenant A", len(books))
+		}
+	})
+
+	t.Run("FindPage", func(t *testing.T) {
+		books, total, err := repo.FindPage(tenantB, models.BookFilter{ISBN: bookA.ISBN})
+		if err != nil {
+			t.Fatalf("FindPage: %v", err)
+		}
+		if len(books) != 0 || total != 0 {
+			t.Errorf("tenant B found %d books (total %d) of tenant A", len(books), total)
+		}
+	})
+
+	t.Run("FindByID", func(t *testing.T) {
+		if _, err := repo.FindByID(tenantB, bookA.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("FindByID from tenant B: got %v, want record not found", err)
+		}
+		if _, err := repo.FindByID(tenantA, bookA.ID); err != nil {
+			t.Errorf("FindByID from tenant A: %v", err)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		hijack := models.Book{ID: bookA.ID, Title: "Hijacked", Author: "B", ISBN: "0000000000", Price: 1}
+		if err := repo.Update(tenantB, &hijack); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Update from tenant B: got %v, want record not found", err)
+		}
+		assertBookUnchanged(t, repo, tenantA, bookA)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := repo.Delete(tenantB, bookA.ID); err != nil {
+			t.Fatalf("Delete from tenant B: %v", err)
+		}
+		assertBookUnchanged(t, repo, tenantA, bookA)
+	})
+
+	t.Run("DefaultTenant", func(t *testing.T) {
+		books, err := repo.FindAll(models.DefaultTenantID)
+		if err != nil {
+			t.Fatalf("FindAll: %v", err)
+		}
+		if len(books) != 0 {
+			t.Errorf("default tenant sees %d books of tenant A", len(books))
+		}
+	})
+}
+
+func TestBookRepositoryISBNUniquePerTenant(t *testing.T) {
+	db, tenantA, tenantB := newTestDB(t)
+	repo := NewGormBookRepository(db)
+
+	newBook := func() *models.Book {
+		return &models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441013593", Price: 9.99}
+	}
+	if err := repo.Create(tenantA, newBook()); err != nil {
+		t.Fatalf("Create in tenant A: %v", err)
+	}
+	if err := repo.Create(tenantB, newBook()); err != nil {
+		t.Errorf("Create same ISBN in tenant B: %v", err)
+	}
+	if err := repo.Create(tenantA, newBook()); !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Errorf("Create duplicate ISBN in tenant A: got %v, want duplicated key", err)
+	}
+}
+
+func TestBookRepositoryUpdateCreatesInOwnTenant(t *testing.T) {
+	db, tenantA, tenantB := newTestDB(t)
+	repo := NewGormBookRepository(db)
+
+	book := models.Book{ID: 42, Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587", Price: 5}
+	if err := repo.Update(tenantA, &book); err != nil {
+		t.Fatalf("Update with unused ID: %v", err)
+	}
+	if book.TenantID != tenantA {
+		t.Errorf("TenantID = %d, want %d", book.TenantID, tenantA)
+	}
+	if _, err := repo.FindByID(tenantB, 42); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("FindByID from tenant B: got %v, want record not found", err)
+	}
+}
+
+func TestBookRepositoryFiltersByCategoryTreeAndTag(t *testing.T) {
+	db, tenantA, tenantB := newTestDB(t)
+	repo := NewGormBookRepository(db)
+	categories := NewGormCategoryRepository(db)
+
+	fiction := models.Category{TenantID: tenantA, Name: "Fiction", Slug: "fiction"}
+	if err := categories.Create(&fiction); err != nil {
+		t.Fatalf("Create category: %v", err)
+	}
+	scifi := models.Category{TenantID: tenantA, Name: "Science Fiction", Slug: "sci-fi", ParentID: &fiction.ID}
+	if err := categories.Create(&scifi); err != nil {
+		t.Fatalf("Create category: %v", err)
+	}
+
+	books := []models.Book{
+		{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441013593", Price: 9.99, CategoryID: &scifi.ID, Tags: []string{"classic"}},
+		{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587", Price: 30, CategoryID: &fiction.ID, Tags: []string{"classic", "romance"}},
+		{Title: "The Go Programming Language", Author: "Alan Donovan", ISBN: "9780134190440", Price: 120},
+	}
+	for i := range books {
+		if err := repo.Create(tenantA, &books[i]); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	other := models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441013593", Price: 9.99, Tags: []string{"classic"}}
+	if err := repo.Create(tenantB, &other); err != nil {
+		t.Fatalf("Create in tenant B: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter models.BookFilter
+		want   int64
+	}{
+		{"parent category includes subcategories", models.BookFilter{CategoryID: &fiction.ID}, 2},
+		{"leaf category", models.BookFilter{CategoryID: &scifi.ID}, 1},
+		{"tag", models.BookFilter{Tag: "classic"}, 2},
+		{"tag and category", models.BookFilter{Tag: "romance", CategoryID: &scifi.ID}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, total, err := repo.FindPage(tenantA, tt.filter)
+			if err != nil {
+				t.Fatalf("FindPage: %v", err)
+			}
+			if total != tt.want {
+				t.Errorf("total = %d, want %d", total, tt.want)
+			}
+		})
+	}
+
+	t.Run("Facets", func(t *testing.T) {
+		facets, err := repo.FindFacets(tenantA, models.BookFilter{})
+		if err != nil {
+			t.Fatalf("FindFacets: %v", err)
+		}
+		if len(facets.Categories) != 2 || len(facets.Authors) != 3 {
+			t.Errorf("got %d category and %d author facets, want 2 and 3", len(facets.Categories), len(facets.Authors))
+		}
+		counts := make([]int64, len(facets.PriceRanges))
+		for i, r := range facets.PriceRanges {
+			counts[i] = r.Count
+		}
+		if want := []int64{1, 0, 1, 0, 1}; !slices.Equal(counts, want) {
+			t.Errorf("price range counts = %v, want %v", counts, want)
+		}
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		tags, err := repo.FindTags(tenantA)
+		if err != nil {
+			t.Fatalf("FindTags: %v", err)
+		}
+		want := []models.TagCount{{Tag: "classic", Count: 2}, {Tag: "romance", Count: 1}}
+		if !slices.Equal(tags, want) {
+			t.Errorf("FindTags = %v, want %v", tags, want)
+		}
+	})
+}
+
+// assertBookUnchanged fails the test if the stored book differs from want.
+func assertBookUnchanged(t *testing.T, repo BookRepository, tenantID uint, want models.Book) {
+	t.Helper()
+	got, err := repo.FindByID(tenantID, want.ID)
+	if err != nil {
+		t.Fatalf("FindByID from owning tenant: %v", err)
+	}
+	if got.Title != want.Title || got.ISBN != want.ISBN || got.Price != want.Price || got.TenantID != tenantID {
+		t.Errorf("book changed by another tenant: got %+v, want %+v", *got, want)
+	}
+}
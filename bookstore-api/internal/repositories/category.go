@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"bookstore-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// CategoryRepository defines the interface for category data access. Every
+// method is scoped to one tenant's categories.
+type CategoryRepository interface {
+	Create(category *models.Category) error
+	FindAll(tenantID uint) ([]models.Category, error)
+	FindByID(tenantID, id uint) (*models.Category, error)
+	Update(category *models.Category) error
+	Delete(tenantID, id uint) error
+	// CountUsage returns the number of subcategories and books directly
+	// in a category.
+	CountUsage(tenantID, id uint) (children, books int64, err error)
+}
+
+// gormCategoryRepository implements CategoryRepository using GORM.
+type gormCategoryRepository struct {
+	db *gorm.DB
+}
+
+// NewGormCategoryRepository creates a new CategoryRepository using GORM.
+func NewGormCategoryRepository(db *gorm.DB) CategoryRepository {
+	return &gormCategoryRepository{db: db}
+}
+
+// Create inserts a new category.
+func (r *gormCategoryRepository) Create(category *models.Category) error {
+	return r.db.Create(category).Error
+}
+
+// FindAll retrieves all of a tenant's categories ordered by name.
+func (r *gormCategoryRepository) FindAll(tenantID uint) ([]models.Category, error) {
+	var categories []models.Category
+	err := r.db.Scopes(forTenant(tenantID)).Order("name, id").Find(&categories).Error
+	return categories, err
+}
+
+// FindByID retrieves a tenant's category by its ID.
+func (r *gormCategoryRepository) FindByID(tenantID, id uint) (*models.Category, error) {
+	var category models.Category
+	err := r.db.Scopes(forTenant(tenantID)).First(&category, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// Update saves an existing category.
+func (r *gormCategoryRepository) Update(category *models.Category) error {
+	return r.db.Save(category).Error
+}
+
+// Delete removes a tenant's category by its ID.
+func (r *gormCategoryRepository) Delete(tenantID, id uint) error {
+	return r.db.Scopes(forTenant(tenantID)).Delete(&models.Category{}, id).Error
+}
+
+// CountUsage returns the number of subcategories and books directly in a
+// tenant's category.
+func (r *gormCategoryRepository) CountUsage(tenantID, id uint) (int64, int64, error) {
+	var children, books int64
+	if err := r.db.Model(&models.Category{}).Scopes(forTenant(tenantID)).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+		return 0, 0, err
+	}
+	if err := r.db.Model(&models.Book{}).Scopes(forTenant(tenantID)).Where("category_id = ?", id).Count(&books).Error; err != nil {
+		return 0, 0, err
+	}
+	return children, books, nil
+}
@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"time"
+
+	"bookstore-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// IdempotencyRepository defines the interface for idempotency record data
+// access.
+type IdempotencyRepository interface {
+	Find(scope, key string) (*models.IdempotencyRecord, error)
+	Create(record *models.IdempotencyRecord) error
+	Update(record *models.IdempotencyRecord) error
+	Delete(id uint) error
+	// DeleteExpired removes records that expired before now.
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+// gormIdempotencyRepository implements IdempotencyRepository using GORM.
+type gormIdempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewGormIdempotencyRepository creates a new IdempotencyRepository using
+// GORM.
+func NewGormIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &gormIdempotencyRepository{db: db}
+}
+
+// Find retrieves the record for a key within a scope. Most keys are new,
+// so a miss is queried with Find to keep it out of the error log.
+func (r *gormIdempotencyRepository) Find(scope, key string) (*models.IdempotencyRecord, error) {
+	var records []models.IdempotencyRecord
+	if err := r.db.Where("scope = ? AND idempotency_key = ?", scope, key).Limit(1).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &records[0], nil
+}
+
+// Create inserts a new record. Inserting a key that already exists in the
+// scope fails with gorm.ErrDuplicatedKey.
+func (r *gormIdempotencyRepository) Create(record *models.IdempotencyRecord) error {
+	return r.db.Create(record).Error
+}
+
+// Update saves a record's stored response.
+func (r *gormIdempotencyRepository) Update(record *models.IdempotencyRecord) error {
+	return r.db.Save(record).Error
+}
+
+// Delete removes a record by its ID.
+func (r *gormIdempotencyRepository) Delete(id uint) error {
+	return r.db.Delete(&models.IdempotencyRecord{}, id).Error
+}
+
+// DeleteExpired removes records that expired before now.
+func (r *gormIdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&models.IdempotencyRecord{})
+	return result.RowsAffected, result.Error
+}
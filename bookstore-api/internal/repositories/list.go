@@ -0,0 +1,232 @@
+package repositories
+
+import (
+	"time"
+
+	"bookstore-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ListRepository defines the interface for reading list data access. Lists
+// are looked up within one tenant and, except through a share token, for
+// one owner. Lists are returned with their entries.
+type ListRepository interface {
+	Create(list *models.ReadingList) error
+	FindAll(tenantID uint, owner string) ([]models.ReadingList, error)
+	FindByID(tenantID uint, owner string, id uint) (*models.ReadingList, error)
+	FindByShareToken(tenantID uint, token string) (*models.ReadingList, error)
+	Update(list *models.ReadingList) error
+	Delete(list *models.ReadingList) error
+
+	// AddEntry inserts a book at a 1-based position, shifting the entries
+	// at and after it down. Positions past the end append the book.
+	AddEntry(listID, bookID uint, position int) error
+	// RemoveEntry removes a book and closes the gap it leaves.
+	RemoveEntry(listID, bookID uint) error
+	// ReorderEntries renumbers a list's entries in the order of bookIDs,
+	// which must hold each of the list's books once.
+	ReorderEntries(listID uint, bookIDs []uint) error
+}
+
+// gormListRepository implements ListRepository using GORM.
+type gormListRepository struct {
+	db *gorm.DB
+}
+
+// NewGormListRepository creates a new ListRepository using GORM.
+func NewGormListRepository(db *gorm.DB) ListRepository {
+	return &gormListRepository{db: db}
+}
+
+// Create inserts a new reading list.
+func (r *gormListRepository) Create(list *models.ReadingList) error {
+	if err := r.db.Create(list).Error; err != nil {
+		return err
+	}
+	list.Entries = []models.ListEntry{}
+	return nil
+}
+
+// FindAll retrieves an owner's reading lists in the order they were
+// created.
+func (r *gormListRepository) FindAll(tenantID uint, owner string) ([]models.ReadingList, error) {
+	var lists []models.ReadingList
+	err := r.db.Scopes(forTenant(tenantID)).Where("owner = ?", owner).Order("id").Find(&lists).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadEntries(lists); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+// FindByID retrieves one of an owner's reading lists by its ID.
+func (r *gormListRepository) FindByID(tenantID uint, owner string, id uint) (*models.ReadingList, error) {
+	return r.findOne(r.db.Scopes(forTenant(tenantID)).Where("owner = ? AND id = ?", owner, id))
+}
+
+// FindByShareToken retrieves the tenant's reading list shared with token.
+func (r *gormListRepository) FindByShareToken(tenantID uint, token string) (*models.ReadingList, error) {
+	return r.findOne(r.db.Scopes(forTenant(tenantID)).Where("share_token = ?", token))
+}
+
+// Update saves a reading list's name, kind and share token.
+func (r *gormListRepository) Update(list *models.ReadingList) error {
+	return r.db.Model(list).Select("name", "kind", "share_token").Updates(list).Error
+}
+
+// Delete removes a reading list and its entries.
+func (r *gormListRepository) Delete(list *models.ReadingList) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("list_id = ?", list.ID).Delete(&models.ListEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(list).Error
+	})
+}
+
+// AddEntry inserts a book into a list at position.
+func (r *gormListRepository) AddEntry(listID, bookID uint, position int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		bookIDs, err := entryBookIDs(tx, listID)
+		if err != nil {
+			return err
+		}
+		entry := models.ListEntry{ListID: listID, BookID: bookID, Position: len(bookIDs) + 1, AddedAt: time.Now().UTC()}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		if position < 1 || position > len(bookIDs) {
+			return nil
+		}
+		ordered := append(append(append([]uint(nil), bookIDs[:position-1]...), bookID), bookIDs[position-1:]...)
+		return renumberEntries(tx, listID, ordered)
+	})
+}
+
+// RemoveEntry removes a book from a list.
+func (r *gormListRepository) RemoveEntry(listID, bookID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("list_id = ? AND book_id = ?", listID, bookID).Delete(&models.ListEntry{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		bookIDs, err := entryBookIDs(tx, listID)
+		if err != nil {
+			return err
+		}
+		return renumberEntries(tx, listID, bookIDs)
+	})
+}
+
+// ReorderEntries renumbers a list's entries in the order of bookIDs.
+func (r *gormListRepository) ReorderEntries(listID uint, bookIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return renumberEntries(tx, listID, bookIDs)
+	})
+}
+
+// findOne retrieves the list matched by query with its entries.
+func (r *gormListRepository) findOne(query *gorm.DB) (*models.ReadingList, error) {
+	var list models.ReadingList
+	if err := query.First(&list).Error; err != nil {
+		return nil, err
+	}
+	lists := []models.ReadingList{list}
+	if err := r.loadEntries(lists); err != nil {
+		return nil, err
+	}
+	return &lists[0], nil
+}
+
+// loadEntries fills in the entries of lists, in order, with their books.
+func (r *gormListRepository) loadEntries(lists []models.ReadingList) error {
+	if len(lists) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(lists))
+	byID := make(map[uint]*models.ReadingList, len(lists))
+	for i := range lists {
+		ids[i] = lists[i].ID
+		byID[lists[i].ID] = &lists[i]
+		lists[i].Entries = []models.ListEntry{}
+	}
+
+	var entries []models.ListEntry
+	if err := r.db.Where("list_id IN ?", ids).Order("list_id, position").Find(&entries).Error; err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	bookIDs := make([]uint, len(entries))
+	for i, entry := range entries {
+		bookIDs[i] = entry.BookID
+	}
+	var books []models.Book
+	if err := r.db.Where("id IN ?", bookIDs).Find(&books).Error; err != nil {
+		return err
+	}
+	if err := loadTags(r.db, books); err != nil {
+		return err
+	}
+	booksByID := make(map[uint]*models.Book, len(books))
+	for i := range books {
+		booksByID[books[i].ID] = &books[i]
+	}
+
+	for _, entry := range entries {
+		entry.Book = booksByID[entry.BookID]
+		list := byID[entry.ListID]
+		list.Entries = append(list.Entries, entry)
+	}
+	return nil
+}
+
+// entryBookIDs returns the books on a list in order.
+func entryBookIDs(tx *gorm.DB, listID uint) ([]uint, error) {
+	var bookIDs []uint
+	err := tx.Model(&models.ListEntry{}).Where("list_id = ?", listID).Order("position").Pluck("book_id", &bookIDs).Error
+	return bookIDs, err
+}
+
+// renumberEntries numbers a list's entries from 1 in the order of bookIDs.
+func renumberEntries(tx *gorm.DB, listID uint, bookIDs []uint) error {
+	for i, bookID := range bookIDs {
+		err := tx.Model(&models.ListEntry{}).
+			Where("list_id = ? AND book_id = ?", listID, bookID).
+			Update("position", i+1).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeFromLists removes a deleted book from every reading list and
+// renumbers the lists it was on.
+func removeFromLists(tx *gorm.DB, bookID uint) error {
+	var listIDs []uint
+	if err := tx.Model(&models.ListEntry{}).Where("book_id = ?", bookID).Pluck("list_id", &listIDs).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("book_id = ?", bookID).Delete(&models.ListEntry{}).Error; err != nil {
+		return err
+	}
+	for _, listID := range listIDs {
+		bookIDs, err := entryBookIDs(tx, listID)
+		if err != nil {
+			return err
+		}
+		if err := renumberEntries(tx, listID, bookIDs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
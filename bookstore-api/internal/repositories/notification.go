@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"time"
+
+	"bookstore-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// NotificationRepository defines the interface for notification data
+// access. Every method is scoped to one owner in one tenant.
+type NotificationRepository interface {
+	// FindAll returns up to limit of an owner's notifications, newest
+	// first, leaving out read ones when unreadOnly is set.
+	FindAll(tenantID uint, owner string, unreadOnly bool, limit int) ([]models.Notification, error)
+	FindByID(tenantID uint, owner string, id uint) (*models.Notification, error)
+	MarkRead(notification *models.Notification, at time.Time) error
+}
+
+// gormNotificationRepository implements NotificationRepository using GORM.
+type gormNotificationRepository struct {
+	db *gorm.DB
+}
+
+// NewGormNotificationRepository creates a new NotificationRepository using
+// GORM.
+func NewGormNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &gormNotificationRepository{db: db}
+}
+
+// FindAll retrieves an owner's notifications, newest first.
+func (r *gormNotificationRepository) FindAll(tenantID uint, owner string, unreadOnly bool, limit int) ([]models.Notification, error) {
+	query := r.db.Scopes(forTenant(tenantID)).Where("owner = ?", owner)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	var notifications []models.Notification
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+// FindByID retrieves one of an owner's notifications by its ID.
+func (r *gormNotificationRepository) FindByID(tenantID uint, owner string, id uint) (*models.Notification, error) {
+	var notification models.Notification
+	if err := r.db.Scopes(forTenant(tenantID)).Where("owner = ?", owner).First(&notification, id).Error; err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+// MarkRead records when a notification was read.
+func (r *gormNotificationRepository) MarkRead(notification *models.Notification, at time.Time) error {
+	notification.ReadAt = &at
+	return r.db.Model(notification).Update("read_at", at).Error
+}
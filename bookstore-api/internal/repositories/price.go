@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"bookstore-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// PriceRepository defines the interface for price history and schedule
+// data access. Lookups by book are scoped to a tenant; FindDueSchedules
+// works across tenants for the scheduler.
+type PriceRepository interface {
+	FindHistory(tenantID, bookID uint) ([]models.PricePoint, error)
+
+	CreateSchedule(schedule *models.PriceSchedule) error
+	FindSchedules(tenantID, bookID uint) ([]models.PriceSchedule, error)
+	FindScheduleByID(tenantID, bookID, id uint) (*models.PriceSchedule, error)
+	UpdateSchedule(schedule *models.PriceSchedule) error
+	// FindActiveSale returns the running sale of a book, or nil.
+	FindActiveSale(tenantID, bookID uint) (*models.PriceSchedule, error)
+	// HasOverlappingSale reports whether a scheduled or running sale of the
+	// book overlaps the period from start to end.
+	HasOverlappingSale(tenantID, bookID uint, start, end time.Time) (bool, error)
+	// FindDueSchedules returns up to limit schedules that should start or
+	// end by now, in the order they fall due.
+	FindDueSchedules(now time.Time, limit int) ([]models.PriceSchedule, error)
+}
+
+// gormPriceRepository implements PriceRepository using GORM.
+type gormPriceRepository struct {
+	db *gorm.DB
+}
+
+// NewGormPriceRepository creates a new PriceRepository using GORM.
+func NewGormPriceRepository(db *gorm.DB) PriceRepository {
+	return &gormPriceRepository{db: db}
+}
+
+// FindHistory retrieves a book's price points, newest first.
+func (r *gormPriceRepository) FindHistory(tenantID, bookID uint) ([]models.PricePoint, error) {
+	var points []models.PricePoint
+	err := r.db.Scopes(forTenant(tenantID)).
+		Where("book_id = ?", bookID).
+		Order("changed_at DESC, id DESC").
+		Find(&points).Error
+	return points, err
+}
+
+// CreateSchedule inserts a new price schedule.
+func (r *gormPriceRepository) CreateSchedule(schedule *models.PriceSchedule) error {
+	return r.db.Create(schedule).Error
+}
+
+// FindSchedules retrieves a book's price schedules by start time.
+func (r *gormPriceRepository) FindSchedules(tenantID, bookID uint) ([]models.PriceSchedule, error) {
+	var schedules []models.PriceSchedule
+	err := r.db.Scopes(forTenant(tenantID)).
+		Where("book_id = ?", bookID).
+		Order("starts_at, id").
+		Find(&schedules).Error
+	return schedules, err
+}
+
+// FindScheduleByID retrieves one of a book's price schedules.
+func (r *gormPriceRepository) FindScheduleByID(tenantID, bookID, id uint) (*models.PriceSchedule, error) {
+	var schedule models.PriceSchedule
+	if err := r.db.Scopes(forTenant(tenantID)).Where("book_id = ?", bookID).First(&schedule, id).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// UpdateSchedule saves a price schedule's state.
+func (r *gormPriceRepository) UpdateSchedule(schedule *models.PriceSchedule) error {
+	return r.db.Save(schedule).Error
+}
+
+// FindActiveSale returns the running sale of a book, or nil.
+func (r *gormPriceRepository) FindActiveSale(tenantID, bookID uint) (*models.PriceSchedule, error) {
+	var schedule models.PriceSchedule
+	err := r.db.Scopes(forTenant(tenantID)).
+		Where("book_id = ? AND status = ?", bookID, models.ScheduleActive).
+		First(&schedule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// HasOverlappingSale reports whether another pending or running sale
+// overlaps the period.
+func (r *gormPriceRepository) HasOverlappingSale(tenantID, bookID uint, start, end time.Time) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.PriceSchedule{}).Scopes(forTenant(tenantID)).
+		Where("book_id = ? AND status IN ?", bookID, []string{models.ScheduleScheduled, models.ScheduleActive}).
+		Where("ends_at IS NOT NULL AND starts_at < ? AND ends_at > ?", end, start).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FindDueSchedules returns schedules that should start or end by now.
+func (r *gormPriceRepository) FindDueSchedules(now time.Time, limit int) ([]models.PriceSchedule, error) {
+	var schedules []models.PriceSchedule
+	err := r.db.
+		Where("(status = ? AND starts_at <= ?) OR (status = ? AND ends_at <= ?)",
+			models.ScheduleScheduled, now, models.ScheduleActive, now).
+		Order("id").
+		Limit(limit).
+		Find(&schedules).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(schedules, func(i, j int) bool {
+		return dueAt(schedules[i]).Before(dueAt(schedules[j]))
+	})
+	return schedules, nil
+}
+
+// dueAt returns when a schedule's next transition is due: its end for a
+// running sale, otherwise its start.
+func dueAt(schedule models.PriceSchedule) time.Time {
+	if schedule.Status == models.ScheduleActive && schedule.EndsAt != nil {
+		return *schedule.EndsAt
+	}
+	return schedule.StartsAt
+}
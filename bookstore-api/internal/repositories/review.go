@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"bookstore-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ReviewRepository defines the interface for review data access. Every
+// method is scoped to one tenant.
+type ReviewRepository interface {
+	Create(review *models.Review) error
+	// FindByBooks returns the reviews of each of the given books, oldest
+	// first, keyed by book ID.
+	FindByBooks(tenantID uint, bookIDs []uint) (map[uint][]models.Review, error)
+}
+
+// gormReviewRepository implements ReviewRepository using GORM.
+type gormReviewRepository struct {
+	db *gorm.DB
+}
+
+// NewGormReviewRepository creates a new ReviewRepository using GORM.
+func NewGormReviewRepository(db *gorm.DB) ReviewRepository {
+	return &gormReviewRepository{db: db}
+}
+
+// Create inserts a new review.
+func (r *gormReviewRepository) Create(review *models.Review) error {
+	return r.db.Create(review).Error
+}
+
+// FindByBooks retrieves the reviews of several books in one query.
+func (r *gormReviewRepository) FindByBooks(tenantID uint, bookIDs []uint) (map[uint][]models.Review, error) {
+	found := make(map[uint][]models.Review, len(bookIDs))
+	if len(bookIDs) == 0 {
+		return found, nil
+	}
+	var reviews []models.Review
+	err := r.db.Scopes(forTenant(tenantID)).Where("book_id IN ?", bookIDs).Order("id").Find(&reviews).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, review := range reviews {
+		found[review.BookID] = append(found[review.BookID], review)
+	}
+	return found, nil
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"bookstore-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// TenantRepository defines the interface for tenant data access.
+type TenantRepository interface {
+	Create(tenant *models.Tenant) error
+	FindAll() ([]models.Tenant, error)
+	FindByID(id uint) (*models.Tenant, error)
+	FindByDomain(domain string) (*models.Tenant, error)
+	Update(tenant *models.Tenant) error
+	// Delete removes a tenant along with its API keys, webhook
+	// subscriptions, categories, reading lists and notifications. Callers
+	// must ensure its catalog is empty.
+	Delete(id uint) error
+	// CountBooks returns the number of books in a tenant's catalog.
+	CountBooks(id uint) (int64, error)
+}
+
+// gormTenantRepository implements TenantRepository using GORM.
+type gormTenantRepository struct {
+	db *gorm.DB
+}
+
+// NewGormTenantRepository creates a new TenantRepository using GORM.
+func NewGormTenantRepository(db *gorm.DB) TenantRepository {
+	return &gormTenantRepository{db: db}
+}
+
+// Create inserts a new tenant.
+func (r *gormTenantRepository) Create(tenant *models.Tenant) error {
+	return r.db.Create(tenant).Error
+}
+
+// FindAll retrieves all tenants.
+func (r *gormTenantRepository) FindAll() ([]models.Tenant, error) {
+	var tenants []models.Tenant
+	err := r.db.Order("id").Find(&tenants).Error
+	return tenants, err
+}
+
+// FindByID retrieves a tenant by its ID.
+func (r *gormTenantRepository) FindByID(id uint) (*models.Tenant, error) {
+	var tenant models.Tenant
+	if err := r.db.First(&tenant, id).Error; err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// FindByDomain retrieves the tenant serving a host name.
+func (r *gormTenantRepository) FindByDomain(domain string) (*models.Tenant, error) {
+	var tenant models.Tenant
+	if err := r.db.Where("domain = ?", domain).First(&tenant).Error; err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// Update saves a tenant's fields.
+func (r *gormTenantRepository) Update(tenant *models.Tenant) error {
+	return r.db.Save(tenant).Error
+}
+
+// Delete removes a tenant with its API keys, webhook subscriptions,
+// categories, reading lists and notifications.
+func (r *gormTenantRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		subscriptions := tx.Model(&models.WebhookSubscription{}).Select("id").Where("tenant_id = ?", id)
+		if err := tx.Where("subscription_id IN (?)", subscriptions).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tenant_id = ?", id).Delete(&models.WebhookSubscription{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tenant_id = ?", id).Delete(&models.APIKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tenant_id = ?", id).Delete(&models.Category{}).Error; err != nil {
+			return err
+		}
+		lists := tx.Model(&models.ReadingList{}).Select("id").Where("tenant_id = ?", id)
+		if err := tx.Where("list_id IN (?)", lists).Delete(&models.ListEntry{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tenant_id = ?", id).Delete(&models.ReadingList{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tenant_id = ?", id).Delete(&models.Notification{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Tenant{}, id).Error
+	})
+}
+
+// CountBooks returns the number of books in a tenant's catalog.
+func (r *gormTenantRepository) CountBooks(id uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Book{}).Where("tenant_id = ?", id).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,164 @@
+package repositories
+
+import (
+	"time"
+
+	"bookstore-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// WebhookRepository defines the interface for webhook data access.
+// Subscription lookups are scoped to a tenant; the dispatcher methods
+// work across tenants.
+type WebhookRepository interface {
+	CreateSubscription(sub *models.WebhookSubscription) error
+	FindSubscriptions(tenantID uint) ([]models.WebhookSubscription, error)
+	FindSubscriptionByID(tenantID, id uint) (*models.WebhookSubscription, error)
+	DeleteSubscription(id uint) error
+
+	// FanOut turns up to limit unprocessed outbox events into pending
+	// deliveries for every matching active subscription of the event's
+	// tenant.
+	FanOut(limit int) (int, error)
+	FindDueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error)
+	FindDeliveries(subscriptionID uint, status string) ([]models.WebhookDelivery, error)
+	FindDeliveryByID(id uint) (*models.WebhookDelivery, error)
+	UpdateDelivery(delivery *models.WebhookDelivery) error
+}
+
+// gormWebhookRepository implements WebhookRepository using GORM.
+type gormWebhookRepository struct {
+	db *gorm.DB
+}
+
+// NewGormWebhookRepository creates a new WebhookRepository using GORM.
+func NewGormWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &gormWebhookRepository{db: db}
+}
+
+// CreateSubscription inserts a new webhook subscription.
+func (r *gormWebhookRepository) CreateSubscription(sub *models.WebhookSubscription) error {
+	return r.db.Create(sub).Error
+}
+
+// FindSubscriptions retrieves a tenant's webhook subscriptions.
+func (r *gormWebhookRepository) FindSubscriptions(tenantID uint) ([]models.WebhookSubscription, error) {
+	var subs []models.WebhookSubscription
+	err := r.db.Scopes(forTenant(tenantID)).Order("id").Find(&subs).Error
+	return subs, err
+}
+
+// FindSubscriptionByID retrieves a tenant's webhook subscription by its ID.
+func (r *gormWebhookRepository) FindSubscriptionByID(tenantID, id uint) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	if err := r.db.Scopes(forTenant(tenantID)).First(&sub, id).Error; err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// DeleteSubscription removes a subscription and its delivery history.
+func (r *gormWebhookRepository) DeleteSubscription(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.WebhookSubscription{}, id).Error
+	})
+}
+
+// FanOut turns unprocessed outbox events into deliveries.
+func (r *gormWebhookRepository) FanOut(limit int) (int, error) {
+	processed := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var events []models.OutboxEvent
+		if err := tx.Where("processed_at IS NULL").Order("id").Limit(limit).Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		var subs []models.WebhookSubscription
+		if err := tx.Where("active = ?", true).Find(&subs).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, event := range events {
+			for _, sub := range subs {
+				if sub.TenantID != event.TenantID || !subscribesTo(sub, event.EventType) {
+					continue
+				}
+				delivery := models.WebhookDelivery{
+					SubscriptionID: sub.ID,
+					EventID:        event.ID,
+					EventType:      event.EventType,
+					Payload:        event.Payload,
+					Status:         models.DeliveryPending,
+					NextAttemptAt:  now,
+				}
+				if err := tx.Create(&delivery).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&event).Update("processed_at", now).Error; err != nil {
+				return err
+			}
+		}
+		processed = len(events)
+		return nil
+	})
+	return processed, err
+}
+
+// FindDueDeliveries retrieves pending deliveries whose next attempt is due,
+// with their subscription loaded.
+func (r *gormWebhookRepository) FindDueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Preload("Subscription").
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// FindDeliveries retrieves a subscription's deliveries, newest first,
+// optionally filtered by status.
+func (r *gormWebhookRepository) FindDeliveries(subscriptionID uint, status string) ([]models.WebhookDelivery, error) {
+	query := r.db.Where("subscription_id = ?", subscriptionID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []models.WebhookDelivery
+	err := query.Order("id DESC").Find(&deliveries).Error
+	return deliveries, err
+}
+
+// FindDeliveryByID retrieves a delivery by its ID with its subscription
+// loaded.
+func (r *gormWebhookRepository) FindDeliveryByID(id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := r.db.Preload("Subscription").First(&delivery, id).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// UpdateDelivery saves a delivery's state.
+func (r *gormWebhookRepository) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	return r.db.Omit("Subscription").Save(delivery).Error
+}
+
+// subscribesTo reports whether the subscription wants the event type.
+func subscribesTo(sub models.WebhookSubscription, eventType string) bool {
+	for _, t := range sub.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"encoding/json"
+	"testing"
+
+	"bookstore-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func TestFanOutDeliversOnlyToOwnTenant(t *testing.T) {
+	db, tenantA, tenantB := newTestDB(t)
+
+	// Record one outbox event per book change, as the webhooks package does.
+	recordEvent := func(tx *gorm.DB, change BookChange) error {
+		payload, err := json.Marshal(change.After)
+		if err != nil {
+			return err
+		}
+		return tx.Create(&models.OutboxEvent{
+			TenantID:  change.After.TenantID,
+			EventType: models.EventBookCreated,
+			Payload:   string(payload),
+		}).Error
+	}
+	books := NewGormBookRepository(db, recordEvent)
+	webhooks := NewGormWebhookRepository(db)
+
+	subB := models.WebhookSubscription{
+		TenantID:   tenantB,
+		URL:        "https://b.example.com/hook",
+		Secret:     "secret",
+		EventTypes: []string{models.EventBookCreated},
+		Active:     true,
+	}
+	if err := webhooks.CreateSubscription(&subB); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	book := models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441013593", Price: 9.99}
+	if err := books.Create(tenantA, &book); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := webhooks.FanOut(10); err != nil {
+		t.Fatalf("FanOut: %v", err)
+	}
+
+	deliveries, err := webhooks.FindDeliveries(subB.ID, "")
+	if err != nil {
+		t.Fatalf("FindDeliveries: %v", err)
+	}
+	if len(deliveries) != 0 {
+		t.Errorf("tenant B subscription received %d events for tenant A's book", len(deliveries))
+	}
+
+	if subs, err := webhooks.FindSubscriptions(tenantA); err != nil || len(subs) != 0 {
+		t.Errorf("tenant A sees %d subscriptions of tenant B (err %v)", len(subs), err)
+	}
+}
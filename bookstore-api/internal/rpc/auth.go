@@ -0,0 +1,106 @@
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+
+	"bookstore-api/internal/middleware"
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/rpc/bookstorev1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// publicMethods lists the RPCs that may be called without credentials,
+// mirroring the public GET routes of the REST API.
+var publicMethods = map[string]bool{
+	bookstorev1.BookService_GetBook_FullMethodName:   true,
+	bookstorev1.BookService_ListBooks_FullMethodName: true,
+}
+
+// UnaryAuthInterceptor authenticates unary calls to protected methods and
+// selects the tenant each call operates on.
+func UnaryAuthInterceptor(authn *middleware.Authenticator, tenants middleware.TenantResolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorize(ctx, info.FullMethod, authn, tenants)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthInterceptor authenticates streaming calls to protected methods
+// and selects the tenant each call operates on.
+func StreamAuthInterceptor(authn *middleware.Authenticator, tenants middleware.TenantResolver) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(ss.Context(), info.FullMethod, authn, tenants)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticatedStream overrides the context of a server stream.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context carrying the caller's identity and tenant.
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// authorize attaches the caller's identity and tenant to ctx. Callers
+// authenticate with an "x-api-key" or "authorization" metadata value or a
+// verified client certificate. Protected methods require credentials with
+// the books:write scope; on public methods credentials are optional but
+// must be valid when present. The tenant is the one bound to the
+// credentials, otherwise the one serving the call's :authority; unbound
+// credentials are refused outside the default tenant.
+func authorize(ctx context.Context, method string, authn *middleware.Authenticator, tenants middleware.TenantResolver) (context.Context, error) {
+	ctx = middleware.WithTenant(ctx, middleware.TenantForHost(tenants, metadataValue(ctx, ":authority")))
+	authHeader := metadataValue(ctx, "authorization")
+	apiKey := metadataValue(ctx, "x-api-key")
+
+	var state *tls.ConnectionState
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state = &tlsInfo.State
+		}
+	}
+
+	if publicMethods[method] && !middleware.HasCredentials(authHeader, apiKey, state) {
+		return ctx, nil
+	}
+
+	identity, err := authn.Authenticate(authHeader, apiKey, state)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if !publicMethods[method] && !identity.HasScope(models.ScopeBooksWrite) {
+		return nil, status.Error(codes.PermissionDenied, "insufficient scope")
+	}
+	tenantID, err := middleware.TenantForIdentity(identity, middleware.TenantFromContext(ctx))
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	return middleware.WithIdentity(middleware.WithTenant(ctx, tenantID), identity), nil
+}
+
+// metadataValue returns the first incoming metadata value for key.
+func metadataValue(ctx context.Context, key string) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,163 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"bookstore-api/internal/middleware"
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/rpc/bookstorev1"
+	"bookstore-api/internal/services"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// listPageSize is the number of books fetched per query while streaming
+// ListBooks results.
+const listPageSize = 100
+
+// BookServer implements the gRPC BookService over services.BookService.
+type BookServer struct {
+	bookstorev1.UnimplementedBookServiceServer
+	service services.BookService
+}
+
+// NewBookServer creates a new BookServer with the given service.
+func NewBookServer(service services.BookService) *BookServer {
+	return &BookServer{service: service}
+}
+
+// NewServer creates a gRPC server with auth interceptors and the
+// BookService registered. Extra options, such as TLS credentials, are
+// applied after the interceptors.
+func NewServer(service services.BookService, authn *middleware.Authenticator, tenants middleware.TenantResolver, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{
+		grpc.UnaryInterceptor(UnaryAuthInterceptor(authn, tenants)),
+		grpc.StreamInterceptor(StreamAuthInterceptor(authn, tenants)),
+	}, opts...)
+	server := grpc.NewServer(opts...)
+	bookstorev1.RegisterBookServiceServer(server, NewBookServer(service))
+	return server
+}
+
+// GetBook returns a single book by ID.
+func (s *BookServer) GetBook(ctx context.Context, req *bookstorev1.GetBookRequest) (*bookstorev1.Book, error) {
+	book, err := s.service.GetBookByID(middleware.TenantFromContext(ctx), uint(req.GetId()))
+	if err != nil {
+		return nil, statusFromError(err, "failed to fetch book")
+	}
+	return toProto(book), nil
+}
+
+// ListBooks streams every book matching the request filters.
+func (s *BookServer) ListBooks(req *bookstorev1.ListBooksRequest, stream bookstorev1.BookService_ListBooksServer) error {
+	filter := models.BookFilter{
+		Title:    req.GetTitle(),
+		Author:   req.GetAuthor(),
+		ISBN:     req.GetIsbn(),
+		MinPrice: req.MinPrice,
+		MaxPrice: req.MaxPrice,
+		Limit:    listPageSize,
+	}
+
+	for {
+		books, _, err := s.service.SearchBooks(middleware.TenantFromContext(stream.Context()), filter)
+		if err != nil {
+			return status.Error(codes.Internal, "failed to fetch books")
+		}
+		for i := range books {
+			if err := stream.Send(toProto(&books[i])); err != nil {
+				return err
+			}
+		}
+		if len(books) < filter.Limit {
+			return nil
+		}
+		filter.Offset += len(books)
+	}
+}
+
+// CreateBook creates a new book.
+func (s *BookServer) CreateBook(ctx context.Context, req *bookstorev1.CreateBookRequest) (*bookstorev1.Book, error) {
+	if req.GetBook() == nil {
+		return nil, status.Error(codes.InvalidArgument, "book is required")
+	}
+
+	book := fromProto(req.GetBook())
+	if err := s.service.CreateBook(middleware.TenantFromContext(ctx), &book); err != nil {
+		return nil, statusFromError(err, "failed to create book")
+	}
+	return toProto(&book), nil
+}
+
+// UpdateBook replaces the fields of an existing book.
+func (s *BookServer) UpdateBook(ctx context.Context, req *bookstorev1.UpdateBookRequest) (*bookstorev1.Book, error) {
+	if req.GetBook() == nil {
+		return nil, status.Error(codes.InvalidArgument, "book is required")
+	}
+
+	existing, err := s.service.GetBookByID(middleware.TenantFromContext(ctx), uint(req.GetId()))
+	if err != nil {
+		return nil, statusFromError(err, "failed to fetch book")
+	}
+
+	book := fromProto(req.GetBook())
+	book.ID = existing.ID
+	book.CreatedAt = existing.CreatedAt
+	// Categories and tags are not part of the protobuf Book; keep them.
+	book.CategoryID = existing.CategoryID
+	book.Tags = existing.Tags
+	if err := s.service.UpdateBook(middleware.TenantFromContext(ctx), &book); err != nil {
+		return nil, statusFromError(err, "failed to update book")
+	}
+	return toProto(&book), nil
+}
+
+// DeleteBook deletes a book by ID.
+func (s *BookServer) DeleteBook(ctx context.Context, req *bookstorev1.DeleteBookRequest) (*emptypb.Empty, error) {
+	if err := s.service.DeleteBook(middleware.TenantFromContext(ctx), uint(req.GetId())); err != nil {
+		return nil, statusFromError(err, "failed to delete book")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// statusFromError maps a service error to a gRPC status. Unexpected
+// errors become Internal with the given message.
+func statusFromError(err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		return status.Error(codes.NotFound, "book not found")
+	case errors.Is(err, services.ErrInvalidInput):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, services.ErrConflict):
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	return status.Error(codes.Internal, message)
+}
+
+// toProto converts a book model into its protobuf message.
+func toProto(book *models.Book) *bookstorev1.Book {
+	return &bookstorev1.Book{
+		Id:        uint32(book.ID),
+		Title:     book.Title,
+		Author:    book.Author,
+		Isbn:      book.ISBN,
+		Price:     book.Price,
+		CreatedAt: timestamppb.New(book.CreatedAt),
+		UpdatedAt: timestamppb.New(book.UpdatedAt),
+	}
+}
+
+// fromProto converts a protobuf book input into a book model.
+func fromProto(input *bookstorev1.BookInput) models.Book {
+	return models.Book{
+		Title:  input.GetTitle(),
+		Author: input.GetAuthor(),
+		ISBN:   input.GetIsbn(),
+		Price:  input.GetPrice(),
+	}
+}
@@ -0,0 +1,276 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"bookstore-api/internal/middleware"
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+	"bookstore-api/internal/rpc/bookstorev1"
+	"bookstore-api/internal/services"
+	"bookstore-api/internal/testutil"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gorm.io/gorm"
+)
+
+const testJWTSecret = "rpc-test-secret-that-is-at-least-32-chars"
+
+// storeBDomain is the domain of the second tenant created by newTestClient.
+const storeBDomain = "store-b.example.com"
+
+// fakeKeys verifies the API keys in a map.
+type fakeKeys map[string]models.APIKey
+
+func (k fakeKeys) VerifyAPIKey(key string) (*models.APIKey, error) {
+	found, ok := k[key]
+	if !ok {
+		return nil, errors.New("unknown key")
+	}
+	return &found, nil
+}
+
+// rpcFixture holds BookService clients connected over an in-memory
+// listener to a server with the default tenant and store B. storeBClient
+// calls with store B's domain as the authority.
+type rpcFixture struct {
+	client       bookstorev1.BookServiceClient
+	storeBClient bookstorev1.BookServiceClient
+	db           *gorm.DB
+}
+
+// newRPCFixture starts the server, which is stopped when the test
+// finishes. API keys are those of testKeys.
+func newRPCFixture(t *testing.T) *rpcFixture {
+	t.Helper()
+
+	db := testutil.NewDB(t)
+	domain := storeBDomain
+	storeB := models.Tenant{Slug: "store-b", Name: "Store B", Domain: &domain}
+	tenantRepo := repositories.NewGormTenantRepository(db)
+	if err := tenantRepo.Create(&storeB); err != nil {
+		t.Fatalf("create tenant: %v", err)
+	}
+
+	bookRepo := repositories.NewGormBookRepository(db)
+	books := services.NewBookService(bookRepo, repositories.NewGormCategoryRepository(db), nil)
+	authn := middleware.NewAuthenticator(testJWTSecret, nil, testKeys(storeB.ID))
+	server := NewServer(books, authn, services.NewTenantService(tenantRepo))
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	dial := func(opts ...grpc.DialOption) bookstorev1.BookServiceClient {
+		opts = append(opts,
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()))
+		conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return bookstorev1.NewBookServiceClient(conn)
+	}
+	return &rpcFixture{
+		client:       dial(),
+		storeBClient: dial(grpc.WithAuthority(storeBDomain)),
+		db:           db,
+	}
+}
+
+// withMetadata returns a context carrying the given metadata pairs.
+func withMetadata(pairs ...string) context.Context {
+	return metadata.NewOutgoingContext(context.Background(), metadata.Pairs(pairs...))
+}
+
+// userToken returns an authorization metadata value for subject.
+func userToken(t *testing.T, subject string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": subject}).SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return "Bearer " + token
+}
+
+// listTitles streams ListBooks and returns the titles received.
+func listTitles(t *testing.T, client bookstorev1.BookServiceClient, ctx context.Context) []string {
+	t.Helper()
+	stream, err := client.ListBooks(ctx, &bookstorev1.ListBooksRequest{})
+	if err != nil {
+		t.Fatalf("ListBooks: %v", err)
+	}
+	var titles []string
+	for {
+		book, err := stream.Recv()
+		if err == io.EOF {
+			return titles
+		}
+		if err != nil {
+			t.Fatalf("ListBooks: %v", err)
+		}
+		titles = append(titles, book.GetTitle())
+	}
+}
+
+// testKeys returns writer keys bound to each tenant and a read-only key.
+func testKeys(storeB uint) fakeKeys {
+	return fakeKeys{
+		"writer-default": {Prefix: "wd", TenantID: models.DefaultTenantID, Scopes: []string{models.ScopeBooksRead, models.ScopeBooksWrite}},
+		"writer-store-b": {Prefix: "wb", TenantID: storeB, Scopes: []string{models.ScopeBooksRead, models.ScopeBooksWrite}},
+		"reader":         {Prefix: "rd", TenantID: models.DefaultTenantID, Scopes: []string{models.ScopeBooksRead}},
+	}
+}
+
+func TestAuthMetadata(t *testing.T) {
+	f := newRPCFixture(t)
+	input := &bookstorev1.BookInput{Title: "Dune", Author: "Frank Herbert", Isbn: "9780441013593", Price: 9.99}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want codes.Code
+	}{
+		{"no credentials", context.Background(), codes.Unauthenticated},
+		{"malformed authorization", withMetadata("authorization", "Token abc"), codes.Unauthenticated},
+		{"invalid token", withMetadata("authorization", "Bearer not-a-jwt"), codes.Unauthenticated},
+		{"unknown API key", withMetadata("x-api-key", "nope"), codes.Unauthenticated},
+		{"read-only API key", withMetadata("x-api-key", "reader"), codes.PermissionDenied},
+		{"user token", withMetadata("authorization", userToken(t, "alice")), codes.OK},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input.Isbn = "isbn-" + string(rune('a'+i))
+			_, err := f.client.CreateBook(tt.ctx, &bookstorev1.CreateBookRequest{Book: input})
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("CreateBook code = %v, want %v (%v)", got, tt.want, err)
+			}
+		})
+	}
+
+	// Public methods accept anonymous calls but reject bad credentials.
+	if got := listTitles(t, f.client, context.Background()); len(got) != 1 {
+		t.Errorf("anonymous ListBooks returned %v, want the one book", got)
+	}
+	_, err := f.client.GetBook(withMetadata("authorization", "Bearer not-a-jwt"), &bookstorev1.GetBookRequest{Id: 1})
+	if got := status.Code(err); got != codes.Unauthenticated {
+		t.Errorf("GetBook with a bad token code = %v, want Unauthenticated", got)
+	}
+}
+
+func TestTenantScoping(t *testing.T) {
+	f := newRPCFixture(t)
+	create := func(key, title string) *bookstorev1.Book {
+		t.Helper()
+		book, err := f.client.CreateBook(withMetadata("x-api-key", key), &bookstorev1.CreateBookRequest{
+			Book: &bookstorev1.BookInput{Title: title, Author: "A. Writer", Isbn: "9780000000001", Price: 5},
+		})
+		if err != nil {
+			t.Fatalf("CreateBook %s: %v", title, err)
+		}
+		return book
+	}
+	// The same ISBN may be used once in each tenant.
+	inDefault := create("writer-default", "Default Book")
+	inStoreB := create("writer-store-b", "Store B Book")
+
+	if got := listTitles(t, f.client, context.Background()); len(got) != 1 || got[0] != "Default Book" {
+		t.Errorf("default tenant lists %v", got)
+	}
+	if got := listTitles(t, f.storeBClient, context.Background()); len(got) != 1 || got[0] != "Store B Book" {
+		t.Errorf("store B lists %v", got)
+	}
+	// Credentials bound to a tenant override the authority.
+	if got := listTitles(t, f.client, withMetadata("x-api-key", "writer-store-b")); len(got) != 1 || got[0] != "Store B Book" {
+		t.Errorf("store B key lists %v", got)
+	}
+
+	// Unbound credentials only act on the default tenant.
+	_, err := f.storeBClient.CreateBook(withMetadata("authorization", userToken(t, "alice")), &bookstorev1.CreateBookRequest{
+		Book: &bookstorev1.BookInput{Title: "Planted", Author: "A. Writer", Isbn: "9780000000002", Price: 5},
+	})
+	if got := status.Code(err); got != codes.PermissionDenied {
+		t.Errorf("CreateBook on store B with an unbound token code = %v, want PermissionDenied", got)
+	}
+
+	_, err = f.client.GetBook(context.Background(), &bookstorev1.GetBookRequest{Id: inStoreB.GetId()})
+	if got := status.Code(err); got != codes.NotFound {
+		t.Errorf("GetBook of store B's book on the default tenant code = %v, want NotFound", got)
+	}
+	_, err = f.client.UpdateBook(withMetadata("x-api-key", "writer-store-b"), &bookstorev1.UpdateBookRequest{
+		Id: inDefault.GetId(), Book: &bookstorev1.BookInput{Title: "Taken", Author: "B", Isbn: "x", Price: 1},
+	})
+	if got := status.Code(err); got != codes.NotFound {
+		t.Errorf("UpdateBook of another tenant's book code = %v, want NotFound", got)
+	}
+	if _, err := f.client.DeleteBook(withMetadata("x-api-key", "writer-store-b"), &bookstorev1.DeleteBookRequest{Id: inDefault.GetId()}); err != nil {
+		t.Fatalf("DeleteBook: %v", err)
+	}
+	if _, err := f.client.GetBook(context.Background(), &bookstorev1.GetBookRequest{Id: inDefault.GetId()}); err != nil {
+		t.Errorf("another tenant's delete removed the book: %v", err)
+	}
+}
+
+func TestStatusCodes(t *testing.T) {
+	f := newRPCFixture(t)
+	writer := withMetadata("x-api-key", "writer-default")
+	input := &bookstorev1.BookInput{Title: "Dune", Author: "Frank Herbert", Isbn: "9780441013593", Price: 9.99}
+	book, err := f.client.CreateBook(writer, &bookstorev1.CreateBookRequest{Book: input})
+	if err != nil {
+		t.Fatalf("CreateBook: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"missing book", func() error {
+			_, err := f.client.GetBook(context.Background(), &bookstorev1.GetBookRequest{Id: 9999})
+			return err
+		}, codes.NotFound},
+		{"create without a book", func() error {
+			_, err := f.client.CreateBook(writer, &bookstorev1.CreateBookRequest{})
+			return err
+		}, codes.InvalidArgument},
+		{"duplicate ISBN", func() error {
+			_, err := f.client.CreateBook(writer, &bookstorev1.CreateBookRequest{Book: input})
+			return err
+		}, codes.AlreadyExists},
+		{"update missing book", func() error {
+			_, err := f.client.UpdateBook(writer, &bookstorev1.UpdateBookRequest{Id: 9999, Book: input})
+			return err
+		}, codes.NotFound},
+		{"delete missing book", func() error {
+			_, err := f.client.DeleteBook(writer, &bookstorev1.DeleteBookRequest{Id: 9999})
+			return err
+		}, codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(tt.call()); got != tt.want {
+				t.Errorf("code = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Storage failures are Internal rather than NotFound.
+	if err := f.db.Migrator().DropTable("book_tags"); err != nil {
+		t.Fatalf("drop book_tags: %v", err)
+	}
+	_, err = f.client.GetBook(context.Background(), &bookstorev1.GetBookRequest{Id: book.GetId()})
+	if got := status.Code(err); got != codes.Internal {
+		t.Errorf("GetBook with a broken store code = %v, want Internal", got)
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+)
+
+// apiKeyPrefix starts every generated API key so leaked keys are easy to
+// recognise.
+const apiKeyPrefix = "bsk_"
+
+// lastUsedResolution limits how often last-used timestamps are written.
+const lastUsedResolution = time.Minute
+
+// APIKeyService defines the interface for API key business logic.
+type APIKeyService interface {
+	// CreateAPIKey stores a new key and returns its raw value, which is not
+	// recoverable afterwards.
+	CreateAPIKey(key *models.APIKey) (string, error)
+	ListAPIKeys() ([]models.APIKey, error)
+	RevokeAPIKey(id uint) error
+	// VerifyAPIKey returns the active key matching a raw key value.
+	VerifyAPIKey(raw string) (*models.APIKey, error)
+}
+
+// apiKeyService implements APIKeyService.
+type apiKeyService struct {
+	repo    repositories.APIKeyRepository
+	tenants repositories.TenantRepository
+}
+
+// NewAPIKeyService creates a new APIKeyService with the given repositories.
+func NewAPIKeyService(repo repositories.APIKeyRepository, tenants repositories.TenantRepository) APIKeyService {
+	return &apiKeyService{repo: repo, tenants: tenants}
+}
+
+// CreateAPIKey validates the name, scopes and tenant and generates a key
+// of the form "bsk_<prefix>_<secret>".
+func (s *apiKeyService) CreateAPIKey(key *models.APIKey) (string, error) {
+	key.Name = strings.TrimSpace(key.Name)
+	if key.Name == "" {
+		return "", fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	if len(key.Scopes) == 0 {
+		return "", fmt.Errorf("%w: at least one scope is required", ErrInvalidInput)
+	}
+	for _, scope := range key.Scopes {
+		if !isAPIKeyScope(scope) {
+			return "", fmt.Errorf("%w: scope %q cannot be granted to an API key", ErrInvalidInput, scope)
+		}
+	}
+
+	if _, err := s.tenants.FindByID(key.TenantID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return "", fmt.Errorf("%w: unknown tenant %d", ErrInvalidInput, key.TenantID)
+		}
+		return "", err
+	}
+
+	prefix, err := randomHex(4)
+	if err != nil {
+		return "", err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return "", err
+	}
+	raw := apiKeyPrefix + prefix + "_" + secret
+
+	key.Prefix = prefix
+	key.Hash = hashAPIKey(raw)
+	key.LastUsedAt = nil
+	key.RevokedAt = nil
+	if err := s.repo.Create(key); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// ListAPIKeys retrieves all API keys.
+func (s *apiKeyService) ListAPIKeys() ([]models.APIKey, error) {
+	return s.repo.FindAll()
+}
+
+// RevokeAPIKey revokes an API key. Revoking a revoked key is a conflict.
+func (s *apiKeyService) RevokeAPIKey(id uint) error {
+	key, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if key.RevokedAt != nil {
+		return fmt.Errorf("%w: API key is already revoked", ErrConflict)
+	}
+	return s.repo.Revoke(id, time.Now())
+}
+
+// VerifyAPIKey looks up a key by hash, rejects revoked keys and records
+// its use at most once per minute.
+func (s *apiKeyService) VerifyAPIKey(raw string) (*models.APIKey, error) {
+	if !strings.HasPrefix(raw, apiKeyPrefix) {
+		return nil, ErrNotFound
+	}
+	key, err := s.repo.FindByHash(hashAPIKey(raw))
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrNotFound
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		if err := s.repo.TouchLastUsed(key.ID, now); err != nil {
+			log.Printf("Failed to record API key use: %v", err)
+		}
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
+
+// hashAPIKey returns the hex SHA-256 digest stored for a raw key.
+func hashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex returns n random bytes, hex encoded.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// isAPIKeyScope reports whether scope may be granted to an API key.
+func isAPIKeyScope(scope string) bool {
+	for _, known := range models.APIKeyScopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
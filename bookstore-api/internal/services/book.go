@@ -1,50 +1,218 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
 	"bookstore-api/internal/models"
 	"bookstore-api/internal/repositories"
 )
 
-// BookService defines the interface for book business logic.
+// Limits on the tags of one book.
+const (
+	maxTags      = 20
+	maxTagLength = 50
+)
+
+// BookService defines the interface for book business logic. Every method
+// operates on the catalog of the given tenant.
 type BookService interface {
-	CreateBook(book *models.Book) error
-	GetAllBooks() ([]models.Book, error)
-	GetBookByID(id uint) (*models.Book, error)
-	UpdateBook(book *models.Book) error
-	DeleteBook(id uint) error
+	CreateBook(tenantID uint, book *models.Book) error
+	GetAllBooks(tenantID uint) ([]models.Book, error)
+	SearchBooks(tenantID uint, filter models.BookFilter) ([]models.Book, int64, error)
+	GetBookByID(tenantID, id uint) (*models.Book, error)
+	UpdateBook(tenantID uint, book *models.Book) error
+	DeleteBook(tenantID, id uint) error
+	// GetFacets counts the books matching the filter by category, author
+	// and price range. Category counts include subcategories.
+	GetFacets(tenantID uint, filter models.BookFilter) (*models.BookFacets, error)
+	ListTags(tenantID uint) ([]models.TagCount, error)
+}
+
+// BookPublisher is notified of every book change once it is committed.
+// eventType is one of models.EventBookCreated, EventBookUpdated and
+// EventBookDeleted.
+type BookPublisher interface {
+	Publish(eventType string, book models.Book)
 }
 
 // bookService implements BookService.
 type bookService struct {
-	repo repositories.BookRepository
+	repo       repositories.BookRepository
+	categories repositories.CategoryRepository
+	publisher  BookPublisher
 }
 
-// NewBookService creates a new BookService with the given repository.
-func NewBookService(repo repositories.BookRepository) BookService {
-	return &bookService{repo: repo}
+// NewBookService creates a new BookService with the given repositories.
+// publisher may be nil to disable change notifications.
+func NewBookService(repo repositories.BookRepository, categories repositories.CategoryRepository, publisher BookPublisher) BookService {
+	return &bookService{repo: repo, categories: categories, publisher: publisher}
 }
 
 // CreateBook creates a new book.
-func (s *bookService) CreateBook(book *models.Book) error {
-	return s.repo.Create(book)
+func (s *bookService) CreateBook(tenantID uint, book *models.Book) error {
+	if err := s.normalizeBook(tenantID, book); err != nil {
+		return err
+	}
+	if err := s.repo.Create(tenantID, book); err != nil {
+		return conflictOnDuplicate(err, "a book with this ISBN already exists")
+	}
+	s.publish(models.EventBookCreated, *book)
+	return nil
 }
 
 // GetAllBooks retrieves all books.
-func (s *bookService) GetAllBooks() ([]models.Book, error) {
-	return s.repo.FindAll()
+func (s *bookService) GetAllBooks(tenantID uint) ([]models.Book, error) {
+	return s.repo.FindAll(tenantID)
+}
+
+// SearchBooks retrieves a page of books matching the filter and the total
+// number of matches.
+func (s *bookService) SearchBooks(tenantID uint, filter models.BookFilter) ([]models.Book, int64, error) {
+	return s.repo.FindPage(tenantID, filter)
 }
 
 // GetBookByID retrieves a book by its ID.
-func (s *bookService) GetBookByID(id uint) (*models.Book, error) {
-	return s.repo.FindByID(id)
+func (s *bookService) GetBookByID(tenantID, id uint) (*models.Book, error) {
+	return s.repo.FindByID(tenantID, id)
+}
+
+// UpdateBook updates an existing book, creating it when the ID is unused.
+func (s *bookService) UpdateBook(tenantID uint, book *models.Book) error {
+	if err := s.normalizeBook(tenantID, book); err != nil {
+		return err
+	}
+	eventType := models.EventBookUpdated
+	if _, err := s.repo.FindByID(tenantID, book.ID); errors.Is(err, ErrNotFound) {
+		eventType = models.EventBookCreated
+	} else if err != nil {
+		return err
+	}
+	if err := s.repo.Update(tenantID, book); err != nil {
+		return conflictOnDuplicate(err, "a book with this ISBN already exists")
+	}
+	s.publish(eventType, *book)
+	return nil
+}
+
+// DeleteBook deletes a book by its ID. Deleting a missing book succeeds.
+func (s *bookService) DeleteBook(tenantID, id uint) error {
+	book, err := s.repo.FindByID(tenantID, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(tenantID, id); err != nil {
+		return err
+	}
+	s.publish(models.EventBookDeleted, *book)
+	return nil
 }
 
-// UpdateBook updates an existing book.
-func (s *bookService) UpdateBook(book *models.Book) error {
-	return s.repo.Update(book)
+// GetFacets counts the books matching the filter, rolling category counts
+// up into parent categories.
+func (s *bookService) GetFacets(tenantID uint, filter models.BookFilter) (*models.BookFacets, error) {
+	facets, err := s.repo.FindFacets(tenantID, filter)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.categories.FindAll(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	facets.Categories = rollUpCategoryCounts(categories, facets.Categories)
+	return facets, nil
 }
 
-// DeleteBook deletes a book by its ID.
-func (s *bookService) DeleteBook(id uint) error {
-	return s.repo.Delete(id)
+// ListTags retrieves every tag in use with its number of books.
+func (s *bookService) ListTags(tenantID uint) ([]models.TagCount, error) {
+	return s.repo.FindTags(tenantID)
+}
+
+// publish notifies the publisher, if any, of a committed change.
+func (s *bookService) publish(eventType string, book models.Book) {
+	if s.publisher != nil {
+		s.publisher.Publish(eventType, book)
+	}
+}
+
+// normalizeBook checks that the book's category belongs to the tenant and
+// normalizes its tags.
+func (s *bookService) normalizeBook(tenantID uint, book *models.Book) error {
+	if book.CategoryID != nil {
+		if _, err := s.categories.FindByID(tenantID, *book.CategoryID); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return fmt.Errorf("%w: category %d does not exist", ErrInvalidInput, *book.CategoryID)
+			}
+			return err
+		}
+	}
+
+	tags, err := normalizeTags(book.Tags)
+	if err != nil {
+		return err
+	}
+	book.Tags = tags
+	return nil
+}
+
+// normalizeTags trims, lowercases, deduplicates and sorts tags.
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, fmt.Errorf("%w: tags must be at most %d characters", ErrInvalidInput, maxTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxTags {
+		return nil, fmt.Errorf("%w: a book can have at most %d tags", ErrInvalidInput, maxTags)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// rollUpCategoryCounts adds each category's direct book count to all of
+// its ancestors and returns the categories with matching books, in the
+// order of categories.
+func rollUpCategoryCounts(categories []models.Category, direct []models.CategoryFacet) []models.CategoryFacet {
+	parents := make(map[uint]*uint, len(categories))
+	for _, category := range categories {
+		parents[category.ID] = category.ParentID
+	}
+
+	counts := make(map[uint]int64, len(categories))
+	for _, facet := range direct {
+		id := &facet.ID
+		// The depth bound guards against cycles in the stored tree.
+		for depth := 0; id != nil && depth <= len(categories); depth++ {
+			counts[*id] += facet.Count
+			id = parents[*id]
+		}
+	}
+
+	facets := []models.CategoryFacet{}
+	for _, category := range categories {
+		if counts[category.ID] == 0 {
+			continue
+		}
+		facets = append(facets, models.CategoryFacet{
+			ID:       category.ID,
+			ParentID: category.ParentID,
+			Name:     category.Name,
+			Slug:     category.Slug,
+			Count:    counts[category.ID],
+		})
+	}
+	return facets
 }
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+)
+
+// CategoryService defines the interface for category business logic.
+// Every method operates on the categories of the given tenant.
+type CategoryService interface {
+	CreateCategory(tenantID uint, category *models.Category) error
+	ListCategories(tenantID uint) ([]models.Category, error)
+	GetCategory(tenantID, id uint) (*models.Category, error)
+	UpdateCategory(tenantID uint, category *models.Category) error
+	DeleteCategory(tenantID, id uint) error
+}
+
+// categoryService implements CategoryService.
+type categoryService struct {
+	repo repositories.CategoryRepository
+}
+
+// NewCategoryService creates a new CategoryService with the given
+// repository.
+func NewCategoryService(repo repositories.CategoryRepository) CategoryService {
+	return &categoryService{repo: repo}
+}
+
+// CreateCategory validates and stores a new category.
+func (s *categoryService) CreateCategory(tenantID uint, category *models.Category) error {
+	category.ID = 0
+	category.TenantID = tenantID
+	if err := s.validateCategory(category); err != nil {
+		return err
+	}
+	return conflictOnDuplicate(s.repo.Create(category), "a category with this slug already exists")
+}
+
+// ListCategories retrieves all categories ordered by name.
+func (s *categoryService) ListCategories(tenantID uint) ([]models.Category, error) {
+	return s.repo.FindAll(tenantID)
+}
+
+// GetCategory retrieves a category by its ID.
+func (s *categoryService) GetCategory(tenantID, id uint) (*models.Category, error) {
+	return s.repo.FindByID(tenantID, id)
+}
+
+// UpdateCategory replaces an existing category's name, slug and parent.
+func (s *categoryService) UpdateCategory(tenantID uint, category *models.Category) error {
+	existing, err := s.repo.FindByID(tenantID, category.ID)
+	if err != nil {
+		return err
+	}
+	category.TenantID = tenantID
+	category.CreatedAt = existing.CreatedAt
+	if err := s.validateCategory(category); err != nil {
+		return err
+	}
+	return conflictOnDuplicate(s.repo.Update(category), "a category with this slug already exists")
+}
+
+// DeleteCategory removes a category that has no subcategories or books.
+func (s *categoryService) DeleteCategory(tenantID, id uint) error {
+	if _, err := s.repo.FindByID(tenantID, id); err != nil {
+		return err
+	}
+	children, books, err := s.repo.CountUsage(tenantID, id)
+	if err != nil {
+		return err
+	}
+	if children > 0 {
+		return fmt.Errorf("%w: category still has %d subcategories", ErrConflict, children)
+	}
+	if books > 0 {
+		return fmt.Errorf("%w: category still has %d books", ErrConflict, books)
+	}
+	return s.repo.Delete(tenantID, id)
+}
+
+// validateCategory trims and validates a category's fields. The parent
+// must belong to the same tenant and may not be the category itself or
+// one of its subcategories.
+func (s *categoryService) validateCategory(category *models.Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	category.Slug = strings.TrimSpace(category.Slug)
+	if category.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	if !slugPattern.MatchString(category.Slug) {
+		return fmt.Errorf("%w: slug must be 1-63 lowercase letters, digits or hyphens", ErrInvalidInput)
+	}
+	if category.ParentID == nil {
+		return nil
+	}
+
+	categories, err := s.repo.FindAll(category.TenantID)
+	if err != nil {
+		return err
+	}
+	parents := make(map[uint]*uint, len(categories))
+	for _, c := range categories {
+		parents[c.ID] = c.ParentID
+	}
+	if _, ok := parents[*category.ParentID]; !ok {
+		return fmt.Errorf("%w: parent category %d does not exist", ErrInvalidInput, *category.ParentID)
+	}
+	for id, depth := category.ParentID, 0; id != nil && depth <= len(categories); id, depth = parents[*id], depth+1 {
+		if *id == category.ID {
+			return fmt.Errorf("%w: a category cannot be its own ancestor", ErrInvalidInput)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Errors returned by services. ErrInvalidInput and ErrConflict are usually
+// wrapped with details.
+var (
+	ErrNotFound     = gorm.ErrRecordNotFound
+	ErrInvalidInput = errors.New("invalid input")
+	ErrConflict     = errors.New("conflict")
+)
+
+// conflictOnDuplicate reports unique constraint violations as ErrConflict
+// with the given detail, passing other errors through.
+func conflictOnDuplicate(err error, detail string) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return fmt.Errorf("%w: %s", ErrConflict, detail)
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// staleClaimTimeout is how long an unfinished request may hold a key
+// before a retry may take it over, e.g. after a crash.
+const staleClaimTimeout = time.Minute
+
+// IdempotencyService defines the interface for idempotency key handling.
+type IdempotencyService interface {
+	// Begin claims a key for a new request. When the key was already used
+	// it returns the earlier request's record and claimed is false.
+	Begin(scope, key, requestHash string) (record *models.IdempotencyRecord, claimed bool, err error)
+	// Complete stores the response to a claimed request.
+	Complete(record *models.IdempotencyRecord) error
+	// Release gives up a claim so the request can be retried.
+	Release(record *models.IdempotencyRecord) error
+	// PurgeExpired removes expired records.
+	PurgeExpired() (int64, error)
+}
+
+// idempotencyService implements IdempotencyService.
+type idempotencyService struct {
+	repo repositories.IdempotencyRepository
+	ttl  time.Duration
+}
+
+// NewIdempotencyService creates a new IdempotencyService that keeps
+// responses for ttl.
+func NewIdempotencyService(repo repositories.IdempotencyRepository, ttl time.Duration) IdempotencyService {
+	return &idempotencyService{repo: repo, ttl: ttl}
+}
+
+// Begin claims a key, replacing expired records and stale claims.
+func (s *idempotencyService) Begin(scope, key, requestHash string) (*models.IdempotencyRecord, bool, error) {
+	now := time.Now()
+	existing, err := s.repo.Find(scope, key)
+	switch {
+	case errors.Is(err, ErrNotFound):
+	case err != nil:
+		return nil, false, err
+	case !now.Before(existing.ExpiresAt), existing.StatusCode == 0 && now.Sub(existing.CreatedAt) > staleClaimTimeout:
+		if err := s.repo.Delete(existing.ID); err != nil {
+			return nil, false, err
+		}
+	default:
+		return existing, false, nil
+	}
+
+	record := &models.IdempotencyRecord{
+		Scope:       scope,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+	if err := s.repo.Create(record); err != nil {
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, false, err
+		}
+		// A concurrent request claimed the key first.
+		existing, err := s.repo.Find(scope, key)
+		if err != nil {
+			return nil, false, err
+		}
+		return existing, false, nil
+	}
+	return record, true, nil
+}
+
+// Complete stores the response to a claimed request.
+func (s *idempotencyService) Complete(record *models.IdempotencyRecord) error {
+	return s.repo.Update(record)
+}
+
+// Release deletes a claim.
+func (s *idempotencyService) Release(record *models.IdempotencyRecord) error {
+	return s.repo.Delete(record.ID)
+}
+
+// PurgeExpired removes expired records.
+func (s *idempotencyService) PurgeExpired() (int64, error) {
+	return s.repo.DeleteExpired(time.Now())
+}
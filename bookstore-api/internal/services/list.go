@@ -0,0 +1,216 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+)
+
+// Reading list limits.
+const (
+	maxListNameLength = 100
+	maxListEntries    = 500
+)
+
+// ListService defines the interface for reading list business logic.
+// Lists belong to one owner, the subject of the caller's credentials, in
+// one tenant; other owners' lists are reported as not found.
+type ListService interface {
+	CreateList(tenantID uint, owner string, list *models.ReadingList) error
+	ListLists(tenantID uint, owner string) ([]models.ReadingList, error)
+	GetList(tenantID uint, owner string, id uint) (*models.ReadingList, error)
+	UpdateList(tenantID uint, owner string, list *models.ReadingList) error
+	DeleteList(tenantID uint, owner string, id uint) error
+
+	// AddEntry adds a book at a 1-based position; zero appends it.
+	AddEntry(tenantID uint, owner string, listID, bookID uint, position int) (*models.ReadingList, error)
+	RemoveEntry(tenantID uint, owner string, listID, bookID uint) error
+	// ReorderEntries puts a list's books in the order of bookIDs, which
+	// must hold each of them once.
+	ReorderEntries(tenantID uint, owner string, listID uint, bookIDs []uint) (*models.ReadingList, error)
+
+	// ShareList gives a list a share token, keeping an existing one.
+	ShareList(tenantID uint, owner string, id uint) (*models.ReadingList, error)
+	// UnshareList revokes a list's share token.
+	UnshareList(tenantID uint, owner string, id uint) error
+	// GetSharedList retrieves the list shared with token.
+	GetSharedList(tenantID uint, token string) (*models.ReadingList, error)
+}
+
+// listService implements ListService.
+type listService struct {
+	repo  repositories.ListRepository
+	books repositories.BookRepository
+}
+
+// NewListService creates a new ListService with the given repositories.
+func NewListService(repo repositories.ListRepository, books repositories.BookRepository) ListService {
+	return &listService{repo: repo, books: books}
+}
+
+// CreateList validates and stores a new, empty reading list.
+func (s *listService) CreateList(tenantID uint, owner string, list *models.ReadingList) error {
+	list.ID = 0
+	list.TenantID = tenantID
+	list.Owner = owner
+	list.ShareToken = nil
+	if err := validateList(list); err != nil {
+		return err
+	}
+	return s.repo.Create(list)
+}
+
+// ListLists retrieves the owner's lists in the order they were created.
+func (s *listService) ListLists(tenantID uint, owner string) ([]models.ReadingList, error) {
+	return s.repo.FindAll(tenantID, owner)
+}
+
+// GetList retrieves one of the owner's lists.
+func (s *listService) GetList(tenantID uint, owner string, id uint) (*models.ReadingList, error) {
+	return s.repo.FindByID(tenantID, owner, id)
+}
+
+// UpdateList renames a list or changes its kind.
+func (s *listService) UpdateList(tenantID uint, owner string, list *models.ReadingList) error {
+	existing, err := s.repo.FindByID(tenantID, owner, list.ID)
+	if err != nil {
+		return err
+	}
+	existing.Name = list.Name
+	existing.Kind = list.Kind
+	if err := validateList(existing); err != nil {
+		return err
+	}
+	if err := s.repo.Update(existing); err != nil {
+		return err
+	}
+	*list = *existing
+	return nil
+}
+
+// DeleteList removes one of the owner's lists.
+func (s *listService) DeleteList(tenantID uint, owner string, id uint) error {
+	list, err := s.repo.FindByID(tenantID, owner, id)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(list)
+}
+
+// AddEntry adds a book from the tenant's catalog to a list.
+func (s *listService) AddEntry(tenantID uint, owner string, listID, bookID uint, position int) (*models.ReadingList, error) {
+	list, err := s.repo.FindByID(tenantID, owner, listID)
+	if err != nil {
+		return nil, err
+	}
+	if position < 0 {
+		return nil, fmt.Errorf("%w: position must not be negative", ErrInvalidInput)
+	}
+	if len(list.Entries) >= maxListEntries {
+		return nil, fmt.Errorf("%w: a list holds at most %d books", ErrInvalidInput, maxListEntries)
+	}
+	if _, err := s.books.FindByID(tenantID, bookID); errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: book %d does not exist", ErrInvalidInput, bookID)
+	} else if err != nil {
+		return nil, err
+	}
+	if err := s.repo.AddEntry(list.ID, bookID, position); err != nil {
+		return nil, conflictOnDuplicate(err, "the book is already on this list")
+	}
+	return s.repo.FindByID(tenantID, owner, listID)
+}
+
+// RemoveEntry removes a book from a list.
+func (s *listService) RemoveEntry(tenantID uint, owner string, listID, bookID uint) error {
+	list, err := s.repo.FindByID(tenantID, owner, listID)
+	if err != nil {
+		return err
+	}
+	return s.repo.RemoveEntry(list.ID, bookID)
+}
+
+// ReorderEntries reorders a list's books.
+func (s *listService) ReorderEntries(tenantID uint, owner string, listID uint, bookIDs []uint) (*models.ReadingList, error) {
+	list, err := s.repo.FindByID(tenantID, owner, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := make(map[uint]bool, len(list.Entries))
+	for _, entry := range list.Entries {
+		remaining[entry.BookID] = true
+	}
+	for _, bookID := range bookIDs {
+		if !remaining[bookID] {
+			return nil, fmt.Errorf("%w: book_ids must list each book on the list exactly once", ErrInvalidInput)
+		}
+		delete(remaining, bookID)
+	}
+	if len(remaining) > 0 {
+		return nil, fmt.Errorf("%w: book_ids must list each book on the list exactly once", ErrInvalidInput)
+	}
+
+	if err := s.repo.ReorderEntries(list.ID, bookIDs); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByID(tenantID, owner, listID)
+}
+
+// ShareList gives a list a random share token.
+func (s *listService) ShareList(tenantID uint, owner string, id uint) (*models.ReadingList, error) {
+	list, err := s.repo.FindByID(tenantID, owner, id)
+	if err != nil {
+		return nil, err
+	}
+	if list.ShareToken != nil {
+		return list, nil
+	}
+	token, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	list.ShareToken = &token
+	if err := s.repo.Update(list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// UnshareList removes a list's share token, invalidating its share link.
+func (s *listService) UnshareList(tenantID uint, owner string, id uint) error {
+	list, err := s.repo.FindByID(tenantID, owner, id)
+	if err != nil {
+		return err
+	}
+	if list.ShareToken == nil {
+		return nil
+	}
+	list.ShareToken = nil
+	return s.repo.Update(list)
+}
+
+// GetSharedList retrieves a shared list by its token.
+func (s *listService) GetSharedList(tenantID uint, token string) (*models.ReadingList, error) {
+	return s.repo.FindByShareToken(tenantID, token)
+}
+
+// validateList trims and validates a list's name and kind.
+func validateList(list *models.ReadingList) error {
+	list.Name = strings.TrimSpace(list.Name)
+	if list.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	if utf8.RuneCountInString(list.Name) > maxListNameLength {
+		return fmt.Errorf("%w: name must be at most %d characters", ErrInvalidInput, maxListNameLength)
+	}
+	for _, kind := range models.ListKinds {
+		if list.Kind == kind {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: kind must be one of %s", ErrInvalidInput, strings.Join(models.ListKinds, ", "))
+}
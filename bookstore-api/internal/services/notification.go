@@ -0,0 +1,54 @@
+package services
+
+import (
+	"time"
+
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+)
+
+// notificationLimit bounds the notifications returned by
+// ListNotifications.
+const notificationLimit = 100
+
+// NotificationService defines the interface for reading a user's in-app
+// notifications.
+type NotificationService interface {
+	// ListNotifications returns the owner's latest notifications, newest
+	// first, leaving out read ones when unreadOnly is set.
+	ListNotifications(tenantID uint, owner string, unreadOnly bool) ([]models.Notification, error)
+	// MarkRead marks one of the owner's notifications as read.
+	MarkRead(tenantID uint, owner string, id uint) (*models.Notification, error)
+}
+
+// notificationService implements NotificationService.
+type notificationService struct {
+	repo repositories.NotificationRepository
+}
+
+// NewNotificationService creates a new NotificationService with the given
+// repository.
+func NewNotificationService(repo repositories.NotificationRepository) NotificationService {
+	return &notificationService{repo: repo}
+}
+
+// ListNotifications retrieves the owner's latest notifications.
+func (s *notificationService) ListNotifications(tenantID uint, owner string, unreadOnly bool) ([]models.Notification, error) {
+	return s.repo.FindAll(tenantID, owner, unreadOnly, notificationLimit)
+}
+
+// MarkRead records that a notification was read. Marking it again keeps
+// the first time.
+func (s *notificationService) MarkRead(tenantID uint, owner string, id uint) (*models.Notification, error) {
+	notification, err := s.repo.FindByID(tenantID, owner, id)
+	if err != nil {
+		return nil, err
+	}
+	if notification.ReadAt != nil {
+		return notification, nil
+	}
+	if err := s.repo.MarkRead(notification, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+)
+
+// PricingService defines the interface for price history and schedule
+// business logic. Every method operates on the given tenant's books.
+type PricingService interface {
+	GetPriceHistory(tenantID, bookID uint) ([]models.PricePoint, error)
+	SchedulePrice(tenantID uint, schedule *models.PriceSchedule) error
+	ListSchedules(tenantID, bookID uint) ([]models.PriceSchedule, error)
+	CancelSchedule(tenantID, bookID, id uint) (*models.PriceSchedule, error)
+}
+
+// pricingService implements PricingService.
+type pricingService struct {
+	books  repositories.BookRepository
+	prices repositories.PriceRepository
+}
+
+// NewPricingService creates a new PricingService with the given
+// repositories.
+func NewPricingService(books repositories.BookRepository, prices repositories.PriceRepository) PricingService {
+	return &pricingService{books: books, prices: prices}
+}
+
+// GetPriceHistory retrieves a book's price points, newest first.
+func (s *pricingService) GetPriceHistory(tenantID, bookID uint) ([]models.PricePoint, error) {
+	if _, err := s.books.FindByID(tenantID, bookID); err != nil {
+		return nil, err
+	}
+	return s.prices.FindHistory(tenantID, bookID)
+}
+
+// SchedulePrice validates and stores a price change or sale. Sales of the
+// same book may not overlap.
+func (s *pricingService) SchedulePrice(tenantID uint, schedule *models.PriceSchedule) error {
+	if _, err := s.books.FindByID(tenantID, schedule.BookID); err != nil {
+		return err
+	}
+	if schedule.Price < 0 {
+		return fmt.Errorf("%w: price must not be negative", ErrInvalidInput)
+	}
+	if schedule.StartsAt.IsZero() {
+		return fmt.Errorf("%w: starts_at is required", ErrInvalidInput)
+	}
+	if schedule.EndsAt != nil {
+		if !schedule.EndsAt.After(schedule.StartsAt) {
+			return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidInput)
+		}
+		if !schedule.EndsAt.After(time.Now()) {
+			return fmt.Errorf("%w: ends_at must be in the future", ErrInvalidInput)
+		}
+		overlaps, err := s.prices.HasOverlappingSale(tenantID, schedule.BookID, schedule.StartsAt, *schedule.EndsAt)
+		if err != nil {
+			return err
+		}
+		if overlaps {
+			return fmt.Errorf("%w: the sale overlaps another sale of this book", ErrConflict)
+		}
+	}
+
+	schedule.TenantID = tenantID
+	schedule.Status = models.ScheduleScheduled
+	schedule.RevertPrice = nil
+	schedule.AppliedAt = nil
+	schedule.EndedAt = nil
+	return s.prices.CreateSchedule(schedule)
+}
+
+// ListSchedules retrieves a book's price schedules.
+func (s *pricingService) ListSchedules(tenantID, bookID uint) ([]models.PriceSchedule, error) {
+	if _, err := s.books.FindByID(tenantID, bookID); err != nil {
+		return nil, err
+	}
+	return s.prices.FindSchedules(tenantID, bookID)
+}
+
+// CancelSchedule cancels a schedule that has not started. A running sale
+// is ended now instead; the scheduler restores the price on its next poll.
+func (s *pricingService) CancelSchedule(tenantID, bookID, id uint) (*models.PriceSchedule, error) {
+	schedule, err := s.prices.FindScheduleByID(tenantID, bookID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch schedule.Status {
+	case models.ScheduleScheduled:
+		schedule.Status = models.ScheduleCancelled
+	case models.ScheduleActive:
+		now := time.Now()
+		schedule.EndsAt = &now
+	default:
+		return nil, fmt.Errorf("%w: schedule is already %s", ErrConflict, schedule.Status)
+	}
+
+	if err := s.prices.UpdateSchedule(schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
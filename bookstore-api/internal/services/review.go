@@ -0,0 +1,63 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+)
+
+// Review limits.
+const (
+	minReviewRating        = 1
+	maxReviewRating        = 5
+	maxReviewCommentLength = 2000
+)
+
+// ReviewService defines the interface for review business logic.
+type ReviewService interface {
+	// AddReview stores a review of a book in the tenant's catalog by the
+	// given reviewer, the subject of the caller's credentials.
+	AddReview(tenantID uint, reviewer string, review *models.Review) error
+	// ReviewsForBooks returns the reviews of each book, keyed by book ID.
+	ReviewsForBooks(tenantID uint, bookIDs []uint) (map[uint][]models.Review, error)
+}
+
+// reviewService implements ReviewService.
+type reviewService struct {
+	repo  repositories.ReviewRepository
+	books repositories.BookRepository
+}
+
+// NewReviewService creates a new ReviewService with the given repositories.
+func NewReviewService(repo repositories.ReviewRepository, books repositories.BookRepository) ReviewService {
+	return &reviewService{repo: repo, books: books}
+}
+
+// AddReview validates and stores a review.
+func (s *reviewService) AddReview(tenantID uint, reviewer string, review *models.Review) error {
+	review.ID = 0
+	review.TenantID = tenantID
+	review.Reviewer = reviewer
+	review.Comment = strings.TrimSpace(review.Comment)
+	if review.Rating < minReviewRating || review.Rating > maxReviewRating {
+		return fmt.Errorf("%w: rating must be between %d and %d", ErrInvalidInput, minReviewRating, maxReviewRating)
+	}
+	if utf8.RuneCountInString(review.Comment) > maxReviewCommentLength {
+		return fmt.Errorf("%w: comment must be at most %d characters", ErrInvalidInput, maxReviewCommentLength)
+	}
+	if _, err := s.books.FindByID(tenantID, review.BookID); errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%w: book %d does not exist", ErrInvalidInput, review.BookID)
+	} else if err != nil {
+		return err
+	}
+	return conflictOnDuplicate(s.repo.Create(review), "the book has already been reviewed by this user")
+}
+
+// ReviewsForBooks retrieves the reviews of several books.
+func (s *reviewService) ReviewsForBooks(tenantID uint, bookIDs []uint) (map[uint][]models.Review, error) {
+	return s.repo.FindByBooks(tenantID, bookIDs)
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+)
+
+// hostCacheTTL bounds how long a host-to-tenant lookup is reused.
+const hostCacheTTL = time.Minute
+
+// slugPattern is the accepted form of a tenant slug.
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// TenantService defines the interface for tenant business logic.
+type TenantService interface {
+	CreateTenant(tenant *models.Tenant) error
+	ListTenants() ([]models.Tenant, error)
+	GetTenant(id uint) (*models.Tenant, error)
+	UpdateTenant(tenant *models.Tenant) error
+	DeleteTenant(id uint) error
+	// ResolveHost returns the tenant whose domain is host.
+	ResolveHost(host string) (uint, bool)
+}
+
+// hostEntry is a cached host lookup; tenantID is 0 for unknown hosts.
+type hostEntry struct {
+	tenantID uint
+	expires  time.Time
+}
+
+// tenantService implements TenantService.
+type tenantService struct {
+	repo repositories.TenantRepository
+
+	mu    sync.RWMutex
+	hosts map[string]hostEntry
+}
+
+// NewTenantService creates a new TenantService with the given repository.
+func NewTenantService(repo repositories.TenantRepository) TenantService {
+	return &tenantService{repo: repo, hosts: make(map[string]hostEntry)}
+}
+
+// CreateTenant validates and stores a new tenant.
+func (s *tenantService) CreateTenant(tenant *models.Tenant) error {
+	if err := normalizeTenant(tenant); err != nil {
+		return err
+	}
+	defer s.flushHosts()
+	return conflictOnDuplicate(s.repo.Create(tenant), "slug or domain is already in use")
+}
+
+// ListTenants retrieves all tenants.
+func (s *tenantService) ListTenants() ([]models.Tenant, error) {
+	return s.repo.FindAll()
+}
+
+// GetTenant retrieves a tenant by its ID.
+func (s *tenantService) GetTenant(id uint) (*models.Tenant, error) {
+	return s.repo.FindByID(id)
+}
+
+// UpdateTenant replaces an existing tenant's slug, name and domain.
+func (s *tenantService) UpdateTenant(tenant *models.Tenant) error {
+	existing, err := s.repo.FindByID(tenant.ID)
+	if err != nil {
+		return err
+	}
+	if err := normalizeTenant(tenant); err != nil {
+		return err
+	}
+	tenant.CreatedAt = existing.CreatedAt
+	defer s.flushHosts()
+	return conflictOnDuplicate(s.repo.Update(tenant), "slug or domain is already in use")
+}
+
+// DeleteTenant removes a tenant whose catalog is empty. The default tenant
+// cannot be deleted.
+func (s *tenantService) DeleteTenant(id uint) error {
+	if id == models.DefaultTenantID {
+		return fmt.Errorf("%w: the default tenant cannot be deleted", ErrConflict)
+	}
+	if _, err := s.repo.FindByID(id); err != nil {
+		return err
+	}
+	books, err := s.repo.CountBooks(id)
+	if err != nil {
+		return err
+	}
+	if books > 0 {
+		return fmt.Errorf("%w: tenant still has %d books", ErrConflict, books)
+	}
+	defer s.flushHosts()
+	return s.repo.Delete(id)
+}
+
+// ResolveHost looks up the tenant for a host, caching hits and misses.
+func (s *tenantService) ResolveHost(host string) (uint, bool) {
+	now := time.Now()
+	s.mu.RLock()
+	entry, ok := s.hosts[host]
+	s.mu.RUnlock()
+	if ok && now.Before(entry.expires) {
+		return entry.tenantID, entry.tenantID != 0
+	}
+
+	entry = hostEntry{expires: now.Add(hostCacheTTL)}
+	tenant, err := s.repo.FindByDomain(host)
+	switch {
+	case err == nil:
+		entry.tenantID = tenant.ID
+	case !errors.Is(err, ErrNotFound):
+		// Do not cache lookup failures.
+		return 0, false
+	}
+
+	s.mu.Lock()
+	s.hosts[host] = entry
+	s.mu.Unlock()
+	return entry.tenantID, entry.tenantID != 0
+}
+
+// flushHosts discards cached host lookups after a tenant changes.
+func (s *tenantService) flushHosts() {
+	s.mu.Lock()
+	s.hosts = make(map[string]hostEntry)
+	s.mu.Unlock()
+}
+
+// normalizeTenant trims and validates a tenant's fields. An empty domain
+// is stored as NULL.
+func normalizeTenant(tenant *models.Tenant) error {
+	tenant.Slug = strings.TrimSpace(tenant.Slug)
+	tenant.Name = strings.TrimSpace(tenant.Name)
+	if !slugPattern.MatchString(tenant.Slug) {
+		return fmt.Errorf("%w: slug must be 1-63 lowercase letters, digits or hyphens", ErrInvalidInput)
+	}
+	if tenant.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	if tenant.Domain != nil {
+		domain := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(*tenant.Domain), "."))
+		if domain == "" {
+			tenant.Domain = nil
+		} else if strings.ContainsAny(domain, ":/ ") {
+			return fmt.Errorf("%w: domain must be a bare host name", ErrInvalidInput)
+		} else {
+			tenant.Domain = &domain
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+	"bookstore-api/internal/webhooks"
+)
+
+// WebhookService defines the interface for webhook business logic. Every
+// method operates on the subscriptions of the given tenant.
+type WebhookService interface {
+	Subscribe(tenantID uint, sub *models.WebhookSubscription) error
+	ListSubscriptions(tenantID uint) ([]models.WebhookSubscription, error)
+	Unsubscribe(tenantID, id uint) error
+	ListDeliveries(tenantID, subscriptionID uint, status string) ([]models.WebhookDelivery, error)
+	ReplayDelivery(tenantID, id uint) (*models.WebhookDelivery, error)
+}
+
+// webhookService implements WebhookService.
+type webhookService struct {
+	repo         repositories.WebhookRepository
+	allowPrivate bool
+}
+
+// NewWebhookService creates a new WebhookService with the given repository.
+// Unless allowPrivate is set, subscriptions to loopback, private and
+// link-local addresses are refused.
+func NewWebhookService(repo repositories.WebhookRepository, allowPrivate bool) WebhookService {
+	return &webhookService{repo: repo, allowPrivate: allowPrivate}
+}
+
+// Subscribe validates and stores a new subscription. A random secret is
+// generated when none is supplied.
+func (s *webhookService) Subscribe(tenantID uint, sub *models.WebhookSubscription) error {
+	target, err := url.Parse(sub.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidInput)
+	}
+	if !s.allowPrivate {
+		if err := webhooks.CheckHost(target.Hostname()); err != nil {
+			return fmt.Errorf("%w: url %v", ErrInvalidInput, err)
+		}
+	}
+	if len(sub.EventTypes) == 0 {
+		return fmt.Errorf("%w: at least one event type is required", ErrInvalidInput)
+	}
+	for _, eventType := range sub.EventTypes {
+		if !isEventType(eventType) {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidInput, eventType)
+		}
+	}
+
+	if sub.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return err
+		}
+		sub.Secret = hex.EncodeToString(secret)
+	}
+	sub.TenantID = tenantID
+	sub.Active = true
+
+	return s.repo.CreateSubscription(sub)
+}
+
+// ListSubscriptions retrieves all subscriptions.
+func (s *webhookService) ListSubscriptions(tenantID uint) ([]models.WebhookSubscription, error) {
+	return s.repo.FindSubscriptions(tenantID)
+}
+
+// Unsubscribe deletes a subscription.
+func (s *webhookService) Unsubscribe(tenantID, id uint) error {
+	if _, err := s.repo.FindSubscriptionByID(tenantID, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteSubscription(id)
+}
+
+// ListDeliveries retrieves a subscription's deliveries.
+func (s *webhookService) ListDeliveries(tenantID, subscriptionID uint, status string) ([]models.WebhookDelivery, error) {
+	if _, err := s.repo.FindSubscriptionByID(tenantID, subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindDeliveries(subscriptionID, status)
+}
+
+// ReplayDelivery queues a finished or dead-lettered delivery to be sent
+// again with a fresh retry budget.
+func (s *webhookService) ReplayDelivery(tenantID, id uint) (*models.WebhookDelivery, error) {
+	delivery, err := s.repo.FindDeliveryByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Subscription.TenantID != tenantID {
+		return nil, ErrNotFound
+	}
+	if delivery.Status == models.DeliveryPending {
+		return nil, fmt.Errorf("%w: delivery is already pending", ErrConflict)
+	}
+
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	delivery.LastError = ""
+	if err := s.repo.UpdateDelivery(delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// isEventType reports whether t is a known webhook event type.
+func isEventType(t string) bool {
+	for _, known := range models.EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
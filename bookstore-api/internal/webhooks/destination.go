@@ -0,0 +1,55 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+// ErrForbiddenDestination is returned for webhook destinations on internal
+// networks.
+var ErrForbiddenDestination = errors.New("destination is a loopback, private or link-local address")
+
+// forbiddenAddr reports whether deliveries to ip could reach internal
+// services: loopback, private (RFC 1918 and fc00::/7), link-local,
+// unspecified and multicast addresses.
+func forbiddenAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// CheckHost rejects webhook hosts that are forbidden addresses or
+// localhost names. Other names are checked when deliveries dial them, since
+// what they resolve to can change after the subscription is saved.
+func CheckHost(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrForbiddenDestination
+	}
+	if ip, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil && forbiddenAddr(ip) {
+		return ErrForbiddenDestination
+	}
+	return nil
+}
+
+// checkDial is a net.Dialer Control function that refuses connections to
+// forbidden addresses. It runs after name resolution, for every address
+// tried, including those of redirect targets.
+func checkDial(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if forbiddenAddr(ip) {
+		return fmt.Errorf("dial %s: %w", address, ErrForbiddenDestination)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bookstore-api/internal/config"
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+)
+
+// Headers sent with every webhook delivery.
+const (
+	SignatureHeader = "X-Bookstore-Signature"
+	TimestampHeader = "X-Bookstore-Timestamp"
+	EventHeader     = "X-Bookstore-Event"
+	DeliveryHeader  = "X-Bookstore-Delivery"
+)
+
+// batchSize bounds the events and deliveries handled per poll.
+const batchSize = 50
+
+// Dispatcher moves outbox events into deliveries and sends them to
+// subscribers. Only one dispatcher should run per database.
+type Dispatcher struct {
+	repo         repositories.WebhookRepository
+	client       *http.Client
+	pollInterval time.Duration
+	maxAttempts  int
+	baseBackoff  time.Duration
+	maxBackoff   time.Duration
+}
+
+// NewDispatcher creates a new Dispatcher with the given polling and retry
+// settings. Unless cfg.AllowPrivateTargets is set, deliveries refuse to
+// connect to loopback, private and link-local addresses.
+func NewDispatcher(repo repositories.WebhookRepository, cfg config.WebhookConfig) *Dispatcher {
+	dialer := &net.Dialer{Timeout: cfg.RequestTimeout}
+	if !cfg.AllowPrivateTargets {
+		dialer.Control = checkDial
+	}
+	// No proxy: it would dial the destination on our behalf, unchecked.
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: cfg.RequestTimeout,
+	}
+	return &Dispatcher{
+		repo:         repo,
+		client:       &http.Client{Timeout: cfg.RequestTimeout, Transport: transport},
+		pollInterval: cfg.PollInterval,
+		maxAttempts:  cfg.MaxAttempts,
+		baseBackoff:  cfg.BaseBackoff,
+		maxBackoff:   cfg.MaxBackoff,
+	}
+}
+
+// Sign returns the signature header value for a payload sent at the given
+// Unix time: "sha256=" followed by the hex HMAC-SHA256, keyed by secret, of
+// the timestamp, a period and the body. Receivers should check the
+// timestamp is recent to refuse replayed requests.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run polls for work until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick fans out new events and sends every due delivery once.
+func (d *Dispatcher) tick(ctx context.Context) {
+	if _, err := d.repo.FanOut(batchSize); err != nil {
+		log.Printf("webhooks: fan-out failed: %v", err)
+		return
+	}
+
+	deliveries, err := d.repo.FindDueDeliveries(time.Now(), batchSize)
+	if err != nil {
+		log.Printf("webhooks: loading deliveries failed: %v", err)
+		return
+	}
+
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		d.deliver(ctx, &deliveries[i])
+	}
+}
+
+// deliver attempts one delivery and records the outcome, scheduling a
+// retry with exponential backoff or moving it to the dead-letter state.
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	delivery.Attempts++
+	statusCode, err := d.send(ctx, delivery)
+	delivery.LastStatusCode = statusCode
+
+	switch {
+	case err == nil:
+		now := time.Now()
+		delivery.Status = models.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= d.maxAttempts:
+		delivery.Status = models.DeliveryDead
+		delivery.LastError = err.Error()
+	default:
+		delivery.NextAttemptAt = time.Now().Add(d.backoff(delivery.Attempts))
+		delivery.LastError = err.Error()
+	}
+
+	if err := d.repo.UpdateDelivery(delivery); err != nil {
+		log.Printf("webhooks: saving delivery %d failed: %v", delivery.ID, err)
+	}
+}
+
+// send POSTs the signed payload. Any non-2xx response is an error.
+func (d *Dispatcher) send(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Subscription.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	timestamp := time.Now().Unix()
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Subscription.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the next attempt: the base delay doubled
+// for every failed attempt, capped at the maximum.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.baseBackoff
+	for i := 1; i < attempts && delay < d.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.maxBackoff {
+		delay = d.maxBackoff
+	}
+	return delay
+}
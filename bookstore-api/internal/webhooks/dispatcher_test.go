@@ -0,0 +1,261 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"bookstore-api/internal/config"
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+	"bookstore-api/internal/testutil"
+)
+
+// receiver records webhook requests and answers with a fixed status.
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   []string
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, string(body))
+	w.WriteHeader(r.status)
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// newDelivery subscribes url to book.created events and queues one event,
+// returning the repository and the dispatcher, which retries immediately.
+func newDelivery(t *testing.T, url string, cfg config.WebhookConfig) (repositories.WebhookRepository, *Dispatcher) {
+	t.Helper()
+	db := testutil.NewDB(t)
+	repo := repositories.NewGormWebhookRepository(db)
+	sub := models.WebhookSubscription{
+		TenantID:   models.DefaultTenantID,
+		URL:        url,
+		Secret:     "top-secret",
+		EventTypes: []string{models.EventBookCreated},
+		Active:     true,
+	}
+	if err := repo.CreateSubscription(&sub); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	event := models.OutboxEvent{TenantID: models.DefaultTenantID, EventType: models.EventBookCreated, Payload: `{"id":1}`}
+	if err := db.Create(&event).Error; err != nil {
+		t.Fatalf("create event: %v", err)
+	}
+	cfg.RequestTimeout = 5 * time.Second
+	return repo, NewDispatcher(repo, cfg)
+}
+
+// onlyDelivery returns the single delivery in the store.
+func onlyDelivery(t *testing.T, repo repositories.WebhookRepository) *models.WebhookDelivery {
+	t.Helper()
+	delivery, err := repo.FindDeliveryByID(1)
+	if err != nil {
+		t.Fatalf("FindDeliveryByID: %v", err)
+	}
+	return delivery
+}
+
+func TestSign(t *testing.T) {
+	// HMAC-SHA256 of `1700000000.{"id":1}` keyed by "top-secret".
+	const want = "sha256=2ca7de22d7ca0178b18fe3528ebb2276d93d7df65941772033bedaa1ce859a88"
+	got := Sign("top-secret", 1700000000, []byte(`{"id":1}`))
+	if got != want {
+		t.Fatalf("Sign = %q, want %q", got, want)
+	}
+	if got == Sign("top-secret", 1700000001, []byte(`{"id":1}`)) {
+		t.Error("signature does not depend on the timestamp")
+	}
+}
+
+func TestDeliverySignsTimestampAndBody(t *testing.T) {
+	rec := &receiver{status: http.StatusNoContent}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	repo, d := newDelivery(t, server.URL, config.WebhookConfig{MaxAttempts: 3, AllowPrivateTargets: true})
+
+	d.tick(context.Background())
+	if rec.count() != 1 {
+		t.Fatalf("got %d requests, want 1", rec.count())
+	}
+	req := rec.requests[0]
+	timestamp, err := strconv.ParseInt(req.Header.Get(TimestampHeader), 10, 64)
+	if err != nil || time.Since(time.Unix(timestamp, 0)) > time.Minute {
+		t.Fatalf("%s = %q, want the current Unix time", TimestampHeader, req.Header.Get(TimestampHeader))
+	}
+	if got, want := req.Header.Get(SignatureHeader), Sign("top-secret", timestamp, []byte(rec.bodies[0])); got != want {
+		t.Errorf("%s = %q, want %q", SignatureHeader, got, want)
+	}
+	if got := req.Header.Get(EventHeader); got != models.EventBookCreated {
+		t.Errorf("%s = %q", EventHeader, got)
+	}
+	if got := req.Header.Get(DeliveryHeader); got != "1" {
+		t.Errorf("%s = %q, want 1", DeliveryHeader, got)
+	}
+	if delivery := onlyDelivery(t, repo); delivery.Status != models.DeliveryDelivered || delivery.DeliveredAt == nil {
+		t.Errorf("delivery = %s, want delivered", delivery.Status)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{baseBackoff: 30 * time.Second, maxBackoff: 5 * time.Minute}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{5, 5 * time.Minute},
+		{50, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestFailedDeliveriesRetryThenDeadLetter(t *testing.T) {
+	rec := &receiver{status: http.StatusBadGateway}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	repo, d := newDelivery(t, server.URL, config.WebhookConfig{
+		MaxAttempts: 3, BaseBackoff: time.Hour, MaxBackoff: time.Hour, AllowPrivateTargets: true,
+	})
+
+	d.tick(context.Background())
+	delivery := onlyDelivery(t, repo)
+	if delivery.Status != models.DeliveryPending || delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusBadGateway {
+		t.Fatalf("after one failure: %s, %d attempts, status %d", delivery.Status, delivery.Attempts, delivery.LastStatusCode)
+	}
+	if wait := time.Until(delivery.NextAttemptAt); wait < 59*time.Minute {
+		t.Errorf("next attempt in %v, want the one hour backoff", wait)
+	}
+
+	// The retry is not due yet.
+	d.tick(context.Background())
+	if rec.count() != 1 {
+		t.Fatalf("retried before the backoff elapsed: %d requests", rec.count())
+	}
+
+	for attempt := 2; attempt <= 3; attempt++ {
+		delivery.NextAttemptAt = time.Now().Add(-time.Second)
+		if err := repo.UpdateDelivery(delivery); err != nil {
+			t.Fatalf("UpdateDelivery: %v", err)
+		}
+		d.tick(context.Background())
+		delivery = onlyDelivery(t, repo)
+	}
+	if delivery.Status != models.DeliveryDead || delivery.Attempts != 3 || delivery.LastError != "unexpected status 502" {
+		t.Fatalf("after the last attempt: %s, %d attempts, error %q; want dead after 3", delivery.Status, delivery.Attempts, delivery.LastError)
+	}
+
+	// Replaying, as the webhook service does, sends it again.
+	rec.mu.Lock()
+	rec.status = http.StatusOK
+	rec.mu.Unlock()
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	if err := repo.UpdateDelivery(delivery); err != nil {
+		t.Fatalf("UpdateDelivery: %v", err)
+	}
+	d.tick(context.Background())
+	if delivery = onlyDelivery(t, repo); delivery.Status != models.DeliveryDelivered || delivery.Attempts != 1 {
+		t.Errorf("after replay: %s, %d attempts, want delivered on the first", delivery.Status, delivery.Attempts)
+	}
+	if rec.count() != 4 {
+		t.Errorf("got %d requests, want 4", rec.count())
+	}
+}
+
+func TestDeliveriesRefusePrivateAddresses(t *testing.T) {
+	rec := &receiver{status: http.StatusOK}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	repo, d := newDelivery(t, server.URL, config.WebhookConfig{MaxAttempts: 1})
+
+	d.tick(context.Background())
+	if rec.count() != 0 {
+		t.Fatal("the delivery reached a loopback address")
+	}
+	delivery := onlyDelivery(t, repo)
+	if delivery.Status != models.DeliveryDead || !strings.Contains(delivery.LastError, ErrForbiddenDestination.Error()) {
+		t.Errorf("delivery = %s with error %q, want dead with the forbidden destination error", delivery.Status, delivery.LastError)
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	tests := []struct {
+		host    string
+		allowed bool
+	}{
+		{"example.com", true},
+		{"93.184.216.34", true},
+		{"2606:2800:220:1::", true},
+		{"localhost", false},
+		{"LOCALHOST.", false},
+		{"api.localhost", false},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"[::1]", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::ffff:10.0.0.1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		err := CheckHost(tt.host)
+		if allowed := err == nil; allowed != tt.allowed {
+			t.Errorf("CheckHost(%q) = %v, want allowed %v", tt.host, err, tt.allowed)
+		}
+		if err != nil && !errors.Is(err, ErrForbiddenDestination) {
+			t.Errorf("CheckHost(%q) = %v, want ErrForbiddenDestination", tt.host, err)
+		}
+	}
+}
+
+func TestCheckDial(t *testing.T) {
+	for address, allowed := range map[string]bool{
+		"93.184.216.34:443":  true,
+		"127.0.0.1:80":       false,
+		"[::1]:80":           false,
+		"10.0.0.8:8080":      false,
+		"169.254.169.254:80": false,
+	} {
+		err := checkDial("tcp", address, nil)
+		if (err == nil) != allowed {
+			t.Errorf("checkDial(%q) = %v, want allowed %v", address, err, allowed)
+		}
+	}
+	if !forbiddenAddr(netip.MustParseAddr("::ffff:127.0.0.1")) {
+		t.Error("IPv4-mapped loopback is allowed")
+	}
+}
@@ -0,0 +1,51 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"time"
+
+	"bookstore-api/internal/models"
+	"bookstore-api/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// Payload is the JSON body POSTed to webhook subscribers.
+type Payload struct {
+	Type       string       `json:"type"`
+	OccurredAt time.Time    `json:"occurred_at"`
+	Book       models.Book  `json:"book"`
+	Previous   *models.Book `json:"previous,omitempty"`
+}
+
+// RecordOutbox is a repositories.ChangeHook that writes the events for a
+// book change to the outbox inside the mutation's transaction.
+func RecordOutbox(tx *gorm.DB, change repositories.BookChange) error {
+	now := time.Now().UTC()
+
+	var payloads []Payload
+	switch change.Type {
+	case repositories.ChangeCreated:
+		payloads = append(payloads, Payload{Type: models.EventBookCreated, Book: *change.After})
+	case repositories.ChangeUpdated:
+		payloads = append(payloads, Payload{Type: models.EventBookUpdated, Book: *change.After, Previous: change.Before})
+		if change.Before.Price != change.After.Price {
+			payloads = append(payloads, Payload{Type: models.EventBookRepriced, Book: *change.After, Previous: change.Before})
+		}
+	case repositories.ChangeDeleted:
+		payloads = append(payloads, Payload{Type: models.EventBookDeleted, Book: *change.Before})
+	}
+
+	for _, payload := range payloads {
+		payload.OccurredAt = now
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		event := models.OutboxEvent{TenantID: payload.Book.TenantID, EventType: payload.Type, Payload: string(body)}
+		if err := tx.Create(&event).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
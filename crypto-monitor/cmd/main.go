@@ -7,11 +7,22 @@ import (
 	"os/signal"
 	"syscall"
 
+	"crypto-monitor/internal/aggregate"
 	"crypto-monitor/internal/alerts"
 	"crypto-monitor/internal/binance"
 	"crypto-monitor/internal/cache"
+	"crypto-monitor/internal/coinbase"
 	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/feed"
+	"crypto-monitor/internal/history"
+	"crypto-monitor/internal/incidents"
+	"crypto-monitor/internal/jsonws"
+	"crypto-monitor/internal/kraken"
+	"crypto-monitor/internal/notify"
+	"crypto-monitor/internal/rules"
 	"crypto-monitor/internal/wsserver"
+
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
@@ -25,26 +36,189 @@ func main() {
 
 	redisClient := cache.NewRedisClient(config.RedisAddr, config.RedisPassword, config.RedisDB)
 	cacheStore := cache.NewRedisCache(redisClient)
-	binanceClient := binance.NewClient()
-	alertEngine := alerts.NewEngine(cacheStore, config.AlertThresholdPct)
-	wsServer := wsserver.NewServer(config.InternalWsAddr)
+	sources := newPriceSources(config)
+	aggregator := aggregate.New(aggregate.Config{
+		Method:         config.AggregateMethod,
+		StaleAfter:     config.StaleAfter,
+		OutlierPct:     config.OutlierPct,
+		SpreadAlertBps: config.SpreadAlertBps,
+	})
+	windows := make([]alerts.Window, len(config.AlertWindows))
+	for i, window := range config.AlertWindows {
+		windows[i] = alerts.Window{Span: window.Span, ThresholdPct: window.ThresholdPct}
+	}
+	alertEngine := alerts.NewEngine(cacheStore, windows, config.AlertHysteresis)
+	ruleEngine := rules.NewEngine()
+	if config.RulesFile != "" {
+		loaded, err := rules.Load(config.RulesFile)
+		if err != nil {
+			log.Fatalf("rules error: %v", err)
+		}
+		ruleEngine.SetRules(loaded)
+		log.Printf("loaded %d rules from %s", len(loaded), config.RulesFile)
+
+		// SIGHUP reloads the rules file at once.
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+		go logErrors(ctx, "rules", rules.Watch(ctx, config.RulesFile, config.RulesReload, reload, ruleEngine))
+	}
+
+	var tokens []wsserver.Token
+	if config.WsTokensFile != "" {
+		tokens, err = wsserver.LoadTokens(config.WsTokensFile)
+		if err != nil {
+			log.Fatalf("tokens error: %v", err)
+		}
+		log.Printf("loaded %d WebSocket tokens from %s", len(tokens), config.WsTokensFile)
+	}
+
+	var dispatcher *notify.Dispatcher
+	if config.NotifyFile != "" {
+		dispatcher, err = notify.Load(config.NotifyFile, notify.Retry{
+			Attempts:   config.NotifyAttempts,
+			Backoff:    config.NotifyBackoff,
+			MaxBackoff: config.NotifyMaxBackoff,
+		})
+		if err != nil {
+			log.Fatalf("notify error: %v", err)
+		}
+		log.Printf("loaded notifiers from %s", config.NotifyFile)
+	}
 
-	prices, priceErrs := binanceClient.StreamTickers(ctx, config.Symbols)
-	alertStream, alertErrs := alertEngine.Start(ctx, prices)
+	alertHistory, err := newHistory(config, redisClient)
+	if err != nil {
+		log.Fatalf("history error: %v", err)
+	}
+
+	tracker := incidents.New(incidents.Config{
+		Cooldown:    config.AlertCooldown,
+		WarningPct:  config.WarningPct,
+		CriticalPct: config.CriticalPct,
+	})
+	wsServer := wsserver.NewServer(config.InternalWsAddr, wsserver.Options{
+		QueueSize:          config.WsQueueSize,
+		SlowConsumerPolicy: config.WsSlowPolicy,
+		PingInterval:       config.WsPingInterval,
+		WriteTimeout:       config.WsWriteTimeout,
+		MaxPriceRate:       config.PriceStreamRate,
+		Tokens:             tokens,
+		AllowedOrigins:     config.WsAllowedOrigins,
+		ReplayLimit:        config.WsReplayLimit,
+	})
+	wsServer.HandleFunc("GET /alert-groups", tracker.ServeGroups(wsserver.PermittedSymbols))
+	wsServer.SetSnapshot(func(ctx context.Context) (wsserver.Snapshot, error) {
+		return snapshot(ctx, cacheStore, config.Symbols, tracker)
+	})
+	wsServer.HandleFunc("POST /alert-groups/{id}/ack", tracker.ServeAcknowledge(wsserver.PermittedSymbols))
+	wsServer.HandleFunc("GET /prices", cache.ServePrices(cacheStore, config.Symbols, wsserver.PermittedSymbols))
+	if alertHistory != nil {
+		wsServer.SetHistory(alertHistory)
+		wsServer.HandleFunc("GET /alerts", history.ServeAlerts(alertHistory, wsserver.PermittedSymbols))
+	}
+
+	venuePrices, priceErrs := feed.Merge(ctx, config.Symbols, sources...)
+	venueTee := feed.Tee(ctx, venuePrices, 2)
+	prices, spreadAlerts, aggregateErrs := aggregator.Start(ctx, venueTee[0])
+	tee := feed.Tee(ctx, prices, 3)
+	priceAlerts, alertErrs := alertEngine.Start(ctx, tee[0])
+	ruleAlerts := ruleEngine.Start(ctx, tee[1])
+	alertStream := tracker.Start(ctx, alerts.Merge(ctx, priceAlerts, spreadAlerts, ruleAlerts))
+
+	if alertHistory != nil {
+		var historyErrs <-chan error
+		alertStream, historyErrs = history.Record(ctx, alertHistory, alertStream)
+		go logErrors(ctx, "history", historyErrs)
+	}
+	if dispatcher != nil {
+		alertTee := alerts.Tee(ctx, alertStream, 2)
+		alertStream = alertTee[0]
+		go logErrors(ctx, "notify", dispatcher.Start(ctx, alertTee[1]))
+	}
 
 	go wsServer.Broadcast(ctx, alertStream)
-	go logErrors(ctx, "binance", priceErrs)
+	go wsServer.BroadcastPrices(ctx, venueTee[1])
+	go wsServer.BroadcastPrices(ctx, tee[2])
+	go logErrors(ctx, "feed", priceErrs)
+	go logErrors(ctx, "aggregate", aggregateErrs)
 	go logErrors(ctx, "alerts", alertErrs)
 
 	if err := wsServer.Run(ctx); err != nil {
 		log.Printf("ws server error: %v", err)
 	}
 
+	if alertHistory != nil {
+		if err := alertHistory.Close(); err != nil {
+			log.Printf("history close error: %v", err)
+		}
+	}
 	if err := redisClient.Close(); err != nil {
 		log.Printf("redis close error: %v", err)
 	}
 }
 
+// newPriceSources builds the price sources selected in PRICE_SOURCES.
+func newPriceSources(cfg config.Config) []feed.PriceSource {
+	sources := make([]feed.PriceSource, 0, len(cfg.PriceSources))
+	for _, name := range cfg.PriceSources {
+		switch name {
+		case config.SourceBinance:
+			sources = append(sources, binance.NewClient())
+		case config.SourceCoinbase:
+			sources = append(sources, coinbase.NewClient(cfg.CoinbaseURL, cfg.CoinbaseProducts))
+		case config.SourceKraken:
+			sources = append(sources, kraken.NewClient(cfg.KrakenURL, cfg.KrakenPairs))
+		case config.SourceGeneric:
+			generic := cfg.GenericFeed
+			sources = append(sources, jsonws.NewClient(jsonws.Config{
+				Name:        generic.Name,
+				URL:         generic.URL,
+				Subscribe:   generic.Subscribe,
+				Symbols:     generic.Symbols,
+				SymbolField: generic.SymbolField,
+				PriceField:  generic.PriceField,
+				TimeField:   generic.TimeField,
+				VolumeField: generic.VolumeField,
+			}))
+		}
+	}
+	return sources
+}
+
+// newHistory opens the alert history selected in HISTORY_STORE, or returns
+// nil when it is disabled.
+func newHistory(cfg config.Config, redisClient *redis.Client) (history.Store, error) {
+	switch cfg.HistoryStore {
+	case config.HistoryRedis:
+		return history.NewRedisStore(redisClient, cfg.HistoryRedisKey, cfg.HistoryMaxAlerts), nil
+	case config.HistorySQLite:
+		store, err := history.OpenSQLite(cfg.HistorySQLitePath, cfg.HistoryMaxAlerts)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+	return nil, nil
+}
+
+// snapshot collects the cached price of each symbol and the latest alert
+// of each open alert group.
+func snapshot(ctx context.Context, store cache.Cache, symbols []string, tracker *incidents.Tracker) (wsserver.Snapshot, error) {
+	var snap wsserver.Snapshot
+	for _, symbol := range symbols {
+		price, ok, err := store.GetPrice(ctx, symbol)
+		if err != nil {
+			return wsserver.Snapshot{}, err
+		}
+		if ok {
+			snap.Prices = append(snap.Prices, price)
+		}
+	}
+	for _, group := range tracker.Groups() {
+		snap.Alerts = append(snap.Alerts, group.Latest)
+	}
+	return snap, nil
+}
+
 func logErrors(ctx context.Context, source string, errs <-chan error) {
 	for {
 		select {
@@ -0,0 +1,305 @@
+// Package aggregate combines the latest prices from several venues into one
+// reference price per symbol and watches the spread between them.
+package aggregate
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"crypto-monitor/internal/alerts"
+	"crypto-monitor/internal/feed"
+)
+
+// Source is the source name of reference price events.
+const Source = "aggregate"
+
+// Reference price methods.
+const (
+	MethodMedian = "median"
+	MethodVWAP   = "vwap"
+)
+
+// Config controls how venues are combined.
+type Config struct {
+	// Method is MethodMedian or MethodVWAP. VWAP weights venues by the
+	// volume they report and falls back to the median when none do.
+	Method string
+	// StaleAfter drops a venue that has not updated a symbol for longer.
+	StaleAfter time.Duration
+	// OutlierPct drops a venue whose price is further than this percentage
+	// from the median of the fresh venues. It needs at least three venues
+	// to tell which one is off; zero disables it.
+	OutlierPct float64
+	// SpreadAlertBps raises a spread alert when the dearest and cheapest
+	// fresh venues are this many basis points apart; zero disables it.
+	SpreadAlertBps float64
+}
+
+// quote is a venue's latest price for a symbol.
+type quote struct {
+	source   string
+	price    float64
+	volume   float64
+	received time.Time
+	// excluded is why the venue was left out of the last reference price:
+	// excludedStale, excludedOutlier or empty.
+	excluded string
+}
+
+// Reasons a venue is left out of the reference price.
+const (
+	excludedStale   = "stale"
+	excludedOutlier = "outlier"
+)
+
+// exclusion is why a venue is left out, with details for the notice.
+type exclusion struct {
+	reason string
+	detail string
+}
+
+// Aggregator turns per-venue price events into reference price events and
+// spread alerts.
+type Aggregator struct {
+	config Config
+	now    func() time.Time
+	quotes map[string]map[string]*quote
+	// spreadOpen marks symbols whose spread is above the threshold, so one
+	// excursion raises one alert.
+	spreadOpen map[string]bool
+}
+
+// New builds an aggregator.
+func New(config Config) *Aggregator {
+	return &Aggregator{
+		config:     config,
+		now:        time.Now,
+		quotes:     make(map[string]map[string]*quote),
+		spreadOpen: make(map[string]bool),
+	}
+}
+
+// Start consumes venue price events and emits a reference price event for
+// the symbol after each one, spread alerts, and a notice on the error
+// stream whenever a venue is dropped or taken back.
+func (a *Aggregator) Start(ctx context.Context, prices <-chan feed.PriceEvent) (<-chan feed.PriceEvent, <-chan alerts.Alert, <-chan error) {
+	out := make(chan feed.PriceEvent, 32)
+	alertCh := make(chan alerts.Alert, 32)
+	errCh := make(chan error, 8)
+
+	go func() {
+		defer close(out)
+		defer close(alertCh)
+		defer close(errCh)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-prices:
+				if !ok {
+					return
+				}
+
+				reference, alert, notices := a.update(event)
+				for _, notice := range notices {
+					select {
+					case errCh <- notice:
+					default:
+						// Notices are advisory; drop them rather than stall
+						// prices.
+					}
+				}
+				if alert != nil {
+					select {
+					case <-ctx.Done():
+						return
+					case alertCh <- *alert:
+					}
+				}
+				if reference == nil {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case out <- *reference:
+				}
+			}
+		}
+	}()
+
+	return out, alertCh, errCh
+}
+
+// update records event and recomputes its symbol. It returns nil for the
+// reference when no venue is usable.
+func (a *Aggregator) update(event feed.PriceEvent) (*feed.PriceEvent, *alerts.Alert, []error) {
+	now := a.now()
+	venues := a.quotes[event.Symbol]
+	if venues == nil {
+		venues = make(map[string]*quote)
+		a.quotes[event.Symbol] = venues
+	}
+	q := venues[event.Source]
+	if q == nil {
+		q = &quote{source: event.Source}
+		venues[event.Source] = q
+	}
+	q.price = event.Price
+	q.volume = event.Volume
+	q.received = now
+
+	quotes := sortedQuotes(venues)
+	exclusions := make(map[*quote]exclusion)
+	fresh := make([]*quote, 0, len(quotes))
+	for _, q := range quotes {
+		if age := now.Sub(q.received); a.config.StaleAfter > 0 && age > a.config.StaleAfter {
+			exclusions[q] = exclusion{excludedStale, fmt.Sprintf("no update for %s", age.Truncate(time.Second))}
+		} else {
+			fresh = append(fresh, q)
+		}
+	}
+
+	used := fresh
+	if a.config.OutlierPct > 0 && len(fresh) >= 3 {
+		used = make([]*quote, 0, len(fresh))
+		mid := median(fresh)
+		for _, q := range fresh {
+			deviation := math.Abs(q.price-mid) / mid * 100
+			if deviation > a.config.OutlierPct {
+				exclusions[q] = exclusion{excludedOutlier, fmt.Sprintf("%.2f%% from the median", deviation)}
+			} else {
+				used = append(used, q)
+			}
+		}
+	}
+
+	var notices []error
+	for _, q := range quotes {
+		notices = a.setExcluded(notices, event.Symbol, q, exclusions[q])
+	}
+	if len(used) == 0 {
+		return nil, nil, notices
+	}
+
+	price := a.reference(used)
+	var volume float64
+	for _, q := range used {
+		volume += q.volume
+	}
+	reference := &feed.PriceEvent{
+		Source:    Source,
+		Symbol:    event.Symbol,
+		Price:     price,
+		RawPrice:  strconv.FormatFloat(price, 'f', -1, 64),
+		Volume:    volume,
+		EventTime: event.EventTime,
+	}
+	return reference, a.checkSpread(event, fresh), notices
+}
+
+// setExcluded records why a venue is left out, noting changes.
+func (a *Aggregator) setExcluded(notices []error, symbol string, q *quote, e exclusion) []error {
+	if e.reason == q.excluded {
+		return notices
+	}
+	if e.reason == "" {
+		notices = append(notices, fmt.Errorf("aggregate: %s %s is back in the reference price", q.source, symbol))
+	} else {
+		notices = append(notices, fmt.Errorf("aggregate: %s %s excluded as %s: %s", q.source, symbol, e.reason, e.detail))
+	}
+	q.excluded = e.reason
+	return notices
+}
+
+// reference combines the usable venues' prices.
+func (a *Aggregator) reference(quotes []*quote) float64 {
+	if a.config.Method == MethodVWAP {
+		var weighted, volume float64
+		for _, q := range quotes {
+			weighted += q.price * q.volume
+			volume += q.volume
+		}
+		if volume > 0 {
+			return weighted / volume
+		}
+	}
+	return median(quotes)
+}
+
+// checkSpread compares the cheapest and dearest fresh venues, returning an
+// alert when the spread first rises above the threshold. Outliers count
+// here: a venue far from the others is the spread being watched for.
+func (a *Aggregator) checkSpread(event feed.PriceEvent, fresh []*quote) *alerts.Alert {
+	if a.config.SpreadAlertBps <= 0 || len(fresh) < 2 {
+		delete(a.spreadOpen, event.Symbol)
+		return nil
+	}
+
+	low, high := fresh[0], fresh[0]
+	for _, q := range fresh[1:] {
+		if q.price < low.price {
+			low = q
+		}
+		if q.price > high.price {
+			high = q
+		}
+	}
+	change := high.price - low.price
+	bps := change / ((high.price + low.price) / 2) * 10000
+	if bps < a.config.SpreadAlertBps {
+		delete(a.spreadOpen, event.Symbol)
+		return nil
+	}
+	if a.spreadOpen[event.Symbol] {
+		return nil
+	}
+	a.spreadOpen[event.Symbol] = true
+
+	return &alerts.Alert{
+		Type:        alerts.TypeSpread,
+		Symbol:      event.Symbol,
+		OldPrice:    low.price,
+		NewPrice:    high.price,
+		Change:      change,
+		ChangePct:   change / low.price * 100,
+		OccurredAt:  a.now().UTC(),
+		Observation: event.EventTime,
+		Spread: &alerts.Spread{
+			LowSource:  low.source,
+			LowPrice:   low.price,
+			HighSource: high.source,
+			HighPrice:  high.price,
+			Bps:        bps,
+		},
+	}
+}
+
+// sortedQuotes returns a symbol's quotes ordered by venue name, so notices
+// and ties come out the same way every time.
+func sortedQuotes(venues map[string]*quote) []*quote {
+	quotes := make([]*quote, 0, len(venues))
+	for _, q := range venues {
+		quotes = append(quotes, q)
+	}
+	sort.Slice(quotes, func(i, j int) bool { return quotes[i].source < quotes[j].source })
+	return quotes
+}
+
+func median(quotes []*quote) float64 {
+	prices := make([]float64, len(quotes))
+	for i, q := range quotes {
+		prices[i] = q.price
+	}
+	sort.Float64s(prices)
+	mid := len(prices) / 2
+	if len(prices)%2 == 0 {
+		return (prices[mid-1] + prices[mid]) / 2
+	}
+	return prices[mid]
+}
@@ -0,0 +1,169 @@
+package aggregate
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/feed"
+)
+
+// tick is one venue update, received at an offset from the test start.
+type tick struct {
+	at     time.Duration
+	source string
+	price  float64
+	volume float64
+
+	// wantPrice is the reference price, or 0 when none is emitted.
+	wantPrice   float64
+	wantNotices []string
+	// wantSpread is the venues of the spread alert raised, low first.
+	wantSpread []string
+}
+
+func TestAggregatorUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		ticks  []tick
+	}{
+		{
+			name:   "median",
+			config: Config{Method: MethodMedian},
+			ticks: []tick{
+				{source: "a", price: 100, wantPrice: 100},
+				{source: "b", price: 110, wantPrice: 105},
+				{source: "c", price: 104, wantPrice: 104},
+			},
+		},
+		{
+			name:   "vwap",
+			config: Config{Method: MethodVWAP},
+			ticks: []tick{
+				{source: "a", price: 100, volume: 1, wantPrice: 100},
+				{source: "b", price: 110, volume: 3, wantPrice: 107.5},
+				// A venue without volume carries no weight.
+				{source: "c", price: 90, wantPrice: 107.5},
+			},
+		},
+		{
+			name:   "vwap falls back to the median without volume",
+			config: Config{Method: MethodVWAP},
+			ticks: []tick{
+				{source: "a", price: 100, wantPrice: 100},
+				{source: "b", price: 110, wantPrice: 105},
+				{source: "c", price: 90, wantPrice: 100},
+			},
+		},
+		{
+			name:   "stale venues are excluded until they update",
+			config: Config{Method: MethodMedian, StaleAfter: 10 * time.Second},
+			ticks: []tick{
+				{source: "a", price: 100, wantPrice: 100},
+				{source: "b", price: 110, wantPrice: 105},
+				{at: 10 * time.Second, source: "b", price: 111, wantPrice: 105.5},
+				{
+					at: 15 * time.Second, source: "b", price: 112, wantPrice: 112,
+					wantNotices: []string{"aggregate: a BTCUSDT excluded as stale: no update for 15s"},
+				},
+				{
+					at: 16 * time.Second, source: "a", price: 101, wantPrice: 106.5,
+					wantNotices: []string{"aggregate: a BTCUSDT is back in the reference price"},
+				},
+			},
+		},
+		{
+			name:   "outliers need three venues",
+			config: Config{Method: MethodMedian, OutlierPct: 5},
+			ticks: []tick{
+				{source: "a", price: 100, wantPrice: 100},
+				// With two venues neither can be called the outlier.
+				{source: "b", price: 200, wantPrice: 150},
+				{
+					source: "c", price: 101, wantPrice: 100.5,
+					wantNotices: []string{"aggregate: b BTCUSDT excluded as outlier: 98.02% from the median"},
+				},
+				// Still out: no new notice.
+				{source: "b", price: 190, wantPrice: 100.5},
+				{
+					source: "b", price: 102, wantPrice: 101,
+					wantNotices: []string{"aggregate: b BTCUSDT is back in the reference price"},
+				},
+			},
+		},
+		{
+			name:   "one spread alert per excursion",
+			config: Config{Method: MethodMedian, SpreadAlertBps: 50},
+			ticks: []tick{
+				{source: "a", price: 100, wantPrice: 100},
+				{source: "b", price: 101, wantPrice: 100.5, wantSpread: []string{"a", "b"}},
+				{source: "b", price: 101.2, wantPrice: 100.6},
+				{source: "a", price: 100.9, wantPrice: 101.05},
+				{source: "a", price: 102, wantPrice: 101.6, wantSpread: []string{"b", "a"}},
+			},
+		},
+		{
+			name:   "stale venues do not widen the spread",
+			config: Config{Method: MethodMedian, StaleAfter: 10 * time.Second, SpreadAlertBps: 50},
+			ticks: []tick{
+				{source: "a", price: 100, wantPrice: 100},
+				{
+					at: 20 * time.Second, source: "b", price: 110, wantPrice: 110,
+					wantNotices: []string{"aggregate: a BTCUSDT excluded as stale: no update for 20s"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Date(2024, 5, 14, 10, 0, 0, 0, time.UTC)
+			var now time.Time
+			a := New(tt.config)
+			a.now = func() time.Time { return now }
+
+			for i, tk := range tt.ticks {
+				now = start.Add(tk.at)
+				reference, alert, notices := a.update(feed.PriceEvent{
+					Source: tk.source, Symbol: "BTCUSDT", Price: tk.price, Volume: tk.volume, EventTime: now,
+				})
+
+				switch {
+				case tk.wantPrice == 0 && reference != nil:
+					t.Errorf("tick %d: reference %v, want none", i, reference.Price)
+				case tk.wantPrice != 0 && reference == nil:
+					t.Errorf("tick %d: no reference, want %v", i, tk.wantPrice)
+				case reference != nil && math.Abs(reference.Price-tk.wantPrice) > 1e-9:
+					t.Errorf("tick %d: reference %v, want %v", i, reference.Price, tk.wantPrice)
+				}
+				if reference != nil && (reference.Source != Source || reference.Symbol != "BTCUSDT") {
+					t.Errorf("tick %d: reference %+v", i, reference)
+				}
+
+				var got []string
+				for _, notice := range notices {
+					got = append(got, notice.Error())
+				}
+				if !slices.Equal(got, tk.wantNotices) {
+					t.Errorf("tick %d: notices %q, want %q", i, got, tk.wantNotices)
+				}
+
+				switch {
+				case alert == nil && tk.wantSpread != nil:
+					t.Errorf("tick %d: no spread alert, want %v", i, tk.wantSpread)
+				case alert != nil && tk.wantSpread == nil:
+					t.Errorf("tick %d: unexpected spread alert %+v", i, alert.Spread)
+				case alert != nil:
+					if got := []string{alert.Spread.LowSource, alert.Spread.HighSource}; !slices.Equal(got, tk.wantSpread) {
+						t.Errorf("tick %d: spread between %v, want %v", i, got, tk.wantSpread)
+					}
+					if alert.Spread.Bps < tt.config.SpreadAlertBps {
+						t.Errorf("tick %d: spread of %.1f bps is below the threshold", i, alert.Spread.Bps)
+					}
+				}
+			}
+		})
+	}
+}
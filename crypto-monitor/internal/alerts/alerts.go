@@ -4,14 +4,36 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sync"
 	"time"
 
-	"crypto-monitor/internal/binance"
 	"crypto-monitor/internal/cache"
+	"crypto-monitor/internal/feed"
+	"crypto-monitor/internal/series"
 )
 
-// Alert represents a significant price change.
+// Alert types. Rule alerts take the type of the rule that raised them.
+const (
+	TypePriceChange  = "price_change"
+	TypeSpread       = "spread"
+	TypeCrossAbove   = "cross_above"
+	TypeCrossBelow   = "cross_below"
+	TypeWindowChange = "window_change"
+	TypeVolumeSpike  = "volume_spike"
+)
+
+// Types lists every alert type.
+var Types = []string{TypePriceChange, TypeSpread, TypeCrossAbove, TypeCrossBelow, TypeWindowChange, TypeVolumeSpike}
+
+// Alert represents a significant price change. Price change alerts compare
+// the price a Window ago (OldPrice) with the latest one. Spread alerts
+// compare the cheapest venue (OldPrice) with the dearest one (NewPrice) and
+// carry the venues in Spread. Rule alerts name the rule and describe what
+// matched in Detail. ID is set once the alert is recorded in the alert
+// history.
 type Alert struct {
+	ID          string    `json:"id,omitempty"`
+	Type        string    `json:"type"`
 	Symbol      string    `json:"symbol"`
 	OldPrice    float64   `json:"old_price"`
 	NewPrice    float64   `json:"new_price"`
@@ -19,21 +41,69 @@ type Alert struct {
 	ChangePct   float64   `json:"change_pct"`
 	OccurredAt  time.Time `json:"occurred_at"`
 	Observation time.Time `json:"observation"`
+	Spread      *Spread   `json:"spread,omitempty"`
+	Window      string    `json:"window,omitempty"`
+	Rule        string    `json:"rule,omitempty"`
+	Detail      string    `json:"detail,omitempty"`
+
+	// Severity, Group and Occurrences are set once the alert has been
+	// grouped with repeats of it; Occurrences counts the group's alerts,
+	// including those held back.
+	Severity    string `json:"severity,omitempty"`
+	Group       string `json:"group,omitempty"`
+	Occurrences int    `json:"occurrences,omitempty"`
+}
+
+// Spread describes the venues behind a spread alert.
+type Spread struct {
+	LowSource  string  `json:"low_source"`
+	LowPrice   float64 `json:"low_price"`
+	HighSource string  `json:"high_source"`
+	HighPrice  float64 `json:"high_price"`
+	Bps        float64 `json:"bps"`
 }
 
-// Engine detects significant price changes.
+// sampleResolution is how finely the engine's price history is kept. Each
+// symbol's history spans the longest window; see series.Ring for its cost.
+const sampleResolution = time.Second
+
+// Window is a period to measure price changes over and the percent change
+// that raises an alert.
+type Window struct {
+	Span         time.Duration
+	ThresholdPct float64
+}
+
+// Engine detects significant price changes over rolling windows. Each
+// window alerts when the change reaches its threshold and re-arms once the
+// change falls back below the threshold less the hysteresis, so a price
+// hovering at the threshold does not alert on every tick.
 type Engine struct {
-	cache     cache.Cache
-	threshold float64
+	cache      cache.Cache
+	windows    []Window
+	hysteresis float64
+	now        func() time.Time
+	history    map[string]*series.Ring
+	// active holds, per symbol, whether each window has alerted and not
+	// yet re-armed.
+	active map[string][]bool
 }
 
-// NewEngine builds an alerting engine.
-func NewEngine(cache cache.Cache, threshold float64) *Engine {
-	return &Engine{cache: cache, threshold: threshold}
+// NewEngine builds an alerting engine. hysteresis is the fraction of each
+// threshold the change must fall back by before a window alerts again.
+func NewEngine(cache cache.Cache, windows []Window, hysteresis float64) *Engine {
+	return &Engine{
+		cache:      cache,
+		windows:    windows,
+		hysteresis: hysteresis,
+		now:        time.Now,
+		history:    make(map[string]*series.Ring),
+		active:     make(map[string][]bool),
+	}
 }
 
 // Start processes price events and emits alerts.
-func (e *Engine) Start(ctx context.Context, prices <-chan binance.PriceEvent) (<-chan Alert, <-chan error) {
+func (e *Engine) Start(ctx context.Context, prices <-chan feed.PriceEvent) (<-chan Alert, <-chan error) {
 	alerts := make(chan Alert, 32)
 	errCh := make(chan error, 1)
 
@@ -50,12 +120,6 @@ func (e *Engine) Start(ctx context.Context, prices <-chan binance.PriceEvent) (<
 					return
 				}
 
-				previous, ok, err := e.cache.GetPrice(ctx, priceEvent.Symbol)
-				if err != nil {
-					errCh <- fmt.Errorf("alerts: cache read failed: %w", err)
-					continue
-				}
-
 				snapshot := cache.Snapshot{
 					Symbol:    priceEvent.Symbol,
 					Price:     priceEvent.Price,
@@ -65,34 +129,131 @@ func (e *Engine) Start(ctx context.Context, prices <-chan binance.PriceEvent) (<
 					errCh <- fmt.Errorf("alerts: cache write failed: %w", err)
 				}
 
-				if !ok || previous.Price == 0 {
-					continue
+				for _, alert := range e.evaluate(priceEvent) {
+					select {
+					case <-ctx.Done():
+						return
+					case alerts <- alert:
+					}
 				}
+			}
+		}
+	}()
 
-				change := priceEvent.Price - previous.Price
-				changePct := (change / previous.Price) * 100
-				if math.Abs(changePct) < e.threshold {
-					continue
-				}
+	return alerts, errCh
+}
+
+// evaluate records a price in its symbol's history and returns the alerts
+// of windows whose change has reached their threshold.
+func (e *Engine) evaluate(priceEvent feed.PriceEvent) []Alert {
+	now := e.now()
+	ring, ok := e.history[priceEvent.Symbol]
+	if !ok {
+		ring = series.NewRing(e.span(), sampleResolution)
+		e.history[priceEvent.Symbol] = ring
+		e.active[priceEvent.Symbol] = make([]bool, len(e.windows))
+	}
+	ring.Add(series.Sample{At: now, Price: priceEvent.Price, Volume: priceEvent.Volume})
+	active := e.active[priceEvent.Symbol]
 
-				alert := Alert{
-					Symbol:      priceEvent.Symbol,
-					OldPrice:    previous.Price,
-					NewPrice:    priceEvent.Price,
-					Change:      change,
-					ChangePct:   changePct,
-					OccurredAt:  time.Now().UTC(),
-					Observation: priceEvent.EventTime,
+	var fired []Alert
+	for i, window := range e.windows {
+		start, ok := ring.At(now.Add(-window.Span))
+		if !ok || start.Price == 0 {
+			continue
+		}
+		change := priceEvent.Price - start.Price
+		changePct := (change / start.Price) * 100
+		size := math.Abs(changePct)
+
+		if active[i] {
+			if size < window.ThresholdPct*(1-e.hysteresis) {
+				active[i] = false
+			}
+			continue
+		}
+		if size < window.ThresholdPct {
+			continue
+		}
+		active[i] = true
+		fired = append(fired, Alert{
+			Type:        TypePriceChange,
+			Symbol:      priceEvent.Symbol,
+			OldPrice:    start.Price,
+			NewPrice:    priceEvent.Price,
+			Change:      change,
+			ChangePct:   changePct,
+			OccurredAt:  now.UTC(),
+			Observation: priceEvent.EventTime,
+			Window:      series.FormatWindow(window.Span),
+		})
+	}
+	return fired
+}
+
+// span returns the longest window.
+func (e *Engine) span() time.Duration {
+	var span time.Duration
+	for _, window := range e.windows {
+		span = max(span, window.Span)
+	}
+	return span
+}
+
+// Merge forwards alerts from every stream until they are all closed or the
+// context is cancelled.
+func Merge(ctx context.Context, streams ...<-chan Alert) <-chan Alert {
+	out := make(chan Alert, 32)
+
+	var wg sync.WaitGroup
+	for _, stream := range streams {
+		wg.Add(1)
+		go func(stream <-chan Alert) {
+			defer wg.Done()
+			for alert := range stream {
+				select {
+				case <-ctx.Done():
+					return
+				case out <- alert:
 				}
+			}
+		}(stream)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// Tee copies every alert from in to n streams. A slow consumer holds up the
+// others, so consumers should keep up or buffer.
+func Tee(ctx context.Context, in <-chan Alert, n int) []<-chan Alert {
+	outs := make([]chan Alert, n)
+	streams := make([]<-chan Alert, n)
+	for i := range outs {
+		outs[i] = make(chan Alert, 32)
+		streams[i] = outs[i]
+	}
 
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+		for alert := range in {
+			for _, out := range outs {
 				select {
 				case <-ctx.Done():
 					return
-				case alerts <- alert:
+				case out <- alert:
 				}
 			}
 		}
 	}()
 
-	return alerts, errCh
+	return streams
 }
@@ -0,0 +1,115 @@
+package alerts
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/feed"
+)
+
+func TestEngineHysteresis(t *testing.T) {
+	// Every step is for BTCUSDT unless it names another symbol. All
+	// changes are measured against 100, the price at the start.
+	type step struct {
+		at     time.Duration
+		symbol string
+		price  float64
+		// want lists the windows that alert.
+		want []string
+	}
+	oneMinute := Window{Span: time.Minute, ThresholdPct: 2}
+
+	tests := []struct {
+		name       string
+		windows    []Window
+		hysteresis float64
+		steps      []step
+	}{
+		{
+			name:       "re-arms below the threshold less the hysteresis",
+			windows:    []Window{oneMinute},
+			hysteresis: 0.25,
+			steps: []step{
+				{price: 100},
+				{at: 60 * time.Second, price: 102, want: []string{"1m"}},
+				{at: 61 * time.Second, price: 102.5},
+				// 1.6% is below the threshold but above 1.5%.
+				{at: 62 * time.Second, price: 101.6},
+				{at: 63 * time.Second, price: 102.1},
+				{at: 64 * time.Second, price: 101.4},
+				{at: 65 * time.Second, price: 102, want: []string{"1m"}},
+			},
+		},
+		{
+			name:    "without hysteresis any dip re-arms",
+			windows: []Window{oneMinute},
+			steps: []step{
+				{price: 100},
+				{at: 60 * time.Second, price: 102, want: []string{"1m"}},
+				{at: 61 * time.Second, price: 101.9},
+				{at: 62 * time.Second, price: 102, want: []string{"1m"}},
+			},
+		},
+		{
+			name:       "falls count",
+			windows:    []Window{oneMinute},
+			hysteresis: 0.25,
+			steps: []step{
+				{price: 100},
+				{at: 60 * time.Second, price: 97, want: []string{"1m"}},
+				// A swing to the other side is still beyond the threshold.
+				{at: 61 * time.Second, price: 103},
+			},
+		},
+		{
+			name:       "windows and symbols are independent",
+			windows:    []Window{oneMinute, {Span: 2 * time.Minute, ThresholdPct: 5}},
+			hysteresis: 0.25,
+			steps: []step{
+				{price: 100},
+				{symbol: "ETHUSDT", price: 100},
+				{at: 60 * time.Second, price: 102, want: []string{"1m"}},
+				{at: 60 * time.Second, symbol: "ETHUSDT", price: 102, want: []string{"1m"}},
+				{at: 120 * time.Second, price: 105, want: []string{"2m"}},
+				{at: 121 * time.Second, price: 105},
+			},
+		},
+		{
+			name:    "needs a full window of history",
+			windows: []Window{oneMinute},
+			steps: []step{
+				{price: 100},
+				{at: 30 * time.Second, price: 110},
+				{at: 59 * time.Second, price: 110},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Date(2024, 5, 14, 10, 0, 0, 0, time.UTC)
+			var now time.Time
+			e := NewEngine(nil, tt.windows, tt.hysteresis)
+			e.now = func() time.Time { return now }
+
+			for i, s := range tt.steps {
+				now = start.Add(s.at)
+				symbol := s.symbol
+				if symbol == "" {
+					symbol = "BTCUSDT"
+				}
+				var got []string
+				for _, alert := range e.evaluate(feed.PriceEvent{Symbol: symbol, Price: s.price, EventTime: now}) {
+					if alert.Type != TypePriceChange || alert.Symbol != symbol || alert.OldPrice != 100 || alert.NewPrice != s.price {
+						t.Errorf("step %d: alert %+v", i, alert)
+					}
+					got = append(got, alert.Window)
+				}
+				if !slices.Equal(got, s.want) {
+					t.Errorf("step %d (%s %v at %s): alerts %v, want %v", i, symbol, s.price, s.at, got, s.want)
+				}
+			}
+		})
+	}
+}
@@ -6,28 +6,19 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
-	"strconv"
 	"strings"
-	"time"
 
-	"github.com/gorilla/websocket"
+	"crypto-monitor/internal/feed"
 )
 
 const defaultBaseURL = "wss://stream.binance.com:9443"
 
+// Name is the venue name set on Binance price events.
+const Name = "binance"
+
 // Client connects to Binance WebSocket feeds.
 type Client struct {
-	baseURL          string
-	dialer           *websocket.Dialer
-	reconnectBackoff time.Duration
-}
-
-// PriceEvent represents a normalized price update.
-type PriceEvent struct {
-	Symbol    string
-	Price     float64
-	RawPrice  string
-	EventTime time.Time
+	baseURL string
 }
 
 type combinedStreamMessage struct {
@@ -37,59 +28,25 @@ type combinedStreamMessage struct {
 
 // NewClient creates a Binance WebSocket client with defaults.
 func NewClient() *Client {
-	return &Client{
-		baseURL:          defaultBaseURL,
-		dialer:           websocket.DefaultDialer,
-		reconnectBackoff: 2 * time.Second,
-	}
+	return &Client{baseURL: defaultBaseURL}
 }
 
-// StreamTickers connects to Binance and emits price events until context cancel.
-func (c *Client) StreamTickers(ctx context.Context, symbols []string) (<-chan PriceEvent, <-chan error) {
-	out := make(chan PriceEvent, 32)
-	errCh := make(chan error, 1)
-
-	go func() {
-		defer close(out)
-		defer close(errCh)
-
-		if len(symbols) == 0 {
-			errCh <- errors.New("binance: at least one symbol required")
-			return
-		}
+// Name returns the venue name.
+func (c *Client) Name() string {
+	return Name
+}
 
-		backoff := c.reconnectBackoff
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-			}
-
-			conn, _, err := c.dialer.Dial(c.buildStreamURL(symbols), nil)
-			if err != nil {
-				errCh <- fmt.Errorf("binance: dial failed: %w", err)
-				timer := time.NewTimer(backoff)
-				select {
-				case <-ctx.Done():
-					timer.Stop()
-					return
-				case <-timer.C:
-				}
-				backoff = minDuration(backoff*2, 30*time.Second)
-				continue
-			}
-			backoff = c.reconnectBackoff
-
-			err = c.readLoop(ctx, conn, out)
-			_ = conn.Close()
-			if err != nil && !errors.Is(err, context.Canceled) {
-				errCh <- err
-			}
-		}
-	}()
+// StreamTickers connects to the symbols' ticker streams and emits price
+// events until context cancel.
+func (c *Client) StreamTickers(ctx context.Context, symbols []string) (<-chan feed.PriceEvent, <-chan error) {
+	if len(symbols) == 0 {
+		return feed.Failed(errors.New("at least one symbol required"))
+	}
 
-	return out, errCh
+	return feed.WebSocketFeed{
+		URL:    c.buildStreamURL(symbols),
+		Decode: decode,
+	}.Stream(ctx)
 }
 
 func (c *Client) buildStreamURL(symbols []string) string {
@@ -104,118 +61,60 @@ func (c *Client) buildStreamURL(symbols []string) string {
 	return fmt.Sprintf("%s/stream?%s", c.baseURL, query.Encode())
 }
 
-func (c *Client) readLoop(ctx context.Context, conn *websocket.Conn, out chan<- PriceEvent) error {
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		_, payload, err := conn.ReadMessage()
-		if err != nil {
-			return fmt.Errorf("binance: read failed: %w", err)
-		}
-
-		var msg combinedStreamMessage
-		if err := json.Unmarshal(payload, &msg); err != nil {
-			return fmt.Errorf("binance: decode failed: %w", err)
-		}
-
-		var data map[string]json.RawMessage
-		if err := json.Unmarshal(msg.Data, &data); err != nil {
-			return fmt.Errorf("binance: data decode failed: %w", err)
-		}
-
-		symbolRaw, ok := data["s"]
-		if !ok {
-			return errors.New("binance: missing symbol")
-		}
-		var symbol string
-		if err := json.Unmarshal(symbolRaw, &symbol); err != nil {
-			return fmt.Errorf("binance: symbol parse failed: %w", err)
-		}
-
-		priceRaw, ok := data["c"]
-		if !ok {
-			return errors.New("binance: missing close price")
-		}
-		price, rawPrice, err := parsePrice(priceRaw)
-		if err != nil {
-			return fmt.Errorf("binance: price parse failed: %w", err)
-		}
-
-		eventRaw, ok := data["E"]
-		if !ok {
-			return errors.New("binance: missing event time")
-		}
-		eventTimeMs, err := parseEventTime(eventRaw)
-		if err != nil {
-			return fmt.Errorf("binance: event time parse failed: %w", err)
-		}
-
-		event := PriceEvent{
-			Symbol:    symbol,
-			Price:     price,
-			RawPrice:  rawPrice,
-			EventTime: time.UnixMilli(eventTimeMs),
-		}
-
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case out <- event:
-		}
+// decode converts a combined stream ticker message into a price event.
+// Binance symbols are already canonical.
+func decode(payload []byte) ([]feed.PriceEvent, error) {
+	var msg combinedStreamMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return nil, err
 	}
-}
 
-func parseEventTime(raw json.RawMessage) (int64, error) {
-	if len(raw) == 0 {
-		return 0, errors.New("missing event time")
+	// Ticker fields differ only in case, such as "c" and "C", which
+	// struct decoding would mix up.
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		return nil, fmt.Errorf("data decode failed: %w", err)
 	}
 
-	var asInt int64
-	if err := json.Unmarshal(raw, &asInt); err == nil {
-		return asInt, nil
+	symbolRaw, ok := data["s"]
+	if !ok {
+		return nil, errors.New("missing symbol")
 	}
-
-	var asString string
-	if err := json.Unmarshal(raw, &asString); err == nil {
-		value, err := strconv.ParseInt(asString, 10, 64)
-		if err != nil {
-			return 0, fmt.Errorf("invalid event time string: %w", err)
-		}
-		return value, nil
+	var symbol string
+	if err := json.Unmarshal(symbolRaw, &symbol); err != nil {
+		return nil, fmt.Errorf("symbol parse failed: %w", err)
 	}
 
-	return 0, fmt.Errorf("unsupported event time payload: %s", string(raw))
-}
-
-func parsePrice(raw json.RawMessage) (float64, string, error) {
-	if len(raw) == 0 {
-		return 0, "", errors.New("missing price")
+	priceRaw, ok := data["c"]
+	if !ok {
+		return nil, errors.New("missing close price")
 	}
-
-	var asString string
-	if err := json.Unmarshal(raw, &asString); err == nil {
-		price, err := strconv.ParseFloat(asString, 64)
-		if err != nil {
-			return 0, "", fmt.Errorf("invalid price string: %w", err)
-		}
-		return price, asString, nil
+	price, rawPrice, err := feed.ParseNumber(priceRaw)
+	if err != nil {
+		return nil, fmt.Errorf("price parse failed: %w", err)
 	}
 
-	var asFloat float64
-	if err := json.Unmarshal(raw, &asFloat); err == nil {
-		return asFloat, strconv.FormatFloat(asFloat, 'f', -1, 64), nil
+	eventRaw, ok := data["E"]
+	if !ok {
+		return nil, errors.New("missing event time")
+	}
+	eventTime, err := feed.ParseTime(eventRaw)
+	if err != nil {
+		return nil, fmt.Errorf("event time parse failed: %w", err)
 	}
 
-	return 0, "", fmt.Errorf("unsupported price payload: %s", string(raw))
-}
-
-func minDuration(a, b time.Duration) time.Duration {
-	if a < b {
-		return a
+	event := feed.PriceEvent{
+		Source:    Name,
+		Symbol:    symbol,
+		Price:     price,
+		RawPrice:  rawPrice,
+		EventTime: eventTime,
+	}
+	// The 24h base asset volume is optional.
+	if volumeRaw, ok := data["v"]; ok {
+		if event.Volume, _, err = feed.ParseNumber(volumeRaw); err != nil {
+			return nil, fmt.Errorf("volume parse failed: %w", err)
+		}
 	}
-	return b
+	return []feed.PriceEvent{event}, nil
 }
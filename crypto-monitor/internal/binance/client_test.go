@@ -0,0 +1,108 @@
+package binance
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/feed"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    []feed.PriceEvent
+		wantErr string
+	}{
+		{
+			name:    "ticker",
+			payload: `{"stream":"btcusdt@ticker","data":{"e":"24hrTicker","E":1715681742123,"s":"BTCUSDT","p":"1311.51","P":"1.987","w":"66712.40","x":"66010.01","c":"67321.52","Q":"0.00150000","b":"67321.51","B":"0.01000000","a":"67321.52","A":"0.50000000","o":"66010.01","h":"67800.00","l":"65500.01","v":"12534.77451203","q":"836201474.12","O":1715595342123,"C":1715681742123,"F":3580000000,"L":3581234567,"n":1234568}}`,
+			want: []feed.PriceEvent{{
+				Source:    Name,
+				Symbol:    "BTCUSDT",
+				Price:     67321.52,
+				RawPrice:  "67321.52",
+				Volume:    12534.77451203,
+				EventTime: time.UnixMilli(1715681742123),
+			}},
+		},
+		{
+			name:    "numbers and no volume",
+			payload: `{"stream":"ethusdt@ticker","data":{"e":"24hrTicker","E":"1715681742123","s":"ETHUSDT","c":3012.5}}`,
+			want: []feed.PriceEvent{{
+				Source:    Name,
+				Symbol:    "ETHUSDT",
+				Price:     3012.5,
+				RawPrice:  "3012.5",
+				EventTime: time.UnixMilli(1715681742123),
+			}},
+		},
+		{
+			name:    "missing symbol",
+			payload: `{"stream":"btcusdt@ticker","data":{"E":1715681742123,"c":"67321.52"}}`,
+			wantErr: "missing symbol",
+		},
+		{
+			name:    "missing close price",
+			payload: `{"stream":"btcusdt@ticker","data":{"E":1715681742123,"s":"BTCUSDT"}}`,
+			wantErr: "missing close price",
+		},
+		{
+			name:    "bad price",
+			payload: `{"stream":"btcusdt@ticker","data":{"E":1715681742123,"s":"BTCUSDT","c":"n/a"}}`,
+			wantErr: "price parse failed",
+		},
+		{
+			name:    "missing event time",
+			payload: `{"stream":"btcusdt@ticker","data":{"s":"BTCUSDT","c":"67321.52"}}`,
+			wantErr: "missing event time",
+		},
+		{
+			name:    "bad volume",
+			payload: `{"stream":"btcusdt@ticker","data":{"E":1715681742123,"s":"BTCUSDT","c":"67321.52","v":true}}`,
+			wantErr: "volume parse failed",
+		},
+		{
+			name:    "not JSON",
+			payload: `<html>`,
+			wantErr: "invalid character",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decode([]byte(tt.payload))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d events, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !got[i].EventTime.Equal(tt.want[i].EventTime) {
+					t.Errorf("event %d time = %v, want %v", i, got[i].EventTime, tt.want[i].EventTime)
+				}
+				got[i].EventTime = tt.want[i].EventTime
+				if got[i] != tt.want[i] {
+					t.Errorf("event %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBuildStreamURL(t *testing.T) {
+	c := NewClient()
+	got := c.buildStreamURL([]string{"BTCUSDT", "ethusdt"})
+	want := "wss://stream.binance.com:9443/stream?streams=btcusdt%40ticker%2Fethusdt%40ticker"
+	if got != want {
+		t.Errorf("buildStreamURL = %s, want %s", got, want)
+	}
+}
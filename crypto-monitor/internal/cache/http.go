@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ServePrices handles GET /prices, listing the cached snapshot of each
+// symbol, or of the comma-separated symbols query parameter. permitted,
+// when set, returns the symbols a request may see, nil meaning all.
+func ServePrices(c Cache, symbols []string, permitted func(*http.Request) []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		if permitted != nil {
+			allowed = permitted(r)
+		}
+
+		var wanted []string
+		if value := strings.TrimSpace(r.URL.Query().Get("symbols")); value != "" {
+			for _, symbol := range strings.Split(value, ",") {
+				symbol = strings.ToUpper(strings.TrimSpace(symbol))
+				if !slices.Contains(symbols, symbol) {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown symbol " + symbol})
+					return
+				}
+				if allowed != nil && !slices.Contains(allowed, symbol) {
+					writeJSON(w, http.StatusForbidden, map[string]string{"error": "symbol not permitted"})
+					return
+				}
+				wanted = append(wanted, symbol)
+			}
+		} else {
+			for _, symbol := range symbols {
+				if allowed == nil || slices.Contains(allowed, symbol) {
+					wanted = append(wanted, symbol)
+				}
+			}
+		}
+
+		snapshots := make([]Snapshot, 0, len(wanted))
+		for _, symbol := range wanted {
+			snapshot, ok, err := c.GetPrice(r.Context(), symbol)
+			if err != nil {
+				writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "prices unavailable"})
+				return
+			}
+			if ok {
+				snapshots = append(snapshots, snapshot)
+			}
+		}
+		writeJSON(w, http.StatusOK, snapshots)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// memoryCache is a Cache holding snapshots in a map.
+type memoryCache map[string]Snapshot
+
+func (c memoryCache) GetPrice(_ context.Context, symbol string) (Snapshot, bool, error) {
+	if symbol == "FAILUSDT" {
+		return Snapshot{}, false, errors.New("cache down")
+	}
+	snapshot, ok := c[symbol]
+	return snapshot, ok, nil
+}
+
+func (c memoryCache) SetPrice(_ context.Context, snapshot Snapshot) error {
+	c[snapshot.Symbol] = snapshot
+	return nil
+}
+
+func TestServePrices(t *testing.T) {
+	c := memoryCache{
+		"BTCUSDT": {Symbol: "BTCUSDT", Price: 67321.52},
+		"ETHUSDT": {Symbol: "ETHUSDT", Price: 3012.5},
+		"SOLUSDT": {Symbol: "SOLUSDT", Price: 151.2},
+	}
+	// A request carrying "btc" may only see BTCUSDT and SOLUSDT.
+	permitted := func(r *http.Request) []string {
+		if r.Header.Get("Authorization") == "btc" {
+			return []string{"BTCUSDT", "SOLUSDT"}
+		}
+		return nil
+	}
+	handler := ServePrices(c, []string{"BTCUSDT", "ETHUSDT", "SOLUSDT", "XRPUSDT"}, permitted)
+
+	tests := []struct {
+		name       string
+		target     string
+		btcOnly    bool
+		wantStatus int
+		want       []string
+	}{
+		{name: "all", target: "/prices", wantStatus: http.StatusOK, want: []string{"BTCUSDT", "ETHUSDT", "SOLUSDT"}},
+		{name: "symbols", target: "/prices?symbols=solusdt,%20ETHUSDT", wantStatus: http.StatusOK, want: []string{"SOLUSDT", "ETHUSDT"}},
+		{name: "not cached", target: "/prices?symbols=XRPUSDT", wantStatus: http.StatusOK, want: []string{}},
+		{name: "unknown symbol", target: "/prices?symbols=DOGEUSDT", wantStatus: http.StatusBadRequest},
+		{name: "permitted symbols", target: "/prices", btcOnly: true, wantStatus: http.StatusOK, want: []string{"BTCUSDT", "SOLUSDT"}},
+		{name: "permitted symbol", target: "/prices?symbols=SOLUSDT", btcOnly: true, wantStatus: http.StatusOK, want: []string{"SOLUSDT"}},
+		{name: "symbol not permitted", target: "/prices?symbols=BTCUSDT,ETHUSDT", btcOnly: true, wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.btcOnly {
+				r.Header.Set("Authorization", "btc")
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.want == nil {
+				return
+			}
+			var snapshots []Snapshot
+			if err := json.Unmarshal(rec.Body.Bytes(), &snapshots); err != nil || snapshots == nil {
+				t.Fatalf("body %s: %v", rec.Body, err)
+			}
+			got := make([]string, 0, len(snapshots))
+			for _, snapshot := range snapshots {
+				if snapshot != c[snapshot.Symbol] {
+					t.Errorf("snapshot %+v, want %+v", snapshot, c[snapshot.Symbol])
+				}
+				got = append(got, snapshot.Symbol)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("prices for %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	ServePrices(c, []string{"FAILUSDT"}, nil)(rec, httptest.NewRequest(http.MethodGet, "/prices", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("failing cache: status %d, want 503", rec.Code)
+	}
+}
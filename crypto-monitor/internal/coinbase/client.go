@@ -0,0 +1,128 @@
+package coinbase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"crypto-monitor/internal/feed"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultURL is the Coinbase Exchange WebSocket feed.
+const DefaultURL = "wss://ws-feed.exchange.coinbase.com"
+
+// Name is the venue name set on Coinbase price events.
+const Name = "coinbase"
+
+// Client connects to the Coinbase Exchange ticker channel.
+type Client struct {
+	url      string
+	products map[string]string
+}
+
+type subscribeMessage struct {
+	Type       string   `json:"type"`
+	ProductIDs []string `json:"product_ids"`
+	Channels   []string `json:"channels"`
+}
+
+type tickerMessage struct {
+	Type      string          `json:"type"`
+	ProductID string          `json:"product_id"`
+	Price     json.RawMessage `json:"price"`
+	Volume24h json.RawMessage `json:"volume_24h"`
+	Time      json.RawMessage `json:"time"`
+	Message   string          `json:"message"`
+	Reason    string          `json:"reason"`
+}
+
+// NewClient creates a Coinbase client for the feed at url. Products maps
+// canonical symbols to product IDs; unmapped symbols such as BTCUSDT are
+// requested as BTC-USDT.
+func NewClient(url string, products map[string]string) *Client {
+	if url == "" {
+		url = DefaultURL
+	}
+	return &Client{url: url, products: products}
+}
+
+// Name returns the venue name.
+func (c *Client) Name() string {
+	return Name
+}
+
+// StreamTickers subscribes to the products' tickers and emits price events
+// until context cancel.
+func (c *Client) StreamTickers(ctx context.Context, symbols []string) (<-chan feed.PriceEvent, <-chan error) {
+	products, err := feed.VenueSymbols(symbols, c.products, "-")
+	if err == nil && len(products) == 0 {
+		err = errors.New("at least one symbol required")
+	}
+	if err != nil {
+		return feed.Failed(err)
+	}
+	canonical := feed.Invert(products)
+
+	productIDs := make([]string, 0, len(products))
+	for _, symbol := range symbols {
+		productIDs = append(productIDs, products[symbol])
+	}
+
+	return feed.WebSocketFeed{
+		URL: c.url,
+		Subscribe: func(conn *websocket.Conn) error {
+			return conn.WriteJSON(subscribeMessage{Type: "subscribe", ProductIDs: productIDs, Channels: []string{"ticker"}})
+		},
+		Decode: func(payload []byte) ([]feed.PriceEvent, error) {
+			return decode(payload, canonical)
+		},
+	}.Stream(ctx)
+}
+
+// decode converts a ticker message into a price event. Subscription
+// confirmations and heartbeats yield no events.
+func decode(payload []byte, canonical map[string]string) ([]feed.PriceEvent, error) {
+	var msg tickerMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return nil, err
+	}
+
+	switch msg.Type {
+	case "ticker":
+	case "error":
+		return nil, fmt.Errorf("%s: %s", msg.Message, msg.Reason)
+	default:
+		return nil, nil
+	}
+
+	symbol, ok := canonical[msg.ProductID]
+	if !ok {
+		return nil, fmt.Errorf("unexpected product %q", msg.ProductID)
+	}
+	price, rawPrice, err := feed.ParseNumber(msg.Price)
+	if err != nil {
+		return nil, fmt.Errorf("price parse failed: %w", err)
+	}
+	event := feed.PriceEvent{
+		Source:    Name,
+		Symbol:    symbol,
+		Price:     price,
+		RawPrice:  rawPrice,
+		EventTime: time.Now().UTC(),
+	}
+	if len(msg.Volume24h) > 0 {
+		if event.Volume, _, err = feed.ParseNumber(msg.Volume24h); err != nil {
+			return nil, fmt.Errorf("volume parse failed: %w", err)
+		}
+	}
+	if len(msg.Time) > 0 {
+		if event.EventTime, err = feed.ParseTime(msg.Time); err != nil {
+			return nil, fmt.Errorf("time parse failed: %w", err)
+		}
+	}
+	return []feed.PriceEvent{event}, nil
+}
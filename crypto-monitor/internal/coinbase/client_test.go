@@ -0,0 +1,88 @@
+package coinbase
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/feed"
+)
+
+func TestDecode(t *testing.T) {
+	canonical := map[string]string{"BTC-USD": "BTCUSD"}
+
+	tests := []struct {
+		name    string
+		payload string
+		want    []feed.PriceEvent
+		wantErr string
+	}{
+		{
+			name:    "ticker",
+			payload: `{"type":"ticker","sequence":37475248783,"product_id":"BTC-USD","price":"67321.52","open_24h":"66010.01","volume_24h":"12534.77451203","low_24h":"65500.01","high_24h":"67800","volume_30d":"412093.1","best_bid":"67321.51","best_bid_size":"0.01000000","best_ask":"67321.52","best_ask_size":"0.50000000","side":"buy","time":"2024-05-14T10:15:42.123456Z","trade_id":641234567,"last_size":"0.0015"}`,
+			want: []feed.PriceEvent{{
+				Source:    Name,
+				Symbol:    "BTCUSD",
+				Price:     67321.52,
+				RawPrice:  "67321.52",
+				Volume:    12534.77451203,
+				EventTime: time.Date(2024, 5, 14, 10, 15, 42, 123456000, time.UTC),
+			}},
+		},
+		{
+			name:    "subscriptions",
+			payload: `{"type":"subscriptions","channels":[{"name":"ticker","product_ids":["BTC-USD"],"account_ids":null}]}`,
+		},
+		{
+			name:    "heartbeat",
+			payload: `{"type":"heartbeat","last_trade_id":641234567,"product_id":"BTC-USD","sequence":37475248790,"time":"2024-05-14T10:15:43.000000Z"}`,
+		},
+		{
+			name:    "error",
+			payload: `{"type":"error","message":"Failed to subscribe","reason":"BTC-USDX is not a valid product"}`,
+			wantErr: "Failed to subscribe: BTC-USDX is not a valid product",
+		},
+		{
+			name:    "unknown product",
+			payload: `{"type":"ticker","product_id":"ETH-USD","price":"3012.5","time":"2024-05-14T10:15:42Z"}`,
+			wantErr: `unexpected product "ETH-USD"`,
+		},
+		{
+			name:    "bad price",
+			payload: `{"type":"ticker","product_id":"BTC-USD","price":"n/a","time":"2024-05-14T10:15:42Z"}`,
+			wantErr: "price parse failed",
+		},
+		{
+			name:    "not JSON",
+			payload: `<html>`,
+			wantErr: "invalid character",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decode([]byte(tt.payload), canonical)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d events, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !got[i].EventTime.Equal(tt.want[i].EventTime) {
+					t.Errorf("event %d time = %v, want %v", i, got[i].EventTime, tt.want[i].EventTime)
+				}
+				got[i].EventTime = tt.want[i].EventTime
+				if got[i] != tt.want[i] {
+					t.Errorf("event %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -1,36 +1,154 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"time"
+)
+
+// Price source names accepted in PRICE_SOURCES.
+const (
+	SourceBinance  = "binance"
+	SourceCoinbase = "coinbase"
+	SourceKraken   = "kraken"
+	SourceGeneric  = "generic"
+)
+
+// Slow consumer policies accepted in WS_SLOW_CONSUMER_POLICY.
+const (
+	SlowConsumerDropOldest = "drop_oldest"
+	SlowConsumerDisconnect = "disconnect"
+)
+
+// Alert history stores accepted in HISTORY_STORE.
+const (
+	HistoryRedis  = "redis"
+	HistorySQLite = "sqlite"
+	HistoryNone   = "none"
+)
+
+// Reference price methods accepted in AGGREGATE_METHOD.
+const (
+	AggregateMedian = "median"
+	AggregateVWAP   = "vwap"
 )
 
 // Config holds runtime configuration.
 type Config struct {
 	Symbols           []string
+	PriceSources      []string
 	RedisAddr         string
 	RedisPassword     string
 	RedisDB           int
 	AlertThresholdPct float64
+	AlertWindows      []AlertWindow
+	AlertHysteresis   float64
+	AlertCooldown     time.Duration
+	WarningPct        float64
+	CriticalPct       float64
 	InternalWsAddr    string
+	WsQueueSize       int
+	WsSlowPolicy      string
+	WsPingInterval    time.Duration
+	WsWriteTimeout    time.Duration
+	PriceStreamRate   float64
+	WsTokensFile      string
+	WsAllowedOrigins  []string
+	NotifyFile        string
+	NotifyAttempts    int
+	NotifyBackoff     time.Duration
+	NotifyMaxBackoff  time.Duration
+	HistoryStore      string
+	HistoryRedisKey   string
+	HistorySQLitePath string
+	HistoryMaxAlerts  int64
+	WsReplayLimit     int
+	CoinbaseURL       string
+	CoinbaseProducts  map[string]string
+	KrakenURL         string
+	KrakenPairs       map[string]string
+	GenericFeed       GenericFeedConfig
+	AggregateMethod   string
+	StaleAfter        time.Duration
+	OutlierPct        float64
+	SpreadAlertBps    float64
+	RulesFile         string
+	RulesReload       time.Duration
+}
+
+// AlertWindow is a rolling window for price change alerts and the percent
+// change it alerts on.
+type AlertWindow struct {
+	Span         time.Duration
+	ThresholdPct float64
+}
+
+// GenericFeedConfig describes a JSON-over-WebSocket feed for the generic
+// price source.
+type GenericFeedConfig struct {
+	Name        string
+	URL         string
+	Subscribe   string
+	Symbols     map[string]string
+	SymbolField string
+	PriceField  string
+	TimeField   string
+	VolumeField string
 }
 
 // Load reads configuration from environment variables.
 func Load() (Config, error) {
 	config := Config{
 		Symbols:           []string{"BTCUSDT"},
+		PriceSources:      []string{SourceBinance, SourceKraken},
 		RedisAddr:         "localhost:6379",
 		RedisPassword:     "",
 		RedisDB:           0,
 		AlertThresholdPct: 1.0,
+		AlertHysteresis:   0.2,
+		AlertCooldown:     5 * time.Minute,
+		WarningPct:        2,
+		CriticalPct:       5,
 		InternalWsAddr:    ":8080",
+		WsQueueSize:       64,
+		WsSlowPolicy:      SlowConsumerDropOldest,
+		WsPingInterval:    30 * time.Second,
+		WsWriteTimeout:    5 * time.Second,
+		PriceStreamRate:   5,
+		AggregateMethod:   AggregateMedian,
+		StaleAfter:        30 * time.Second,
+		OutlierPct:        2.0,
+		SpreadAlertBps:    50,
+		RulesReload:       10 * time.Second,
+		NotifyAttempts:    4,
+		NotifyBackoff:     time.Second,
+		NotifyMaxBackoff:  time.Minute,
+		HistoryStore:      HistoryRedis,
+		HistoryRedisKey:   "alerts:history",
+		HistorySQLitePath: "alerts.db",
+		HistoryMaxAlerts:  100000,
+		WsReplayLimit:     500,
+		GenericFeed: GenericFeedConfig{
+			Name:        SourceGeneric,
+			SymbolField: "symbol",
+			PriceField:  "price",
+		},
 	}
 
+	// SYMBOLS names canonical symbols for every source; BINANCE_SYMBOLS is
+	// its older name.
 	if value := strings.TrimSpace(os.Getenv("BINANCE_SYMBOLS")); value != "" {
 		config.Symbols = splitCSV(value)
 	}
+	if value := strings.TrimSpace(os.Getenv("SYMBOLS")); value != "" {
+		config.Symbols = splitCSV(value)
+	}
+	if value := strings.TrimSpace(os.Getenv("PRICE_SOURCES")); value != "" {
+		config.PriceSources = splitCSV(strings.ToLower(value))
+	}
 	if value := strings.TrimSpace(os.Getenv("REDIS_ADDR")); value != "" {
 		config.RedisAddr = value
 	}
@@ -51,12 +169,304 @@ func Load() (Config, error) {
 		}
 		config.AlertThresholdPct = parsed
 	}
+	if err := loadWindows(&config); err != nil {
+		return Config{}, err
+	}
 	if value := strings.TrimSpace(os.Getenv("INTERNAL_WS_ADDR")); value != "" {
 		config.InternalWsAddr = value
 	}
+	if err := loadWebSocket(&config); err != nil {
+		return Config{}, err
+	}
+	config.RulesFile = strings.TrimSpace(os.Getenv("RULES_FILE"))
+	if value := strings.TrimSpace(os.Getenv("RULES_RELOAD_INTERVAL")); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid RULES_RELOAD_INTERVAL: %w", err)
+		}
+		config.RulesReload = parsed
+	}
+	if err := loadNotify(&config); err != nil {
+		return Config{}, err
+	}
+	if err := loadHistory(&config); err != nil {
+		return Config{}, err
+	}
+	if err := loadFeeds(&config); err != nil {
+		return Config{}, err
+	}
+	if err := loadAggregate(&config); err != nil {
+		return Config{}, err
+	}
 	return config, nil
 }
 
+// loadWindows reads the price change windows, each written as a duration
+// with an optional threshold, such as 5m:1.5, and how alerts are repeated
+// and graded. Windows without a threshold use ALERT_THRESHOLD_PCT.
+func loadWindows(config *Config) error {
+	value := strings.TrimSpace(os.Getenv("ALERT_WINDOWS"))
+	if value == "" {
+		value = "1m"
+	}
+	for _, entry := range splitCSV(value) {
+		span, threshold, hasThreshold := strings.Cut(entry, ":")
+		window := AlertWindow{ThresholdPct: config.AlertThresholdPct}
+		parsed, err := time.ParseDuration(strings.TrimSpace(span))
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("invalid ALERT_WINDOWS: %q is not a positive duration", span)
+		}
+		window.Span = parsed
+		if hasThreshold {
+			window.ThresholdPct, err = strconv.ParseFloat(strings.TrimSpace(threshold), 64)
+			if err != nil || window.ThresholdPct <= 0 {
+				return fmt.Errorf("invalid ALERT_WINDOWS: threshold %q for %s is not a positive number", threshold, span)
+			}
+		}
+		config.AlertWindows = append(config.AlertWindows, window)
+	}
+	if len(config.AlertWindows) == 0 {
+		return errors.New("invalid ALERT_WINDOWS: at least one window required")
+	}
+
+	if value := strings.TrimSpace(os.Getenv("ALERT_COOLDOWN")); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid ALERT_COOLDOWN: %w", err)
+		}
+		config.AlertCooldown = parsed
+	}
+	for name, field := range map[string]*float64{
+		"SEVERITY_WARNING_PCT":  &config.WarningPct,
+		"SEVERITY_CRITICAL_PCT": &config.CriticalPct,
+	} {
+		value := strings.TrimSpace(os.Getenv(name))
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+		*field = parsed
+	}
+	if config.WarningPct > config.CriticalPct {
+		return errors.New("invalid SEVERITY_WARNING_PCT: must not exceed SEVERITY_CRITICAL_PCT")
+	}
+
+	if value := strings.TrimSpace(os.Getenv("ALERT_HYSTERESIS")); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid ALERT_HYSTERESIS: %w", err)
+		}
+		if parsed < 0 || parsed >= 1 {
+			return errors.New("invalid ALERT_HYSTERESIS: must be at least 0 and below 1")
+		}
+		config.AlertHysteresis = parsed
+	}
+	return nil
+}
+
+// loadWebSocket reads how alerts are written to WebSocket clients.
+func loadWebSocket(config *Config) error {
+	if value := strings.TrimSpace(os.Getenv("WS_QUEUE_SIZE")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return fmt.Errorf("invalid WS_QUEUE_SIZE: %q is not a positive integer", value)
+		}
+		config.WsQueueSize = parsed
+	}
+	if value := strings.TrimSpace(os.Getenv("WS_SLOW_CONSUMER_POLICY")); value != "" {
+		config.WsSlowPolicy = strings.ToLower(value)
+	}
+	switch config.WsSlowPolicy {
+	case SlowConsumerDropOldest, SlowConsumerDisconnect:
+	default:
+		return fmt.Errorf("invalid WS_SLOW_CONSUMER_POLICY: %q is not drop_oldest or disconnect", config.WsSlowPolicy)
+	}
+	for name, field := range map[string]*time.Duration{
+		"WS_PING_INTERVAL": &config.WsPingInterval,
+		"WS_WRITE_TIMEOUT": &config.WsWriteTimeout,
+	} {
+		value := strings.TrimSpace(os.Getenv(name))
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("invalid %s: %q is not a positive duration", name, value)
+		}
+		*field = parsed
+	}
+	if value := strings.TrimSpace(os.Getenv("PRICE_STREAM_MAX_RATE")); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("invalid PRICE_STREAM_MAX_RATE: %q is not a positive number", value)
+		}
+		config.PriceStreamRate = parsed
+	}
+	config.WsTokensFile = strings.TrimSpace(os.Getenv("WS_TOKENS_FILE"))
+	if value := strings.TrimSpace(os.Getenv("WS_ALLOWED_ORIGINS")); value != "" {
+		config.WsAllowedOrigins = splitCSV(value)
+	}
+	return nil
+}
+
+// loadNotify reads where alerts are sent besides WebSocket clients and
+// how failed deliveries are retried.
+func loadNotify(config *Config) error {
+	config.NotifyFile = strings.TrimSpace(os.Getenv("NOTIFY_FILE"))
+	if value := strings.TrimSpace(os.Getenv("NOTIFY_MAX_ATTEMPTS")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return fmt.Errorf("invalid NOTIFY_MAX_ATTEMPTS: %q is not a positive integer", value)
+		}
+		config.NotifyAttempts = parsed
+	}
+	for name, field := range map[string]*time.Duration{
+		"NOTIFY_BACKOFF":     &config.NotifyBackoff,
+		"NOTIFY_MAX_BACKOFF": &config.NotifyMaxBackoff,
+	} {
+		value := strings.TrimSpace(os.Getenv(name))
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("invalid %s: %q is not a positive duration", name, value)
+		}
+		*field = parsed
+	}
+	return nil
+}
+
+// loadHistory reads where sent alerts are recorded and how many are
+// replayed to reconnecting clients.
+func loadHistory(config *Config) error {
+	if value := strings.TrimSpace(os.Getenv("HISTORY_STORE")); value != "" {
+		config.HistoryStore = strings.ToLower(value)
+	}
+	switch config.HistoryStore {
+	case HistoryRedis, HistorySQLite, HistoryNone:
+	default:
+		return fmt.Errorf("invalid HISTORY_STORE: %q is not redis, sqlite or none", config.HistoryStore)
+	}
+	if value := strings.TrimSpace(os.Getenv("HISTORY_REDIS_KEY")); value != "" {
+		config.HistoryRedisKey = value
+	}
+	if value := strings.TrimSpace(os.Getenv("HISTORY_SQLITE_PATH")); value != "" {
+		config.HistorySQLitePath = value
+	}
+	if value := strings.TrimSpace(os.Getenv("HISTORY_MAX_ALERTS")); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 1 {
+			return fmt.Errorf("invalid HISTORY_MAX_ALERTS: %q is not a positive integer", value)
+		}
+		config.HistoryMaxAlerts = parsed
+	}
+	if value := strings.TrimSpace(os.Getenv("WS_REPLAY_LIMIT")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return fmt.Errorf("invalid WS_REPLAY_LIMIT: %q is not a positive integer", value)
+		}
+		config.WsReplayLimit = parsed
+	}
+	return nil
+}
+
+// loadAggregate reads how venue prices are combined and compared.
+func loadAggregate(config *Config) error {
+	if value := strings.TrimSpace(os.Getenv("AGGREGATE_METHOD")); value != "" {
+		config.AggregateMethod = strings.ToLower(value)
+	}
+	switch config.AggregateMethod {
+	case AggregateMedian, AggregateVWAP:
+	default:
+		return fmt.Errorf("invalid AGGREGATE_METHOD: %q is not median or vwap", config.AggregateMethod)
+	}
+	if value := strings.TrimSpace(os.Getenv("AGGREGATE_STALE_AFTER")); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid AGGREGATE_STALE_AFTER: %w", err)
+		}
+		config.StaleAfter = parsed
+	}
+	for name, field := range map[string]*float64{
+		"AGGREGATE_OUTLIER_PCT": &config.OutlierPct,
+		"SPREAD_ALERT_BPS":      &config.SpreadAlertBps,
+	} {
+		value := strings.TrimSpace(os.Getenv(name))
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+		if parsed < 0 {
+			return fmt.Errorf("invalid %s: must not be negative", name)
+		}
+		*field = parsed
+	}
+	return nil
+}
+
+// loadFeeds reads the settings of the non-Binance price sources and checks
+// the selected sources.
+func loadFeeds(config *Config) error {
+	var err error
+	config.CoinbaseURL = strings.TrimSpace(os.Getenv("COINBASE_WS_URL"))
+	if config.CoinbaseProducts, err = splitPairs("COINBASE_PRODUCTS"); err != nil {
+		return err
+	}
+	config.KrakenURL = strings.TrimSpace(os.Getenv("KRAKEN_WS_URL"))
+	if config.KrakenPairs, err = splitPairs("KRAKEN_PAIRS"); err != nil {
+		return err
+	}
+
+	generic := &config.GenericFeed
+	for name, field := range map[string]*string{
+		"GENERIC_WS_NAME":         &generic.Name,
+		"GENERIC_WS_URL":          &generic.URL,
+		"GENERIC_WS_SUBSCRIBE":    &generic.Subscribe,
+		"GENERIC_WS_SYMBOL_FIELD": &generic.SymbolField,
+		"GENERIC_WS_PRICE_FIELD":  &generic.PriceField,
+		"GENERIC_WS_TIME_FIELD":   &generic.TimeField,
+		"GENERIC_WS_VOLUME_FIELD": &generic.VolumeField,
+	} {
+		if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+			*field = value
+		}
+	}
+	if generic.Symbols, err = splitPairs("GENERIC_WS_SYMBOLS"); err != nil {
+		return err
+	}
+
+	if len(config.Symbols) == 0 {
+		return errors.New("invalid SYMBOLS: at least one symbol required")
+	}
+	if len(config.PriceSources) == 0 {
+		return errors.New("invalid PRICE_SOURCES: at least one source required")
+	}
+	seen := make(map[string]bool, len(config.PriceSources))
+	for _, source := range config.PriceSources {
+		switch source {
+		case SourceBinance, SourceCoinbase, SourceKraken:
+		case SourceGeneric:
+			if generic.URL == "" {
+				return errors.New("GENERIC_WS_URL is required for the generic price source")
+			}
+		default:
+			return fmt.Errorf("invalid PRICE_SOURCES: unknown source %q", source)
+		}
+		if seen[source] {
+			return fmt.Errorf("invalid PRICE_SOURCES: %q listed twice", source)
+		}
+		seen[source] = true
+	}
+	return nil
+}
+
 func splitCSV(value string) []string {
 	parts := strings.Split(value, ",")
 	result := make([]string, 0, len(parts))
@@ -68,3 +478,18 @@ func splitCSV(value string) []string {
 	}
 	return result
 }
+
+// splitPairs parses the environment variable name as a comma-separated list
+// of SYMBOL:VENUE_SYMBOL pairs.
+func splitPairs(name string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	for _, pair := range splitCSV(os.Getenv(name)) {
+		symbol, venue, ok := strings.Cut(pair, ":")
+		symbol, venue = strings.TrimSpace(symbol), strings.TrimSpace(venue)
+		if !ok || symbol == "" || venue == "" {
+			return nil, fmt.Errorf("invalid %s: %q is not SYMBOL:VENUE_SYMBOL", name, pair)
+		}
+		pairs[symbol] = venue
+	}
+	return pairs, nil
+}
@@ -0,0 +1,118 @@
+// Package feed defines the venue-neutral price events produced by exchange
+// clients and the helpers they share.
+package feed
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PriceEvent represents a normalized price update. Symbol is the canonical
+// symbol, such as BTCUSDT, whatever the venue calls the market.
+type PriceEvent struct {
+	Source    string    `json:"source"`
+	Symbol    string    `json:"symbol"`
+	Price     float64   `json:"price"`
+	RawPrice  string    `json:"raw_price,omitempty"`
+	Volume    float64   `json:"volume,omitempty"`
+	EventTime time.Time `json:"event_time"`
+}
+
+// PriceSource streams price updates from one venue.
+type PriceSource interface {
+	// Name identifies the venue in events and errors.
+	Name() string
+	// StreamTickers emits price events for the canonical symbols until
+	// the context is cancelled, reconnecting as needed.
+	StreamTickers(ctx context.Context, symbols []string) (<-chan PriceEvent, <-chan error)
+}
+
+// Merge streams from every source and merges their events and errors.
+// Errors are prefixed with the source name unless they already are.
+func Merge(ctx context.Context, symbols []string, sources ...PriceSource) (<-chan PriceEvent, <-chan error) {
+	out := make(chan PriceEvent, 32*len(sources))
+	errCh := make(chan error, len(sources))
+
+	var wg sync.WaitGroup
+	for _, source := range sources {
+		events, errs := source.StreamTickers(ctx, symbols)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for event := range events {
+				select {
+				case <-ctx.Done():
+				case out <- event:
+				}
+			}
+		}()
+		go func(name string) {
+			defer wg.Done()
+			for err := range errs {
+				select {
+				case <-ctx.Done():
+				case errCh <- withSource(name, err):
+				}
+			}
+		}(source.Name())
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+		close(errCh)
+	}()
+
+	return out, errCh
+}
+
+func withSource(name string, err error) error {
+	if strings.HasPrefix(err.Error(), name+": ") {
+		return err
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// Failed returns closed streams that report err, for sources that cannot
+// start.
+func Failed(err error) (<-chan PriceEvent, <-chan error) {
+	out := make(chan PriceEvent)
+	errCh := make(chan error, 1)
+	errCh <- err
+	close(out)
+	close(errCh)
+	return out, errCh
+}
+
+// Tee copies every event from in to n streams. A slow consumer holds up
+// the others, so consumers should keep up or buffer.
+func Tee(ctx context.Context, in <-chan PriceEvent, n int) []<-chan PriceEvent {
+	outs := make([]chan PriceEvent, n)
+	streams := make([]<-chan PriceEvent, n)
+	for i := range outs {
+		outs[i] = make(chan PriceEvent, 32)
+		streams[i] = outs[i]
+	}
+
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+		for event := range in {
+			for _, out := range outs {
+				select {
+				case <-ctx.Done():
+					return
+				case out <- event:
+				}
+			}
+		}
+	}()
+
+	return streams
+}
@@ -0,0 +1,101 @@
+package feed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// quoteAssets are the quote currencies recognised when splitting a
+// canonical symbol, longest first so that USDT wins over USD.
+var quoteAssets = []string{"FDUSD", "USDT", "USDC", "BUSD", "USD", "EUR", "GBP", "JPY", "TRY", "BTC", "ETH", "BNB"}
+
+// VenueSymbols maps canonical symbols to a venue's names. Overrides are
+// used as given; other symbols are split into base and quote assets and
+// joined with separator, so BTCUSDT becomes BTC-USDT with "-".
+func VenueSymbols(symbols []string, overrides map[string]string, separator string) (map[string]string, error) {
+	venue := make(map[string]string, len(symbols))
+	for _, symbol := range symbols {
+		if name, ok := overrides[symbol]; ok {
+			venue[symbol] = name
+			continue
+		}
+		base, quote, ok := SplitSymbol(symbol)
+		if !ok {
+			return nil, fmt.Errorf("cannot derive a venue symbol for %s; configure it explicitly", symbol)
+		}
+		venue[symbol] = base + separator + quote
+	}
+	return venue, nil
+}
+
+// Invert returns the reverse of a symbol map.
+func Invert(symbols map[string]string) map[string]string {
+	inverted := make(map[string]string, len(symbols))
+	for canonical, venue := range symbols {
+		inverted[venue] = canonical
+	}
+	return inverted
+}
+
+// SplitSymbol splits a canonical symbol such as BTCUSDT into its base and
+// quote assets.
+func SplitSymbol(symbol string) (string, string, bool) {
+	symbol = strings.ToUpper(symbol)
+	for _, quote := range quoteAssets {
+		if base, ok := strings.CutSuffix(symbol, quote); ok && base != "" {
+			return base, quote, true
+		}
+	}
+	return "", "", false
+}
+
+// ParseNumber decodes a number sent either as a JSON number or a string,
+// returning it with its textual form.
+func ParseNumber(raw json.RawMessage) (float64, string, error) {
+	if len(raw) == 0 {
+		return 0, "", errors.New("missing number")
+	}
+
+	var asString string
+	if err := json.Unmarshal(raw, &asString); err == nil {
+		value, err := strconv.ParseFloat(asString, 64)
+		if err != nil {
+			return 0, "", fmt.Errorf("invalid number string: %w", err)
+		}
+		return value, asString, nil
+	}
+
+	var asFloat float64
+	if err := json.Unmarshal(raw, &asFloat); err == nil {
+		return asFloat, strconv.FormatFloat(asFloat, 'f', -1, 64), nil
+	}
+
+	return 0, "", fmt.Errorf("unsupported number payload: %s", string(raw))
+}
+
+// ParseTime decodes a timestamp sent as an RFC 3339 string or as Unix
+// milliseconds, either as a number or a string.
+func ParseTime(raw json.RawMessage) (time.Time, error) {
+	var asString string
+	if err := json.Unmarshal(raw, &asString); err == nil {
+		if parsed, err := time.Parse(time.RFC3339Nano, asString); err == nil {
+			return parsed, nil
+		}
+		millis, err := strconv.ParseInt(asString, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time string: %q", asString)
+		}
+		return time.UnixMilli(millis), nil
+	}
+
+	var millis int64
+	if err := json.Unmarshal(raw, &millis); err == nil {
+		return time.UnixMilli(millis), nil
+	}
+
+	return time.Time{}, fmt.Errorf("unsupported time payload: %s", string(raw))
+}
@@ -0,0 +1,156 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const maxReconnectBackoff = 30 * time.Second
+
+// Connection timeouts. Without a read deadline a connection that silently
+// stops delivering, e.g. behind a dead NAT mapping, would never be
+// replaced.
+const (
+	defaultReadTimeout = time.Minute
+	pongWriteTimeout   = 5 * time.Second
+)
+
+// WebSocketFeed consumes a JSON-over-WebSocket ticker feed, reconnecting
+// with exponential backoff whenever the connection fails.
+type WebSocketFeed struct {
+	URL              string
+	Dialer           *websocket.Dialer
+	ReconnectBackoff time.Duration
+	// ReadTimeout is how long the connection may stay silent before it is
+	// replaced; every message and ping restarts it. It defaults to a
+	// minute, so feeds that can be quiet for longer should subscribe to
+	// heartbeats.
+	ReadTimeout time.Duration
+	// Subscribe sends the subscription request after each connect. It may
+	// be nil for feeds that stream without one.
+	Subscribe func(conn *websocket.Conn) error
+	// Decode converts one message into price events. Messages that carry
+	// no prices, such as heartbeats and acknowledgements, yield none. A
+	// decode error is reported and the message skipped.
+	Decode func(payload []byte) ([]PriceEvent, error)
+}
+
+// Stream connects to the feed and emits its events until context cancel.
+func (f WebSocketFeed) Stream(ctx context.Context) (<-chan PriceEvent, <-chan error) {
+	out := make(chan PriceEvent, 32)
+	errCh := make(chan error, 1)
+
+	dialer := f.Dialer
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+	initialBackoff := f.ReconnectBackoff
+	if initialBackoff <= 0 {
+		initialBackoff = 2 * time.Second
+	}
+
+	go func() {
+		defer close(out)
+		defer close(errCh)
+
+		backoff := initialBackoff
+		for ctx.Err() == nil {
+			connected, err := f.session(ctx, dialer, out, errCh)
+			if ctx.Err() != nil {
+				return
+			}
+			report(ctx, errCh, err)
+			if connected {
+				backoff = initialBackoff
+			}
+
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			if !connected {
+				backoff = min(backoff*2, maxReconnectBackoff)
+			}
+		}
+	}()
+
+	return out, errCh
+}
+
+// session runs one connection until it fails or the context ends. It
+// reports whether the dial succeeded.
+func (f WebSocketFeed) session(ctx context.Context, dialer *websocket.Dialer, out chan<- PriceEvent, errCh chan<- error) (bool, error) {
+	conn, _, err := dialer.DialContext(ctx, f.URL, nil)
+	if err != nil {
+		return false, fmt.Errorf("dial failed: %w", err)
+	}
+	defer conn.Close()
+
+	// Unblock ReadMessage on shutdown.
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	readTimeout := f.ReadTimeout
+	if readTimeout <= 0 {
+		readTimeout = defaultReadTimeout
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+	conn.SetPingHandler(func(data string) error {
+		_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+		return pong(conn, data)
+	})
+
+	if f.Subscribe != nil {
+		if err := f.Subscribe(conn); err != nil {
+			return true, fmt.Errorf("subscribe failed: %w", err)
+		}
+	}
+
+	for {
+		_, payload, err := conn.ReadMessage()
+		if err != nil {
+			return true, fmt.Errorf("read failed: %w", err)
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+
+		events, err := f.Decode(payload)
+		if err != nil {
+			report(ctx, errCh, fmt.Errorf("decode failed: %w", err))
+			continue
+		}
+		for _, event := range events {
+			select {
+			case <-ctx.Done():
+				return true, ctx.Err()
+			case out <- event:
+			}
+		}
+	}
+}
+
+// pong answers a ping like gorilla's default handler: a pong that cannot
+// be sent is not an error of the read loop, which notices a broken
+// connection on its own.
+func pong(conn *websocket.Conn, data string) error {
+	err := conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(pongWriteTimeout))
+	var netErr net.Error
+	if errors.Is(err, websocket.ErrCloseSent) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return nil
+	}
+	return err
+}
+
+func report(ctx context.Context, errCh chan<- error, err error) {
+	select {
+	case <-ctx.Done():
+	case errCh <- err:
+	}
+}
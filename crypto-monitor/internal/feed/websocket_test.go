@@ -0,0 +1,99 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocketFeedReadDeadline(t *testing.T) {
+	const readTimeout = 100 * time.Millisecond
+	var connections, pongs atomic.Int32
+	pinging := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if connections.Add(1) > 1 {
+			<-r.Context().Done()
+			return
+		}
+
+		conn.SetPongHandler(func(string) error {
+			pongs.Add(1)
+			return nil
+		})
+		// Control frames are only processed while reading.
+		go func() {
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"price":1}`))
+		// Pings alone keep the connection alive for several timeouts...
+		for i := 0; i < 15; i++ {
+			time.Sleep(readTimeout / 5)
+			if err := conn.WriteControl(websocket.PingMessage, []byte("hi"), time.Now().Add(time.Second)); err != nil {
+				return
+			}
+		}
+		close(pinging)
+		// ...but silence does not.
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, errs := WebSocketFeed{
+		URL:              "ws" + strings.TrimPrefix(server.URL, "http"),
+		ReconnectBackoff: 10 * time.Millisecond,
+		ReadTimeout:      readTimeout,
+		Decode: func(payload []byte) ([]PriceEvent, error) {
+			return []PriceEvent{{Symbol: "BTCUSDT", Price: 1}}, nil
+		},
+	}.Stream(ctx)
+
+	select {
+	case <-events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+
+	select {
+	case err := <-errs:
+		t.Fatalf("error while the server was pinging: %v", err)
+	case <-pinging:
+	}
+	if got := pongs.Load(); got < 10 {
+		t.Errorf("server received %d pongs, want at least 10", got)
+	}
+
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "read failed") {
+			t.Errorf("error = %v, want a read timeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("silent connection was not dropped")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for connections.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("feed did not reconnect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,79 @@
+// Package history keeps a record of sent alerts so they can be queried and
+// replayed to clients that missed them.
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"crypto-monitor/internal/alerts"
+)
+
+// ErrInvalidID is returned for alert IDs the store could not have issued.
+var ErrInvalidID = errors.New("invalid alert id")
+
+// Query selects recorded alerts.
+type Query struct {
+	// Symbol, when set, selects one symbol's alerts.
+	Symbol string
+	// Symbols, when set, limits the alerts to these symbols.
+	Symbols []string
+	// Since, when set, selects alerts recorded at or after it.
+	Since time.Time
+	// Limit caps how many alerts are returned.
+	Limit int
+}
+
+// Store records alerts. IDs are issued in recording order.
+type Store interface {
+	// Append records an alert and returns it with its ID set.
+	Append(ctx context.Context, alert alerts.Alert) (alerts.Alert, error)
+	// Query returns the alerts matching q, newest first.
+	Query(ctx context.Context, q Query) ([]alerts.Alert, error)
+	// After returns up to limit alerts recorded after the one with the
+	// given ID, oldest first. trimmed reports that some alerts after it
+	// may have been deleted to keep the store to its size.
+	After(ctx context.Context, id string, limit int) (found []alerts.Alert, trimmed bool, err error)
+	Close() error
+}
+
+// Record appends each alert to the store and passes it on with its ID set.
+// Alerts that fail to append are passed on without an ID and the failure
+// is reported on the error stream.
+func Record(ctx context.Context, store Store, in <-chan alerts.Alert) (<-chan alerts.Alert, <-chan error) {
+	out := make(chan alerts.Alert, 32)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(errCh)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case alert, ok := <-in:
+				if !ok {
+					return
+				}
+				recorded, err := store.Append(ctx, alert)
+				if err != nil {
+					select {
+					case errCh <- fmt.Errorf("history: %w", err):
+					default:
+					}
+					recorded = alert
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case out <- recorded:
+				}
+			}
+		}
+	}()
+
+	return out, errCh
+}
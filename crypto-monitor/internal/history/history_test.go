@@ -0,0 +1,294 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/alerts"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// stores opens each kind of store, keeping about maxAlerts alerts.
+var stores = []struct {
+	name string
+	open func(t *testing.T, maxAlerts int64) Store
+}{
+	{name: "sqlite", open: func(t *testing.T, maxAlerts int64) Store { return openSQLite(t, maxAlerts) }},
+	{name: "redis", open: func(t *testing.T, maxAlerts int64) Store { return openRedis(t, maxAlerts) }},
+}
+
+func openSQLite(t *testing.T, maxAlerts int64) *SQLiteStore {
+	t.Helper()
+	store, err := OpenSQLite(filepath.Join(t.TempDir(), "history.db"), maxAlerts)
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+// openRedis starts a throwaway redis-server, skipping the test when there
+// is none installed.
+func openRedis(t *testing.T, maxLen int64) *RedisStore {
+	t.Helper()
+	path, err := exec.LookPath("redis-server")
+	if err != nil {
+		t.Skip("redis-server is not installed")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().(*net.TCPAddr)
+	_ = listener.Close()
+
+	cmd := exec.Command(path, "--bind", "127.0.0.1", "--port", strconv.Itoa(addr.Port), "--save", "", "--appendonly", "no")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start redis-server: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	client := redis.NewClient(&redis.Options{Addr: addr.String()})
+	t.Cleanup(func() { _ = client.Close() })
+	deadline := time.Now().Add(5 * time.Second)
+	for client.Ping(context.Background()).Err() != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("redis-server did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return NewRedisStore(client, "alerts", maxLen)
+}
+
+// appendAlerts records an alert for each symbol, with ChangePct counting
+// up from 1, and returns them as recorded.
+func appendAlerts(t *testing.T, store Store, symbols ...string) []alerts.Alert {
+	t.Helper()
+	recorded := make([]alerts.Alert, len(symbols))
+	for i, symbol := range symbols {
+		alert, err := store.Append(context.Background(), alerts.Alert{ID: "ignored", Type: alerts.TypePriceChange, Symbol: symbol, ChangePct: float64(i + 1)})
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		if alert.ID == "" || alert.ID == "ignored" {
+			t.Fatalf("Append set ID %q", alert.ID)
+		}
+		recorded[i] = alert
+	}
+	return recorded
+}
+
+// ids returns the IDs of alerts, checking each matches the one recorded.
+func ids(t *testing.T, found []alerts.Alert, recorded []alerts.Alert) []string {
+	t.Helper()
+	out := make([]string, 0, len(found))
+	for _, alert := range found {
+		i := slices.IndexFunc(recorded, func(r alerts.Alert) bool { return r.ID == alert.ID })
+		if i < 0 || alert != recorded[i] {
+			t.Errorf("found %+v, which was not recorded", alert)
+		}
+		out = append(out, alert.ID)
+	}
+	return out
+}
+
+// pick returns the IDs of the recorded alerts at the given indexes.
+func pick(recorded []alerts.Alert, indexes ...int) []string {
+	out := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		out = append(out, recorded[i].ID)
+	}
+	return out
+}
+
+func TestStoreQuery(t *testing.T) {
+	for _, kind := range stores {
+		t.Run(kind.name, func(t *testing.T) {
+			store := kind.open(t, 100)
+			recorded := appendAlerts(t, store, "BTCUSDT", "ETHUSDT", "BTCUSDT", "SOLUSDT", "BTCUSDT")
+
+			tests := []struct {
+				name string
+				q    Query
+				want []int
+			}{
+				{name: "all", q: Query{Limit: 10}, want: []int{4, 3, 2, 1, 0}},
+				{name: "limit", q: Query{Limit: 2}, want: []int{4, 3}},
+				{name: "symbol", q: Query{Symbol: "BTCUSDT", Limit: 10}, want: []int{4, 2, 0}},
+				{name: "symbols", q: Query{Symbols: []string{"ETHUSDT", "SOLUSDT"}, Limit: 10}, want: []int{3, 1}},
+				{name: "symbol outside symbols", q: Query{Symbol: "BTCUSDT", Symbols: []string{"ETHUSDT"}, Limit: 10}},
+				{name: "since an hour ago", q: Query{Since: time.Now().Add(-time.Hour), Limit: 10}, want: []int{4, 3, 2, 1, 0}},
+				{name: "since later", q: Query{Since: time.Now().Add(time.Hour), Limit: 10}},
+			}
+			for _, tt := range tests {
+				found, err := store.Query(context.Background(), tt.q)
+				if err != nil {
+					t.Fatalf("%s: Query: %v", tt.name, err)
+				}
+				if got, want := ids(t, found, recorded), pick(recorded, tt.want...); !slices.Equal(got, want) {
+					t.Errorf("%s: found %v, want %v", tt.name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreQueryPages(t *testing.T) {
+	for _, kind := range stores {
+		t.Run(kind.name, func(t *testing.T) {
+			store := kind.open(t, 2000)
+			// One BTCUSDT alert in four, so finding them reads past
+			// several Redis pages.
+			symbols := make([]string, 1100)
+			var btc []int
+			for i := range symbols {
+				symbols[i] = "ETHUSDT"
+				if i%4 == 0 {
+					symbols[i] = "BTCUSDT"
+					btc = append([]int{i}, btc...)
+				}
+			}
+			recorded := appendAlerts(t, store, symbols...)
+
+			found, err := store.Query(context.Background(), Query{Symbol: "BTCUSDT", Limit: MaxLimit})
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			if got, want := ids(t, found, recorded), pick(recorded, btc...); !slices.Equal(got, want) {
+				t.Errorf("found %d alerts, want the %d BTCUSDT ones newest first", len(got), len(want))
+			}
+
+			found, err = store.Query(context.Background(), Query{Limit: 600})
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			if len(found) != 600 || found[0].ID != recorded[1099].ID || found[599].ID != recorded[500].ID {
+				t.Errorf("found %d alerts, from %s to %s", len(found), found[0].ID, found[len(found)-1].ID)
+			}
+		})
+	}
+}
+
+func TestStoreAfter(t *testing.T) {
+	for _, kind := range stores {
+		t.Run(kind.name, func(t *testing.T) {
+			store := kind.open(t, 100)
+			recorded := appendAlerts(t, store, "BTCUSDT", "ETHUSDT", "BTCUSDT", "SOLUSDT", "BTCUSDT")
+
+			tests := []struct {
+				id    string
+				limit int
+				want  []int
+			}{
+				{id: recorded[1].ID, limit: 10, want: []int{2, 3, 4}},
+				{id: recorded[1].ID, limit: 2, want: []int{2, 3}},
+				{id: recorded[4].ID, limit: 10},
+			}
+			for _, tt := range tests {
+				found, trimmed, err := store.After(context.Background(), tt.id, tt.limit)
+				if err != nil || trimmed {
+					t.Fatalf("After(%s, %d): trimmed %v, error %v", tt.id, tt.limit, trimmed, err)
+				}
+				if got, want := ids(t, found, recorded), pick(recorded, tt.want...); !slices.Equal(got, want) {
+					t.Errorf("After(%s, %d) = %v, want %v", tt.id, tt.limit, got, want)
+				}
+			}
+
+			for _, id := range []string{"", "abc", "-1", "1-2-3"} {
+				if _, _, err := store.After(context.Background(), id, 10); !errors.Is(err, ErrInvalidID) {
+					t.Errorf("After(%q): error %v, want ErrInvalidID", id, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSQLiteStoreTrims(t *testing.T) {
+	store := openSQLite(t, 3)
+	recorded := appendAlerts(t, store, "BTCUSDT", "ETHUSDT", "BTCUSDT", "SOLUSDT", "BTCUSDT")
+
+	found, err := store.Query(context.Background(), Query{Limit: 10})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if got, want := ids(t, found, recorded), pick(recorded, 4, 3, 2); !slices.Equal(got, want) {
+		t.Errorf("kept %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		id          string
+		want        []int
+		wantTrimmed bool
+	}{
+		// The alert after the first is gone too.
+		{id: recorded[0].ID, want: []int{2, 3, 4}, wantTrimmed: true},
+		// Only the alert itself is gone; nothing after it was missed.
+		{id: recorded[1].ID, want: []int{2, 3, 4}},
+		{id: recorded[2].ID, want: []int{3, 4}},
+	}
+	for _, tt := range tests {
+		found, trimmed, err := store.After(context.Background(), tt.id, 10)
+		if err != nil {
+			t.Fatalf("After(%s): %v", tt.id, err)
+		}
+		if got, want := ids(t, found, recorded), pick(recorded, tt.want...); !slices.Equal(got, want) || trimmed != tt.wantTrimmed {
+			t.Errorf("After(%s) = %v, trimmed %v; want %v, %v", tt.id, got, trimmed, want, tt.wantTrimmed)
+		}
+	}
+}
+
+func TestRedisStoreTrims(t *testing.T) {
+	store := openRedis(t, 10)
+	// Redis trims whole nodes of the stream, about a hundred entries, so
+	// it keeps more than asked for; enough alerts make it trim some.
+	symbols := make([]string, 500)
+	for i := range symbols {
+		symbols[i] = "BTCUSDT"
+	}
+	recorded := appendAlerts(t, store, symbols...)
+
+	found, err := store.Query(context.Background(), Query{Limit: MaxLimit})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(found) < 10 || len(found) >= len(recorded) || found[0].ID != recorded[len(recorded)-1].ID {
+		t.Fatalf("kept %d of %d alerts", len(found), len(recorded))
+	}
+
+	oldest := found[len(found)-1].ID
+	if _, trimmed, err := store.After(context.Background(), recorded[0].ID, 10); err != nil || !trimmed {
+		t.Errorf("After the first alert: trimmed %v, error %v; want trimmed", trimmed, err)
+	}
+	if _, trimmed, err := store.After(context.Background(), oldest, 10); err != nil || trimmed {
+		t.Errorf("After the oldest kept alert: trimmed %v, error %v", trimmed, err)
+	}
+}
+
+func TestCompareIDs(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1-0", b: "1-0", want: 0},
+		{a: "1", b: "1-0", want: 0},
+		{a: "2-0", b: "10-0", want: -1},
+		{a: "5-10", b: "5-9", want: 1},
+		{a: "6-0", b: "5-99", want: 1},
+	}
+	for _, tt := range tests {
+		if got := compareIDs(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareIDs(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package history
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"crypto-monitor/internal/alerts"
+)
+
+// Limits on the alerts GET /alerts returns.
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// ServeAlerts handles GET /alerts?symbol=&since=&limit=, listing recorded
+// alerts newest first. since is an RFC 3339 time or a duration back from
+// now, such as 1h. permitted, when set, returns the symbols a request may
+// see, nil meaning all.
+func ServeAlerts(store Store, permitted func(*http.Request) []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		q := Query{
+			Symbol: strings.ToUpper(strings.TrimSpace(query.Get("symbol"))),
+			Limit:  DefaultLimit,
+		}
+		if value := query.Get("since"); value != "" {
+			since, err := parseSince(value)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "since must be an RFC 3339 time or a duration such as 1h"})
+				return
+			}
+			q.Since = since
+		}
+		if value := query.Get("limit"); value != "" {
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 1 || limit > MaxLimit {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be between 1 and " + strconv.Itoa(MaxLimit)})
+				return
+			}
+			q.Limit = limit
+		}
+		if permitted != nil {
+			if symbols := permitted(r); symbols != nil {
+				if q.Symbol != "" && !slices.Contains(symbols, q.Symbol) {
+					writeJSON(w, http.StatusForbidden, map[string]string{"error": "symbol not permitted"})
+					return
+				}
+				q.Symbols = symbols
+			}
+		}
+
+		found, err := store.Query(r.Context(), q)
+		if err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "alert history unavailable"})
+			return
+		}
+		if found == nil {
+			found = []alerts.Alert{}
+		}
+		writeJSON(w, http.StatusOK, found)
+	}
+}
+
+func parseSince(value string) (time.Time, error) {
+	if ago, err := time.ParseDuration(value); err == nil && ago >= 0 {
+		return time.Now().Add(-ago), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
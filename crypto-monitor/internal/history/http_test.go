@@ -0,0 +1,66 @@
+package history
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"crypto-monitor/internal/alerts"
+)
+
+func TestServeAlerts(t *testing.T) {
+	store := openSQLite(t, 100)
+	recorded := appendAlerts(t, store, "BTCUSDT", "ETHUSDT", "BTCUSDT", "SOLUSDT")
+	// A request carrying "btc" may only see BTCUSDT.
+	permitted := func(r *http.Request) []string {
+		if r.Header.Get("Authorization") == "btc" {
+			return []string{"BTCUSDT"}
+		}
+		return nil
+	}
+	handler := ServeAlerts(store, permitted)
+
+	tests := []struct {
+		name       string
+		target     string
+		btcOnly    bool
+		wantStatus int
+		want       []int
+	}{
+		{name: "all", target: "/alerts", wantStatus: http.StatusOK, want: []int{3, 2, 1, 0}},
+		{name: "symbol", target: "/alerts?symbol=ethusdt", wantStatus: http.StatusOK, want: []int{1}},
+		{name: "limit", target: "/alerts?limit=2", wantStatus: http.StatusOK, want: []int{3, 2}},
+		{name: "since", target: "/alerts?since=1h", wantStatus: http.StatusOK, want: []int{3, 2, 1, 0}},
+		{name: "none since", target: "/alerts?since=2999-01-01T00:00:00Z", wantStatus: http.StatusOK, want: []int{}},
+		{name: "bad limit", target: "/alerts?limit=1001", wantStatus: http.StatusBadRequest},
+		{name: "bad since", target: "/alerts?since=yesterday", wantStatus: http.StatusBadRequest},
+		{name: "permitted symbols", target: "/alerts", btcOnly: true, wantStatus: http.StatusOK, want: []int{2, 0}},
+		{name: "permitted symbol", target: "/alerts?symbol=BTCUSDT&limit=1", btcOnly: true, wantStatus: http.StatusOK, want: []int{2}},
+		{name: "symbol not permitted", target: "/alerts?symbol=ETHUSDT", btcOnly: true, wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.btcOnly {
+				r.Header.Set("Authorization", "btc")
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.want == nil {
+				return
+			}
+			var found []alerts.Alert
+			if err := json.Unmarshal(rec.Body.Bytes(), &found); err != nil || found == nil {
+				t.Fatalf("body %s: %v", rec.Body, err)
+			}
+			if got, want := ids(t, found, recorded), pick(recorded, tt.want...); !slices.Equal(got, want) {
+				t.Errorf("found %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package history
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"crypto-monitor/internal/alerts"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// streamID matches Redis stream entry IDs.
+var streamID = regexp.MustCompile(`^\d+(-\d+)?$`)
+
+// pageSize is how many entries a Redis query reads at a time while
+// filtering by symbol.
+const pageSize = 500
+
+// RedisStore records alerts in a Redis stream, using entry IDs as alert
+// IDs. The stream is trimmed to about maxLen entries.
+type RedisStore struct {
+	client *redis.Client
+	key    string
+	maxLen int64
+}
+
+// NewRedisStore builds a store on the stream at key.
+func NewRedisStore(client *redis.Client, key string, maxLen int64) *RedisStore {
+	return &RedisStore{client: client, key: key, maxLen: maxLen}
+}
+
+// Append adds an alert to the stream.
+func (s *RedisStore) Append(ctx context.Context, alert alerts.Alert) (alerts.Alert, error) {
+	alert.ID = ""
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return alert, fmt.Errorf("redis encode failed: %w", err)
+	}
+	id, err := s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.key,
+		MaxLen: s.maxLen,
+		Approx: true,
+		Values: map[string]any{"symbol": alert.Symbol, "alert": payload},
+	}).Result()
+	if err != nil {
+		return alert, fmt.Errorf("redis xadd failed: %w", err)
+	}
+	alert.ID = id
+	return alert, nil
+}
+
+// Query reads the stream backwards from its newest entry, down to the
+// entry ID for q.Since.
+func (s *RedisStore) Query(ctx context.Context, q Query) ([]alerts.Alert, error) {
+	stop := "-"
+	if !q.Since.IsZero() {
+		stop = strconv.FormatInt(q.Since.UnixMilli(), 10)
+	}
+	start := "+"
+	symbols := make(map[string]bool, len(q.Symbols))
+	for _, symbol := range q.Symbols {
+		symbols[symbol] = true
+	}
+	found := make([]alerts.Alert, 0, q.Limit)
+	for len(found) < q.Limit {
+		messages, err := s.client.XRevRangeN(ctx, s.key, start, stop, pageSize).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis xrevrange failed: %w", err)
+		}
+		for _, message := range messages {
+			symbol, _ := message.Values["symbol"].(string)
+			if (q.Symbol != "" && symbol != q.Symbol) || (len(symbols) > 0 && !symbols[symbol]) {
+				continue
+			}
+			alert, err := decode(message)
+			if err != nil {
+				return nil, err
+			}
+			found = append(found, alert)
+			if len(found) == q.Limit {
+				break
+			}
+		}
+		if len(messages) < pageSize {
+			break
+		}
+		start = "(" + messages[len(messages)-1].ID
+	}
+	return found, nil
+}
+
+// After reads the stream forwards from the entry after id. Entries may
+// have been trimmed when the oldest left is newer than id.
+func (s *RedisStore) After(ctx context.Context, id string, limit int) ([]alerts.Alert, bool, error) {
+	if !streamID.MatchString(id) {
+		return nil, false, ErrInvalidID
+	}
+	messages, err := s.client.XRangeN(ctx, s.key, "("+id, "+", int64(limit)).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("redis xrange failed: %w", err)
+	}
+	found := make([]alerts.Alert, 0, len(messages))
+	for _, message := range messages {
+		alert, err := decode(message)
+		if err != nil {
+			return nil, false, err
+		}
+		found = append(found, alert)
+	}
+	oldest, err := s.client.XRangeN(ctx, s.key, "-", "+", 1).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("redis xrange failed: %w", err)
+	}
+	return found, len(oldest) > 0 && compareIDs(oldest[0].ID, id) > 0, nil
+}
+
+// Close does nothing; the Redis client is shared and closed by its owner.
+func (s *RedisStore) Close() error {
+	return nil
+}
+
+// compareIDs orders two stream entry IDs, returning -1, 0 or +1.
+func compareIDs(a, b string) int {
+	aMillis, aSeq := splitID(a)
+	bMillis, bSeq := splitID(b)
+	if c := cmp.Compare(aMillis, bMillis); c != 0 {
+		return c
+	}
+	return cmp.Compare(aSeq, bSeq)
+}
+
+// splitID parses a stream entry ID; a missing sequence number is 0.
+func splitID(id string) (millis, seq uint64) {
+	millisPart, seqPart, _ := strings.Cut(id, "-")
+	millis, _ = strconv.ParseUint(millisPart, 10, 64)
+	seq, _ = strconv.ParseUint(seqPart, 10, 64)
+	return millis, seq
+}
+
+func decode(message redis.XMessage) (alerts.Alert, error) {
+	payload, _ := message.Values["alert"].(string)
+	var alert alerts.Alert
+	if err := json.Unmarshal([]byte(payload), &alert); err != nil {
+		return alerts.Alert{}, fmt.Errorf("redis decode failed: %w", err)
+	}
+	alert.ID = message.ID
+	return alert, nil
+}
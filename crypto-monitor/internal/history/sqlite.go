@@ -0,0 +1,149 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"crypto-monitor/internal/alerts"
+
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS alerts (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	symbol      TEXT    NOT NULL,
+	recorded_at INTEGER NOT NULL,
+	alert       TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS alerts_recorded_at ON alerts (recorded_at);
+CREATE INDEX IF NOT EXISTS alerts_symbol ON alerts (symbol, id);
+`
+
+// SQLiteStore records alerts in an SQLite database file, using row IDs as
+// alert IDs. Rows beyond the newest maxAlerts are deleted.
+type SQLiteStore struct {
+	db        *sql.DB
+	maxAlerts int64
+	now       func() time.Time
+}
+
+// OpenSQLite opens, or creates, the database at path.
+func OpenSQLite(path string, maxAlerts int64) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("sqlite open failed: %w", err)
+	}
+	// SQLite allows one writer; sharing one connection avoids lock errors.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("sqlite schema failed: %w", err)
+	}
+	return &SQLiteStore{db: db, maxAlerts: maxAlerts, now: time.Now}, nil
+}
+
+// Append inserts an alert and deletes rows past the limit.
+func (s *SQLiteStore) Append(ctx context.Context, alert alerts.Alert) (alerts.Alert, error) {
+	alert.ID = ""
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return alert, fmt.Errorf("sqlite encode failed: %w", err)
+	}
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO alerts (symbol, recorded_at, alert) VALUES (?, ?, ?)`,
+		alert.Symbol, s.now().UnixMilli(), string(payload))
+	if err != nil {
+		return alert, fmt.Errorf("sqlite insert failed: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return alert, fmt.Errorf("sqlite insert failed: %w", err)
+	}
+	if s.maxAlerts > 0 && id > s.maxAlerts {
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM alerts WHERE id <= ?`, id-s.maxAlerts); err != nil {
+			return alert, fmt.Errorf("sqlite trim failed: %w", err)
+		}
+	}
+	alert.ID = strconv.FormatInt(id, 10)
+	return alert, nil
+}
+
+// Query selects matching rows, newest first.
+func (s *SQLiteStore) Query(ctx context.Context, q Query) ([]alerts.Alert, error) {
+	query := `SELECT id, alert FROM alerts WHERE recorded_at >= ?`
+	args := []any{int64(0)}
+	if !q.Since.IsZero() {
+		args[0] = q.Since.UnixMilli()
+	}
+	if q.Symbol != "" {
+		query += ` AND symbol = ?`
+		args = append(args, q.Symbol)
+	}
+	if len(q.Symbols) > 0 {
+		query += ` AND symbol IN (?` + strings.Repeat(`, ?`, len(q.Symbols)-1) + `)`
+		for _, symbol := range q.Symbols {
+			args = append(args, symbol)
+		}
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, q.Limit)
+	return s.scan(ctx, query, args...)
+}
+
+// After selects the rows after id, oldest first. Rows were trimmed when
+// the oldest left is not the one right after id.
+func (s *SQLiteStore) After(ctx context.Context, id string, limit int) ([]alerts.Alert, bool, error) {
+	after, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || after < 0 {
+		return nil, false, ErrInvalidID
+	}
+	found, err := s.scan(ctx, `SELECT id, alert FROM alerts WHERE id > ? ORDER BY id LIMIT ?`, after, limit)
+	if err != nil {
+		return nil, false, err
+	}
+	var oldest int64
+	if err := s.db.QueryRowContext(ctx, `SELECT COALESCE(MIN(id), 0) FROM alerts`).Scan(&oldest); err != nil {
+		return nil, false, fmt.Errorf("sqlite query failed: %w", err)
+	}
+	return found, oldest > after+1, nil
+}
+
+// Close closes the database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) scan(ctx context.Context, query string, args ...any) ([]alerts.Alert, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var found []alerts.Alert
+	for rows.Next() {
+		var (
+			id      int64
+			payload string
+			alert   alerts.Alert
+		)
+		if err := rows.Scan(&id, &payload); err != nil {
+			return nil, fmt.Errorf("sqlite scan failed: %w", err)
+		}
+		if err := json.Unmarshal([]byte(payload), &alert); err != nil {
+			return nil, fmt.Errorf("sqlite decode failed: %w", err)
+		}
+		alert.ID = strconv.FormatInt(id, 10)
+		found = append(found, alert)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite query failed: %w", err)
+	}
+	return found, nil
+}
@@ -0,0 +1,49 @@
+package incidents
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+)
+
+// ServeGroups handles GET /alert-groups, listing the open groups.
+// permitted, when set, returns the symbols a request may see, nil meaning
+// all; groups for other symbols are left out.
+func (t *Tracker) ServeGroups(permitted func(*http.Request) []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groups := t.Groups()
+		if symbols := permittedSymbols(permitted, r); symbols != nil {
+			groups = slices.DeleteFunc(groups, func(group Group) bool {
+				return !slices.Contains(symbols, group.Symbol)
+			})
+		}
+		writeJSON(w, http.StatusOK, groups)
+	}
+}
+
+// ServeAcknowledge handles POST /alert-groups/{id}/ack. Groups for symbols
+// a request may not see are reported as not found.
+func (t *Tracker) ServeAcknowledge(permitted func(*http.Request) []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		group, err := t.acknowledge(r.PathValue("id"), permittedSymbols(permitted, r))
+		if errors.Is(err, ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, group)
+	}
+}
+
+func permittedSymbols(permitted func(*http.Request) []string, r *http.Request) []string {
+	if permitted == nil {
+		return nil
+	}
+	return permitted(r)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,120 @@
+package incidents
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/alerts"
+)
+
+// btcOnly permits only BTCUSDT to requests carrying "btc".
+func btcOnly(r *http.Request) []string {
+	if r.Header.Get("Authorization") == "btc" {
+		return []string{"BTCUSDT"}
+	}
+	return nil
+}
+
+// openGroups tracks an alert for each symbol a minute apart and returns
+// their group IDs by symbol.
+func openGroups(t *testing.T, tracker *Tracker, symbols ...string) map[string]string {
+	t.Helper()
+	now := time.Date(2024, 5, 14, 10, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+	ids := make(map[string]string, len(symbols))
+	for _, symbol := range symbols {
+		now = now.Add(time.Minute)
+		alert, _ := tracker.track(alerts.Alert{Type: alerts.TypePriceChange, Symbol: symbol, Window: "1m", ChangePct: 1})
+		ids[symbol] = alert.Group
+	}
+	return ids
+}
+
+func TestServeGroups(t *testing.T) {
+	tracker := New(Config{Cooldown: time.Hour, WarningPct: 2, CriticalPct: 5})
+	openGroups(t, tracker, "BTCUSDT", "ETHUSDT")
+	handler := tracker.ServeGroups(btcOnly)
+
+	tests := []struct {
+		name    string
+		btcOnly bool
+		want    []string
+	}{
+		{name: "all", want: []string{"ETHUSDT", "BTCUSDT"}},
+		{name: "permitted symbols", btcOnly: true, want: []string{"BTCUSDT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/alert-groups", nil)
+			if tt.btcOnly {
+				r.Header.Set("Authorization", "btc")
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, r)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body)
+			}
+			var groups []Group
+			if err := json.Unmarshal(rec.Body.Bytes(), &groups); err != nil {
+				t.Fatalf("body %s: %v", rec.Body, err)
+			}
+			var got []string
+			for _, group := range groups {
+				got = append(got, group.Symbol)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("groups for %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeAcknowledge(t *testing.T) {
+	tracker := New(Config{Cooldown: time.Hour, WarningPct: 2, CriticalPct: 5})
+	ids := openGroups(t, tracker, "BTCUSDT", "ETHUSDT", "SOLUSDT")
+	handler := tracker.ServeAcknowledge(btcOnly)
+
+	tests := []struct {
+		name       string
+		id         string
+		btcOnly    bool
+		wantStatus int
+	}{
+		{name: "acknowledged", id: ids["ETHUSDT"], wantStatus: http.StatusOK},
+		{name: "permitted symbol", id: ids["BTCUSDT"], btcOnly: true, wantStatus: http.StatusOK},
+		{name: "symbol not permitted", id: ids["SOLUSDT"], btcOnly: true, wantStatus: http.StatusNotFound},
+		{name: "unknown group", id: "0123456789abcdef", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/alert-groups/"+tt.id+"/ack", nil)
+			r.SetPathValue("id", tt.id)
+			if tt.btcOnly {
+				r.Header.Set("Authorization", "btc")
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var group Group
+			if err := json.Unmarshal(rec.Body.Bytes(), &group); err != nil || group.ID != tt.id || !group.Acknowledged {
+				t.Errorf("body %s: %v", rec.Body, err)
+			}
+		})
+	}
+
+	// The group the request could not see was left alone.
+	for _, group := range tracker.Groups() {
+		if group.Symbol == "SOLUSDT" && group.Acknowledged {
+			t.Error("SOLUSDT group was acknowledged")
+		}
+	}
+}
@@ -0,0 +1,256 @@
+// Package incidents groups repeated alerts so consumers see one escalating
+// alert per incident instead of one per tick.
+package incidents
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"crypto-monitor/internal/alerts"
+)
+
+// Severity tiers, from the magnitude of an alert's ChangePct.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// ErrNotFound is returned for groups that do not exist or have closed.
+var ErrNotFound = errors.New("alert group not found")
+
+// Config controls grouping and severity.
+type Config struct {
+	// Cooldown is how long a group stays quiet after sending an alert,
+	// and how long it stays open after its last alert.
+	Cooldown time.Duration
+	// WarningPct and CriticalPct are the absolute ChangePct at which
+	// alerts become warnings and critical.
+	WarningPct  float64
+	CriticalPct float64
+}
+
+// Group is a run of alerts for one symbol and rule, each within Cooldown of
+// the one before.
+type Group struct {
+	ID             string       `json:"id"`
+	Key            string       `json:"key"`
+	Symbol         string       `json:"symbol"`
+	Type           string       `json:"type"`
+	Severity       string       `json:"severity"`
+	Occurrences    int          `json:"occurrences"`
+	FirstSeen      time.Time    `json:"first_seen"`
+	LastSeen       time.Time    `json:"last_seen"`
+	LastSent       time.Time    `json:"last_sent"`
+	Acknowledged   bool         `json:"acknowledged"`
+	AcknowledgedAt *time.Time   `json:"acknowledged_at,omitempty"`
+	Latest         alerts.Alert `json:"latest"`
+}
+
+// Tracker groups alerts, holding back repeats within a group's cooldown.
+// A group sends its first alert, any alert that raises its severity, and
+// after each cooldown a reminder with the latest alert unless it has been
+// acknowledged. Escalation clears an acknowledgement.
+type Tracker struct {
+	mu     sync.Mutex
+	config Config
+	now    func() time.Time
+	groups map[string]*Group
+	byID   map[string]*Group
+}
+
+// New builds a tracker.
+func New(config Config) *Tracker {
+	return &Tracker{
+		config: config,
+		now:    time.Now,
+		groups: make(map[string]*Group),
+		byID:   make(map[string]*Group),
+	}
+}
+
+// Start groups alerts and emits the ones to send, with their severity,
+// group ID and occurrence count set.
+func (t *Tracker) Start(ctx context.Context, in <-chan alerts.Alert) <-chan alerts.Alert {
+	out := make(chan alerts.Alert, 32)
+
+	go func() {
+		defer close(out)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case alert, ok := <-in:
+				if !ok {
+					return
+				}
+				alert, send := t.track(alert)
+				if !send {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case out <- alert:
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// track adds an alert to its group and reports whether to send it.
+func (t *Tracker) track(alert alerts.Alert) (alerts.Alert, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now().UTC()
+	t.expire(now)
+
+	alert.Severity = t.severity(alert.ChangePct)
+	key := Key(alert)
+	group, open := t.groups[key]
+	if !open {
+		group = &Group{
+			ID:        newID(),
+			Key:       key,
+			Symbol:    alert.Symbol,
+			Type:      alert.Type,
+			Severity:  alert.Severity,
+			FirstSeen: now,
+		}
+		t.groups[key] = group
+		t.byID[group.ID] = group
+	}
+	group.Occurrences++
+	group.LastSeen = now
+	alert.Group = group.ID
+	alert.Occurrences = group.Occurrences
+	group.Latest = alert
+
+	send := !open
+	switch {
+	case open && rank(alert.Severity) > rank(group.Severity):
+		group.Severity = alert.Severity
+		group.Acknowledged = false
+		group.AcknowledgedAt = nil
+		send = true
+	case open && !group.Acknowledged && now.Sub(group.LastSent) >= t.config.Cooldown:
+		send = true
+	}
+	if send {
+		group.LastSent = now
+	}
+	return alert, send
+}
+
+// expire closes groups that have been quiet for a cooldown.
+func (t *Tracker) expire(now time.Time) {
+	for key, group := range t.groups {
+		if now.Sub(group.LastSeen) >= t.config.Cooldown {
+			delete(t.groups, key)
+			delete(t.byID, group.ID)
+		}
+	}
+}
+
+// Groups returns the open groups, most recently active first.
+func (t *Tracker) Groups() []Group {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.expire(t.now())
+	groups := make([]Group, 0, len(t.groups))
+	for _, group := range t.groups {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].LastSeen.After(groups[j].LastSeen) })
+	return groups
+}
+
+// Acknowledge silences an open group's reminders until it escalates.
+func (t *Tracker) Acknowledge(id string) (Group, error) {
+	return t.acknowledge(id, nil)
+}
+
+// acknowledge is Acknowledge limited to groups for symbols, nil meaning
+// all; other groups are not found.
+func (t *Tracker) acknowledge(id string, symbols []string) (Group, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.expire(now)
+	group, ok := t.byID[id]
+	if !ok || (symbols != nil && !slices.Contains(symbols, group.Symbol)) {
+		return Group{}, ErrNotFound
+	}
+	if !group.Acknowledged {
+		at := now.UTC()
+		group.Acknowledged = true
+		group.AcknowledgedAt = &at
+	}
+	return *group, nil
+}
+
+// Key identifies the symbol and rule an alert belongs to: its rule ID for
+// rule alerts, otherwise its type and window.
+func Key(alert alerts.Alert) string {
+	switch {
+	case alert.Rule != "":
+		return alert.Symbol + "/rule/" + alert.Rule
+	case alert.Window != "":
+		return alert.Symbol + "/" + alert.Type + "/" + alert.Window
+	}
+	return alert.Symbol + "/" + alert.Type
+}
+
+func (t *Tracker) severity(changePct float64) string {
+	size := math.Abs(changePct)
+	switch {
+	case size >= t.config.CriticalPct:
+		return SeverityCritical
+	case size >= t.config.WarningPct:
+		return SeverityWarning
+	}
+	return SeverityInfo
+}
+
+// AtLeast reports whether severity is as high as min.
+func AtLeast(severity, min string) bool {
+	return rank(severity) >= rank(min)
+}
+
+// ValidSeverity reports whether severity is one of the tiers.
+func ValidSeverity(severity string) bool {
+	switch severity {
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+		return true
+	}
+	return false
+}
+
+func rank(severity string) int {
+	switch severity {
+	case SeverityCritical:
+		return 2
+	case SeverityWarning:
+		return 1
+	}
+	return 0
+}
+
+func newID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,135 @@
+package incidents
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/alerts"
+)
+
+func TestTrackerTrack(t *testing.T) {
+	// Every step is a BTCUSDT price change alert over 1m unless it names
+	// another window. Cooldown is five minutes; changes of 2% are warnings
+	// and of 5% critical.
+	type step struct {
+		at     time.Duration
+		window string
+		pct    float64
+		// ack acknowledges the window's group instead of tracking an
+		// alert, expecting ackErr.
+		ack    bool
+		ackErr error
+
+		send        bool
+		severity    string
+		occurrences int
+		// newGroup is set when the alert opens a group.
+		newGroup bool
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "repeats are held for the cooldown",
+			steps: []step{
+				{pct: 1, send: true, severity: SeverityInfo, occurrences: 1, newGroup: true},
+				{at: time.Minute, pct: 1, severity: SeverityInfo, occurrences: 2},
+				{at: 4*time.Minute + 59*time.Second, pct: 1, severity: SeverityInfo, occurrences: 3},
+				{at: 5 * time.Minute, pct: 1, send: true, severity: SeverityInfo, occurrences: 4},
+				{at: 6 * time.Minute, pct: -1, severity: SeverityInfo, occurrences: 5},
+			},
+		},
+		{
+			name: "acknowledged groups send no reminders",
+			steps: []step{
+				{pct: 1, send: true, severity: SeverityInfo, occurrences: 1, newGroup: true},
+				{at: time.Minute, ack: true},
+				{at: 2 * time.Minute, pct: 1, severity: SeverityInfo, occurrences: 2},
+				{at: 6 * time.Minute, pct: 1, severity: SeverityInfo, occurrences: 3},
+				{at: 10 * time.Minute, pct: 1, severity: SeverityInfo, occurrences: 4},
+			},
+		},
+		{
+			name: "escalation is sent at once and clears the ack",
+			steps: []step{
+				{pct: 1, send: true, severity: SeverityInfo, occurrences: 1, newGroup: true},
+				{at: time.Minute, ack: true},
+				{at: 2 * time.Minute, pct: -3, send: true, severity: SeverityWarning, occurrences: 2},
+				// Back under the cooldown from the escalation.
+				{at: 3 * time.Minute, pct: 3, severity: SeverityWarning, occurrences: 3},
+				// No longer acknowledged, so reminders resume.
+				{at: 7 * time.Minute, pct: 3, send: true, severity: SeverityWarning, occurrences: 4},
+				{at: 8 * time.Minute, pct: 6, send: true, severity: SeverityCritical, occurrences: 5},
+				// A smaller move is not an escalation.
+				{at: 9 * time.Minute, pct: 3, severity: SeverityWarning, occurrences: 6},
+			},
+		},
+		{
+			name: "quiet groups expire",
+			steps: []step{
+				{pct: 1, send: true, severity: SeverityInfo, occurrences: 1, newGroup: true},
+				{at: 4 * time.Minute, pct: 1, severity: SeverityInfo, occurrences: 2},
+				{at: 9 * time.Minute, pct: 1, send: true, severity: SeverityInfo, occurrences: 1, newGroup: true},
+				{at: 14 * time.Minute, ack: true, ackErr: ErrNotFound},
+				{at: 14 * time.Minute, pct: 1, send: true, severity: SeverityInfo, occurrences: 1, newGroup: true},
+			},
+		},
+		{
+			name: "windows are grouped apart",
+			steps: []step{
+				{pct: 1, send: true, severity: SeverityInfo, occurrences: 1, newGroup: true},
+				{window: "5m", pct: 1, send: true, severity: SeverityInfo, occurrences: 1, newGroup: true},
+				{at: time.Minute, ack: true},
+				{at: 4 * time.Minute, pct: 1, severity: SeverityInfo, occurrences: 2},
+				{at: 4 * time.Minute, window: "5m", pct: 1, severity: SeverityInfo, occurrences: 2},
+				{at: 8 * time.Minute, pct: 1, severity: SeverityInfo, occurrences: 3},
+				{at: 8 * time.Minute, window: "5m", pct: 1, send: true, severity: SeverityInfo, occurrences: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Date(2024, 5, 14, 10, 0, 0, 0, time.UTC)
+			var now time.Time
+			tracker := New(Config{Cooldown: 5 * time.Minute, WarningPct: 2, CriticalPct: 5})
+			tracker.now = func() time.Time { return now }
+			// groups holds each window's latest group ID.
+			groups := make(map[string]string)
+
+			for i, s := range tt.steps {
+				now = start.Add(s.at)
+				window := s.window
+				if window == "" {
+					window = "1m"
+				}
+
+				if s.ack {
+					group, err := tracker.Acknowledge(groups[window])
+					if !errors.Is(err, s.ackErr) {
+						t.Fatalf("step %d: Acknowledge error = %v, want %v", i, err, s.ackErr)
+					}
+					if err == nil && (!group.Acknowledged || group.AcknowledgedAt == nil || !group.AcknowledgedAt.Equal(now)) {
+						t.Errorf("step %d: acknowledged group %+v", i, group)
+					}
+					continue
+				}
+
+				alert, send := tracker.track(alerts.Alert{Type: alerts.TypePriceChange, Symbol: "BTCUSDT", Window: window, ChangePct: s.pct})
+				if send != s.send {
+					t.Errorf("step %d (%v%% at %s): send = %v, want %v", i, s.pct, s.at, send, s.send)
+				}
+				if alert.Severity != s.severity || alert.Occurrences != s.occurrences {
+					t.Errorf("step %d: severity %q, occurrences %d; want %q, %d", i, alert.Severity, alert.Occurrences, s.severity, s.occurrences)
+				}
+				if opened := alert.Group != groups[window]; opened != s.newGroup {
+					t.Errorf("step %d: opened a group = %v, want %v", i, opened, s.newGroup)
+				}
+				groups[window] = alert.Group
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+// Package jsonws consumes any JSON-over-WebSocket ticker feed whose
+// messages can be described by field paths.
+package jsonws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"crypto-monitor/internal/feed"
+
+	"github.com/gorilla/websocket"
+)
+
+// SymbolsPlaceholder in a subscribe template is replaced by a JSON array of
+// the venue symbols.
+const SymbolsPlaceholder = "{{symbols}}"
+
+// Config describes a feed. Field paths are dot-separated keys into each
+// message object, such as "data.price". Messages may also be arrays of
+// such objects.
+type Config struct {
+	Name string
+	URL  string
+	// Subscribe is sent after each connect, if set.
+	Subscribe string
+	// Symbols maps canonical symbols to the feed's names; unmapped symbols
+	// are used as is.
+	Symbols     map[string]string
+	SymbolField string
+	PriceField  string
+	// TimeField and VolumeField are optional. Without a time the receive
+	// time is used.
+	TimeField   string
+	VolumeField string
+}
+
+// Client streams prices from a configured feed.
+type Client struct {
+	config Config
+}
+
+// NewClient creates a client for the described feed.
+func NewClient(config Config) *Client {
+	return &Client{config: config}
+}
+
+// Name returns the configured venue name.
+func (c *Client) Name() string {
+	return c.config.Name
+}
+
+// StreamTickers connects to the feed and emits price events for the
+// symbols until context cancel. Messages for other symbols, and messages
+// without a symbol or price, are ignored.
+func (c *Client) StreamTickers(ctx context.Context, symbols []string) (<-chan feed.PriceEvent, <-chan error) {
+	if len(symbols) == 0 {
+		return feed.Failed(errors.New("at least one symbol required"))
+	}
+
+	venue := make(map[string]string, len(symbols))
+	names := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		name, ok := c.config.Symbols[symbol]
+		if !ok {
+			name = symbol
+		}
+		venue[symbol] = name
+		names = append(names, name)
+	}
+	canonical := feed.Invert(venue)
+
+	var subscribe func(conn *websocket.Conn) error
+	if c.config.Subscribe != "" {
+		list, err := json.Marshal(names)
+		if err != nil {
+			return feed.Failed(err)
+		}
+		message := strings.ReplaceAll(c.config.Subscribe, SymbolsPlaceholder, string(list))
+		subscribe = func(conn *websocket.Conn) error {
+			return conn.WriteMessage(websocket.TextMessage, []byte(message))
+		}
+	}
+
+	return feed.WebSocketFeed{
+		URL:       c.config.URL,
+		Subscribe: subscribe,
+		Decode: func(payload []byte) ([]feed.PriceEvent, error) {
+			return c.decode(payload, canonical)
+		},
+	}.Stream(ctx)
+}
+
+// decode extracts price events from one message.
+func (c *Client) decode(payload []byte, canonical map[string]string) ([]feed.PriceEvent, error) {
+	var objects []json.RawMessage
+	if trimmed := strings.TrimSpace(string(payload)); strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(payload, &objects); err != nil {
+			return nil, err
+		}
+	} else {
+		objects = []json.RawMessage{payload}
+	}
+
+	var events []feed.PriceEvent
+	for _, object := range objects {
+		event, ok, err := c.decodeObject(object, canonical)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// decodeObject extracts a price event from one message object. It reports
+// false for objects that are not prices for a subscribed symbol.
+func (c *Client) decodeObject(object json.RawMessage, canonical map[string]string) (feed.PriceEvent, bool, error) {
+	symbolRaw, ok := lookup(object, c.config.SymbolField)
+	if !ok {
+		return feed.PriceEvent{}, false, nil
+	}
+	var name string
+	if err := json.Unmarshal(symbolRaw, &name); err != nil {
+		return feed.PriceEvent{}, false, fmt.Errorf("symbol parse failed: %w", err)
+	}
+	symbol, ok := canonical[name]
+	if !ok {
+		return feed.PriceEvent{}, false, nil
+	}
+
+	priceRaw, ok := lookup(object, c.config.PriceField)
+	if !ok {
+		return feed.PriceEvent{}, false, nil
+	}
+	price, rawPrice, err := feed.ParseNumber(priceRaw)
+	if err != nil {
+		return feed.PriceEvent{}, false, fmt.Errorf("price parse failed: %w", err)
+	}
+
+	event := feed.PriceEvent{
+		Source:    c.config.Name,
+		Symbol:    symbol,
+		Price:     price,
+		RawPrice:  rawPrice,
+		EventTime: time.Now().UTC(),
+	}
+	if raw, ok := lookup(object, c.config.VolumeField); ok {
+		if event.Volume, _, err = feed.ParseNumber(raw); err != nil {
+			return feed.PriceEvent{}, false, fmt.Errorf("volume parse failed: %w", err)
+		}
+	}
+	if raw, ok := lookup(object, c.config.TimeField); ok {
+		if event.EventTime, err = feed.ParseTime(raw); err != nil {
+			return feed.PriceEvent{}, false, fmt.Errorf("time parse failed: %w", err)
+		}
+	}
+	return event, true, nil
+}
+
+// lookup follows a dot-separated path of object keys. An empty path or a
+// null value is reported as missing.
+func lookup(raw json.RawMessage, path string) (json.RawMessage, bool) {
+	if path == "" {
+		return nil, false
+	}
+	for _, key := range strings.Split(path, ".") {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return nil, false
+		}
+		value, ok := object[key]
+		if !ok {
+			return nil, false
+		}
+		raw = value
+	}
+	if string(raw) == "null" {
+		return nil, false
+	}
+	return raw, true
+}
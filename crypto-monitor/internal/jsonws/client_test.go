@@ -0,0 +1,125 @@
+package jsonws
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/feed"
+)
+
+func TestDecode(t *testing.T) {
+	// A Binance-style array of mini tickers with flat fields.
+	flat := NewClient(Config{
+		Name:        "binance-mini",
+		SymbolField: "s",
+		PriceField:  "c",
+		TimeField:   "E",
+		VolumeField: "v",
+	})
+	// A feed that wraps each ticker in an envelope.
+	nested := NewClient(Config{
+		Name:        "nested",
+		Symbols:     map[string]string{"BTCUSDT": "btc_usdt"},
+		SymbolField: "data.market",
+		PriceField:  "data.last",
+		TimeField:   "data.ts",
+	})
+	canonical := func(c *Client) map[string]string {
+		if c == nested {
+			return map[string]string{"btc_usdt": "BTCUSDT"}
+		}
+		return map[string]string{"BTCUSDT": "BTCUSDT", "ETHUSDT": "ETHUSDT"}
+	}
+
+	tests := []struct {
+		name    string
+		client  *Client
+		payload string
+		want    []feed.PriceEvent
+		wantErr string
+	}{
+		{
+			name:    "array with an unsubscribed symbol",
+			client:  flat,
+			payload: `[{"e":"24hrMiniTicker","E":1715681742123,"s":"BTCUSDT","c":"67321.52000000","o":"66010.01000000","h":"67800.00000000","l":"65500.01000000","v":"12534.77451000","q":"838274651.12"},{"e":"24hrMiniTicker","E":1715681742125,"s":"SOLUSDT","c":"145.20000000","o":"140.00000000","h":"146.00000000","l":"139.50000000","v":"991234.10000000","q":"142000000.00"}]`,
+			want: []feed.PriceEvent{{
+				Source: "binance-mini", Symbol: "BTCUSDT", Price: 67321.52, RawPrice: "67321.52000000",
+				Volume: 12534.77451, EventTime: time.UnixMilli(1715681742123),
+			}},
+		},
+		{
+			name:    "single object",
+			client:  flat,
+			payload: `{"e":"24hrMiniTicker","E":1715681742200,"s":"ETHUSDT","c":"3012.45000000","o":"2970.00000000","h":"3050.00000000","l":"2950.00000000","v":"20511.50000000","q":"61700000.00"}`,
+			want: []feed.PriceEvent{{
+				Source: "binance-mini", Symbol: "ETHUSDT", Price: 3012.45, RawPrice: "3012.45000000",
+				Volume: 20511.5, EventTime: time.UnixMilli(1715681742200),
+			}},
+		},
+		{
+			name:    "nested fields with a mapped symbol",
+			client:  nested,
+			payload: `{"channel":"tickers","data":{"market":"btc_usdt","last":67321.5,"ts":"2024-05-14T10:15:42.123Z"}}`,
+			want: []feed.PriceEvent{{
+				Source: "nested", Symbol: "BTCUSDT", Price: 67321.5, RawPrice: "67321.5",
+				EventTime: time.Date(2024, 5, 14, 10, 15, 42, 123000000, time.UTC),
+			}},
+		},
+		{
+			name:    "acknowledgement without fields",
+			client:  nested,
+			payload: `{"event":"subscribed","channel":"tickers"}`,
+		},
+		{
+			name:    "null price",
+			client:  nested,
+			payload: `{"channel":"tickers","data":{"market":"btc_usdt","last":null}}`,
+		},
+		{
+			name:    "bad price",
+			client:  flat,
+			payload: `{"E":1715681742200,"s":"BTCUSDT","c":"n/a"}`,
+			wantErr: "price parse failed",
+		},
+		{
+			name:    "bad time",
+			client:  nested,
+			payload: `{"data":{"market":"btc_usdt","last":1,"ts":"yesterday"}}`,
+			wantErr: "time parse failed",
+		},
+		{
+			name:    "non-string symbol",
+			client:  flat,
+			payload: `{"s":42,"c":"1"}`,
+			wantErr: "symbol parse failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.client.decode([]byte(tt.payload), canonical(tt.client))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d events, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !got[i].EventTime.Equal(tt.want[i].EventTime) {
+					t.Errorf("event %d time = %v, want %v", i, got[i].EventTime, tt.want[i].EventTime)
+				}
+				got[i].EventTime = tt.want[i].EventTime
+				if got[i] != tt.want[i] {
+					t.Errorf("event %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package kraken
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"crypto-monitor/internal/feed"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultURL is the Kraken WebSocket v2 public endpoint.
+const DefaultURL = "wss://ws.kraken.com/v2"
+
+// Name is the venue name set on Kraken price events.
+const Name = "kraken"
+
+// Client connects to the Kraken v2 ticker channel.
+type Client struct {
+	url   string
+	pairs map[string]string
+}
+
+type subscribeRequest struct {
+	Method string          `json:"method"`
+	Params subscribeParams `json:"params"`
+}
+
+type subscribeParams struct {
+	Channel string   `json:"channel"`
+	Symbol  []string `json:"symbol"`
+}
+
+type message struct {
+	Channel string          `json:"channel"`
+	Type    string          `json:"type"`
+	Method  string          `json:"method"`
+	Success *bool           `json:"success"`
+	Error   string          `json:"error"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type ticker struct {
+	Symbol    string          `json:"symbol"`
+	Last      json.RawMessage `json:"last"`
+	Volume    json.RawMessage `json:"volume"`
+	Timestamp json.RawMessage `json:"timestamp"`
+}
+
+// NewClient creates a Kraken client for the feed at url. Pairs maps
+// canonical symbols to Kraken pairs; unmapped symbols such as BTCUSD are
+// requested as BTC/USD.
+func NewClient(url string, pairs map[string]string) *Client {
+	if url == "" {
+		url = DefaultURL
+	}
+	return &Client{url: url, pairs: pairs}
+}
+
+// Name returns the venue name.
+func (c *Client) Name() string {
+	return Name
+}
+
+// StreamTickers subscribes to the pairs' tickers and emits price events
+// until context cancel.
+func (c *Client) StreamTickers(ctx context.Context, symbols []string) (<-chan feed.PriceEvent, <-chan error) {
+	pairs, err := feed.VenueSymbols(symbols, c.pairs, "/")
+	if err == nil && len(pairs) == 0 {
+		err = errors.New("at least one symbol required")
+	}
+	if err != nil {
+		return feed.Failed(err)
+	}
+	canonical := feed.Invert(pairs)
+
+	names := make([]string, 0, len(pairs))
+	for _, symbol := range symbols {
+		names = append(names, pairs[symbol])
+	}
+
+	return feed.WebSocketFeed{
+		URL: c.url,
+		Subscribe: func(conn *websocket.Conn) error {
+			return conn.WriteJSON(subscribeRequest{
+				Method: "subscribe",
+				Params: subscribeParams{Channel: "ticker", Symbol: names},
+			})
+		},
+		Decode: func(payload []byte) ([]feed.PriceEvent, error) {
+			return decode(payload, canonical)
+		},
+	}.Stream(ctx)
+}
+
+// decode converts a ticker snapshot or update into price events. Status
+// messages, heartbeats and successful acknowledgements yield no events.
+func decode(payload []byte, canonical map[string]string) ([]feed.PriceEvent, error) {
+	var msg message
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return nil, err
+	}
+	if msg.Success != nil && !*msg.Success {
+		return nil, fmt.Errorf("%s failed: %s", msg.Method, msg.Error)
+	}
+	if msg.Channel != "ticker" || (msg.Type != "snapshot" && msg.Type != "update") {
+		return nil, nil
+	}
+
+	var tickers []ticker
+	if err := json.Unmarshal(msg.Data, &tickers); err != nil {
+		return nil, fmt.Errorf("ticker decode failed: %w", err)
+	}
+
+	events := make([]feed.PriceEvent, 0, len(tickers))
+	for _, t := range tickers {
+		symbol, ok := canonical[t.Symbol]
+		if !ok {
+			return nil, fmt.Errorf("unexpected pair %q", t.Symbol)
+		}
+		price, rawPrice, err := feed.ParseNumber(t.Last)
+		if err != nil {
+			return nil, fmt.Errorf("price parse failed: %w", err)
+		}
+		event := feed.PriceEvent{
+			Source:    Name,
+			Symbol:    symbol,
+			Price:     price,
+			RawPrice:  rawPrice,
+			EventTime: time.Now().UTC(),
+		}
+		if len(t.Volume) > 0 {
+			if event.Volume, _, err = feed.ParseNumber(t.Volume); err != nil {
+				return nil, fmt.Errorf("volume parse failed: %w", err)
+			}
+		}
+		// Older v2 ticker messages carry no timestamp.
+		if len(t.Timestamp) > 0 {
+			if event.EventTime, err = feed.ParseTime(t.Timestamp); err != nil {
+				return nil, fmt.Errorf("time parse failed: %w", err)
+			}
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
@@ -0,0 +1,96 @@
+package kraken
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/feed"
+)
+
+func TestDecode(t *testing.T) {
+	canonical := map[string]string{"BTC/USD": "BTCUSD", "ETH/USD": "ETHUSD"}
+
+	tests := []struct {
+		name    string
+		payload string
+		want    []feed.PriceEvent
+		wantErr string
+	}{
+		{
+			name:    "snapshot",
+			payload: `{"channel":"ticker","type":"snapshot","data":[{"symbol":"BTC/USD","bid":67320.1,"bid_qty":0.5,"ask":67320.2,"ask_qty":1.2,"last":67320.1,"volume":1834.12345678,"vwap":66890.3,"low":65500.0,"high":67800.0,"change":1320.1,"change_pct":2.0,"timestamp":"2024-05-14T10:15:42.123456Z"},{"symbol":"ETH/USD","bid":3012.4,"bid_qty":3.1,"ask":3012.5,"ask_qty":2.0,"last":3012.45,"volume":20511.5,"vwap":2990.1,"low":2950.0,"high":3050.0,"change":40.2,"change_pct":1.35,"timestamp":"2024-05-14T10:15:42.200000Z"}]}`,
+			want: []feed.PriceEvent{
+				{Source: Name, Symbol: "BTCUSD", Price: 67320.1, RawPrice: "67320.1", Volume: 1834.12345678, EventTime: time.Date(2024, 5, 14, 10, 15, 42, 123456000, time.UTC)},
+				{Source: Name, Symbol: "ETHUSD", Price: 3012.45, RawPrice: "3012.45", Volume: 20511.5, EventTime: time.Date(2024, 5, 14, 10, 15, 42, 200000000, time.UTC)},
+			},
+		},
+		{
+			name:    "update without timestamp",
+			payload: `{"channel":"ticker","type":"update","data":[{"symbol":"BTC/USD","bid":67321.0,"bid_qty":0.1,"ask":67321.1,"ask_qty":0.4,"last":67321.0,"volume":1834.2,"vwap":66890.4,"low":65500.0,"high":67800.0,"change":1321.0,"change_pct":2.0}]}`,
+			want: []feed.PriceEvent{
+				{Source: Name, Symbol: "BTCUSD", Price: 67321, RawPrice: "67321", Volume: 1834.2},
+			},
+		},
+		{
+			name:    "subscribe acknowledgement",
+			payload: `{"method":"subscribe","result":{"channel":"ticker","event_trigger":"trades","snapshot":true,"symbol":"BTC/USD"},"success":true,"time_in":"2024-05-14T10:15:40.000000Z","time_out":"2024-05-14T10:15:40.000100Z"}`,
+		},
+		{
+			name:    "status",
+			payload: `{"channel":"status","type":"update","data":[{"version":"2.0.0","system":"online","api_version":"v2","connection_id":12893746123}]}`,
+		},
+		{
+			name:    "heartbeat",
+			payload: `{"channel":"heartbeat"}`,
+		},
+		{
+			name:    "subscribe failure",
+			payload: `{"error":"Currency pair not supported BTC/USDX","method":"subscribe","success":false,"symbol":"BTC/USDX","time_in":"2024-05-14T10:15:40.000000Z","time_out":"2024-05-14T10:15:40.000100Z"}`,
+			wantErr: "subscribe failed: Currency pair not supported BTC/USDX",
+		},
+		{
+			name:    "unknown pair",
+			payload: `{"channel":"ticker","type":"update","data":[{"symbol":"SOL/USD","last":145.2}]}`,
+			wantErr: `unexpected pair "SOL/USD"`,
+		},
+		{
+			name:    "malformed data",
+			payload: `{"channel":"ticker","type":"update","data":{"symbol":"BTC/USD"}}`,
+			wantErr: "ticker decode failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decode([]byte(tt.payload), canonical)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d events, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				// Without a timestamp the receive time is used.
+				want := tt.want[i]
+				if want.EventTime.IsZero() {
+					if time.Since(got[i].EventTime) > time.Minute {
+						t.Errorf("event %d time = %v, want the receive time", i, got[i].EventTime)
+					}
+				} else if !got[i].EventTime.Equal(want.EventTime) {
+					t.Errorf("event %d time = %v, want %v", i, got[i].EventTime, want.EventTime)
+				}
+				got[i].EventTime = want.EventTime
+				if got[i] != want {
+					t.Errorf("event %d = %+v, want %+v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
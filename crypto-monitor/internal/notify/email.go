@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"crypto-monitor/internal/alerts"
+)
+
+// sendTimeout bounds each email delivery, from dialling to QUIT.
+const sendTimeout = 30 * time.Second
+
+// EmailConfig describes an SMTP server and who alerts are mailed to.
+type EmailConfig struct {
+	// Addr is the server's host:port. STARTTLS is used when offered.
+	Addr string
+	// Username and Password, when set, authenticate with AUTH PLAIN,
+	// which needs TLS unless the server is on localhost.
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Email mails alerts over SMTP.
+type Email struct {
+	name   string
+	config EmailConfig
+	now    func() time.Time
+}
+
+// NewEmail builds an email notifier.
+func NewEmail(name string, config EmailConfig) *Email {
+	return &Email{name: name, config: config, now: time.Now}
+}
+
+// Name returns the notifier's name.
+func (e *Email) Name() string {
+	return e.name
+}
+
+// Notify mails the alert, with its summary as the subject. Rejections by
+// the server (5xx replies) are permanent.
+func (e *Email) Notify(ctx context.Context, alert alerts.Alert) error {
+	message, err := e.message(alert)
+	if err != nil {
+		return Permanent(err)
+	}
+	err = e.send(ctx, message)
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return Permanent(err)
+	}
+	return err
+}
+
+func (e *Email) send(ctx context.Context, message []byte) error {
+	host, _, err := net.SplitHostPort(e.config.Addr)
+	if err != nil {
+		return Permanent(fmt.Errorf("smtp address: %w", err))
+	}
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", e.config.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if e.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.config.Username, e.config.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(e.config.From); err != nil {
+		return err
+	}
+	for _, to := range e.config.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	data, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := data.Write(message); err != nil {
+		return err
+	}
+	if err := data.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message builds a plain text email with the alert's summary and JSON.
+func (e *Email) message(alert alerts.Alert) ([]byte, error) {
+	body, err := json.MarshalIndent(alert, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	summary := Summary(alert)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", e.config.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(e.config.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", summary))
+	fmt.Fprintf(&b, "Date: %s\r\n", e.now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(summary)
+	b.WriteString("\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(string(body), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes(), nil
+}
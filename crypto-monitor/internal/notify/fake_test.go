@@ -0,0 +1,174 @@
+package notify
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeHTTP is a local HTTP receiver that records requests and answers
+// them with queued status codes, then 200.
+type fakeHTTP struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []recordedRequest
+}
+
+type recordedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func newFakeHTTP(t *testing.T, statuses ...int) *fakeHTTP {
+	f := &fakeHTTP{statuses: statuses}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		f.requests = append(f.requests, recordedRequest{header: r.Header.Clone(), body: body})
+		status := http.StatusOK
+		if len(f.statuses) > 0 {
+			status, f.statuses = f.statuses[0], f.statuses[1:]
+		}
+		f.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeHTTP) received() []recordedRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]recordedRequest(nil), f.requests...)
+}
+
+// fakeSMTP is a local SMTP receiver speaking just enough of the protocol
+// for net/smtp. It records delivered messages, answers MAIL with queued
+// reply lines before accepting, and rejects recipients in reject.
+type fakeSMTP struct {
+	listener    net.Listener
+	mu          sync.Mutex
+	mailReplies []string
+	reject      map[string]bool
+	messages    []fakeMessage
+}
+
+type fakeMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeSMTP{listener: listener, reject: make(map[string]bool)}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeSMTP) addr() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeSMTP) received() []fakeMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeMessage(nil), f.messages...)
+}
+
+func (f *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+
+	var message fakeMessage
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250 fake")
+		case "MAIL":
+			f.mu.Lock()
+			var queued string
+			if len(f.mailReplies) > 0 {
+				queued, f.mailReplies = f.mailReplies[0], f.mailReplies[1:]
+			}
+			f.mu.Unlock()
+			if queued != "" {
+				reply(queued)
+				continue
+			}
+			message = fakeMessage{from: address(arg)}
+			reply("250 OK")
+		case "RCPT":
+			to := address(arg)
+			f.mu.Lock()
+			rejected := f.reject[to]
+			f.mu.Unlock()
+			if rejected {
+				reply("550 no such user")
+				continue
+			}
+			message.to = append(message.to, to)
+			reply("250 OK")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			message.data = data.String()
+			f.mu.Lock()
+			f.messages = append(f.messages, message)
+			f.mu.Unlock()
+			reply("250 queued")
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+// address pulls the address out of "FROM:<a@b>" or "TO:<a@b>".
+func address(arg string) string {
+	start, end := strings.Index(arg, "<"), strings.Index(arg, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return arg[start+1 : end]
+}
@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"crypto-monitor/internal/alerts"
+)
+
+// Webhook signature headers. The signature is the hex HMAC-SHA256 of the
+// timestamp, a dot and the body, keyed with the webhook's secret.
+const (
+	HeaderTimestamp = "X-Crypto-Monitor-Timestamp"
+	HeaderSignature = "X-Crypto-Monitor-Signature"
+)
+
+// requestTimeout bounds each webhook request.
+const requestTimeout = 10 * time.Second
+
+// Webhook posts alerts as JSON to a URL, signed when it has a secret.
+type Webhook struct {
+	name   string
+	url    string
+	secret string
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewWebhook builds a webhook notifier.
+func NewWebhook(name, url, secret string) *Webhook {
+	return &Webhook{
+		name:   name,
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: requestTimeout},
+		now:    time.Now,
+	}
+}
+
+// Name returns the notifier's name.
+func (w *Webhook) Name() string {
+	return w.name
+}
+
+// Notify posts the alert.
+func (w *Webhook) Notify(ctx context.Context, alert alerts.Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return Permanent(err)
+	}
+	headers := make(http.Header)
+	if w.secret != "" {
+		timestamp := strconv.FormatInt(w.now().Unix(), 10)
+		headers.Set(HeaderTimestamp, timestamp)
+		headers.Set(HeaderSignature, "sha256="+Sign(w.secret, timestamp, body))
+	}
+	return post(ctx, w.client, w.url, headers, body)
+}
+
+// Sign returns the signature of a webhook body sent at timestamp, for
+// receivers to compare with the signature header.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Slack posts alerts to a Slack-compatible incoming webhook.
+type Slack struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+// NewSlack builds a Slack notifier.
+func NewSlack(name, url string) *Slack {
+	return &Slack{name: name, url: url, client: &http.Client{Timeout: requestTimeout}}
+}
+
+// Name returns the notifier's name.
+func (s *Slack) Name() string {
+	return s.name
+}
+
+// Notify posts the alert's summary as the message text.
+func (s *Slack) Notify(ctx context.Context, alert alerts.Alert) error {
+	body, err := json.Marshal(struct {
+		Text string `json:"text"`
+	}{Text: Summary(alert)})
+	if err != nil {
+		return Permanent(err)
+	}
+	return post(ctx, s.client, s.url, nil, body)
+}
+
+// post sends a JSON body. Rejections other than rate limiting are
+// permanent; server errors and network failures may be retried.
+func post(ctx context.Context, client *http.Client, url string, headers http.Header, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(err)
+	}
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("post: %s", resp.Status)
+	}
+	return Permanent(fmt.Errorf("post: %s", resp.Status))
+}
@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// Notifier types in a notifiers file.
+const (
+	TypeWebhook = "webhook"
+	TypeSlack   = "slack"
+	TypeEmail   = "email"
+)
+
+// File is the layout of a notifiers file.
+type File struct {
+	Notifiers []Config `json:"notifiers"`
+	Routes    []Route  `json:"routes"`
+}
+
+// Config describes one notifier. Secret and Password may name environment
+// variables as $NAME or ${NAME}, so the file need not hold them.
+type Config struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// URL is where webhook and slack notifiers post; Secret signs webhook
+	// requests.
+	URL    string `json:"url,omitempty"`
+	Secret string `json:"secret,omitempty"`
+
+	// SMTPAddr and the fields after it configure email notifiers.
+	SMTPAddr string   `json:"smtp_addr,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+}
+
+// Load reads a notifiers file and builds a dispatcher for it.
+func Load(path string, retry Retry) (*Dispatcher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("notify: %w", err)
+	}
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("notify: parse %s: %w", path, err)
+	}
+	notifiers := make([]Notifier, 0, len(file.Notifiers))
+	for i, config := range file.Notifiers {
+		notifier, err := config.build()
+		if err != nil {
+			if config.Name == "" {
+				return nil, fmt.Errorf("notify: %s: notifier %d: %w", path, i+1, err)
+			}
+			return nil, fmt.Errorf("notify: %s: notifier %q: %w", path, config.Name, err)
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	dispatcher, err := NewDispatcher(notifiers, file.Routes, retry)
+	if err != nil {
+		return nil, fmt.Errorf("notify: %s: %w", path, err)
+	}
+	return dispatcher, nil
+}
+
+func (c Config) build() (Notifier, error) {
+	if c.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	switch c.Type {
+	case TypeWebhook, TypeSlack:
+		parsed, err := url.Parse(c.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, errors.New("url must be an http or https URL")
+		}
+		if c.Type == TypeSlack {
+			return NewSlack(c.Name, c.URL), nil
+		}
+		return NewWebhook(c.Name, c.URL, os.ExpandEnv(c.Secret)), nil
+	case TypeEmail:
+		switch {
+		case c.SMTPAddr == "":
+			return nil, errors.New("smtp_addr is required")
+		case c.From == "":
+			return nil, errors.New("from is required")
+		case len(c.To) == 0:
+			return nil, errors.New("to is required")
+		}
+		return NewEmail(c.Name, EmailConfig{
+			Addr:     c.SMTPAddr,
+			Username: c.Username,
+			Password: os.ExpandEnv(c.Password),
+			From:     c.From,
+			To:       c.To,
+		}), nil
+	}
+	return nil, fmt.Errorf("type must be %q, %q or %q", TypeWebhook, TypeSlack, TypeEmail)
+}
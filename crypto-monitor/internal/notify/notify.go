@@ -0,0 +1,234 @@
+// Package notify delivers alerts to outbound channels such as webhooks,
+// Slack and email, routed by symbol, type and severity.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"crypto-monitor/internal/alerts"
+	"crypto-monitor/internal/incidents"
+)
+
+// Notifier delivers an alert to one destination.
+type Notifier interface {
+	// Name identifies the notifier in routes and errors.
+	Name() string
+	// Notify delivers an alert. Errors wrapped by Permanent are not retried.
+	Notify(ctx context.Context, alert alerts.Alert) error
+}
+
+// Route sends the alerts it matches to a notifier. Empty Symbols or Types,
+// or ones holding "*", match everything; MinSeverity is the lowest
+// severity sent, or every severity when empty.
+type Route struct {
+	Notifier    string   `json:"notifier"`
+	Symbols     []string `json:"symbols,omitempty"`
+	Types       []string `json:"types,omitempty"`
+	MinSeverity string   `json:"min_severity,omitempty"`
+}
+
+func (r Route) matches(alert alerts.Alert) bool {
+	return matchesList(r.Symbols, alert.Symbol) &&
+		matchesList(r.Types, alert.Type) &&
+		(r.MinSeverity == "" || incidents.AtLeast(alert.Severity, r.MinSeverity))
+}
+
+func matchesList(values []string, value string) bool {
+	return len(values) == 0 || slices.Contains(values, "*") || slices.Contains(values, value)
+}
+
+// Retry controls how failed deliveries are retried. Each retry waits
+// twice as long as the one before, from Backoff up to MaxBackoff.
+type Retry struct {
+	// Attempts is how many times a delivery is tried, at least once.
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// delay returns how long to wait after the given failed attempt, from 1.
+func (r Retry) delay(attempt int) time.Duration {
+	delay := r.Backoff
+	for i := 1; i < attempt && delay < r.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, r.MaxBackoff)
+}
+
+// queueSize is how many alerts may wait for each notifier.
+const queueSize = 64
+
+// Dispatcher routes alerts to notifiers. Each notifier has its own queue
+// and worker, so a slow or failing destination holds up only itself.
+type Dispatcher struct {
+	notifiers []Notifier
+	routes    []Route
+	retry     Retry
+}
+
+// NewDispatcher checks that every route names one of the notifiers and
+// builds a dispatcher.
+func NewDispatcher(notifiers []Notifier, routes []Route, retry Retry) (*Dispatcher, error) {
+	names := make(map[string]bool, len(notifiers))
+	for _, notifier := range notifiers {
+		if names[notifier.Name()] {
+			return nil, fmt.Errorf("notifier %q: name is used twice", notifier.Name())
+		}
+		names[notifier.Name()] = true
+	}
+	for i, route := range routes {
+		if !names[route.Notifier] {
+			return nil, fmt.Errorf("route %d: unknown notifier %q", i+1, route.Notifier)
+		}
+		if route.MinSeverity != "" && !incidents.ValidSeverity(route.MinSeverity) {
+			return nil, fmt.Errorf("route %d: unknown severity %q", i+1, route.MinSeverity)
+		}
+	}
+	retry.Attempts = max(retry.Attempts, 1)
+	retry.MaxBackoff = max(retry.MaxBackoff, retry.Backoff)
+	return &Dispatcher{notifiers: notifiers, routes: routes, retry: retry}, nil
+}
+
+// Start delivers each alert to the notifiers its routes match, at most
+// once per notifier. Deliveries that fail every attempt, and alerts
+// dropped because a notifier's queue is full, are reported on the error
+// stream.
+func (d *Dispatcher) Start(ctx context.Context, in <-chan alerts.Alert) <-chan error {
+	errCh := make(chan error, 8)
+	report := func(err error) {
+		select {
+		case <-ctx.Done():
+		case errCh <- err:
+		}
+	}
+
+	queues := make(map[string]chan alerts.Alert, len(d.notifiers))
+	var wg sync.WaitGroup
+	for _, notifier := range d.notifiers {
+		queue := make(chan alerts.Alert, queueSize)
+		queues[notifier.Name()] = queue
+		wg.Add(1)
+		go func(notifier Notifier) {
+			defer wg.Done()
+			for alert := range queue {
+				if err := d.deliver(ctx, notifier, alert); err != nil {
+					report(fmt.Errorf("notify: %s: %s %s: %w", notifier.Name(), alert.Symbol, alert.Type, err))
+				}
+			}
+		}(notifier)
+	}
+
+	go func() {
+		defer func() {
+			for _, queue := range queues {
+				close(queue)
+			}
+			wg.Wait()
+			close(errCh)
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case alert, ok := <-in:
+				if !ok {
+					return
+				}
+				for _, name := range d.route(alert) {
+					select {
+					case queues[name] <- alert:
+					default:
+						report(fmt.Errorf("notify: %s: queue full, dropped %s %s", name, alert.Symbol, alert.Type))
+					}
+				}
+			}
+		}
+	}()
+
+	return errCh
+}
+
+// route returns the notifiers an alert goes to, in route order.
+func (d *Dispatcher) route(alert alerts.Alert) []string {
+	var names []string
+	for _, route := range d.routes {
+		if route.matches(alert) && !slices.Contains(names, route.Notifier) {
+			names = append(names, route.Notifier)
+		}
+	}
+	return names
+}
+
+// deliver tries a delivery until it succeeds, fails permanently, runs out
+// of attempts or ctx is done.
+func (d *Dispatcher) deliver(ctx context.Context, notifier Notifier, alert alerts.Alert) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = notifier.Notify(ctx, alert); err == nil {
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt >= d.retry.Attempts {
+			break
+		}
+		timer := time.NewTimer(d.retry.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if d.retry.Attempts > 1 {
+		return fmt.Errorf("giving up: %w", err)
+	}
+	return err
+}
+
+// permanentError marks a failure that retrying will not fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying, such as a rejected request.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Summary describes an alert in one line, for chat messages and email
+// subjects.
+func Summary(alert alerts.Alert) string {
+	var b strings.Builder
+	if alert.Severity != "" {
+		fmt.Fprintf(&b, "[%s] ", strings.ToUpper(alert.Severity))
+	}
+	fmt.Fprintf(&b, "%s %s", alert.Symbol, alert.Type)
+	if alert.Rule != "" {
+		fmt.Fprintf(&b, " (rule %s)", alert.Rule)
+	}
+	switch {
+	case alert.Spread != nil:
+		fmt.Fprintf(&b, ": %.1f bps between %s at %g and %s at %g",
+			alert.Spread.Bps, alert.Spread.LowSource, alert.Spread.LowPrice, alert.Spread.HighSource, alert.Spread.HighPrice)
+	case alert.Detail != "":
+		fmt.Fprintf(&b, ": %s, now %g", alert.Detail, alert.NewPrice)
+	case alert.Window != "":
+		fmt.Fprintf(&b, ": %+.2f%% over %s, %g to %g", alert.ChangePct, alert.Window, alert.OldPrice, alert.NewPrice)
+	default:
+		fmt.Fprintf(&b, ": %+.2f%%, %g to %g", alert.ChangePct, alert.OldPrice, alert.NewPrice)
+	}
+	if alert.Occurrences > 1 {
+		fmt.Fprintf(&b, " (%d occurrences)", alert.Occurrences)
+	}
+	return b.String()
+}
@@ -0,0 +1,274 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/alerts"
+	"crypto-monitor/internal/incidents"
+)
+
+var testAlert = alerts.Alert{
+	Type:      alerts.TypePriceChange,
+	Symbol:    "BTCUSDT",
+	OldPrice:  100000,
+	NewPrice:  102500,
+	Change:    2500,
+	ChangePct: 2.5,
+	Window:    "5m",
+	Severity:  incidents.SeverityWarning,
+	Group:     "9f2c4e1ab07d3356",
+}
+
+var fastRetry = Retry{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+// recorder is a notifier that records the alerts it is given.
+type recorder struct {
+	name   string
+	mu     sync.Mutex
+	alerts []alerts.Alert
+}
+
+func (r *recorder) Name() string { return r.name }
+
+func (r *recorder) Notify(ctx context.Context, alert alerts.Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+func (r *recorder) symbols() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var symbols []string
+	for _, alert := range r.alerts {
+		symbols = append(symbols, alert.Symbol)
+	}
+	return symbols
+}
+
+// dispatch runs the alerts through a dispatcher and returns its errors
+// once every delivery has finished.
+func dispatch(t *testing.T, d *Dispatcher, sent ...alerts.Alert) []error {
+	t.Helper()
+	in := make(chan alerts.Alert, len(sent))
+	for _, alert := range sent {
+		in <- alert
+	}
+	close(in)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var errs []error
+	for err := range d.Start(ctx, in) {
+		errs = append(errs, err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("dispatcher did not finish")
+	}
+	return errs
+}
+
+func TestDispatcherRoutes(t *testing.T) {
+	all := &recorder{name: "all"}
+	critical := &recorder{name: "critical"}
+	btc := &recorder{name: "btc"}
+	d, err := NewDispatcher([]Notifier{all, critical, btc}, []Route{
+		{Notifier: "all"},
+		{Notifier: "critical", MinSeverity: incidents.SeverityCritical},
+		{Notifier: "btc", Symbols: []string{"BTCUSDT"}, Types: []string{alerts.TypePriceChange}},
+		{Notifier: "btc", Symbols: []string{"*"}, MinSeverity: incidents.SeverityWarning},
+	}, fastRetry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btcWarning := testAlert
+	ethInfo := testAlert
+	ethInfo.Symbol, ethInfo.Severity = "ETHUSDT", incidents.SeverityInfo
+	solCritical := testAlert
+	solCritical.Symbol, solCritical.Severity = "SOLUSDT", incidents.SeverityCritical
+	if errs := dispatch(t, d, btcWarning, ethInfo, solCritical); len(errs) > 0 {
+		t.Fatalf("errors: %v", errs)
+	}
+
+	for _, tc := range []struct {
+		notifier *recorder
+		want     string
+	}{
+		{all, "BTCUSDT,ETHUSDT,SOLUSDT"},
+		{critical, "SOLUSDT"},
+		// The BTC alert matches both btc routes but is sent once.
+		{btc, "BTCUSDT,SOLUSDT"},
+	} {
+		if got := strings.Join(tc.notifier.symbols(), ","); got != tc.want {
+			t.Errorf("%s got %q, want %q", tc.notifier.name, got, tc.want)
+		}
+	}
+}
+
+func TestNewDispatcherChecksRoutes(t *testing.T) {
+	notifiers := []Notifier{&recorder{name: "a"}}
+	if _, err := NewDispatcher(notifiers, []Route{{Notifier: "b"}}, fastRetry); err == nil {
+		t.Error("unknown notifier accepted")
+	}
+	if _, err := NewDispatcher(notifiers, []Route{{Notifier: "a", MinSeverity: "loud"}}, fastRetry); err == nil {
+		t.Error("unknown severity accepted")
+	}
+	if _, err := NewDispatcher(append(notifiers, &recorder{name: "a"}), nil, fastRetry); err == nil {
+		t.Error("duplicate notifier accepted")
+	}
+}
+
+func TestWebhookSignsAndRetries(t *testing.T) {
+	receiver := newFakeHTTP(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	webhook := NewWebhook("ops", receiver.URL, "s3cret")
+	webhook.now = func() time.Time { return time.Unix(1770000000, 0) }
+	d, err := NewDispatcher([]Notifier{webhook}, []Route{{Notifier: "ops"}}, fastRetry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := dispatch(t, d, testAlert); len(errs) > 0 {
+		t.Fatalf("errors: %v", errs)
+	}
+
+	requests := receiver.received()
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(requests))
+	}
+	last := requests[2]
+	timestamp := last.header.Get(HeaderTimestamp)
+	if timestamp != "1770000000" {
+		t.Errorf("timestamp %q", timestamp)
+	}
+	if got, want := last.header.Get(HeaderSignature), "sha256="+Sign("s3cret", timestamp, last.body); got != want {
+		t.Errorf("signature %q, want %q", got, want)
+	}
+	var got alerts.Alert
+	if err := json.Unmarshal(last.body, &got); err != nil || got.Group != testAlert.Group {
+		t.Errorf("body %s: %v", last.body, err)
+	}
+}
+
+func TestWebhookGivesUp(t *testing.T) {
+	receiver := newFakeHTTP(t, 500, 502, 503, 504)
+	d, err := NewDispatcher([]Notifier{NewWebhook("ops", receiver.URL, "")}, []Route{{Notifier: "ops"}}, fastRetry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := dispatch(t, d, testAlert)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "giving up") {
+		t.Fatalf("errors: %v", errs)
+	}
+	if n := len(receiver.received()); n != fastRetry.Attempts {
+		t.Errorf("got %d requests, want %d", n, fastRetry.Attempts)
+	}
+}
+
+func TestWebhookRejectionIsPermanent(t *testing.T) {
+	receiver := newFakeHTTP(t, http.StatusBadRequest)
+	d, err := NewDispatcher([]Notifier{NewWebhook("ops", receiver.URL, "")}, []Route{{Notifier: "ops"}}, fastRetry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := dispatch(t, d, testAlert)
+	var permanent *permanentError
+	if len(errs) != 1 || !errors.As(errs[0], &permanent) {
+		t.Fatalf("errors: %v", errs)
+	}
+	if n := len(receiver.received()); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+func TestSlackPayload(t *testing.T) {
+	receiver := newFakeHTTP(t)
+	if err := NewSlack("chat", receiver.URL).Notify(context.Background(), testAlert); err != nil {
+		t.Fatal(err)
+	}
+	var payload struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(receiver.received()[0].body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[WARNING] BTCUSDT price_change: +2.50% over 5m, 100000 to 102500"; payload.Text != want {
+		t.Errorf("text %q, want %q", payload.Text, want)
+	}
+}
+
+func TestEmailDelivers(t *testing.T) {
+	server := newFakeSMTP(t)
+	email := NewEmail("oncall", EmailConfig{
+		Addr: server.addr(),
+		From: "alerts@example.com",
+		To:   []string{"oncall@example.com", "desk@example.com"},
+	})
+	if err := email.Notify(context.Background(), testAlert); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("got %d messages", len(messages))
+	}
+	message := messages[0]
+	if message.from != "alerts@example.com" || strings.Join(message.to, ",") != "oncall@example.com,desk@example.com" {
+		t.Errorf("envelope %q to %q", message.from, message.to)
+	}
+	for _, want := range []string{
+		"Subject: [WARNING] BTCUSDT price_change: +2.50% over 5m, 100000 to 102500\r\n",
+		"To: oncall@example.com, desk@example.com\r\n",
+		`"group": "9f2c4e1ab07d3356"`,
+	} {
+		if !strings.Contains(message.data, want) {
+			t.Errorf("message lacks %q:\n%s", want, message.data)
+		}
+	}
+}
+
+func TestEmailRetriesTemporaryFailures(t *testing.T) {
+	server := newFakeSMTP(t)
+	server.mailReplies = []string{"451 try again later"}
+	email := NewEmail("oncall", EmailConfig{Addr: server.addr(), From: "alerts@example.com", To: []string{"oncall@example.com"}})
+	d, err := NewDispatcher([]Notifier{email}, []Route{{Notifier: "oncall"}}, fastRetry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := dispatch(t, d, testAlert); len(errs) > 0 {
+		t.Fatalf("errors: %v", errs)
+	}
+	if n := len(server.received()); n != 1 {
+		t.Errorf("got %d messages, want 1", n)
+	}
+}
+
+func TestEmailRejectionIsPermanent(t *testing.T) {
+	server := newFakeSMTP(t)
+	server.reject["nobody@example.com"] = true
+	email := NewEmail("oncall", EmailConfig{Addr: server.addr(), From: "alerts@example.com", To: []string{"nobody@example.com"}})
+	err := email.Notify(context.Background(), testAlert)
+	var permanent *permanentError
+	if !errors.As(err, &permanent) {
+		t.Fatalf("got %v, want a permanent error", err)
+	}
+	if n := len(server.received()); n != 0 {
+		t.Errorf("got %d messages, want none", n)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	retry := Retry{Attempts: 5, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second} {
+		if got := retry.delay(attempt); got != want {
+			t.Errorf("delay(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
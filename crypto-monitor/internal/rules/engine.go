@@ -0,0 +1,269 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"crypto-monitor/internal/alerts"
+	"crypto-monitor/internal/feed"
+	"crypto-monitor/internal/series"
+)
+
+// volumePeriod is the period venues report volume over.
+const volumePeriod = 24 * time.Hour
+
+// sampleResolution is how finely price history is kept for window rules.
+// Each symbol's history spans the longest rule window; see series.Ring for
+// its cost.
+const sampleResolution = time.Second
+
+// stateKey identifies a rule applied to one symbol.
+type stateKey struct {
+	rule   string
+	symbol string
+}
+
+// ruleState tracks whether a rule has alerted for a symbol and not yet
+// re-armed, so it alerts once each time its condition starts to hold.
+type ruleState struct {
+	active bool
+}
+
+// Engine evaluates rules against price events. Rules can be replaced while
+// it runs.
+type Engine struct {
+	mu        sync.Mutex
+	rules     []Rule
+	maxWindow time.Duration
+	now       func() time.Time
+	history   map[string]*series.Ring
+	// previous holds each symbol's price before the latest, for cross
+	// rules.
+	previous map[string]float64
+	states   map[stateKey]*ruleState
+}
+
+// NewEngine builds a rule engine with no rules.
+func NewEngine() *Engine {
+	return &Engine{
+		now:      time.Now,
+		history:  make(map[string]*series.Ring),
+		previous: make(map[string]float64),
+		states:   make(map[stateKey]*ruleState),
+	}
+}
+
+// SetRules replaces the engine's rules. Rules that are unchanged keep
+// their state, so reloading a file does not raise their alerts again.
+func (e *Engine) SetRules(rules []Rule) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	byID := make(map[string]Rule, len(rules))
+	var maxWindow time.Duration
+	for _, rule := range rules {
+		byID[rule.ID] = rule
+		maxWindow = max(maxWindow, time.Duration(rule.Window))
+	}
+	for _, old := range e.rules {
+		if rule, ok := byID[old.ID]; ok && rule == old {
+			continue
+		}
+		for key := range e.states {
+			if key.rule == old.ID {
+				delete(e.states, key)
+			}
+		}
+	}
+	e.rules = append([]Rule(nil), rules...)
+	if maxWindow != e.maxWindow {
+		for _, ring := range e.history {
+			ring.SetSpan(maxWindow)
+		}
+		e.maxWindow = maxWindow
+	}
+}
+
+// Rules returns the engine's current rules.
+func (e *Engine) Rules() []Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Rule(nil), e.rules...)
+}
+
+// Start evaluates the rules on each price event and emits their alerts.
+func (e *Engine) Start(ctx context.Context, prices <-chan feed.PriceEvent) <-chan alerts.Alert {
+	out := make(chan alerts.Alert, 32)
+
+	go func() {
+		defer close(out)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-prices:
+				if !ok {
+					return
+				}
+				for _, alert := range e.evaluate(event) {
+					select {
+					case <-ctx.Done():
+						return
+					case out <- alert:
+					}
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// evaluate records event in its symbol's history and returns the alerts
+// of rules whose condition has started to hold.
+func (e *Engine) evaluate(event feed.PriceEvent) []alerts.Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	ring, ok := e.history[event.Symbol]
+	if !ok {
+		ring = series.NewRing(e.maxWindow, sampleResolution)
+		e.history[event.Symbol] = ring
+	}
+	previous, hasPrevious := e.previous[event.Symbol]
+	e.previous[event.Symbol] = event.Price
+	current := series.Sample{At: now, Price: event.Price, Volume: event.Volume}
+	ring.Add(current)
+
+	var fired []alerts.Alert
+	for _, rule := range e.rules {
+		if !rule.matches(event.Symbol) {
+			continue
+		}
+		var alert alerts.Alert
+		var value float64
+		if isCross(rule.Type) {
+			if !hasPrevious {
+				continue
+			}
+			alert, value = rule.checkCross(previous, event.Price), event.Price
+		} else if alert, value, ok = rule.checkWindow(ring, current); !ok {
+			continue
+		}
+
+		key := stateKey{rule: rule.ID, symbol: event.Symbol}
+		state, seen := e.states[key]
+		if !seen {
+			// A cross rule has only crossed if the previous price was on
+			// the other side of its level.
+			state = &ruleState{active: isCross(rule.Type) && rule.reached(previous)}
+			e.states[key] = state
+		}
+		switch {
+		case !state.active && rule.reached(value):
+			state.active = true
+			alert.Symbol = event.Symbol
+			alert.OccurredAt = now.UTC()
+			alert.Observation = event.EventTime
+			fired = append(fired, alert)
+		case state.active && rule.rearmed(value):
+			state.active = false
+		}
+	}
+	return fired
+}
+
+// checkCross returns the alert a cross rule raises for a move from
+// previous to price.
+func (r Rule) checkCross(previous, price float64) alerts.Alert {
+	alert := alerts.Alert{Type: r.Type, Rule: r.ID}
+	if r.Type == TypeCrossAbove {
+		alert.Detail = fmt.Sprintf("crossed above %g", r.Level)
+	} else {
+		alert.Detail = fmt.Sprintf("crossed below %g", r.Level)
+	}
+	setChange(&alert, previous, price)
+	return alert
+}
+
+// checkWindow measures a window rule against the current sample: the
+// percent change in the rule's direction, or the volume multiple. It
+// returns the alert to raise if the value reaches the rule's threshold. ok
+// is false when the history does not reach back a full window.
+func (r Rule) checkWindow(ring *series.Ring, current series.Sample) (alert alerts.Alert, value float64, ok bool) {
+	window := time.Duration(r.Window)
+	alert = alerts.Alert{Type: r.Type, Rule: r.ID, Window: series.FormatWindow(window)}
+	start, found := ring.At(current.At.Add(-window))
+	if !found || start.Price == 0 {
+		return alert, 0, false
+	}
+	setChange(&alert, start.Price, current.Price)
+
+	switch r.Type {
+	case TypeWindowChange:
+		switch r.Direction {
+		case DirectionUp:
+			value = alert.ChangePct
+		case DirectionDown:
+			value = -alert.ChangePct
+		default:
+			value = math.Abs(alert.ChangePct)
+		}
+		alert.Detail = fmt.Sprintf("%+.2f%% over %s", alert.ChangePct, alert.Window)
+		return alert, value, true
+
+	case TypeVolumeSpike:
+		if current.Volume == 0 {
+			return alert, 0, false
+		}
+		// Venues report rolling daily volume, so the volume traded in the
+		// window is roughly how much it grew, and the day's average for
+		// a window of that length is its share of the day.
+		traded := current.Volume - start.Volume
+		average := current.Volume * float64(window) / float64(volumePeriod)
+		value = traded / average
+		alert.Detail = fmt.Sprintf("volume %.1fx the daily average over %s", value, alert.Window)
+		return alert, value, true
+	}
+	return alert, 0, false
+}
+
+// reached reports whether a measured value meets the rule: a price for
+// cross rules, the change or volume multiple for window rules.
+func (r Rule) reached(value float64) bool {
+	if r.Type == TypeCrossBelow {
+		return value <= r.Level
+	}
+	return value >= r.threshold()
+}
+
+// rearmed reports whether a value is far enough back from the threshold
+// for the rule to alert again: past Rearm when it is set, otherwise just
+// short of the threshold.
+func (r Rule) rearmed(value float64) bool {
+	if r.Rearm == 0 {
+		return !r.reached(value)
+	}
+	if r.Type == TypeCrossBelow {
+		return value > r.Rearm
+	}
+	return value < r.Rearm
+}
+
+func setChange(alert *alerts.Alert, from, to float64) {
+	alert.OldPrice = from
+	alert.NewPrice = to
+	alert.Change = to - from
+	if from != 0 {
+		alert.ChangePct = alert.Change / from * 100
+	}
+}
+
+func isCross(ruleType string) bool {
+	return ruleType == TypeCrossAbove || ruleType == TypeCrossBelow
+}
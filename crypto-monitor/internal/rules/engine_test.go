@@ -0,0 +1,204 @@
+package rules
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/feed"
+)
+
+// step is one price event for BTCUSDT, observed at an offset from the
+// test start.
+type step struct {
+	at     time.Duration
+	price  float64
+	volume float64
+	// want lists the IDs of the rules that alert.
+	want []string
+}
+
+// testEngine returns an engine with rules and a clock set by run.
+func testEngine(t *testing.T, rules ...Rule) (*Engine, func(steps ...step)) {
+	t.Helper()
+	if err := Validate(rules); err != nil {
+		t.Fatalf("invalid test rules: %v", err)
+	}
+	start := time.Date(2024, 5, 14, 10, 0, 0, 0, time.UTC)
+	var now time.Time
+	e := NewEngine()
+	e.now = func() time.Time { return now }
+	e.SetRules(rules)
+
+	run := func(steps ...step) {
+		t.Helper()
+		for i, s := range steps {
+			now = start.Add(s.at)
+			var got []string
+			for _, alert := range e.evaluate(feed.PriceEvent{Symbol: "BTCUSDT", Price: s.price, Volume: s.volume, EventTime: now}) {
+				got = append(got, alert.Rule)
+			}
+			if !slices.Equal(got, s.want) {
+				t.Errorf("step %d (price %v at %s): alerts %v, want %v", i, s.price, s.at, got, s.want)
+			}
+		}
+	}
+	return e, run
+}
+
+func TestEngineCrossRules(t *testing.T) {
+	above := Rule{ID: "above", Symbol: "BTCUSDT", Type: TypeCrossAbove, Level: 100}
+	below := Rule{ID: "below", Symbol: "*", Type: TypeCrossBelow, Level: 100}
+
+	tests := []struct {
+		name  string
+		rules []Rule
+		steps []step
+	}{
+		{
+			name:  "first price only sets the previous price",
+			rules: []Rule{above},
+			steps: []step{{price: 105}},
+		},
+		{
+			name:  "starting above the level is not a cross",
+			rules: []Rule{above},
+			steps: []step{{price: 105}, {price: 106}, {price: 95}, {price: 101, want: []string{"above"}}},
+		},
+		{
+			name:  "crossing from below",
+			rules: []Rule{above},
+			steps: []step{{price: 95}, {price: 100, want: []string{"above"}}, {price: 110}},
+		},
+		{
+			name:  "cross below for every symbol",
+			rules: []Rule{above, below},
+			steps: []step{{price: 105}, {price: 99, want: []string{"below"}}, {price: 101, want: []string{"above"}}, {price: 100, want: []string{"below"}}},
+		},
+		{
+			name:  "other symbols are ignored",
+			rules: []Rule{{ID: "eth", Symbol: "ETHUSDT", Type: TypeCrossAbove, Level: 100}},
+			steps: []step{{price: 95}, {price: 105}},
+		},
+		{
+			name:  "rearm above",
+			rules: []Rule{{ID: "above", Symbol: "BTCUSDT", Type: TypeCrossAbove, Level: 100, Rearm: 98}},
+			steps: []step{
+				{price: 95}, {price: 101, want: []string{"above"}},
+				// Dipping below the level is not enough.
+				{price: 99}, {price: 101},
+				{price: 97}, {price: 101, want: []string{"above"}},
+			},
+		},
+		{
+			name:  "rearm below",
+			rules: []Rule{{ID: "below", Symbol: "BTCUSDT", Type: TypeCrossBelow, Level: 100, Rearm: 102}},
+			steps: []step{
+				{price: 105}, {price: 99, want: []string{"below"}},
+				{price: 101}, {price: 99},
+				{price: 103}, {price: 99, want: []string{"below"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, run := testEngine(t, tt.rules...)
+			run(tt.steps...)
+		})
+	}
+}
+
+func TestEngineVolumeSpike(t *testing.T) {
+	spike := Rule{ID: "spike", Symbol: "BTCUSDT", Type: TypeVolumeSpike, Window: Duration(time.Hour), Multiplier: 3}
+
+	// Venues report rolling 24h volume. When the day's total grows from
+	// 2100 to V in an hour, the hour traded V-2100 against an hourly
+	// average of V/24.
+	tests := []struct {
+		name   string
+		volume float64
+		want   []string
+	}{
+		{name: "2.09x", volume: 2300},
+		{name: "3.00x", volume: 2400, want: []string{"spike"}},
+		{name: "3.84x", volume: 2500, want: []string{"spike"}},
+		{name: "no volume reported", volume: 0},
+		{name: "volume fell", volume: 2000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, run := testEngine(t, spike)
+			run(
+				step{price: 100, volume: 2100},
+				// Less than a full window of history.
+				step{at: 30 * time.Minute, price: 100, volume: 2500},
+				step{at: time.Hour, price: 100, volume: tt.volume, want: tt.want},
+			)
+		})
+	}
+
+	t.Run("detail", func(t *testing.T) {
+		e, run := testEngine(t, spike)
+		run(step{price: 100, volume: 2100})
+		e.now = func() time.Time { return time.Date(2024, 5, 14, 11, 0, 0, 0, time.UTC) }
+		alerts := e.evaluate(feed.PriceEvent{Symbol: "BTCUSDT", Price: 100, Volume: 2500})
+		if len(alerts) != 1 || alerts[0].Detail != "volume 3.8x the daily average over 1h" || alerts[0].Window != "1h" {
+			t.Fatalf("alerts = %+v", alerts)
+		}
+	})
+}
+
+func TestEngineWindowChange(t *testing.T) {
+	rule := func(id, direction string) Rule {
+		return Rule{ID: id, Symbol: "BTCUSDT", Type: TypeWindowChange, Window: Duration(time.Minute), Pct: 5, Direction: direction}
+	}
+	_, run := testEngine(t, rule("either", ""), rule("up", DirectionUp), rule("down", DirectionDown))
+	run(
+		step{price: 100},
+		step{at: 30 * time.Second, price: 110},
+		step{at: time.Minute, price: 106, want: []string{"either", "up"}},
+		step{at: 2 * time.Minute, price: 100, want: []string{"down"}},
+	)
+}
+
+func TestSetRulesKeepsStateOfUnchangedRules(t *testing.T) {
+	move := Rule{ID: "move", Symbol: "BTCUSDT", Type: TypeWindowChange, Window: Duration(time.Minute), Pct: 5}
+	edited := Rule{ID: "edited", Symbol: "BTCUSDT", Type: TypeWindowChange, Window: Duration(time.Minute), Pct: 5}
+	removed := Rule{ID: "removed", Symbol: "BTCUSDT", Type: TypeWindowChange, Window: Duration(time.Minute), Pct: 5}
+
+	e, run := testEngine(t, move, edited, removed)
+	run(
+		step{price: 100},
+		step{at: time.Minute, price: 110, want: []string{"move", "edited", "removed"}},
+	)
+
+	// Reloading the same rules raises nothing again.
+	e.SetRules([]Rule{move, edited, removed})
+	run(step{at: 61 * time.Second, price: 111})
+
+	// An edited rule, or one removed and added back, starts over.
+	edited.Pct = 6
+	e.SetRules([]Rule{move, edited})
+	e.SetRules([]Rule{move, edited, removed})
+	run(step{at: 62 * time.Second, price: 112, want: []string{"edited", "removed"}})
+
+	if got := e.Rules(); len(got) != 3 || got[1] != edited {
+		t.Errorf("Rules() = %+v", got)
+	}
+}
+
+func TestSetRulesResizesHistory(t *testing.T) {
+	short := Rule{ID: "short", Symbol: "BTCUSDT", Type: TypeWindowChange, Window: Duration(time.Minute), Pct: 5}
+	long := Rule{ID: "long", Symbol: "BTCUSDT", Type: TypeWindowChange, Window: Duration(time.Hour), Pct: 5}
+
+	e, run := testEngine(t, short, long)
+	for i := 0; i <= 90; i++ {
+		run(step{at: time.Duration(i) * time.Second, price: 100})
+	}
+	// Dropping the long rule shrinks the history to a minute and a bit, so
+	// it no longer reaches back an hour when the rule returns.
+	e.SetRules([]Rule{short})
+	e.SetRules([]Rule{short, long})
+	run(step{at: time.Hour, price: 110, want: []string{"short"}})
+}
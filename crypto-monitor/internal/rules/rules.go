@@ -0,0 +1,173 @@
+// Package rules evaluates per-symbol alert rules loaded from a JSON file.
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"crypto-monitor/internal/alerts"
+)
+
+// Rule types.
+const (
+	TypeCrossAbove   = alerts.TypeCrossAbove
+	TypeCrossBelow   = alerts.TypeCrossBelow
+	TypeWindowChange = alerts.TypeWindowChange
+	TypeVolumeSpike  = alerts.TypeVolumeSpike
+)
+
+// Directions for window change rules.
+const (
+	DirectionUp   = "up"
+	DirectionDown = "down"
+)
+
+// AllSymbols is the Symbol of rules that apply to every symbol.
+const AllSymbols = "*"
+
+// MaxWindow is the longest window a rule can look back over.
+const MaxWindow = 24 * time.Hour
+
+// Rule is one alert condition for a symbol.
+type Rule struct {
+	ID     string `json:"id"`
+	Symbol string `json:"symbol"`
+	Type   string `json:"type"`
+
+	// Level is the price a cross rule watches.
+	Level float64 `json:"level,omitempty"`
+	// Window is how far back window change and volume spike rules look.
+	Window Duration `json:"window,omitempty"`
+	// Pct is the percent change over Window that a window change rule
+	// alerts on, in Direction or either way when it is empty.
+	Pct       float64 `json:"pct,omitempty"`
+	Direction string  `json:"direction,omitempty"`
+	// Multiplier is how many times the day's average volume for Window a
+	// volume spike rule alerts on.
+	Multiplier float64 `json:"multiplier,omitempty"`
+
+	// Rearm is the value a rule must move back past before it alerts
+	// again: a price on the near side of Level, or a change or multiple
+	// below Pct or Multiplier. Unset, a rule re-arms as soon as its
+	// condition stops holding.
+	Rearm float64 `json:"rearm,omitempty"`
+}
+
+// File is the layout of a rules file.
+type File struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Duration is a time.Duration written as a string such as "5m".
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return errors.New(`durations are strings such as "5m"`)
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Load reads and checks a rules file.
+func Load(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("rules: %w", err)
+	}
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("rules: parse %s: %w", path, err)
+	}
+	if err := Validate(file.Rules); err != nil {
+		return nil, fmt.Errorf("rules: %s: %w", path, err)
+	}
+	return file.Rules, nil
+}
+
+// Validate checks that every rule is complete and IDs are unique.
+func Validate(rules []Rule) error {
+	seen := make(map[string]bool, len(rules))
+	for i, rule := range rules {
+		if rule.ID == "" {
+			return fmt.Errorf("rule %d: id is required", i+1)
+		}
+		if seen[rule.ID] {
+			return fmt.Errorf("rule %q: id is used twice", rule.ID)
+		}
+		seen[rule.ID] = true
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("rule %q: %w", rule.ID, err)
+		}
+	}
+	return nil
+}
+
+func (r Rule) validate() error {
+	if r.Symbol == "" {
+		return fmt.Errorf("symbol is required; use %q for every symbol", AllSymbols)
+	}
+	window := time.Duration(r.Window)
+	switch r.Type {
+	case TypeCrossAbove, TypeCrossBelow:
+		if r.Level <= 0 {
+			return errors.New("level must be positive")
+		}
+		if r.Rearm < 0 || r.Type == TypeCrossAbove && r.Rearm >= r.Level || r.Type == TypeCrossBelow && r.Rearm != 0 && r.Rearm <= r.Level {
+			return errors.New("rearm must be on the near side of level")
+		}
+		return nil
+	case TypeWindowChange:
+		if r.Pct <= 0 {
+			return errors.New("pct must be positive")
+		}
+		switch r.Direction {
+		case "", DirectionUp, DirectionDown:
+		default:
+			return fmt.Errorf("direction must be %q or %q", DirectionUp, DirectionDown)
+		}
+	case TypeVolumeSpike:
+		if r.Multiplier <= 0 {
+			return errors.New("multiplier must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown type %q", r.Type)
+	}
+	if window <= 0 || window > MaxWindow {
+		return fmt.Errorf("window must be between 0 and %s", MaxWindow)
+	}
+	if r.Rearm < 0 || r.Rearm >= r.threshold() {
+		return errors.New("rearm must be between 0 and the threshold")
+	}
+	return nil
+}
+
+// threshold is the value a rule alerts at.
+func (r Rule) threshold() float64 {
+	switch r.Type {
+	case TypeWindowChange:
+		return r.Pct
+	case TypeVolumeSpike:
+		return r.Multiplier
+	}
+	return r.Level
+}
+
+// matches reports whether the rule applies to symbol.
+func (r Rule) matches(symbol string) bool {
+	return r.Symbol == AllSymbols || r.Symbol == symbol
+}
@@ -0,0 +1,98 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	cross := func(r Rule) Rule {
+		r.ID, r.Symbol = "r", "BTCUSDT"
+		return r
+	}
+	window := Duration(5 * time.Minute)
+
+	tests := []struct {
+		name    string
+		rules   []Rule
+		wantErr string
+	}{
+		{name: "example rules", rules: exampleRules(t)},
+		{name: "missing id", rules: []Rule{{Symbol: "BTCUSDT", Type: TypeCrossAbove, Level: 1}}, wantErr: "rule 1: id is required"},
+		{name: "duplicate id", rules: []Rule{cross(Rule{Type: TypeCrossAbove, Level: 1}), cross(Rule{Type: TypeCrossBelow, Level: 1})}, wantErr: `rule "r": id is used twice`},
+		{name: "missing symbol", rules: []Rule{{ID: "r", Type: TypeCrossAbove, Level: 1}}, wantErr: "symbol is required"},
+		{name: "unknown type", rules: []Rule{cross(Rule{Type: "cross"})}, wantErr: `unknown type "cross"`},
+		{name: "cross without level", rules: []Rule{cross(Rule{Type: TypeCrossAbove})}, wantErr: "level must be positive"},
+		{name: "cross above rearm above level", rules: []Rule{cross(Rule{Type: TypeCrossAbove, Level: 100, Rearm: 100})}, wantErr: "near side of level"},
+		{name: "cross above rearm below level", rules: []Rule{cross(Rule{Type: TypeCrossAbove, Level: 100, Rearm: 95})}},
+		{name: "cross below rearm below level", rules: []Rule{cross(Rule{Type: TypeCrossBelow, Level: 100, Rearm: 95})}, wantErr: "near side of level"},
+		{name: "cross below rearm above level", rules: []Rule{cross(Rule{Type: TypeCrossBelow, Level: 100, Rearm: 105})}},
+		{name: "window change without pct", rules: []Rule{cross(Rule{Type: TypeWindowChange, Window: window})}, wantErr: "pct must be positive"},
+		{name: "bad direction", rules: []Rule{cross(Rule{Type: TypeWindowChange, Window: window, Pct: 1, Direction: "sideways"})}, wantErr: "direction must be"},
+		{name: "window missing", rules: []Rule{cross(Rule{Type: TypeWindowChange, Pct: 1})}, wantErr: "window must be between"},
+		{name: "window too long", rules: []Rule{cross(Rule{Type: TypeVolumeSpike, Window: Duration(MaxWindow + time.Second), Multiplier: 2})}, wantErr: "window must be between"},
+		{name: "volume spike without multiplier", rules: []Rule{cross(Rule{Type: TypeVolumeSpike, Window: window})}, wantErr: "multiplier must be positive"},
+		{name: "rearm at threshold", rules: []Rule{cross(Rule{Type: TypeVolumeSpike, Window: window, Multiplier: 3, Rearm: 3})}, wantErr: "rearm must be between 0 and the threshold"},
+		{name: "rearm below threshold", rules: []Rule{cross(Rule{Type: TypeVolumeSpike, Window: window, Multiplier: 3, Rearm: 2})}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.rules)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate: got %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	rules, err := Load(write("ok.json", `{"rules":[{"id":"a","symbol":"*","type":"window_change","window":"90m","pct":2}]}`))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(rules) != 1 || time.Duration(rules[0].Window) != 90*time.Minute {
+		t.Errorf("Load = %+v", rules)
+	}
+
+	for name, content := range map[string]string{
+		"numeric window": `{"rules":[{"id":"a","symbol":"*","type":"window_change","window":300,"pct":2}]}`,
+		"invalid rule":   `{"rules":[{"id":"a","symbol":"*","type":"window_change","pct":2}]}`,
+		"not JSON":       `rules:`,
+	} {
+		if _, err := Load(write("bad.json", content)); err == nil {
+			t.Errorf("Load with %s succeeded", name)
+		}
+	}
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
+
+// exampleRules loads the rules shipped as an example.
+func exampleRules(t *testing.T) []Rule {
+	t.Helper()
+	rules, err := Load(filepath.Join("..", "..", "rules.example.json"))
+	if err != nil {
+		t.Fatalf("load example: %v", err)
+	}
+	return rules
+}
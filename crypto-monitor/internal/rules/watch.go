@@ -0,0 +1,71 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Watch reloads the rules file into engine when its modification time or
+// size changes, checking every interval, and whenever reload receives a
+// signal. An interval of zero only reloads on signals. A file that fails
+// to load leaves the current rules in place and is reported on the error
+// stream.
+func Watch(ctx context.Context, path string, interval time.Duration, reload <-chan os.Signal, engine *Engine) <-chan error {
+	errCh := make(chan error, 1)
+	// Stat before returning, so a change made once Watch returns is seen.
+	last, _ := os.Stat(path)
+
+	go func() {
+		defer close(errCh)
+
+		report := func(err error) {
+			select {
+			case <-ctx.Done():
+			case errCh <- err:
+			}
+		}
+
+		var ticks <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			ticks = ticker.C
+		}
+
+		missing := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticks:
+				info, err := os.Stat(path)
+				if err != nil {
+					// Report a missing file once, not on every check.
+					if !missing {
+						report(fmt.Errorf("rules: %w", err))
+					}
+					missing = true
+					continue
+				}
+				missing = false
+				if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+					continue
+				}
+				last = info
+			case <-reload:
+				last, _ = os.Stat(path)
+			}
+
+			rules, err := Load(path)
+			if err != nil {
+				report(err)
+				continue
+			}
+			engine.SetRules(rules)
+		}
+	}()
+
+	return errCh
+}
@@ -0,0 +1,92 @@
+package rules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		signal   bool
+	}{
+		{name: "polling", interval: 10 * time.Millisecond},
+		{name: "signal", signal: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			// Replace the file in one step, as editors do, so the watcher
+			// never reads it half written.
+			write := func(content string) {
+				t.Helper()
+				tmp := path + ".tmp"
+				if err := os.WriteFile(tmp, []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Rename(tmp, path); err != nil {
+					t.Fatal(err)
+				}
+			}
+			write(`{"rules":[{"id":"a","symbol":"*","type":"cross_above","level":1}]}`)
+			engine := NewEngine()
+			rules, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			engine.SetRules(rules)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			reload := make(chan os.Signal, 1)
+			errs := Watch(ctx, path, tt.interval, reload, engine)
+			poke := func() {
+				if tt.signal {
+					reload <- syscall.SIGHUP
+				}
+			}
+
+			write(`{"rules":[{"id":"a","symbol":"*","type":"cross_above","level":1},{"id":"b","symbol":"*","type":"cross_below","level":1}]}`)
+			poke()
+			waitFor(t, "the new rules", func() bool { return len(engine.Rules()) == 2 })
+
+			// A broken file is reported and the rules kept.
+			write(`{"rules":[{"id":"c"}]}`)
+			poke()
+			select {
+			case err := <-errs:
+				if !strings.Contains(err.Error(), "symbol is required") {
+					t.Errorf("error = %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no error for an invalid file")
+			}
+			if got := engine.Rules(); len(got) != 2 {
+				t.Errorf("rules after a failed reload = %+v", got)
+			}
+
+			cancel()
+			for range errs {
+			}
+		})
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after a while.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
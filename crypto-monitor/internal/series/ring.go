@@ -0,0 +1,123 @@
+// Package series keeps a bounded, time-indexed history of a symbol's prices
+// so changes can be measured against the price some time ago.
+package series
+
+import (
+	"strings"
+	"time"
+)
+
+// Sample is a price observation.
+type Sample struct {
+	At     time.Time
+	Price  float64
+	Volume float64
+}
+
+// Ring is a fixed-size ring buffer of samples in time order, holding at
+// most one sample per resolution interval. It covers a span of time plus
+// the sample just before it, so the price a full span ago is known. Ring is
+// not safe for concurrent use.
+//
+// Its slots are allocated up front, one per interval at 40 bytes each: a
+// 24h span at one-second resolution holds 86,402 samples, about 3.5 MB.
+type Ring struct {
+	resolution time.Duration
+	samples    []Sample
+	start      int
+	n          int
+}
+
+// NewRing builds a ring covering span at the given resolution.
+func NewRing(span, resolution time.Duration) *Ring {
+	r := &Ring{resolution: resolution}
+	r.SetSpan(span)
+	return r
+}
+
+// SetSpan resizes the ring to cover span, keeping the newest samples.
+func (r *Ring) SetSpan(span time.Duration) {
+	// One slot per interval in the span, one for the interval in progress
+	// and one for the sample from before the span.
+	size := int(span/r.resolution) + 2
+	if size == len(r.samples) {
+		return
+	}
+	samples := make([]Sample, size)
+	keep := min(r.n, size)
+	for i := 0; i < keep; i++ {
+		samples[i] = r.at(r.n - keep + i)
+	}
+	r.samples, r.start, r.n = samples, 0, keep
+}
+
+// Add records a sample. A sample in the same resolution interval as the
+// newest one replaces it, and samples older than the newest are ignored.
+func (r *Ring) Add(s Sample) {
+	if r.n > 0 {
+		last := r.at(r.n - 1)
+		if s.At.Before(last.At) {
+			return
+		}
+		if s.At.Truncate(r.resolution).Equal(last.At.Truncate(r.resolution)) {
+			r.samples[(r.start+r.n-1)%len(r.samples)] = s
+			return
+		}
+	}
+	if r.n < len(r.samples) {
+		r.samples[(r.start+r.n)%len(r.samples)] = s
+		r.n++
+		return
+	}
+	r.samples[r.start] = s
+	r.start = (r.start + 1) % len(r.samples)
+}
+
+// Latest returns the newest sample.
+func (r *Ring) Latest() (Sample, bool) {
+	if r.n == 0 {
+		return Sample{}, false
+	}
+	return r.at(r.n - 1), true
+}
+
+// At returns the newest sample taken at or before t, which is false when
+// the ring does not reach back that far.
+func (r *Ring) At(t time.Time) (Sample, bool) {
+	// Binary search for the first sample after t.
+	lo, hi := 0, r.n
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if r.at(mid).At.After(t) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	if lo == 0 {
+		return Sample{}, false
+	}
+	return r.at(lo - 1), true
+}
+
+// Len returns the number of samples held.
+func (r *Ring) Len() int {
+	return r.n
+}
+
+// at returns the i-th oldest sample.
+func (r *Ring) at(i int) Sample {
+	return r.samples[(r.start+i)%len(r.samples)]
+}
+
+// FormatWindow writes a window as a short duration, such as 5m or 1h30m.
+func FormatWindow(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,175 @@
+package series
+
+import (
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 5, 14, 10, 0, 0, 0, time.UTC)
+
+// prices returns the ring's prices, oldest first.
+func prices(r *Ring) []float64 {
+	out := make([]float64, r.Len())
+	for i := range out {
+		out[i] = r.at(i).Price
+	}
+	return out
+}
+
+// sample returns a sample seconds after start.
+func sample(seconds float64, price float64) Sample {
+	return Sample{At: start.Add(time.Duration(seconds * float64(time.Second))), Price: price}
+}
+
+func equal(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRingAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		span    time.Duration
+		samples []Sample
+		want    []float64
+	}{
+		{
+			name:    "in order",
+			span:    5 * time.Second,
+			samples: []Sample{sample(0, 1), sample(1, 2), sample(2, 3)},
+			want:    []float64{1, 2, 3},
+		},
+		{
+			// Seven slots: five intervals, the one in progress and the
+			// one before the span.
+			name:    "wraps around keeping the newest",
+			span:    5 * time.Second,
+			samples: []Sample{sample(0, 1), sample(1, 2), sample(2, 3), sample(3, 4), sample(4, 5), sample(5, 6), sample(6, 7), sample(7, 8), sample(8, 9)},
+			want:    []float64{3, 4, 5, 6, 7, 8, 9},
+		},
+		{
+			name:    "same interval replaces",
+			span:    5 * time.Second,
+			samples: []Sample{sample(0, 1), sample(1.1, 2), sample(1.9, 3), sample(2, 4)},
+			want:    []float64{1, 3, 4},
+		},
+		{
+			name:    "older samples are ignored",
+			span:    5 * time.Second,
+			samples: []Sample{sample(0, 1), sample(3, 2), sample(2, 3), sample(2.9, 4)},
+			want:    []float64{1, 2},
+		},
+		{
+			name:    "replacing after wrapping around",
+			span:    time.Second,
+			samples: []Sample{sample(0, 1), sample(1, 2), sample(2, 3), sample(3, 4), sample(3.5, 5)},
+			want:    []float64{2, 3, 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRing(tt.span, time.Second)
+			for _, s := range tt.samples {
+				r.Add(s)
+			}
+			if got := prices(r); !equal(got, tt.want) {
+				t.Errorf("prices = %v, want %v", got, tt.want)
+			}
+			latest, ok := r.Latest()
+			if !ok || latest.Price != tt.want[len(tt.want)-1] {
+				t.Errorf("Latest = %v, %v", latest, ok)
+			}
+		})
+	}
+}
+
+func TestRingSetSpan(t *testing.T) {
+	r := NewRing(5*time.Second, time.Second)
+	// Wrap around first, so resizing has to unroll the ring.
+	for i := 0; i < 10; i++ {
+		r.Add(sample(float64(i), float64(i)))
+	}
+
+	r.SetSpan(2 * time.Second)
+	if got, want := prices(r), []float64{6, 7, 8, 9}; !equal(got, want) {
+		t.Fatalf("after shrinking: %v, want %v", got, want)
+	}
+	r.SetSpan(10 * time.Second)
+	if got, want := prices(r), []float64{6, 7, 8, 9}; !equal(got, want) {
+		t.Fatalf("after growing: %v, want %v", got, want)
+	}
+	for i := 10; i < 30; i++ {
+		r.Add(sample(float64(i), float64(i)))
+	}
+	if got := r.Len(); got != 12 {
+		t.Errorf("Len = %d after filling a 10s span, want 12", got)
+	}
+	if s, _ := r.Latest(); s.Price != 29 {
+		t.Errorf("Latest = %v, want 29", s.Price)
+	}
+
+	var empty Ring
+	empty.resolution = time.Second
+	empty.SetSpan(0)
+	empty.Add(sample(0, 1))
+	empty.Add(sample(1, 2))
+	if got, want := prices(&empty), []float64{1, 2}; !equal(got, want) {
+		t.Errorf("zero span holds %v, want %v", got, want)
+	}
+}
+
+func TestRingAt(t *testing.T) {
+	r := NewRing(10*time.Second, time.Second)
+	// Wrapped around, with gaps: of 2, 4, ..., 30 the twelve slots keep
+	// 8 to 30.
+	for i := 2; i <= 30; i += 2 {
+		r.Add(sample(float64(i), float64(i)))
+	}
+
+	tests := []struct {
+		at     float64
+		want   float64
+		wantOK bool
+	}{
+		{at: 7.9},
+		{at: 8, want: 8, wantOK: true},
+		{at: 9.5, want: 8, wantOK: true},
+		{at: 10, want: 10, wantOK: true},
+		{at: 17, want: 16, wantOK: true},
+		{at: 30, want: 30, wantOK: true},
+		{at: 100, want: 30, wantOK: true},
+	}
+	for _, tt := range tests {
+		s, ok := r.At(start.Add(time.Duration(tt.at * float64(time.Second))))
+		if ok != tt.wantOK || s.Price != tt.want {
+			t.Errorf("At(%vs) = %v, %v; want %v, %v", tt.at, s.Price, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	if _, ok := NewRing(time.Minute, time.Second).At(start); ok {
+		t.Error("At on an empty ring succeeded")
+	}
+}
+
+func TestFormatWindow(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		30 * time.Second:             "30s",
+		5 * time.Minute:              "5m",
+		90 * time.Minute:             "1h30m",
+		24 * time.Hour:               "24h",
+		time.Minute + 30*time.Second: "1m30s",
+		time.Hour + 30*time.Second:   "1h0m30s",
+	} {
+		if got := FormatWindow(d); got != want {
+			t.Errorf("FormatWindow(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
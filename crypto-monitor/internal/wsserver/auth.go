@@ -0,0 +1,177 @@
+package wsserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Token grants a client access to the WebSocket streams.
+type Token struct {
+	// Name identifies the token in logs and limits; the secret is Token.
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	// Symbols are the symbols the token may receive. Empty, or holding
+	// Wildcard, means every symbol.
+	Symbols []string `json:"symbols,omitempty"`
+	// MaxConnections caps the token's open connections; 0 means no cap.
+	MaxConnections int `json:"max_connections,omitempty"`
+}
+
+// TokenFile is the layout of a tokens file.
+type TokenFile struct {
+	Tokens []Token `json:"tokens"`
+}
+
+// LoadTokens reads and checks a tokens file.
+func LoadTokens(path string) ([]Token, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("tokens: %w", err)
+	}
+	var file TokenFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("tokens: parse %s: %w", path, err)
+	}
+	if len(file.Tokens) == 0 {
+		return nil, fmt.Errorf("tokens: %s: no tokens", path)
+	}
+	names := make(map[string]bool, len(file.Tokens))
+	secrets := make(map[string]bool, len(file.Tokens))
+	for i := range file.Tokens {
+		token := &file.Tokens[i]
+		switch {
+		case token.Name == "":
+			return nil, fmt.Errorf("tokens: %s: token %d: name is required", path, i+1)
+		case names[token.Name]:
+			return nil, fmt.Errorf("tokens: %s: token %q: name is used twice", path, token.Name)
+		case len(token.Token) < 16:
+			return nil, fmt.Errorf("tokens: %s: token %q: token must be at least 16 characters", path, token.Name)
+		case secrets[token.Token]:
+			return nil, fmt.Errorf("tokens: %s: token %q: token is used twice", path, token.Name)
+		case token.MaxConnections < 0:
+			return nil, fmt.Errorf("tokens: %s: token %q: max_connections must not be negative", path, token.Name)
+		}
+		if token.Symbols, err = parseSymbols(token.Symbols); err != nil {
+			return nil, fmt.Errorf("tokens: %s: token %q: %w", path, token.Name, err)
+		}
+		names[token.Name] = true
+		secrets[token.Token] = true
+	}
+	return file.Tokens, nil
+}
+
+// errUnauthorized is returned for missing or unknown tokens.
+var errUnauthorized = errors.New("a valid token is required")
+
+// authenticate finds the token a request carries, in the token query
+// parameter or an "Authorization: Bearer" header. Without configured
+// tokens every request is let in, as nil.
+func (s *Server) authenticate(r *http.Request) (*Token, error) {
+	if len(s.options.Tokens) == 0 {
+		return nil, nil
+	}
+	secret := r.URL.Query().Get("token")
+	if header := r.Header.Get("Authorization"); secret == "" && header != "" {
+		scheme, value, _ := strings.Cut(header, " ")
+		if strings.EqualFold(scheme, "Bearer") {
+			secret = strings.TrimSpace(value)
+		}
+	}
+	if secret == "" {
+		return nil, errUnauthorized
+	}
+	for i := range s.options.Tokens {
+		token := &s.options.Tokens[i]
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(token.Token)) == 1 {
+			return token, nil
+		}
+	}
+	return nil, errUnauthorized
+}
+
+// tokenKey is the request context key of the token a request carries.
+type tokenKey struct{}
+
+// requireToken answers requests without a valid token with 401 and
+// passes the others on, with their token in the request context.
+func (s *Server) requireToken(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := s.authenticate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		handler(w, r.WithContext(context.WithValue(r.Context(), tokenKey{}, token)))
+	}
+}
+
+// PermittedSymbols returns the symbols the token of a request to a
+// HandleFunc route may see, nil meaning all.
+func PermittedSymbols(r *http.Request) []string {
+	token, _ := r.Context().Value(tokenKey{}).(*Token)
+	return token.permitted()
+}
+
+// acquire takes one of a token's connections, reporting false when it has
+// none left.
+func (s *Server) acquire(token *Token) bool {
+	if token == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token.MaxConnections > 0 && s.tokenConns[token.Name] >= token.MaxConnections {
+		return false
+	}
+	s.tokenConns[token.Name]++
+	return true
+}
+
+// release returns a connection taken by acquire.
+func (s *Server) release(token *Token) {
+	if token == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokenConns[token.Name]--
+	if s.tokenConns[token.Name] <= 0 {
+		delete(s.tokenConns, token.Name)
+	}
+}
+
+// checkOrigin accepts requests without an Origin header, which do not come
+// from browsers, and otherwise those from an allowed origin. Without an
+// allowlist only same-origin requests are accepted.
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if len(s.options.AllowedOrigins) == 0 {
+		parsed, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(parsed.Host, r.Host)
+	}
+	for _, allowed := range s.options.AllowedOrigins {
+		if allowed == Wildcard || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// permitted returns the symbols a token may receive, nil meaning all.
+func (t *Token) permitted() []string {
+	if t == nil || len(t.Symbols) == 0 || slices.Contains(t.Symbols, Wildcard) {
+		return nil
+	}
+	return t.Symbols
+}
@@ -0,0 +1,402 @@
+package wsserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/alerts"
+	"crypto-monitor/internal/cache"
+	"crypto-monitor/internal/history"
+	"crypto-monitor/internal/incidents"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	dashboard = Token{Name: "dashboard", Token: "3f9a6c1d2e8b47a5b0c4"}
+	btcDesk   = Token{Name: "btc-desk", Token: "b71e04d9a2c65f38e1a9", Symbols: []string{"BTCUSDT"}, MaxConnections: 2}
+)
+
+// bearer returns an Authorization header for a token.
+func bearer(token Token) http.Header {
+	return http.Header{"Authorization": {"Bearer " + token.Token}}
+}
+
+// dialStatus tries to connect, returning the status of a refusal or 101.
+func dialStatus(t *testing.T, url string, header http.Header) int {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if err == nil {
+		t.Cleanup(func() { _ = conn.Close() })
+		return http.StatusSwitchingProtocols
+	}
+	if resp == nil {
+		t.Fatalf("dial %s: %v", url, err)
+	}
+	return resp.StatusCode
+}
+
+func TestLoadTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{name: "valid", file: `{"tokens":[{"name":"a","token":"0123456789abcdef","symbols":[" btcusdt"]},{"name":"b","token":"fedcba9876543210"}]}`},
+		{name: "not JSON", file: `tokens:`, wantErr: "parse"},
+		{name: "no tokens", file: `{"tokens":[]}`, wantErr: "no tokens"},
+		{name: "missing name", file: `{"tokens":[{"token":"0123456789abcdef"}]}`, wantErr: "token 1: name is required"},
+		{name: "duplicate name", file: `{"tokens":[{"name":"a","token":"0123456789abcdef"},{"name":"a","token":"fedcba9876543210"}]}`, wantErr: "name is used twice"},
+		{name: "short token", file: `{"tokens":[{"name":"a","token":"0123456789"}]}`, wantErr: "at least 16 characters"},
+		{name: "duplicate token", file: `{"tokens":[{"name":"a","token":"0123456789abcdef"},{"name":"b","token":"0123456789abcdef"}]}`, wantErr: "token is used twice"},
+		{name: "negative limit", file: `{"tokens":[{"name":"a","token":"0123456789abcdef","max_connections":-1}]}`, wantErr: "max_connections must not be negative"},
+		{name: "empty symbol", file: `{"tokens":[{"name":"a","token":"0123456789abcdef","symbols":[""]}]}`, wantErr: "symbols must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tokens.json")
+			if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			tokens, err := LoadTokens(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadTokens: got %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadTokens: %v", err)
+			}
+			if len(tokens) != 2 || !slices.Equal(tokens[0].Symbols, []string{"BTCUSDT"}) {
+				t.Errorf("tokens = %+v", tokens)
+			}
+		})
+	}
+
+	if _, err := LoadTokens(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadTokens of a missing file succeeded")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	s := NewServer("", Options{Tokens: []Token{dashboard, btcDesk}})
+
+	tests := []struct {
+		name   string
+		query  string
+		header string
+		want   string
+	}{
+		{name: "query parameter", query: "token=" + btcDesk.Token, want: "btc-desk"},
+		{name: "bearer header", header: "Bearer " + dashboard.Token, want: "dashboard"},
+		{name: "scheme in any case", header: "bearer  " + dashboard.Token, want: "dashboard"},
+		{name: "query parameter first", query: "token=" + btcDesk.Token, header: "Bearer " + dashboard.Token, want: "btc-desk"},
+		{name: "none"},
+		{name: "unknown", query: "token=0000000000000000"},
+		{name: "prefix of a token", query: "token=" + dashboard.Token[:16]},
+		{name: "other scheme", header: "Basic " + dashboard.Token},
+		{name: "bare header", header: dashboard.Token},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/ws?"+tt.query, nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			token, err := s.authenticate(r)
+			if tt.want == "" {
+				if err != errUnauthorized {
+					t.Fatalf("authenticate = %+v, %v; want errUnauthorized", token, err)
+				}
+				return
+			}
+			if err != nil || token.Name != tt.want {
+				t.Fatalf("authenticate = %+v, %v; want %s", token, err, tt.want)
+			}
+		})
+	}
+
+	t.Run("no tokens configured", func(t *testing.T) {
+		open := NewServer("", Options{})
+		if token, err := open.authenticate(httptest.NewRequest(http.MethodGet, "/ws", nil)); token != nil || err != nil {
+			t.Fatalf("authenticate = %+v, %v", token, err)
+		}
+	})
+}
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{name: "not a browser", want: true},
+		{name: "same origin", origin: "http://monitor.example.com:8080", want: true},
+		{name: "other origin", origin: "https://evil.example.com"},
+		{name: "unparseable origin", origin: "::"},
+		{name: "allowed", allowed: []string{"https://dash.example.com/"}, origin: "https://DASH.example.com", want: true},
+		{name: "allowlist replaces same origin", allowed: []string{"https://dash.example.com"}, origin: "http://monitor.example.com:8080"},
+		{name: "other scheme", allowed: []string{"https://dash.example.com"}, origin: "http://dash.example.com"},
+		{name: "wildcard", allowed: []string{Wildcard}, origin: "https://anywhere.example.com", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer("", Options{AllowedOrigins: tt.allowed})
+			r := httptest.NewRequest(http.MethodGet, "http://monitor.example.com:8080/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := s.checkOrigin(r); got != tt.want {
+				t.Errorf("checkOrigin = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A refused origin fails the upgrade.
+	_, url := newTestServer(t, Options{AllowedOrigins: []string{"https://dash.example.com"}})
+	if status := dialStatus(t, url+"/ws", http.Header{"Origin": {"https://evil.example.com"}}); status != http.StatusForbidden {
+		t.Errorf("status %d for a refused origin, want 403", status)
+	}
+	dial(t, url+"/ws", http.Header{"Origin": {"https://dash.example.com"}})
+}
+
+func TestTokenSymbols(t *testing.T) {
+	s, url := newTestServer(t, Options{Tokens: []Token{dashboard, btcDesk}})
+
+	for _, query := range []string{"", "?symbols=ETHUSDT", "?token=wrong-but-long!!"} {
+		if status := dialStatus(t, url+"/ws"+query, nil); status != http.StatusUnauthorized {
+			t.Errorf("/ws%s: status %d, want 401", query, status)
+		}
+	}
+	if status := dialStatus(t, url+"/ws?symbols=BTCUSDT,ETHUSDT", bearer(btcDesk)); status != http.StatusForbidden {
+		t.Errorf("status %d for a symbol the token lacks, want 403", status)
+	}
+	if status := dialStatus(t, url+"/ws/prices?symbols=ETHUSDT", bearer(btcDesk)); status != http.StatusForbidden {
+		t.Errorf("status %d for a price symbol the token lacks, want 403", status)
+	}
+
+	desk := dial(t, url+"/ws?token="+btcDesk.Token, nil)
+	all := dial(t, url+"/ws", bearer(dashboard))
+	send(t, desk, Request{ID: "1", Action: ActionSubscribe, Symbols: []string{"ETHUSDT"}})
+	if reply := read(t, desk); reply.Type != ReplyError || !strings.Contains(reply.Error, "symbol not permitted: ETHUSDT") {
+		t.Fatalf("reply = %+v", reply)
+	}
+	send(t, desk, Request{ID: "2", Action: ActionSubscribe, Symbols: []string{Wildcard}})
+	ack := read(t, desk)
+	if ack.Type != ReplyAck || !slices.Equal(ack.Subscription.PermittedSymbols, []string{"BTCUSDT"}) {
+		t.Fatalf("ack = %+v (subscription %+v)", ack, ack.Subscription)
+	}
+	send(t, all, Request{ID: "1", Action: ActionSubscribe, Symbols: []string{Wildcard}})
+	if ack := read(t, all); ack.Type != ReplyAck || ack.Subscription.PermittedSymbols != nil {
+		t.Fatalf("ack = %+v (subscription %+v)", ack, ack.Subscription)
+	}
+
+	broadcast(t, s, alerts.Alert{ID: "a1", Type: alerts.TypeSpread, Symbol: "ETHUSDT"})
+	broadcast(t, s, alerts.Alert{ID: "a2", Type: alerts.TypeSpread, Symbol: "BTCUSDT"})
+	if msg := read(t, desk); msg.ID != "a2" {
+		t.Errorf("btc-desk got %+v, want alert a2", msg)
+	}
+	for _, want := range []string{"a1", "a2"} {
+		if msg := read(t, all); msg.ID != want {
+			t.Errorf("dashboard got %+v, want alert %s", msg, want)
+		}
+	}
+}
+
+func TestConnectionLimit(t *testing.T) {
+	s, url := newTestServer(t, Options{Tokens: []Token{dashboard, btcDesk}})
+
+	first := dial(t, url+"/ws", bearer(btcDesk))
+	dial(t, url+"/ws/prices", bearer(btcDesk))
+	if status := dialStatus(t, url+"/ws", bearer(btcDesk)); status != http.StatusTooManyRequests {
+		t.Fatalf("status %d over the limit, want 429", status)
+	}
+	// Other tokens have their own count, and no cap without a limit.
+	for i := 0; i < 3; i++ {
+		dial(t, url+"/ws", bearer(dashboard))
+	}
+
+	// Closing a connection frees it.
+	_ = first.Close()
+	waitFor(t, "the closed connection to be released", func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.tokenConns[btcDesk.Name] == 1
+	})
+	dial(t, url+"/ws", bearer(btcDesk))
+
+	// A refused request does not take a connection.
+	if status := dialStatus(t, url+"/ws?symbols=ETHUSDT", bearer(btcDesk)); status != http.StatusForbidden {
+		t.Fatalf("status %d, want 403", status)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if got := s.tokenConns[btcDesk.Name]; got != 2 {
+		t.Errorf("btc-desk holds %d connections, want 2", got)
+	}
+}
+
+func TestHTTPRoutesNeedToken(t *testing.T) {
+	s := NewServer("", Options{Tokens: []Token{dashboard}})
+	s.HandleFunc("GET /prices", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		target string
+		header string
+		want   int
+	}{
+		{target: "/prices", want: http.StatusUnauthorized},
+		{target: "/prices", header: "Bearer " + dashboard.Token, want: http.StatusOK},
+		{target: "/prices?token=" + dashboard.Token, want: http.StatusOK},
+		{target: "/prices", header: "Bearer 0000000000000000", want: http.StatusUnauthorized},
+		{target: "/metrics", want: http.StatusUnauthorized},
+		{target: "/metrics", header: "Bearer " + dashboard.Token, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		s.mux.ServeHTTP(rec, r)
+		if rec.Code != tt.want {
+			t.Errorf("GET %s with %q: status %d, want %d", tt.target, tt.header, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestPermittedSymbols(t *testing.T) {
+	var got []string
+	record := func(w http.ResponseWriter, r *http.Request) {
+		got = PermittedSymbols(r)
+	}
+	secured := NewServer("", Options{Tokens: []Token{dashboard, btcDesk}})
+	secured.HandleFunc("GET /alerts", record)
+	open := NewServer("", Options{})
+	open.HandleFunc("GET /alerts", record)
+
+	tests := []struct {
+		name   string
+		server *Server
+		token  Token
+		want   []string
+	}{
+		{name: "limited token", server: secured, token: btcDesk, want: []string{"BTCUSDT"}},
+		{name: "unlimited token", server: secured, token: dashboard},
+		{name: "no tokens configured", server: open},
+	}
+	for _, tt := range tests {
+		got = []string{"unset"}
+		r := httptest.NewRequest(http.MethodGet, "/alerts", nil)
+		if tt.token.Token != "" {
+			r.Header.Set("Authorization", "Bearer "+tt.token.Token)
+		}
+		tt.server.mux.ServeHTTP(httptest.NewRecorder(), r)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: PermittedSymbols = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// priceCache is a cache.Cache holding snapshots in a map.
+type priceCache map[string]cache.Snapshot
+
+func (c priceCache) GetPrice(_ context.Context, symbol string) (cache.Snapshot, bool, error) {
+	snapshot, ok := c[symbol]
+	return snapshot, ok, nil
+}
+
+func (c priceCache) SetPrice(_ context.Context, snapshot cache.Snapshot) error {
+	c[snapshot.Symbol] = snapshot
+	return nil
+}
+
+func TestHTTPRoutesLimitSymbols(t *testing.T) {
+	symbols := []string{"BTCUSDT", "ETHUSDT"}
+	prices := priceCache{"BTCUSDT": {Symbol: "BTCUSDT", Price: 100}, "ETHUSDT": {Symbol: "ETHUSDT", Price: 10}}
+	store, _ := recordAlerts(t, 100, symbols...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tracker := incidents.New(incidents.Config{Cooldown: time.Hour, WarningPct: 2, CriticalPct: 5})
+	in := make(chan alerts.Alert, len(symbols))
+	out := tracker.Start(ctx, in)
+	groups := make(map[string]string)
+	for _, symbol := range symbols {
+		in <- alerts.Alert{Type: alerts.TypeSpread, Symbol: symbol}
+		alert := <-out
+		groups[symbol] = alert.Group
+	}
+
+	// Routes as cmd/main.go mounts them.
+	s := NewServer("", Options{Tokens: []Token{dashboard, btcDesk}})
+	s.HandleFunc("GET /prices", cache.ServePrices(prices, symbols, PermittedSymbols))
+	s.HandleFunc("GET /alert-groups", tracker.ServeGroups(PermittedSymbols))
+	s.HandleFunc("POST /alert-groups/{id}/ack", tracker.ServeAcknowledge(PermittedSymbols))
+	s.HandleFunc("GET /alerts", history.ServeAlerts(store, PermittedSymbols))
+
+	tests := []struct {
+		method      string
+		target      string
+		token       Token
+		wantStatus  int
+		wantSymbols []string
+	}{
+		{method: http.MethodGet, target: "/prices", token: dashboard, wantStatus: http.StatusOK, wantSymbols: symbols},
+		{method: http.MethodGet, target: "/prices", token: btcDesk, wantStatus: http.StatusOK, wantSymbols: []string{"BTCUSDT"}},
+		{method: http.MethodGet, target: "/prices?symbols=BTCUSDT,ETHUSDT", token: btcDesk, wantStatus: http.StatusForbidden},
+		{method: http.MethodGet, target: "/alert-groups", token: dashboard, wantStatus: http.StatusOK, wantSymbols: symbols},
+		{method: http.MethodGet, target: "/alert-groups", token: btcDesk, wantStatus: http.StatusOK, wantSymbols: []string{"BTCUSDT"}},
+		{method: http.MethodPost, target: "/alert-groups/" + groups["ETHUSDT"] + "/ack", token: btcDesk, wantStatus: http.StatusNotFound},
+		{method: http.MethodPost, target: "/alert-groups/" + groups["BTCUSDT"] + "/ack", token: btcDesk, wantStatus: http.StatusOK, wantSymbols: []string{"BTCUSDT"}},
+		{method: http.MethodGet, target: "/alerts", token: dashboard, wantStatus: http.StatusOK, wantSymbols: symbols},
+		{method: http.MethodGet, target: "/alerts", token: btcDesk, wantStatus: http.StatusOK, wantSymbols: []string{"BTCUSDT"}},
+		{method: http.MethodGet, target: "/alerts?symbol=ETHUSDT", token: btcDesk, wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.target, nil)
+		r.Header.Set("Authorization", "Bearer "+tt.token.Token)
+		rec := httptest.NewRecorder()
+		s.mux.ServeHTTP(rec, r)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s %s as %s: status %d, want %d: %s", tt.method, tt.target, tt.token.Name, rec.Code, tt.wantStatus, rec.Body)
+			continue
+		}
+		if tt.wantSymbols == nil {
+			continue
+		}
+		// Lists and single objects alike carry a symbol; groups opened
+		// together may list in either order.
+		var items []struct{ Symbol string }
+		body := rec.Body.Bytes()
+		if !strings.HasPrefix(string(body), "[") {
+			body = append(append([]byte("["), body...), ']')
+		}
+		if err := json.Unmarshal(body, &items); err != nil {
+			t.Fatalf("%s %s: body %s: %v", tt.method, tt.target, rec.Body, err)
+		}
+		var got []string
+		for _, item := range items {
+			got = append(got, item.Symbol)
+		}
+		want := slices.Clone(tt.wantSymbols)
+		slices.Sort(got)
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Errorf("%s %s as %s: symbols %v, want %v", tt.method, tt.target, tt.token.Name, got, want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package wsserver
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// metrics counts what the server has sent and dropped.
+type metrics struct {
+	connections     atomic.Int64
+	sent            atomic.Int64
+	dropped         atomic.Int64
+	slowDisconnects atomic.Int64
+}
+
+// Stats is a point-in-time copy of the server's metrics.
+type Stats struct {
+	Clients         int
+	Connections     int64
+	Sent            int64
+	Dropped         int64
+	SlowDisconnects int64
+}
+
+// Stats returns the server's current metrics.
+func (s *Server) Stats() Stats {
+	s.mu.Lock()
+	clients := len(s.clients)
+	s.mu.Unlock()
+	return Stats{
+		Clients:         clients,
+		Connections:     s.metrics.connections.Load(),
+		Sent:            s.metrics.sent.Load(),
+		Dropped:         s.metrics.dropped.Load(),
+		SlowDisconnects: s.metrics.slowDisconnects.Load(),
+	}
+}
+
+// handleMetrics serves the metrics in the Prometheus text format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	stats := s.Stats()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, m := range []struct {
+		name, kind, help string
+		value            int64
+	}{
+		{"wsserver_clients", "gauge", "Connected WebSocket clients.", int64(stats.Clients)},
+		{"wsserver_connections_total", "counter", "WebSocket connections accepted.", stats.Connections},
+		{"wsserver_messages_sent_total", "counter", "Messages written to clients.", stats.Sent},
+		{"wsserver_messages_dropped_total", "counter", "Messages dropped because a client's queue was full.", stats.Dropped},
+		{"wsserver_slow_consumer_disconnects_total", "counter", "Clients disconnected for falling behind.", stats.SlowDisconnects},
+	} {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", m.name, m.help, m.name, m.kind, m.name, m.value)
+	}
+}
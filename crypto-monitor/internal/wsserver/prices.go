@@ -0,0 +1,135 @@
+package wsserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"crypto-monitor/internal/feed"
+)
+
+// priceMessage is a price update on the price stream.
+type priceMessage struct {
+	Type string `json:"type"`
+	feed.PriceEvent
+}
+
+// priceKey identifies the updates a throttle keeps only the latest of.
+type priceKey struct {
+	symbol string
+	source string
+}
+
+// throttle holds a price client's updates between flushes, keeping the
+// latest per symbol and source, so the client gets at most one update for
+// each per interval however fast prices move.
+type throttle struct {
+	interval time.Duration
+	mu       sync.Mutex
+	pending  map[priceKey]feed.PriceEvent
+}
+
+// handlePrices upgrades a price stream connection. The symbols and
+// sources query parameters narrow the initial subscription, rate lowers
+// the updates per second for each symbol and source below the server's
+// maximum, and snapshot=true sends the cached prices straight away.
+func (s *Server) handlePrices(w http.ResponseWriter, r *http.Request) {
+	token, sub, ok := s.admit(w, r, streamPrices)
+	if !ok {
+		return
+	}
+	rate := s.options.MaxPriceRate
+	if value := r.URL.Query().Get("rate"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "rate must be a positive number", http.StatusBadRequest)
+			return
+		}
+		if parsed > rate {
+			http.Error(w, fmt.Sprintf("rate must not exceed %g", rate), http.StatusBadRequest)
+			return
+		}
+		rate = parsed
+	}
+
+	s.serve(w, r, token, sub, &throttle{
+		interval: time.Duration(float64(time.Second) / rate),
+		pending:  make(map[priceKey]feed.PriceEvent),
+	})
+}
+
+// BroadcastPrices hands each price event to the price stream clients
+// subscribed to it. It never blocks on clients, so it can sit on the
+// pricing pipeline.
+func (s *Server) BroadcastPrices(ctx context.Context, prices <-chan feed.PriceEvent) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-prices:
+			if !ok {
+				return
+			}
+			for _, c := range s.snapshotClients() {
+				if c.prices == nil {
+					continue
+				}
+				c.subMu.Lock()
+				wanted := c.sub.wantsPrice(event)
+				c.subMu.Unlock()
+				if !wanted {
+					continue
+				}
+				c.prices.mu.Lock()
+				c.prices.pending[priceKey{symbol: event.Symbol, source: event.Source}] = event
+				c.prices.mu.Unlock()
+			}
+		}
+	}
+}
+
+// flushPump queues a price client's pending updates once per interval.
+func (s *Server) flushPump(c *client) {
+	ticker := time.NewTicker(c.prices.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			for _, event := range c.prices.take() {
+				payload, err := json.Marshal(priceMessage{Type: MessagePrice, PriceEvent: event})
+				if err != nil {
+					continue
+				}
+				s.enqueue(c, payload)
+			}
+		}
+	}
+}
+
+// take empties the pending updates, returning them by symbol and source.
+func (t *throttle) take() []feed.PriceEvent {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[priceKey]feed.PriceEvent, len(pending))
+	t.mu.Unlock()
+
+	events := make([]feed.PriceEvent, 0, len(pending))
+	for _, event := range pending {
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Symbol != events[j].Symbol {
+			return events[i].Symbol < events[j].Symbol
+		}
+		return events[i].Source < events[j].Source
+	})
+	return events
+}
@@ -0,0 +1,125 @@
+package wsserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/feed"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestPriceRate(t *testing.T) {
+	_, url := newTestServer(t, Options{MaxPriceRate: 5})
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantError  string
+	}{
+		{query: "rate=2"},
+		{query: "rate=5"},
+		{query: "rate=0.5"},
+		{query: "rate=6", wantStatus: http.StatusBadRequest, wantError: "rate must not exceed 5"},
+		{query: "rate=0", wantStatus: http.StatusBadRequest, wantError: "rate must be a positive number"},
+		{query: "rate=fast", wantStatus: http.StatusBadRequest, wantError: "rate must be a positive number"},
+		{query: "types=spread", wantStatus: http.StatusBadRequest, wantError: "types apply to the alert stream"},
+	}
+	for _, tt := range tests {
+		conn, resp, err := websocket.DefaultDialer.Dial(url+"/ws/prices?"+tt.query, nil)
+		if tt.wantStatus == 0 {
+			if err != nil {
+				t.Errorf("%s: %v", tt.query, err)
+				continue
+			}
+			_ = conn.Close()
+			continue
+		}
+		if err == nil || resp == nil || resp.StatusCode != tt.wantStatus {
+			t.Errorf("%s: %v, %+v; want status %d", tt.query, err, resp, tt.wantStatus)
+			continue
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if !strings.Contains(string(body), tt.wantError) {
+			t.Errorf("%s: body %q, want %q", tt.query, body, tt.wantError)
+		}
+	}
+}
+
+func TestThrottleTake(t *testing.T) {
+	th := &throttle{pending: make(map[priceKey]feed.PriceEvent)}
+	for _, event := range []feed.PriceEvent{
+		{Symbol: "ETHUSDT", Source: "kraken", Price: 10},
+		{Symbol: "BTCUSDT", Source: "kraken", Price: 100},
+		{Symbol: "BTCUSDT", Source: "coinbase", Price: 101},
+		{Symbol: "BTCUSDT", Source: "kraken", Price: 102},
+	} {
+		th.pending[priceKey{symbol: event.Symbol, source: event.Source}] = event
+	}
+
+	var got []float64
+	for _, event := range th.take() {
+		got = append(got, event.Price)
+	}
+	// Sorted by symbol then source, keeping the latest of each.
+	if want := []float64{101, 102, 10}; !slices.Equal(got, want) {
+		t.Errorf("take = %v, want %v", got, want)
+	}
+	if events := th.take(); len(events) != 0 {
+		t.Errorf("second take = %+v", events)
+	}
+}
+
+func TestPriceStream(t *testing.T) {
+	s, url := newTestServer(t, Options{MaxPriceRate: 20})
+	conn := dial(t, url+"/ws/prices?symbols=BTCUSDT&sources=kraken&rate=10", nil)
+
+	// The ack also shows the client is registered.
+	send(t, conn, Request{ID: "1", Action: ActionSubscribe, Sources: []string{"coinbase"}})
+	ack := read(t, conn)
+	if ack.Type != ReplyAck || !slices.Equal(ack.Subscription.Sources, []string{"coinbase", "kraken"}) || ack.Subscription.Types != nil {
+		t.Fatalf("ack = %+v (subscription %+v)", ack, ack.Subscription)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	prices := make(chan feed.PriceEvent)
+	go s.BroadcastPrices(ctx, prices)
+
+	// Fifty updates for each source over half a second, at ten a second,
+	// come down to about five.
+	start := time.Now()
+	for i := 1; i <= 50; i++ {
+		prices <- feed.PriceEvent{Symbol: "BTCUSDT", Source: "kraken", Price: float64(i)}
+		prices <- feed.PriceEvent{Symbol: "BTCUSDT", Source: "coinbase", Price: float64(1000 + i)}
+		prices <- feed.PriceEvent{Symbol: "BTCUSDT", Source: "binance", Price: 1}
+		prices <- feed.PriceEvent{Symbol: "ETHUSDT", Source: "kraken", Price: 1}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Another flush may follow the last update.
+	limit := int(time.Since(start)/(100*time.Millisecond)) + 2
+
+	counts := make(map[string]int)
+	last := make(map[string]float64)
+	for last["kraken"] != 50 || last["coinbase"] != 1050 {
+		msg := read(t, conn)
+		if msg.Type != MessagePrice || msg.Symbol != "BTCUSDT" || (msg.Source != "kraken" && msg.Source != "coinbase") {
+			t.Fatalf("unexpected message %+v", msg)
+		}
+		if msg.Price <= last[msg.Source] {
+			t.Errorf("%s went from %v to %v", msg.Source, last[msg.Source], msg.Price)
+		}
+		counts[msg.Source]++
+		last[msg.Source] = msg.Price
+	}
+	for source, count := range counts {
+		if count > limit {
+			t.Errorf("%d updates from %s, want at most %d", count, source, limit)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package wsserver
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"crypto-monitor/internal/alerts"
+	"crypto-monitor/internal/cache"
+	"crypto-monitor/internal/feed"
+)
+
+// Client actions.
+const (
+	ActionSubscribe   = "subscribe"
+	ActionUnsubscribe = "unsubscribe"
+	ActionSnapshot    = "snapshot"
+)
+
+// Reply types. Alerts are sent as they are; their types never clash with
+// these. Price updates have type MessagePrice.
+const (
+	ReplyAck      = "ack"
+	ReplyError    = "error"
+	ReplySnapshot = "snapshot"
+	ReplyReplay   = "replay"
+	MessagePrice  = "price"
+)
+
+// Stream kinds: /ws carries alerts, /ws/prices carries price updates.
+const (
+	streamAlerts = iota
+	streamPrices
+)
+
+// Wildcard subscribes to, or unsubscribes from, every symbol or type.
+const Wildcard = "*"
+
+// errNotPermitted is returned for symbols a client's token does not grant.
+var errNotPermitted = errors.New("symbol not permitted")
+
+// Request is a control message from a client. ID is echoed in the reply.
+// Types filter the alert stream and Sources the price stream.
+type Request struct {
+	ID      string   `json:"id,omitempty"`
+	Action  string   `json:"action"`
+	Symbols []string `json:"symbols,omitempty"`
+	Types   []string `json:"types,omitempty"`
+	Sources []string `json:"sources,omitempty"`
+}
+
+// Reply answers a Request.
+type Reply struct {
+	Type         string        `json:"type"`
+	ID           string        `json:"id,omitempty"`
+	Action       string        `json:"action,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	Subscription *Subscription `json:"subscription,omitempty"`
+}
+
+// SnapshotReply answers a snapshot request with the part of the snapshot
+// the client is subscribed to.
+type SnapshotReply struct {
+	Type   string           `json:"type"`
+	ID     string           `json:"id,omitempty"`
+	Action string           `json:"action"`
+	Prices []cache.Snapshot `json:"prices"`
+	Alerts []alerts.Alert   `json:"alerts"`
+}
+
+// ReplayReply follows the alerts replayed to a client that connected with
+// last_id. Truncated is set when more were missed than the replay limit,
+// or when some of those missed have been trimmed from the history.
+type ReplayReply struct {
+	Type      string `json:"type"`
+	LastID    string `json:"last_id"`
+	Count     int    `json:"count"`
+	Truncated bool   `json:"truncated"`
+}
+
+// Snapshot is the current state sent in reply to a snapshot request:
+// the latest prices and the latest alert of each open alert group.
+type Snapshot struct {
+	Prices []cache.Snapshot
+	Alerts []alerts.Alert
+}
+
+// Subscription describes the symbols and alert types, or price sources, a
+// client receives. A list holding Wildcard means everything except the
+// matching Except list, within PermittedSymbols when the client's token
+// limits them.
+type Subscription struct {
+	PermittedSymbols []string `json:"permitted_symbols,omitempty"`
+	Symbols          []string `json:"symbols"`
+	ExceptSymbols    []string `json:"except_symbols,omitempty"`
+	Types            []string `json:"types,omitempty"`
+	ExceptTypes      []string `json:"except_types,omitempty"`
+	Sources          []string `json:"sources,omitempty"`
+	ExceptSources    []string `json:"except_sources,omitempty"`
+}
+
+// filter matches the values of one dimension of a subscription.
+type filter struct {
+	all     bool
+	include map[string]bool
+	exclude map[string]bool
+}
+
+func newFilter(values []string) filter {
+	f := filter{include: make(map[string]bool), exclude: make(map[string]bool)}
+	if len(values) == 0 {
+		f.all = true
+	}
+	f.subscribe(values)
+	return f
+}
+
+func (f *filter) matches(value string) bool {
+	if f.all {
+		return !f.exclude[value]
+	}
+	return f.include[value]
+}
+
+func (f *filter) subscribe(values []string) {
+	for _, value := range values {
+		switch {
+		case value == Wildcard:
+			*f = filter{all: true, include: make(map[string]bool), exclude: make(map[string]bool)}
+		case f.all:
+			delete(f.exclude, value)
+		default:
+			f.include[value] = true
+		}
+	}
+}
+
+func (f *filter) unsubscribe(values []string) {
+	for _, value := range values {
+		switch {
+		case value == Wildcard:
+			*f = filter{include: make(map[string]bool), exclude: make(map[string]bool)}
+		case f.all:
+			f.exclude[value] = true
+		default:
+			delete(f.include, value)
+		}
+	}
+}
+
+// view returns the filter's values and exceptions for a Subscription.
+func (f *filter) view() (values, except []string) {
+	if f.all {
+		return []string{Wildcard}, sortedKeys(f.exclude)
+	}
+	return sortedKeys(f.include), nil
+}
+
+// subscription is a client's symbol filter and its alert type or price
+// source filter, depending on its stream. permitted holds the symbols the
+// client's token grants.
+type subscription struct {
+	stream    int
+	permitted filter
+	symbols   filter
+	types     filter
+	sources   filter
+}
+
+// newSubscription starts a client on the symbols, and types or sources,
+// named in the connection URL's comma-separated parameters, or on
+// everything, within the permitted symbols; nil permits every symbol.
+func newSubscription(stream int, query url.Values, permitted []string) (subscription, error) {
+	req := Request{
+		Symbols: splitList(query.Get("symbols")),
+		Types:   splitList(query.Get("types")),
+		Sources: splitList(query.Get("sources")),
+	}
+	symbols, types, sources, err := parseRequest(stream, req)
+	if err != nil {
+		return subscription{}, err
+	}
+	sub := subscription{
+		stream:    stream,
+		permitted: newFilter(permitted),
+		symbols:   newFilter(symbols),
+		types:     newFilter(types),
+		sources:   newFilter(sources),
+	}
+	if err := sub.checkPermitted(symbols); err != nil {
+		return subscription{}, err
+	}
+	return sub, nil
+}
+
+// apply updates the subscription for a subscribe or unsubscribe request.
+func (s *subscription) apply(req Request) error {
+	symbols, types, sources, err := parseRequest(s.stream, req)
+	if err != nil {
+		return err
+	}
+	if len(symbols) == 0 && len(types) == 0 && len(sources) == 0 {
+		if s.stream == streamPrices {
+			return errors.New("symbols or sources required")
+		}
+		return errors.New("symbols or types required")
+	}
+	if req.Action == ActionSubscribe {
+		if err := s.checkPermitted(symbols); err != nil {
+			return err
+		}
+		s.symbols.subscribe(symbols)
+		s.types.subscribe(types)
+		s.sources.subscribe(sources)
+	} else {
+		s.symbols.unsubscribe(symbols)
+		s.types.unsubscribe(types)
+		s.sources.unsubscribe(sources)
+	}
+	return nil
+}
+
+// checkPermitted rejects symbols outside the permitted ones. Wildcard is
+// always allowed, as it only reaches the permitted symbols.
+func (s *subscription) checkPermitted(symbols []string) error {
+	for _, symbol := range symbols {
+		if symbol != Wildcard && !s.permitted.matches(symbol) {
+			return fmt.Errorf("%w: %s", errNotPermitted, symbol)
+		}
+	}
+	return nil
+}
+
+func (s *subscription) wantsSymbol(symbol string) bool {
+	return s.permitted.matches(symbol) && s.symbols.matches(symbol)
+}
+
+func (s *subscription) wants(alert alerts.Alert) bool {
+	return s.wantsSymbol(alert.Symbol) && s.types.matches(alert.Type)
+}
+
+func (s *subscription) wantsPrice(event feed.PriceEvent) bool {
+	return s.wantsSymbol(event.Symbol) && s.sources.matches(event.Source)
+}
+
+func (s *subscription) view() *Subscription {
+	var view Subscription
+	if !s.permitted.all {
+		view.PermittedSymbols, _ = s.permitted.view()
+	}
+	view.Symbols, view.ExceptSymbols = s.symbols.view()
+	if s.stream == streamPrices {
+		view.Sources, view.ExceptSources = s.sources.view()
+	} else {
+		view.Types, view.ExceptTypes = s.types.view()
+	}
+	return &view
+}
+
+// filterSnapshot keeps the parts of a snapshot the subscription wants.
+func (s *subscription) filterSnapshot(snapshot Snapshot) SnapshotReply {
+	reply := SnapshotReply{Type: ReplySnapshot, Action: ActionSnapshot, Prices: []cache.Snapshot{}, Alerts: []alerts.Alert{}}
+	for _, price := range snapshot.Prices {
+		if s.wantsSymbol(price.Symbol) {
+			reply.Prices = append(reply.Prices, price)
+		}
+	}
+	for _, alert := range snapshot.Alerts {
+		if s.stream == streamAlerts && s.wants(alert) {
+			reply.Alerts = append(reply.Alerts, alert)
+		}
+	}
+	return reply
+}
+
+// parseRequest checks a request's symbols and, for its stream, types or
+// sources.
+func parseRequest(stream int, req Request) (symbols, types, sources []string, err error) {
+	if symbols, err = parseSymbols(req.Symbols); err != nil {
+		return nil, nil, nil, err
+	}
+	if stream == streamPrices {
+		if len(req.Types) > 0 {
+			return nil, nil, nil, errors.New("types apply to the alert stream")
+		}
+		sources, err = parseSources(req.Sources)
+		return symbols, nil, sources, err
+	}
+	if len(req.Sources) > 0 {
+		return nil, nil, nil, errors.New("sources apply to the price stream")
+	}
+	types, err = parseTypes(req.Types)
+	return symbols, types, nil, err
+}
+
+func parseSymbols(values []string) ([]string, error) {
+	symbols := make([]string, 0, len(values))
+	for _, value := range values {
+		symbol := strings.ToUpper(strings.TrimSpace(value))
+		if symbol == "" {
+			return nil, errors.New("symbols must not be empty")
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols, nil
+}
+
+func parseTypes(values []string) ([]string, error) {
+	types := make([]string, 0, len(values))
+	for _, value := range values {
+		alertType := strings.ToLower(strings.TrimSpace(value))
+		if alertType != Wildcard && !slices.Contains(alerts.Types, alertType) {
+			return nil, fmt.Errorf("unknown alert type %q", value)
+		}
+		types = append(types, alertType)
+	}
+	return types, nil
+}
+
+func parseSources(values []string) ([]string, error) {
+	sources := make([]string, 0, len(values))
+	for _, value := range values {
+		source := strings.ToLower(strings.TrimSpace(value))
+		if source == "" {
+			return nil, errors.New("sources must not be empty")
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+func splitList(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -3,44 +3,155 @@ package wsserver
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"sync"
 	"time"
 
 	"crypto-monitor/internal/alerts"
+	"crypto-monitor/internal/history"
 
 	"github.com/gorilla/websocket"
 )
 
-// Server accepts client connections for alerts.
+// SnapshotFunc returns the current state for snapshot requests.
+type SnapshotFunc func(ctx context.Context) (Snapshot, error)
+
+// Slow consumer policies, applied when a client's queue is full.
+const (
+	// PolicyDropOldest discards the client's oldest queued message.
+	PolicyDropOldest = "drop_oldest"
+	// PolicyDisconnect closes the client's connection.
+	PolicyDisconnect = "disconnect"
+)
+
+// Options tunes how the server writes to clients. Zero values take the
+// defaults.
+type Options struct {
+	// QueueSize is how many messages may wait to be written to a client.
+	QueueSize int
+	// SlowConsumerPolicy is PolicyDropOldest or PolicyDisconnect.
+	SlowConsumerPolicy string
+	// PingInterval is how often clients are pinged. A client that has
+	// sent nothing, not even a pong, for two intervals is disconnected.
+	PingInterval time.Duration
+	// WriteTimeout bounds each write to a client.
+	WriteTimeout time.Duration
+	// MaxPriceRate caps the price updates per second a price stream
+	// client receives for each symbol and source.
+	MaxPriceRate float64
+	// Tokens, when set, are required to connect and limit what each
+	// client may receive and how many connections it may hold.
+	Tokens []Token
+	// AllowedOrigins are the browser origins allowed to connect, or
+	// Wildcard for any. Without them only same-origin browsers may.
+	AllowedOrigins []string
+	// ReplayLimit caps the missed alerts replayed to a reconnecting client.
+	ReplayLimit int
+}
+
+func (o Options) withDefaults() Options {
+	if o.QueueSize <= 0 {
+		o.QueueSize = 64
+	}
+	if o.SlowConsumerPolicy == "" {
+		o.SlowConsumerPolicy = PolicyDropOldest
+	}
+	if o.PingInterval <= 0 {
+		o.PingInterval = 30 * time.Second
+	}
+	if o.WriteTimeout <= 0 {
+		o.WriteTimeout = 5 * time.Second
+	}
+	if o.MaxPriceRate <= 0 {
+		o.MaxPriceRate = 5
+	}
+	if o.ReplayLimit <= 0 {
+		o.ReplayLimit = 500
+	}
+	return o
+}
+
+// maxRequestSize caps a client's control messages.
+const maxRequestSize = 4096
+
+// Server accepts client connections for alerts. Each client has its own
+// queue and writer, so a slow client only holds up itself.
 type Server struct {
 	addr     string
+	options  Options
+	mux      *http.ServeMux
 	upgrader websocket.Upgrader
+	snapshot SnapshotFunc
+	history  history.Store
+	metrics  metrics
 	mu       sync.Mutex
-	clients  map[*websocket.Conn]struct{}
+	clients  map[*client]struct{}
+	// tokenConns counts open connections by token name.
+	tokenConns map[string]int
+}
+
+// client is a connection, its write queue and what it has subscribed to.
+// Price stream clients also hold the updates waiting for their next flush.
+type client struct {
+	token  *Token
+	prices *throttle
+	conn   *websocket.Conn
+	send   chan []byte
+	// done is closed when the client is closed; closeOnce guards it.
+	done      chan struct{}
+	closeOnce sync.Once
+	// subMu guards sub, which the read pump updates while the
+	// broadcaster reads it, and the alerts held during a replay.
+	subMu sync.Mutex
+	sub   subscription
+	// holding is set while missed alerts are replayed; live alerts wait
+	// in held until the replay is over.
+	holding bool
+	held    []alerts.Alert
 }
 
 // NewServer builds an alert WebSocket server.
-func NewServer(addr string) *Server {
-	return &Server{
-		addr: addr,
-		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true
-			},
+func NewServer(addr string, options Options) *Server {
+	s := &Server{
+		addr:    addr,
+		options: options.withDefaults(),
+		mux:     http.NewServeMux(),
+		snapshot: func(context.Context) (Snapshot, error) {
+			return Snapshot{}, nil
 		},
-		clients: make(map[*websocket.Conn]struct{}),
+		clients:    make(map[*client]struct{}),
+		tokenConns: make(map[string]int),
 	}
+	s.upgrader.CheckOrigin = s.checkOrigin
+	s.mux.HandleFunc("/ws", s.handleWS)
+	s.mux.HandleFunc("/ws/prices", s.handlePrices)
+	s.mux.HandleFunc("GET /metrics", s.requireToken(s.handleMetrics))
+	return s
+}
+
+// SetSnapshot sets the source of snapshots. Call it before Run.
+func (s *Server) SetSnapshot(snapshot SnapshotFunc) {
+	s.snapshot = snapshot
+}
+
+// SetHistory sets the alert history that reconnecting clients are
+// replayed from. Call it before Run.
+func (s *Server) SetHistory(store history.Store) {
+	s.history = store
+}
+
+// HandleFunc registers an HTTP handler next to the WebSocket endpoint.
+// Like the streams, it needs a token when tokens are configured.
+func (s *Server) HandleFunc(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, s.requireToken(handler))
 }
 
 // Run starts the HTTP server and blocks until shutdown.
 func (s *Server) Run(ctx context.Context) error {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/ws", s.handleWS)
-
 	srv := &http.Server{
 		Addr:    s.addr,
-		Handler: mux,
+		Handler: s.mux,
 	}
 
 	go func() {
@@ -57,7 +168,7 @@ func (s *Server) Run(ctx context.Context) error {
 	return nil
 }
 
-// Broadcast sends alerts to all connected clients.
+// Broadcast sends each alert to the connected clients subscribed to it.
 func (s *Server) Broadcast(ctx context.Context, alertStream <-chan alerts.Alert) {
 	for {
 		select {
@@ -71,55 +182,306 @@ func (s *Server) Broadcast(ctx context.Context, alertStream <-chan alerts.Alert)
 			if err != nil {
 				continue
 			}
-			s.broadcast(payload)
+			s.broadcast(alert, payload)
 		}
 	}
 }
 
+// handleWS upgrades an alert stream connection. The symbols and types
+// query parameters narrow the initial subscription, snapshot=true sends
+// a snapshot straight away, and last_id replays the alerts recorded since
+// the one with that ID.
 func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("last_id") && s.history == nil {
+		http.Error(w, "alert history is not enabled", http.StatusBadRequest)
+		return
+	}
+	token, sub, ok := s.admit(w, r, streamAlerts)
+	if !ok {
+		return
+	}
+	s.serve(w, r, token, sub, nil)
+}
+
+// admit authenticates a connection request and builds its initial
+// subscription, answering with an error when either fails.
+func (s *Server) admit(w http.ResponseWriter, r *http.Request, stream int) (*Token, subscription, bool) {
+	token, err := s.authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return nil, subscription{}, false
+	}
+	sub, err := newSubscription(stream, r.URL.Query(), token.permitted())
+	if errors.Is(err, errNotPermitted) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return nil, subscription{}, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, subscription{}, false
+	}
+	return token, sub, true
+}
+
+// serve upgrades a connection within its token's connection limit and
+// starts its pumps.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, token *Token, sub subscription, prices *throttle) {
+	if !s.acquire(token) {
+		http.Error(w, "too many connections for this token", http.StatusTooManyRequests)
+		return
+	}
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
+		s.release(token)
 		return
 	}
 
-	s.addClient(conn)
-	go s.readPump(conn)
+	c := &client{
+		token:  token,
+		prices: prices,
+		conn:   conn,
+		send:   make(chan []byte, s.options.QueueSize),
+		done:   make(chan struct{}),
+		sub:    sub,
+	}
+	lastID := r.URL.Query().Get("last_id")
+	c.holding = prices == nil && lastID != ""
+	s.addClient(c)
+	go s.writePump(c)
+	if prices != nil {
+		go s.flushPump(c)
+	}
+	if r.URL.Query().Get("snapshot") == "true" {
+		s.handleRequest(r.Context(), c, Request{Action: ActionSnapshot})
+	}
+	if c.holding {
+		s.replay(r.Context(), c, lastID)
+	}
+	go s.readPump(c)
+}
+
+// replay sends a client the alerts it is subscribed to that were recorded
+// after lastID, up to the replay limit, then a replay reply, then the live
+// alerts held back meanwhile that the replay did not include.
+func (s *Server) replay(ctx context.Context, c *client, lastID string) {
+	missed, trimmed, err := s.history.After(ctx, lastID, s.options.ReplayLimit+1)
+	reply := ReplayReply{Type: ReplyReplay, LastID: lastID, Truncated: trimmed}
+	replayed := make(map[string]bool, len(missed))
+	if err == nil {
+		if len(missed) > s.options.ReplayLimit {
+			missed, reply.Truncated = missed[:s.options.ReplayLimit], true
+		}
+		for _, alert := range missed {
+			replayed[alert.ID] = true
+			c.subMu.Lock()
+			wanted := c.sub.wants(alert)
+			c.subMu.Unlock()
+			if !wanted {
+				continue
+			}
+			payload, err := json.Marshal(alert)
+			if err != nil {
+				continue
+			}
+			// Wait for room rather than drop: the client asked for these.
+			select {
+			case <-c.done:
+				return
+			case c.send <- payload:
+				reply.Count++
+			}
+		}
+	}
+
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	switch {
+	case errors.Is(err, history.ErrInvalidID):
+		s.reply(c, Reply{Type: ReplyError, Error: "invalid last_id"})
+	case err != nil:
+		s.reply(c, Reply{Type: ReplyError, Error: "replay unavailable"})
+	default:
+		s.reply(c, reply)
+	}
+	for _, alert := range c.held {
+		if replayed[alert.ID] {
+			continue
+		}
+		if payload, err := json.Marshal(alert); err == nil {
+			s.enqueue(c, payload)
+		}
+	}
+	c.held, c.holding = nil, false
 }
 
-func (s *Server) readPump(conn *websocket.Conn) {
+// readPump handles a client's control messages until it disconnects.
+// Every message, pongs included, extends the read deadline.
+func (s *Server) readPump(c *client) {
+	defer s.closeClient(c)
+
+	pongWait := 2 * s.options.PingInterval
+	c.conn.SetReadLimit(maxRequestSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		var req Request
+		if err := json.Unmarshal(data, &req); err != nil {
+			s.reply(c, Reply{Type: ReplyError, Error: "invalid request: " + err.Error()})
+			continue
+		}
+		s.handleRequest(context.Background(), c, req)
+	}
+}
+
+// writePump writes a client's queued messages and pings it until the
+// client is closed or a write fails.
+func (s *Server) writePump(c *client) {
+	ping := time.NewTicker(s.options.PingInterval)
 	defer func() {
-		s.removeClient(conn)
-		_ = conn.Close()
+		ping.Stop()
+		s.closeClient(c)
 	}()
 
 	for {
-		if _, _, err := conn.ReadMessage(); err != nil {
+		select {
+		case <-c.done:
 			return
+		case payload := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(s.options.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+			s.metrics.sent.Add(1)
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(s.options.WriteTimeout)); err != nil {
+				return
+			}
 		}
 	}
 }
 
-func (s *Server) broadcast(payload []byte) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// handleRequest applies a control message and replies to it.
+func (s *Server) handleRequest(ctx context.Context, c *client, req Request) {
+	switch req.Action {
+	case ActionSubscribe, ActionUnsubscribe:
+		c.subMu.Lock()
+		err := c.sub.apply(req)
+		view := c.sub.view()
+		c.subMu.Unlock()
+		if err != nil {
+			s.reply(c, Reply{Type: ReplyError, ID: req.ID, Action: req.Action, Error: err.Error()})
+			return
+		}
+		s.reply(c, Reply{Type: ReplyAck, ID: req.ID, Action: req.Action, Subscription: view})
+
+	case ActionSnapshot:
+		snapshot, err := s.snapshot(ctx)
+		if err != nil {
+			s.reply(c, Reply{Type: ReplyError, ID: req.ID, Action: req.Action, Error: "snapshot unavailable"})
+			return
+		}
+		c.subMu.Lock()
+		reply := c.sub.filterSnapshot(snapshot)
+		c.subMu.Unlock()
+		reply.ID = req.ID
+		s.reply(c, reply)
 
-	for conn := range s.clients {
-		_ = conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-		if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
-			_ = conn.Close()
-			delete(s.clients, conn)
+	default:
+		s.reply(c, Reply{Type: ReplyError, ID: req.ID, Action: req.Action, Error: "unknown action"})
+	}
+}
+
+// reply queues a reply for a client.
+func (s *Server) reply(c *client, reply any) {
+	payload, err := json.Marshal(reply)
+	if err != nil {
+		return
+	}
+	s.enqueue(c, payload)
+}
+
+func (s *Server) broadcast(alert alerts.Alert, payload []byte) {
+	for _, c := range s.snapshotClients() {
+		if c.prices != nil {
+			continue
+		}
+		c.subMu.Lock()
+		wanted := c.sub.wants(alert)
+		if wanted && c.holding {
+			c.held = append(c.held, alert)
+			if len(c.held) > s.options.QueueSize {
+				c.held = c.held[1:]
+				s.metrics.dropped.Add(1)
+			}
+			wanted = false
+		}
+		c.subMu.Unlock()
+		if wanted {
+			s.enqueue(c, payload)
 		}
 	}
 }
 
-func (s *Server) addClient(conn *websocket.Conn) {
+// snapshotClients returns the connected clients.
+func (s *Server) snapshotClients() []*client {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.clients[conn] = struct{}{}
+	clients := make([]*client, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	return clients
 }
 
-func (s *Server) removeClient(conn *websocket.Conn) {
+// enqueue adds a message to a client's queue without blocking, applying
+// the slow consumer policy when the queue is full.
+func (s *Server) enqueue(c *client, payload []byte) {
+	for {
+		select {
+		case <-c.done:
+			return
+		case c.send <- payload:
+			return
+		default:
+		}
+
+		if s.options.SlowConsumerPolicy == PolicyDisconnect {
+			s.metrics.dropped.Add(1)
+			s.metrics.slowDisconnects.Add(1)
+			s.closeClient(c)
+			return
+		}
+		select {
+		case <-c.send:
+			s.metrics.dropped.Add(1)
+		default:
+		}
+	}
+}
+
+func (s *Server) addClient(c *client) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	delete(s.clients, conn)
+	s.clients[c] = struct{}{}
+	s.metrics.connections.Add(1)
+}
+
+// closeClient removes a client and closes its connection, once.
+func (s *Server) closeClient(c *client) {
+	c.closeOnce.Do(func() {
+		s.mu.Lock()
+		delete(s.clients, c)
+		s.mu.Unlock()
+		close(c.done)
+		_ = c.conn.Close()
+		s.release(c.token)
+	})
 }
@@ -0,0 +1,465 @@
+package wsserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/alerts"
+	"crypto-monitor/internal/cache"
+	"crypto-monitor/internal/history"
+
+	"github.com/gorilla/websocket"
+)
+
+// message holds any message the server sends: replies, alerts and price
+// updates.
+type message struct {
+	Type         string           `json:"type"`
+	ID           string           `json:"id"`
+	Action       string           `json:"action"`
+	Error        string           `json:"error"`
+	Subscription *Subscription    `json:"subscription"`
+	Prices       []cache.Snapshot `json:"prices"`
+	Alerts       []alerts.Alert   `json:"alerts"`
+	LastID       string           `json:"last_id"`
+	Count        int              `json:"count"`
+	Truncated    bool             `json:"truncated"`
+	Symbol       string           `json:"symbol"`
+	Source       string           `json:"source"`
+	Price        float64          `json:"price"`
+}
+
+// newTestServer serves s over HTTP, returning it and its WebSocket URL.
+func newTestServer(t *testing.T, options Options) (*Server, string) {
+	t.Helper()
+	s := NewServer("", options)
+	ts := httptest.NewServer(s.mux)
+	t.Cleanup(ts.Close)
+	return s, "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+// dial connects to url, failing the test if the server refuses.
+func dial(t *testing.T, url string, header http.Header) *websocket.Conn {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		t.Fatalf("dial %s: %v (status %d)", url, err, status)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// send writes a control message.
+func send(t *testing.T, conn *websocket.Conn, req any) {
+	t.Helper()
+	if err := conn.WriteJSON(req); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+// read returns the next message, failing the test if none comes.
+func read(t *testing.T, conn *websocket.Conn) message {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg message
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return msg
+}
+
+// broadcast sends one alert as Broadcast does.
+func broadcast(t *testing.T, s *Server, alert alerts.Alert) {
+	t.Helper()
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.broadcast(alert, payload)
+}
+
+func TestSubscriptions(t *testing.T) {
+	s, url := newTestServer(t, Options{})
+	s.SetSnapshot(func(context.Context) (Snapshot, error) {
+		return Snapshot{
+			Prices: []cache.Snapshot{{Symbol: "BTCUSDT", Price: 100}, {Symbol: "ETHUSDT", Price: 10}},
+			Alerts: []alerts.Alert{
+				{ID: "s1", Type: alerts.TypePriceChange, Symbol: "BTCUSDT"},
+				{ID: "s2", Type: alerts.TypeSpread, Symbol: "BTCUSDT"},
+				{ID: "s3", Type: alerts.TypePriceChange, Symbol: "ETHUSDT"},
+			},
+		}, nil
+	})
+	conn := dial(t, url+"/ws?symbols=btcusdt&types=price_change&snapshot=true", nil)
+
+	// The snapshot is sent once the client is registered, so broadcasts
+	// from here on reach it.
+	snapshot := read(t, conn)
+	if snapshot.Type != ReplySnapshot || len(snapshot.Prices) != 1 || snapshot.Prices[0].Symbol != "BTCUSDT" ||
+		len(snapshot.Alerts) != 1 || snapshot.Alerts[0].ID != "s1" {
+		t.Fatalf("snapshot = %+v", snapshot)
+	}
+
+	broadcast(t, s, alerts.Alert{ID: "a1", Type: alerts.TypePriceChange, Symbol: "BTCUSDT"})
+	broadcast(t, s, alerts.Alert{ID: "a2", Type: alerts.TypeSpread, Symbol: "BTCUSDT"})
+	broadcast(t, s, alerts.Alert{ID: "a3", Type: alerts.TypePriceChange, Symbol: "ETHUSDT"})
+	send(t, conn, Request{ID: "1", Action: ActionSubscribe, Symbols: []string{"ethusdt"}, Types: []string{"spread"}})
+	if msg := read(t, conn); msg.ID != "a1" {
+		t.Fatalf("got %+v, want alert a1", msg)
+	}
+	ack := read(t, conn)
+	if ack.Type != ReplyAck || ack.ID != "1" || ack.Action != ActionSubscribe ||
+		!slices.Equal(ack.Subscription.Symbols, []string{"BTCUSDT", "ETHUSDT"}) ||
+		!slices.Equal(ack.Subscription.Types, []string{"price_change", "spread"}) {
+		t.Fatalf("ack = %+v (subscription %+v)", ack, ack.Subscription)
+	}
+
+	broadcast(t, s, alerts.Alert{ID: "a4", Type: alerts.TypeSpread, Symbol: "ETHUSDT"})
+	broadcast(t, s, alerts.Alert{ID: "a5", Type: alerts.TypeSpread, Symbol: "SOLUSDT"})
+	send(t, conn, Request{ID: "2", Action: ActionSubscribe, Symbols: []string{Wildcard}})
+	if msg := read(t, conn); msg.ID != "a4" {
+		t.Fatalf("got %+v, want alert a4", msg)
+	}
+	if ack := read(t, conn); ack.ID != "2" || !slices.Equal(ack.Subscription.Symbols, []string{Wildcard}) {
+		t.Fatalf("ack = %+v", ack)
+	}
+
+	// Unsubscribing under a wildcard leaves an exception.
+	send(t, conn, Request{ID: "3", Action: ActionUnsubscribe, Symbols: []string{"BTCUSDT"}})
+	ack = read(t, conn)
+	if ack.ID != "3" || !slices.Equal(ack.Subscription.Symbols, []string{Wildcard}) || !slices.Equal(ack.Subscription.ExceptSymbols, []string{"BTCUSDT"}) {
+		t.Fatalf("ack = %+v (subscription %+v)", ack, ack.Subscription)
+	}
+	broadcast(t, s, alerts.Alert{ID: "a6", Type: alerts.TypeSpread, Symbol: "BTCUSDT"})
+	broadcast(t, s, alerts.Alert{ID: "a7", Type: alerts.TypeSpread, Symbol: "SOLUSDT"})
+	if msg := read(t, conn); msg.ID != "a7" {
+		t.Fatalf("got %+v, want alert a7", msg)
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	s, url := newTestServer(t, Options{})
+	s.SetSnapshot(func(context.Context) (Snapshot, error) {
+		return Snapshot{}, errors.New("cache down")
+	})
+	conn := dial(t, url+"/ws", nil)
+
+	tests := []struct {
+		request string
+		want    string
+	}{
+		{request: `not json`, want: "invalid request"},
+		{request: `{"id":"1","action":"dance"}`, want: "unknown action"},
+		{request: `{"id":"1","action":"subscribe"}`, want: "symbols or types required"},
+		{request: `{"id":"1","action":"subscribe","types":["moon"]}`, want: `unknown alert type "moon"`},
+		{request: `{"id":"1","action":"subscribe","symbols":[" "]}`, want: "symbols must not be empty"},
+		{request: `{"id":"1","action":"unsubscribe","sources":["kraken"]}`, want: "sources apply to the price stream"},
+		{request: `{"id":"1","action":"snapshot"}`, want: "snapshot unavailable"},
+	}
+	for _, tt := range tests {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(tt.request)); err != nil {
+			t.Fatal(err)
+		}
+		reply := read(t, conn)
+		if reply.Type != ReplyError || !strings.Contains(reply.Error, tt.want) {
+			t.Errorf("%s: reply %+v, want an error containing %q", tt.request, reply, tt.want)
+		}
+		if strings.HasPrefix(tt.request, "{") && reply.ID != "1" {
+			t.Errorf("%s: reply ID %q, want the request's", tt.request, reply.ID)
+		}
+	}
+
+	// The same checks refuse a connection's initial subscription.
+	for _, query := range []string{"types=moon", "symbols=,", "sources=kraken"} {
+		_, resp, err := websocket.DefaultDialer.Dial(url+"/ws?"+query, nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("connecting with %s: %v, %+v; want 400", query, err, resp)
+		}
+	}
+}
+
+// recordAlerts opens an alert history keeping maxAlerts and records an
+// alert for each symbol in it.
+func recordAlerts(t *testing.T, maxAlerts int64, symbols ...string) (*history.SQLiteStore, []alerts.Alert) {
+	t.Helper()
+	store, err := history.OpenSQLite(filepath.Join(t.TempDir(), "history.db"), maxAlerts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	recorded := make([]alerts.Alert, len(symbols))
+	for i, symbol := range symbols {
+		if recorded[i], err = store.Append(context.Background(), alerts.Alert{Type: alerts.TypeSpread, Symbol: symbol}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store, recorded
+}
+
+func TestReplay(t *testing.T) {
+	symbols := []string{"BTCUSDT", "ETHUSDT", "BTCUSDT", "BTCUSDT", "ETHUSDT", "BTCUSDT"}
+
+	tests := []struct {
+		name      string
+		maxAlerts int64
+		limit     int
+		// after is the index of the last alert the client saw.
+		after int
+		// want are the indexes of the alerts replayed.
+		want          []int
+		wantTruncated bool
+	}{
+		{name: "subscribed alerts since last_id", after: 1, want: []int{2, 3, 5}},
+		{name: "nothing missed", after: 5},
+		{name: "over the replay limit", limit: 3, after: 0, want: []int{2, 3}, wantTruncated: true},
+		{name: "missed alerts trimmed", maxAlerts: 3, after: 1, want: []int{3, 5}, wantTruncated: true},
+		{name: "last_id itself trimmed", maxAlerts: 3, after: 2, want: []int{3, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, recorded := recordAlerts(t, tt.maxAlerts, symbols...)
+			s, url := newTestServer(t, Options{ReplayLimit: tt.limit})
+			s.SetHistory(store)
+			lastID := recorded[tt.after].ID
+			conn := dial(t, url+"/ws?symbols=BTCUSDT&last_id="+lastID, nil)
+
+			var got []string
+			msg := read(t, conn)
+			for ; msg.Type == alerts.TypeSpread; msg = read(t, conn) {
+				got = append(got, msg.ID)
+			}
+			want := make([]string, 0, len(tt.want))
+			for _, i := range tt.want {
+				want = append(want, recorded[i].ID)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("replayed %v, want %v", got, want)
+			}
+			if msg.Type != ReplyReplay || msg.LastID != lastID || msg.Count != len(want) || msg.Truncated != tt.wantTruncated {
+				t.Errorf("reply = %+v", msg)
+			}
+		})
+	}
+
+	t.Run("invalid last_id", func(t *testing.T) {
+		store, _ := recordAlerts(t, 0)
+		s, url := newTestServer(t, Options{})
+		s.SetHistory(store)
+		conn := dial(t, url+"/ws?last_id=latest", nil)
+		if reply := read(t, conn); reply.Type != ReplyError || reply.Error != "invalid last_id" {
+			t.Fatalf("reply = %+v", reply)
+		}
+	})
+
+	t.Run("without history", func(t *testing.T) {
+		_, url := newTestServer(t, Options{})
+		_, resp, err := websocket.DefaultDialer.Dial(url+"/ws?last_id=1", nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("dial: %v, %+v; want 400", err, resp)
+		}
+	})
+}
+
+// blockingStore holds After calls until released.
+type blockingStore struct {
+	history.Store
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) After(ctx context.Context, id string, limit int) ([]alerts.Alert, bool, error) {
+	close(s.entered)
+	<-s.release
+	return s.Store.After(ctx, id, limit)
+}
+
+func TestReplayHoldsLiveAlerts(t *testing.T) {
+	store, recorded := recordAlerts(t, 0, "BTCUSDT", "BTCUSDT", "BTCUSDT")
+	blocking := &blockingStore{Store: store, entered: make(chan struct{}), release: make(chan struct{})}
+	s, url := newTestServer(t, Options{})
+	s.SetHistory(blocking)
+
+	connected := make(chan *websocket.Conn, 1)
+	go func() {
+		conn, _, err := websocket.DefaultDialer.Dial(url+"/ws?symbols=BTCUSDT&last_id="+recorded[0].ID, nil)
+		if err != nil {
+			t.Errorf("dial: %v", err)
+		}
+		connected <- conn
+	}()
+	select {
+	case <-blocking.entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("replay did not start")
+	}
+
+	// Alerts broadcast during the replay: one the replay also finds, an
+	// unsubscribed one and a new one.
+	broadcast(t, s, recorded[2])
+	broadcast(t, s, alerts.Alert{ID: "eth", Type: alerts.TypeSpread, Symbol: "ETHUSDT"})
+	broadcast(t, s, alerts.Alert{ID: "live", Type: alerts.TypeSpread, Symbol: "BTCUSDT"})
+	close(blocking.release)
+
+	conn := <-connected
+	if conn == nil {
+		t.FailNow()
+	}
+	defer conn.Close()
+	// The held copy of an alert the replay sent is not sent again.
+	var got []string
+	for len(got) < 4 {
+		msg := read(t, conn)
+		if msg.Type == ReplyReplay {
+			got = append(got, msg.Type)
+		} else {
+			got = append(got, msg.ID)
+		}
+	}
+	if want := []string{recorded[1].ID, recorded[2].ID, ReplyReplay, "live"}; !slices.Equal(got, want) {
+		t.Errorf("received %v, want %v", got, want)
+	}
+
+	// Once the replay is over, alerts go straight out.
+	broadcast(t, s, alerts.Alert{ID: "after", Type: alerts.TypeSpread, Symbol: "BTCUSDT"})
+	if msg := read(t, conn); msg.ID != "after" {
+		t.Errorf("got %+v, want alert after", msg)
+	}
+}
+
+// serverConn returns both ends of a WebSocket connection, the server's
+// first, with no pumps running on either.
+func serverConn(t *testing.T) (*websocket.Conn, *websocket.Conn) {
+	t.Helper()
+	conns := make(chan *websocket.Conn, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var upgrader websocket.Upgrader
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(ts.Close)
+	client := dial(t, "ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	server := <-conns
+	t.Cleanup(func() { _ = server.Close() })
+	return server, client
+}
+
+func TestSlowConsumerPolicy(t *testing.T) {
+	tests := []struct {
+		policy          string
+		wantQueued      []string
+		wantClosed      bool
+		wantDisconnects int64
+	}{
+		{policy: PolicyDropOldest, wantQueued: []string{"2", "3"}},
+		{policy: PolicyDisconnect, wantQueued: []string{"1", "2"}, wantClosed: true, wantDisconnects: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			s := NewServer("", Options{QueueSize: 2, SlowConsumerPolicy: tt.policy})
+			conn, remote := serverConn(t)
+			c := &client{conn: conn, send: make(chan []byte, s.options.QueueSize), done: make(chan struct{})}
+			s.addClient(c)
+
+			for _, payload := range []string{"1", "2", "3"} {
+				s.enqueue(c, []byte(payload))
+			}
+
+			var queued []string
+			for len(c.send) > 0 {
+				queued = append(queued, string(<-c.send))
+			}
+			if !slices.Equal(queued, tt.wantQueued) {
+				t.Errorf("queued %v, want %v", queued, tt.wantQueued)
+			}
+			select {
+			case <-c.done:
+				if !tt.wantClosed {
+					t.Error("client closed")
+				}
+				_ = remote.SetReadDeadline(time.Now().Add(5 * time.Second))
+				if _, _, err := remote.ReadMessage(); err == nil {
+					t.Error("connection still open")
+				}
+			default:
+				if tt.wantClosed {
+					t.Error("client not closed")
+				}
+			}
+
+			stats := s.Stats()
+			if stats.Dropped != 1 || stats.SlowDisconnects != tt.wantDisconnects {
+				t.Errorf("stats = %+v", stats)
+			}
+			rec := httptest.NewRecorder()
+			s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			if body := rec.Body.String(); !strings.Contains(body, "\nwsserver_messages_dropped_total 1\n") {
+				t.Errorf("metrics:\n%s", body)
+			}
+		})
+	}
+}
+
+func TestKeepalive(t *testing.T) {
+	s, url := newTestServer(t, Options{PingInterval: 50 * time.Millisecond})
+
+	// Reading answers pings; a client that never reads sends no pongs.
+	live := dial(t, url+"/ws", nil)
+	messages := make(chan message, 8)
+	go func() {
+		defer close(messages)
+		for {
+			var msg message
+			if err := live.ReadJSON(&msg); err != nil {
+				return
+			}
+			messages <- msg
+		}
+	}()
+	dial(t, url+"/ws", nil)
+	waitFor(t, "both clients", func() bool { return s.Stats().Connections == 2 })
+
+	waitFor(t, "the silent client to be dropped", func() bool { return s.Stats().Clients == 1 })
+	time.Sleep(300 * time.Millisecond)
+	send(t, live, Request{ID: "1", Action: ActionSnapshot})
+	select {
+	case msg, ok := <-messages:
+		if !ok || msg.Type != ReplySnapshot {
+			t.Fatalf("live client got %+v, %v", msg, ok)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("live client got no reply")
+	}
+	if got := s.Stats().Clients; got != 1 {
+		t.Errorf("%d clients connected, want 1", got)
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after a while.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}